@@ -0,0 +1,14 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// detachedProcessFlag is DETACHED_PROCESS, which syscall does not define.
+const detachedProcessFlag = 0x00000008
+
+// detachedProcess has the agent run without a console and in a process group
+// of its own, so it outlives the console it was started from.
+func detachedProcess() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcessFlag}
+}
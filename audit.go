@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
+)
+
+// auditRecord is one line of the audit log. The token is never recorded.
+type auditRecord struct {
+	Time           time.Time `json:"time"`
+	User           string    `json:"user,omitempty"`
+	Host           string    `json:"host"`
+	App            string    `json:"app,omitempty"`
+	AppID          int64     `json:"app_id"`
+	InstallationID int64     `json:"installation_id"`
+	Argv           []string  `json:"argv"`
+	ExitCode       int       `json:"exit_code"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// auditLog appends records to the audit log file.
+type auditLog struct {
+	path string
+	cfg  *config.Config
+}
+
+// openAuditLog returns the audit log cfg configures, or nil when it is off.
+// The file is created up front so a command never runs unaudited because
+// the log cannot be written.
+func openAuditLog(cfg *config.Config) (*auditLog, error) {
+	path, err := cfg.AuditLogFile()
+	if err != nil || path == "" {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	_ = f.Close()
+	return &auditLog{path: path, cfg: cfg}, nil
+}
+
+// record appends a line for a command run for installationID. It is a
+// no-op on a nil log.
+func (l *auditLog) record(installationID int64, argv []string, code int, runErr error) error {
+	if l == nil {
+		return nil
+	}
+	host := l.cfg.Host()
+	if host == "" {
+		host = config.DefaultHost
+	}
+	rec := auditRecord{
+		Time:           time.Now().UTC(),
+		Host:           host,
+		App:            l.cfg.AppName(),
+		AppID:          l.cfg.AppID,
+		InstallationID: installationID,
+		Argv:           argv,
+		ExitCode:       code,
+	}
+	if u, err := user.Current(); err == nil {
+		rec.User = u.Username
+	}
+	if runErr != nil {
+		rec.Error = runErr.Error()
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encoding audit record: %w", err)
+	}
+
+	unlock, err := fsutil.Lock(l.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_AuditLog(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_audited", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte("#!/bin/sh\nexit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	logPath := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	saveTestConfig(t, &config.Config{
+		AppID:        1,
+		AuditLogPath: logPath,
+		Hosts:        map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	// Auditing runs gh as a child so its exit code can be recorded.
+	for range 2 {
+		if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "--installation-id", "7", "pr", "list"}, ""); code != 3 {
+			t.Fatalf("exit code = %d, want 3; stderr = %s", code, stderr)
+		}
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ghs_audited") {
+		t.Fatal("audit log contains the token")
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log has %d lines, want 2:\n%s", len(lines), data)
+	}
+	var rec auditRecord
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Host != "ghe.test" || rec.AppID != 1 || rec.InstallationID != 7 || rec.ExitCode != 3 ||
+		strings.Join(rec.Argv, " ") != "gh pr list" || rec.Time.IsZero() {
+		t.Errorf("record = %+v", rec)
+	}
+	if info, err := os.Stat(logPath); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %o, want 600", info.Mode().Perm())
+	}
+}
+
+func TestRun_AuditLogUnwritable(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	// A directory in place of the log file cannot be opened for appending.
+	logPath := t.TempDir()
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 7, AuditLogPath: logPath})
+
+	_, stderr, code := runCmd(t, []string{"gha", "pr", "list"}, "")
+	if code != 1 || !strings.Contains(stderr, "opening audit log") {
+		t.Errorf("code = %d, stderr = %s; want the command refused", code, stderr)
+	}
+}
@@ -2,15 +2,21 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/haribote-lab/github-app-cli/internal/auth"
 	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/installcache"
 	"github.com/haribote-lab/github-app-cli/internal/proxy"
 	"github.com/haribote-lab/github-app-cli/internal/update"
 )
@@ -18,27 +24,220 @@ import (
 // Set via -ldflags "-X main.version=..."
 var version = "dev"
 
+// progName is how gha was invoked: "gha", or "gh <name>" when gh runs it as
+// the extension gh-<name>.
+var progName = "gha"
+
+// invokedAs returns progName for a program invoked as arg0.
+func invokedAs(arg0 string) string {
+	name := strings.TrimSuffix(filepath.Base(arg0), ".exe")
+	if ext, ok := strings.CutPrefix(name, "gh-"); ok && ext != "" {
+		return "gh " + ext
+	}
+	return "gha"
+}
+
 func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (exitCode int) {
+	progName = invokedAs(args[0])
+	// Docker runs the credential helper configured as "gha" by the name
+	// docker-credential-gha.
+	if isDockerCredentialHelper(args[0]) {
+		args = append([]string{args[0], "docker-credential"}, args[1:]...)
+	}
+	if len(args) > 1 {
+		globals, rest, err := extractGlobalFlags(args[1:])
+		if err != nil {
+			return reportError(stderr, err)
+		}
+		if f := globals.errorFormat; f != "" && f != "text" && f != "json" {
+			return reportError(stderr, usageErrorf("--error-format must be text or json, got %q", f))
+		}
+		// Global flags are passed on through the environment: commands read
+		// it through config.Path and loadConfig, and it also reaches gh and
+		// nested gha invocations.
+		for env, value := range globals.env() {
+			if err := os.Setenv(env, value); err != nil {
+				return reportError(stderr, err)
+			}
+		}
+		expanded, err := expandAlias(rest)
+		if err != nil {
+			return reportError(stderr, err)
+		}
+		args = append(args[:1:1], expanded...)
+	}
+	logOut, err := logWriter(stderr)
+	if err != nil {
+		return reportError(stderr, err)
+	}
+	stderr, logOutput = logOut, logOut
+	defer func() { logOutput = os.Stderr }()
+	closeDebugLog, err := openDebugLog(stderr)
+	if err != nil {
+		return reportError(stderr, err)
+	}
+	defer closeDebugLog()
+
 	if len(args) < 2 {
 		printUsage(stdout)
-		return 1
+		return exitUsage
+	}
+
+	// "gha cache clear|status" manage gha's own caches, but gh cache list and
+	// delete manage Actions caches and go to gh.
+	if args[1] == "cache" && len(args) > 2 && cacheCommands[args[2]] {
+		if err := runCache(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+		return 0
 	}
 
 	switch args[1] {
 	case "configure":
 		if err := runConfigure(stdin, stderr); err != nil {
-			fmt.Fprintf(stderr, "error: %v\n", err)
+			return reportError(stderr, err)
+		}
+	case "config":
+		if err := runConfig(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "doctor":
+		if err := runConfigCheck(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "reset", "logout":
+		if err := runReset(args[1], args[2:], stdin, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "alias":
+		if err := runAlias(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "jwt":
+		if err := runJWT(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "direnv":
+		if err := runDirenv(args[2:], stdout); err != nil {
+			return reportError(stderr, err)
+		}
+	case "git-credential":
+		if err := runGitCredential(args[2:], stdin, stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "foreach":
+		if err := runForeach(args[2:], stdin, stdout, stderr); err != nil {
+			var exitErr *proxy.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.Code
+			}
+			return reportError(stderr, err)
+		}
+	case "docker-credential":
+		if err := runDockerCredential(args[2:], stdin, stdout); err != nil {
+			return reportError(stderr, err)
+		}
+	case "docker-login":
+		if err := runDockerLogin(args[2:], stdout, stderr); err != nil {
+			var exitErr *proxy.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.Code
+			}
+			return reportError(stderr, err)
+		}
+	case "setup-npm":
+		if err := runSetupNpm(args[2:], stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "setup-maven":
+		if err := runSetupMaven(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "setup-gradle":
+		if err := runSetupGradle(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "setup-go":
+		if err := runSetupGo(args[2:], stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "clone":
+		if err := runClone(args[2:], stdin, stdout, stderr); err != nil {
+			var exitErr *proxy.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.Code
+			}
+			return reportError(stderr, err)
+		}
+	case "serve":
+		if err := runServe(args[2:], stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "agent":
+		if err := runAgent(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "setup-git":
+		if err := runSetupGit(args[2:], stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "revoke-token":
+		if err := runRevokeToken(args[2:], stdin, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "installations", "installation":
+		if err := runInstallations(args[2:], stdout); err != nil {
+			return reportError(stderr, err)
+		}
+	case "app":
+		if err := runApp(args[2:], stdin, stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "install":
+		if err := runInstall(args[2:], stdin, stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "self-update":
+		if err := runSelfUpdate(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case "completion":
+		if err := runCompletion(args[2:], stdout); err != nil {
+			return reportError(stderr, err)
+		}
+	case "help":
+		if err := runHelp(args[2:], stdout); err != nil {
+			return reportError(stderr, err)
+		}
+	case "generate-docs":
+		if err := runGenerateDocs(args[2:], stdout, stderr); err != nil {
+			return reportError(stderr, err)
+		}
+	case completeCommand:
+		if err := runComplete(args[2:], stdout); err != nil {
+			return reportError(stderr, err)
+		}
+	case updateCheckCommand:
+		if err := refreshUpdateCheck(); err != nil {
 			return 1
 		}
 	case "--version", "-v":
-		fmt.Fprintf(stdout, "gha %s\n", version)
+		fmt.Fprintf(stdout, "%s %s\n", progName, version)
 	case "--help", "-h":
 		printUsage(stdout)
 	default:
 		checkForUpdate(stderr)
-		if err := runProxy(args[1:]); err != nil {
-			fmt.Fprintf(stderr, "error: %v\n", err)
-			return 1
+		err := runProxy(args[1:], stdin, stdout, stderr)
+		if errors.Is(err, config.ErrNotFound) && offerWizard(stdin, stderr) {
+			err = runProxy(args[1:], stdin, stdout, stderr)
+		}
+		if err != nil {
+			// gh already reported the failure; pass its exit code on.
+			var exitErr *proxy.ExitError
+			if errors.As(err, &exitErr) {
+				return exitErr.Code
+			}
+			return reportError(stderr, err)
 		}
 	}
 
@@ -46,41 +245,107 @@ func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) (ex
 }
 
 func printUsage(w io.Writer) {
-	fmt.Fprint(w, `gha - proxy gh commands with GitHub App authentication
+	fmt.Fprint(w, strings.ReplaceAll(usage, "gha ", progName+" "))
+}
+
+const usage = `gha - proxy gh commands with GitHub App authentication
 
 Usage:
   gha configure                          Set up GitHub App credentials
+  gha config get|set|unset|list          Read or edit gha's config non-interactively
+  gha config edit                        Edit the config in $EDITOR and validate it on save
+  gha config check [--json]              Validate the config, key, and App credentials
+  gha doctor [--json]                    Same as gha config check
+  gha config encrypt|decrypt             Encrypt the config at rest, or undo it
+  gha config convert <yaml|toml|json>    Rewrite the config file in another format
+  gha config schema                      Print a JSON Schema for the config file
+  gha reset|logout [--force]             Remove the config file and all caches
+  gha cache clear                        Clear cached installation IDs and update checks
+  gha cache status [--json]              Show cached installation IDs, expiry, and update checks
+  gha alias set|list|delete              Manage command aliases
+  gha jwt [--decode] [--plain]           Print a freshly signed App JWT
+  gha shell                              Start a shell with the installation token loaded
+  gha status [--json]                    Show the App, key and installation in effect, and why
+  gha permissions show [--json]          Show the permissions and repositories a token is granted
+  gha revoke-token [--env] [<token>|-]   Revoke an installation token that may have leaked
+  gha env [--shell SHELL] [--format F]   Print the token, or Terraform's variables, as shell exports
+  gha env --output-file PATH             Append the variables to an env file such as $GITHUB_ENV
+  gha token [--github-actions]           Print the token, or set it as a GitHub Actions step output
+  gha direnv hook                        Print the "use gha" function for direnv
+  gha foreach --all|--orgs A,B -- ARGS   Run a gh command once per installation
+  gha serve [--listen ADDR]              Serve installation tokens over HTTP to other tools
+  gha agent start|stop|status            Keep the key loaded and hand tokens to commands, like ssh-agent
+  gha clone <owner>/<repo> [<dir>]       Clone a repository with a token scoped to it
+  gha setup-git [--remove]               Have git fetch credentials for the App's hosts from gha
+  gha setup-npm [--scope @OWNER]         Point npm at GitHub Packages with an App token
+  gha setup-maven [--env]                Print a settings.xml for GitHub Packages with an App token
+  gha setup-gradle                       Print gradle.properties for GitHub Packages with an App token
+  gha setup-go [--private PATTERNS]      Let go fetch private modules with App credentials
+  gha git-credential <get|store|erase>   Git credential helper for setup-git and --refresh
+  gha docker-credential <get|...>        Docker credential helper for ghcr.io (as docker-credential-gha)
+  gha docker-login [--print]             Log docker in to ghcr.io with an installation token
+  gha install [--org ORG] [--wait]       Install the App on an account via the browser
+  gha installations repos <id>           List repositories an installation can access
+  gha installation check <owner>/<repo>  Check whether the App is installed on a repository
+  gha app create <manifest.yaml>         Create a new App from a manifest and configure gha
+  gha app show [--json]                  Show the App's owner, permissions, and events
+  gha app deliveries list|redeliver      Inspect and retry webhook deliveries
+  gha app hook-config get|set            View or change the App's webhook URL and secret
+  gha app hook ping                      Send a ping delivery and report the result
   gha [flags] <gh subcommand>            Proxy any gh command with App token
+  gha [flags] exec <command> [args...]   Run any command with App token in GH_TOKEN
+  gha self-update [--prerelease]         Replace gha with the latest release
+  gha completion <bash|zsh|fish>         Print a shell completion script
+  gha generate-docs --format FORMAT      Generate the man page or a markdown reference
   gha --version                          Show version
+  gha help [topic]                       Show this help, or help on a topic
   gha --help                             Show this help
 
 Flags:
   --installation-id <id>    Use specific installation (overrides config & env)
   --org <name>              Resolve installation by org/user name
-
-Environment Variables:
-  GHA_INSTALLATION_ID       Installation ID (overrides config, overridden by flags)
-  GHA_ORG                   Org/user name to resolve (overrides config, overridden by flags)
-
-Resolution Order (highest to lowest precedence):
-  1. --installation-id / --org flag
-  2. GHA_INSTALLATION_ID / GHA_ORG environment variable
-  3. installation_id in config.yaml
-  4. Auto-detect (works only with single installation)
+  --repo <owner/name>       Resolve installation by repository (before the gh subcommand)
+  --target-type <org|user>  Only match --org / GHA_ORG against this account type
+  --config <path>           Use this config file instead of the default
+  --hostname <host>         Target this host (sets GH_HOST for gh too)
+  --app <name>              Use the App configured under apps.<name>
+  --dry-run                 Print the resolved App, installation and command; do not run it
+  --isolated                Give gh a throwaway config directory instead of yours
+  --refresh                 Keep the token fresh for commands running over an hour
+  --skip-version-check      Do not check gh against min_gh_version
+  --show-rate-limit         Print the installation's remaining rate limit after the command
+  --revoke                  Revoke the token as soon as the command exits
+  --insecure-key-perms      Use a private key other users can read, with a warning
+  -q, --quiet               Suppress the update notice and informational messages
+  --debug                   Trace App/installation resolution and API calls to stderr
+  --log-format <text|json>  Write gha's own messages on stderr as text or JSON records
+  --error-format <format>   Report a failure as text or as JSON with an error code
+  --no-color                Do not color output, for gha or gh
 
 Examples:
   gha configure
   gha pr list
   gha --org myorg repo list
+  gha --repo myorg/myrepo pr list
   gha --installation-id 12345 issue create --title "Bug"
   GHA_ORG=myorg gha pr list
 
-Configuration is stored in ~/.config/github-app-cli/config.yaml
-`)
-}
+Help Topics:
+  auth                      How gha authenticates as the App and what the token can do
+  resolution                How the App and the installation are chosen
+  environment               Environment variables gha reads
+  config                    Where the config lives and what gha config manages
+  exit-codes                What gha's exit status means
+
+Run gha help <topic> for one of these, or gh help <command> for gh's own
+commands.
+`
 
 func runConfigure(stdin io.Reader, stderr io.Writer) error {
 	reader := bufio.NewReader(stdin)
+	if isTerminal(stdin) {
+		return runWizard(reader, stderr)
+	}
 
 	appIDStr, err := prompt(reader, stderr, "GitHub App ID: ")
 	if err != nil {
@@ -125,6 +390,11 @@ func runConfigure(stdin io.Reader, stderr io.Writer) error {
 	if !info.Mode().IsRegular() {
 		return fmt.Errorf("private key path is not a regular file: %s", keyPath)
 	}
+	// Relative paths in the config are resolved against its directory, so
+	// store where the key is as seen from here.
+	if abs, err := filepath.Abs(keyPath); err == nil {
+		keyPath = abs
+	}
 
 	cfg := &config.Config{
 		AppID:          appID,
@@ -136,8 +406,8 @@ func runConfigure(stdin io.Reader, stderr io.Writer) error {
 		return fmt.Errorf("saving config: %w", err)
 	}
 
-	dir, _ := config.Dir()
-	fmt.Fprintf(stderr, "Configuration saved to %s/config.yaml\n", dir)
+	path, _ := config.Path()
+	infof(stderr, "Configuration saved to %s\n", path)
 	return nil
 }
 
@@ -150,30 +420,297 @@ func prompt(reader *bufio.Reader, w io.Writer, msg string) (string, error) {
 	return strings.TrimSpace(line), nil
 }
 
+// loadConfig returns the configuration from GHA_APP_ID and friends when set,
+// and from the config file otherwise, narrowed to the host selected by
+// --hostname / GH_HOST and the App selected by --app / GHA_APP.
+func loadConfig() (*config.Config, error) {
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg, err = cfg.ForApp(os.Getenv(appEnv)); err != nil {
+		return nil, &configError{err}
+	}
+	return cfg, nil
+}
+
+// loadHostConfig is loadConfig without the App selection, leaving it to the
+// caller.
+func loadHostConfig() (*config.Config, error) {
+	cfg, err := loadBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg, err = cfg.ForHost(os.Getenv(ghHostEnv)); err != nil {
+		return nil, &configError{err}
+	}
+	return cfg, nil
+}
+
+// loadBaseConfig loads the configuration from the environment or the config
+// file, before any host or App is selected from it.
+func loadBaseConfig() (*config.Config, error) {
+	cfg, err := config.FromEnv()
+	if err != nil {
+		return nil, &configError{err}
+	}
+	if cfg != nil {
+		debugf("config: from %s", config.AppIDEnv)
+		return cfg, nil
+	}
+	if cfg, err = config.Load(); err != nil {
+		return nil, &configError{err}
+	}
+	if path, err := config.Path(); err == nil {
+		debugf("config: %s", path)
+	}
+	return cfg, nil
+}
+
+// apiOptions points API calls at the configured host's API.
+func apiOptions(cfg *config.Config) []auth.Option {
+	opts := debugAPIOptions()
+	if cfg.APIURL() != "" {
+		opts = append(opts, auth.WithBaseURL(cfg.APIURL()))
+	}
+	return opts
+}
+
+// webURL returns the web root of the configured host.
+func webURL(cfg *config.Config) string {
+	if host := cfg.Host(); host != "" {
+		return "https://" + host
+	}
+	return githubWebURL
+}
+
+// cacheDir returns the directory holding the caches of a config scope (see
+// config.Config.Scope). The top-level scope uses the config directory
+// itself, so existing caches stay valid.
+func cacheDir(scope string) (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	if scope == "" {
+		return dir, nil
+	}
+	return filepath.Join(dir, filepath.FromSlash(scope)), nil
+}
+
+// signJWT signs a JWT for the configured App, once checkPermissions allows
+// its key to be used.
+func signJWT(cfg *config.Config) (string, error) {
+	if err := checkPermissions(cfg, logOutput); err != nil {
+		return "", &configError{err}
+	}
+	return generateJWT(cfg)
+}
+
+// generateJWT signs a JWT for the configured App without checking who else
+// can read its key.
+func generateJWT(cfg *config.Config) (string, error) {
+	var jwtToken string
+	var err error
+	if cfg.PrivateKey != "" {
+		// Go strings cannot be wiped, but this copy of the key can.
+		keyData := []byte(cfg.PrivateKey)
+		jwtToken, err = auth.GenerateJWTFromPEM(cfg.AppID, keyData)
+		clear(keyData)
+	} else {
+		jwtToken, err = auth.GenerateJWT(cfg.AppID, cfg.KeyPath())
+	}
+	if err != nil {
+		return "", &configError{fmt.Errorf("generating JWT: %w", err)}
+	}
+	return jwtToken, nil
+}
+
+// insecureKeyPermsEnv, set by --insecure-key-perms, lets gha use a private
+// key other users can read, with a warning.
+const insecureKeyPermsEnv = "GHA_INSECURE_KEY_PERMS"
+
+// checkPermissions refuses a private key other users can read or another
+// user owns, as ssh does, unless --insecure-key-perms is in effect, and warns
+// on w about it and about a config directory other users can access. With
+// strict_permissions set, any of them is refused.
+func checkPermissions(cfg *config.Config, w io.Writer) error {
+	problems := cfg.PermissionProblems()
+	if len(problems) == 0 {
+		return nil
+	}
+	if cfg.StrictPermissions {
+		return fmt.Errorf("%s (strict_permissions is set)", strings.Join(problems, "; "))
+	}
+	if p := cfg.KeyProblem(); p != "" && !envBool(insecureKeyPermsEnv) {
+		return fmt.Errorf("%s, or put --insecure-key-perms before the command to use it anyway", p)
+	}
+	for _, p := range problems {
+		fmt.Fprintf(w, "warning: %s\n", p)
+	}
+	return nil
+}
+
+// loadJWT loads the configuration and signs a JWT for the configured App.
+func loadJWT() (*config.Config, string, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	jwtToken, err := signJWT(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, jwtToken, nil
+}
+
+// noUpdateCheckEnv turns off the update check, like disable_update_check.
+const noUpdateCheckEnv = "GHA_NO_UPDATE_CHECK"
+
 func checkForUpdate(w io.Writer) {
+	// As a gh extension, gh itself offers gh extension upgrade.
+	if envBool(quietEnv) || envBool(noUpdateCheckEnv) || progName != "gha" || inCI() {
+		return
+	}
+	// A broken config is reported by the command itself.
+	cfg, _ := config.LoadPartial()
+	if cfg != nil && cfg.DisableUpdateCheck {
+		return
+	}
 	dir, err := config.Dir()
 	if err != nil {
 		return
 	}
-	if result := update.Check(version, dir); result != nil {
-		fmt.Fprint(w, update.FormatNotice(result))
+	// The notice comes from the cache, once per release; a stale cache is
+	// refreshed in the background, so the command never waits on GitHub.
+	opts := updateOptions(cfg, false)
+	if cfg != nil && cfg.UpdateNoticeInterval != "" {
+		if interval, err := time.ParseDuration(cfg.UpdateNoticeInterval); err == nil {
+			opts = append(opts, update.WithRemindInterval(interval))
+		}
 	}
+	if result := update.Notify(version, dir, opts...); result != nil {
+		notice := strings.TrimSuffix(update.FormatNotice(result, installMethod()), "\n")
+		fmt.Fprintln(w, paint(w, colorYellow, notice))
+	}
+	if update.Due(version, dir, opts...) {
+		_ = startUpdateCheck()
+	}
+}
+
+// ciEnvVars are set by CI systems; any of them being non-empty means gha
+// runs in CI, where upgrade notices only clutter logs.
+var ciEnvVars = []string{
+	"GITHUB_ACTIONS", "GITLAB_CI", "CIRCLECI", "TRAVIS", "BUILDKITE", "JENKINS_URL",
+	"TF_BUILD", "TEAMCITY_VERSION", "BITBUCKET_BUILD_NUMBER", "CODEBUILD_BUILD_ID", "DRONE",
+}
+
+// inCI reports whether gha runs in a CI job: CI is set to anything but a
+// false value, or a CI system's own variable is set.
+func inCI() bool {
+	if v := os.Getenv("CI"); v != "" {
+		if b, err := strconv.ParseBool(v); err != nil || b {
+			return true
+		}
+	}
+	for _, name := range ciEnvVars {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// installMethod reports how the running gha was installed.
+func installMethod() update.InstallMethod {
+	exe, err := executable()
+	if err != nil {
+		return update.InstallManual
+	}
+	return update.DetectInstall(exe)
+}
+
+// updateCheckCommand is the hidden command a background gha runs to
+// refresh the update-check cache.
+const updateCheckCommand = "__update-check"
+
+// startUpdateCheck starts gha updateCheckCommand without waiting for it;
+// tests replace it.
+var startUpdateCheck = func() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, updateCheckCommand)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// refreshUpdateCheck fetches the latest release into the update-check cache
+// for the next command's notice.
+func refreshUpdateCheck() error {
+	cfg, _ := config.LoadPartial()
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	return update.Refresh(dir, updateOptions(cfg, false)...)
+}
+
+// updateOptions returns the options for the update check and self-update:
+// cfg's release mirror, if any, and the prerelease channel when prerelease
+// is set or cfg selects it. cfg may be nil.
+func updateOptions(cfg *config.Config, prerelease bool) []update.Option {
+	var opts []update.Option
+	if cfg != nil && cfg.UpdateBaseURL != "" {
+		opts = append(opts, update.WithBaseURL(cfg.UpdateBaseURL))
+	}
+	if cfg != nil && cfg.Channel == config.ChannelPrerelease {
+		prerelease = true
+	}
+	return append(append(opts, releaseOptions...), update.WithPrerelease(prerelease))
 }
 
 // installationOverride holds per-command installation selection parsed from flags or env vars.
 type installationOverride struct {
-	id  int64
-	org string
+	id         int64
+	org        string
+	repo       string
+	targetType string
 }
 
-// parseInstallationFlags extracts --installation-id and --org from args,
-// returning the override and the remaining args to pass to gh.
+// installationSources holds every input resolveInstallation chooses between.
+type installationSources struct {
+	scope      string // config scope whose caches and map are used
+	flag       installationOverride
+	env        installationOverride
+	project    installationOverride // from the nearest .gha.yaml
+	gitRepo    string
+	configID   int64
+	targetType string // restricts org lookups to "org" or "user" accounts
+
+	// installations is the config's login → installation ID map.
+	installations map[string]int64
+}
+
+// parseInstallationFlags extracts --installation-id, --org, --target-type and
+// --repo given before the gh subcommand from args, returning the override
+// and the remaining args to pass to gh. Everything from the subcommand on is
+// passed on verbatim, since many gh subcommands take an --org or --repo flag
+// of their own. A "--" also ends gha's flags and is dropped.
 func parseInstallationFlags(args []string) (installationOverride, []string) {
 	var override installationOverride
 	var remaining []string
 
 	for i := 0; i < len(args); i++ {
 		switch {
+		case args[i] == "--":
+			return override, append(remaining, args[i+1:]...)
 		case args[i] == "--installation-id" && i+1 < len(args):
 			if id, err := strconv.ParseInt(args[i+1], 10, 64); err == nil && id > 0 {
 				override.id = id
@@ -189,6 +726,18 @@ func parseInstallationFlags(args []string) (installationOverride, []string) {
 			i++ // skip the value
 		case strings.HasPrefix(args[i], "--org="):
 			override.org = strings.TrimPrefix(args[i], "--org=")
+		case args[i] == "--target-type" && i+1 < len(args):
+			override.targetType = args[i+1]
+			i++ // skip the value
+		case strings.HasPrefix(args[i], "--target-type="):
+			override.targetType = strings.TrimPrefix(args[i], "--target-type=")
+		case args[i] == "--repo" && i+1 < len(args):
+			override.repo = args[i+1]
+			i++ // skip the value
+		case strings.HasPrefix(args[i], "--repo="):
+			override.repo = strings.TrimPrefix(args[i], "--repo=")
+		case !strings.HasPrefix(args[i], "-"):
+			return override, append(remaining, args[i:]...)
 		default:
 			remaining = append(remaining, args[i])
 		}
@@ -197,6 +746,255 @@ func parseInstallationFlags(args []string) (installationOverride, []string) {
 	return override, remaining
 }
 
+// ghHostEnv selects the GitHub host for gh, and for gha's own API calls.
+const ghHostEnv = "GH_HOST"
+
+// appEnv selects an App configured under apps: by name.
+const appEnv = "GHA_APP"
+
+// isolatedEnv, set by --isolated, gives the proxied command a throwaway gh
+// config directory.
+const isolatedEnv = "GHA_ISOLATED"
+
+// globalFlags holds the flags that apply to every gha command.
+type globalFlags struct {
+	config    string
+	hostname  string
+	app       string
+	logFormat string
+	dryRun    bool
+	isolated  bool
+	refresh   bool
+	quiet     bool
+	debug     bool
+
+	errorFormat      string
+	skipVersionCheck bool
+	showRateLimit    bool
+	revoke           bool
+	insecureKeyPerms bool
+	noColor          bool
+}
+
+// env returns the environment variables carrying the flags that were set.
+func (g globalFlags) env() map[string]string {
+	env := map[string]string{}
+	for name, value := range map[string]string{config.PathEnv: g.config, ghHostEnv: g.hostname, appEnv: g.app, logFormatEnv: g.logFormat} {
+		if value != "" {
+			env[name] = value
+		}
+	}
+	if g.errorFormat != "" {
+		env[errorJSONEnv] = strconv.FormatBool(g.errorFormat == "json")
+	}
+	for name, set := range map[string]bool{dryRunEnv: g.dryRun, isolatedEnv: g.isolated, refreshEnv: g.refresh, quietEnv: g.quiet, debugEnv: g.debug, skipVersionCheckEnv: g.skipVersionCheck, showRateLimitEnv: g.showRateLimit, revokeEnv: g.revoke, insecureKeyPermsEnv: g.insecureKeyPerms, noColorEnv: g.noColor} {
+		if set {
+			env[name] = "1"
+		}
+	}
+	return env
+}
+
+// quietEnv, set by --quiet, suppresses the update notice and informational
+// messages on stderr. Errors, warnings and prompts are still shown.
+const quietEnv = "GHA_QUIET"
+
+// infof writes an informational message to w unless --quiet is in effect.
+func infof(w io.Writer, format string, a ...any) {
+	if !envBool(quietEnv) {
+		fmt.Fprintf(w, format, a...)
+	}
+}
+
+// envBool reports whether the environment variable name is set to a true
+// value, such as 1 or true.
+func envBool(name string) bool {
+	v, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && v
+}
+
+// extractGlobalFlags removes --config, --hostname, --app, --log-format and
+// --error-format (in either the "--flag value" or "--flag=value" form),
+// --dry-run, --isolated, --refresh, --quiet/-q, --debug, --skip-version-check,
+// --show-rate-limit, --revoke, --insecure-key-perms and --no-color given
+// before the command from args. Other leading gha flags and their values are
+// kept.
+func extractGlobalFlags(args []string) (globals globalFlags, rest []string, err error) {
+	targets := map[string]*string{"--config": &globals.config, "--hostname": &globals.hostname, "--app": &globals.app, "--log-format": &globals.logFormat, "--error-format": &globals.errorFormat}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		target, isGlobal := targets[name]
+		switch {
+		case arg == "--dry-run":
+			globals.dryRun = true
+		case arg == "--isolated":
+			globals.isolated = true
+		case arg == "--refresh":
+			globals.refresh = true
+		case arg == "--quiet" || arg == "-q":
+			globals.quiet = true
+		case arg == "--debug":
+			globals.debug = true
+		case arg == "--skip-version-check":
+			globals.skipVersionCheck = true
+		case arg == "--show-rate-limit":
+			globals.showRateLimit = true
+		case arg == "--revoke":
+			globals.revoke = true
+		case arg == "--insecure-key-perms":
+			globals.insecureKeyPerms = true
+		case arg == "--no-color":
+			globals.noColor = true
+		case isGlobal && hasValue:
+			*target = value
+		case isGlobal:
+			if i+1 >= len(args) {
+				return globalFlags{}, nil, usageErrorf("%s requires a value", arg)
+			}
+			*target = args[i+1]
+			i++ // skip the value
+		case arg == "--installation-id" || arg == "--org" || arg == "--repo" || arg == "--target-type":
+			rest = append(rest, arg)
+			if i+1 < len(args) {
+				rest = append(rest, args[i+1])
+				i++
+			}
+		case strings.HasPrefix(arg, "-") && arg != "--":
+			rest = append(rest, arg)
+		default:
+			// The command starts here; everything after it belongs to it.
+			return globals, append(rest, args[i:]...), nil
+		}
+	}
+	return globals, rest, nil
+}
+
+// parseRepo splits an "owner/repo" argument.
+func parseRepo(s string) (owner, repo string, err error) {
+	owner, repo, ok := strings.Cut(s, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", usageErrorf("invalid repository %q: expected <owner>/<repo>", s)
+	}
+	return owner, repo, nil
+}
+
+// repoFromGhArgs returns the OWNER/REPO named by a -R/--repo flag in gh's
+// arguments, dropping any [HOST/] prefix, or "" if there is none.
+func repoFromGhArgs(args []string) string {
+	var value string
+loop:
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			break loop
+		case (arg == "-R" || arg == "--repo") && i+1 < len(args):
+			value = args[i+1]
+			i++ // skip the value
+		case strings.HasPrefix(arg, "--repo="):
+			value = strings.TrimPrefix(arg, "--repo=")
+		case strings.HasPrefix(arg, "-R") && len(arg) > 2:
+			value = strings.TrimPrefix(arg[2:], "=")
+		}
+	}
+
+	parts := strings.Split(value, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.Join(parts[len(parts)-2:], "/")
+}
+
+// resolveInstallationByRepo finds the installation that covers owner/repo
+// without listing every installation of the App.
+func resolveInstallationByRepo(jwtToken string, fullName string, opts ...auth.Option) (int64, error) {
+	owner, repo, err := parseRepo(fullName)
+	if err != nil {
+		return 0, err
+	}
+
+	inst, err := auth.GetRepoInstallation(jwtToken, owner, repo, opts...)
+	if isNotFound(err) {
+		return 0, &notInstalledError{repo: fullName}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("looking up installation for %s: %w", fullName, err)
+	}
+	return inst.ID, checkSuspended(inst)
+}
+
+// isNotFound reports whether err is a 404 response from the GitHub API.
+func isNotFound(err error) bool {
+	var apiErr *auth.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// forgetCachedInstallation drops scope's cached org mappings to
+// installationID and reports whether there were any.
+func forgetCachedInstallation(scope string, installationID int64) bool {
+	dir, err := cacheDir(scope)
+	if err != nil {
+		return false
+	}
+	removed := installcache.Forget(dir, installationID)
+	if forgotten, err := config.ForgetInstallation(scope, installationID); err == nil && forgotten {
+		removed = true
+	}
+	return removed
+}
+
+// mintInstallationToken exchanges the JWT for an installation token. GitHub
+// refuses suspended installations with an opaque 403, so that case is turned
+// into a suspendedError explaining what to do. scope may be nil.
+func mintInstallationToken(jwtToken string, installationID int64, scope *auth.TokenRequest, opts ...auth.Option) (*auth.InstallationToken, error) {
+	token, err := auth.IssueInstallationToken(jwtToken, installationID, scope, opts...)
+	var apiErr *auth.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden {
+		if inst, lookupErr := auth.GetInstallation(jwtToken, installationID, opts...); lookupErr == nil {
+			if suspended := checkSuspended(inst); suspended != nil {
+				return nil, suspended
+			}
+		}
+	}
+	return token, err
+}
+
+// checkSuspended returns a suspendedError if the installation is suspended.
+func checkSuspended(inst *auth.Installation) error {
+	if inst.SuspendedAt == nil {
+		return nil
+	}
+	url := inst.HTMLURL
+	if url == "" {
+		url = fmt.Sprintf("%s/settings/installations/%d", githubWebURL, inst.ID)
+	}
+	return &suspendedError{id: inst.ID, since: *inst.SuspendedAt, url: url}
+}
+
+// suspendedError reports that an installation has been suspended by the
+// account owner and cannot issue tokens.
+type suspendedError struct {
+	id    int64
+	since time.Time
+	url   string
+}
+
+func (e *suspendedError) Error() string {
+	return fmt.Sprintf("installation %d is suspended since %s — unsuspend it at %s",
+		e.id, e.since.Format("2006-01-02"), e.url)
+}
+
+// notInstalledError reports that the App has no installation covering a
+// repository.
+type notInstalledError struct {
+	repo string
+}
+
+func (e *notInstalledError) Error() string {
+	return fmt.Sprintf("the GitHub App is not installed on %s (or the repository does not exist)", e.repo)
+}
+
 // resolveInstallationFromEnv reads GHA_INSTALLATION_ID and GHA_ORG environment variables.
 func resolveInstallationFromEnv() installationOverride {
 	var override installationOverride
@@ -212,66 +1010,500 @@ func resolveInstallationFromEnv() installationOverride {
 }
 
 // resolveInstallationByOrg finds the installation ID for a given org/user login.
-func resolveInstallationByOrg(jwtToken string, org string, opts ...auth.Option) (int64, error) {
+// Mappings are cached per config scope under the config directory, so only a
+// cache miss lists the App's installations. A successful match is also
+// remembered in the scope's installations map.
+//
+// targetType ("org", "user" or "" for either) restricts matches to that kind
+// of account.
+func resolveInstallationByOrg(jwtToken, scope, org, targetType string, opts ...auth.Option) (int64, error) {
+	apiTargetType, err := normalizeTargetType(targetType)
+	if err != nil {
+		return 0, err
+	}
+
+	cacheDir, cacheErr := cacheDir(scope)
+	if cacheErr == nil {
+		if id, ok := installcache.Lookup(cacheDir, org, apiTargetType); ok {
+			debugf("org %s: installation %d from the installation cache", org, id)
+			return id, nil
+		}
+	}
+	debugf("org %s: not cached, listing installations", org)
+
 	installations, err := auth.GetInstallations(jwtToken, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("listing installations: %w", err)
 	}
 
-	for _, inst := range installations {
-		if strings.EqualFold(inst.Account.Login, org) {
+	if cacheErr == nil {
+		mappings := make([]installcache.Mapping, 0, len(installations))
+		for _, inst := range installations {
+			mappings = append(mappings, installcache.Mapping{Login: inst.Account.Login, TargetType: inst.TargetType, ID: inst.ID})
+		}
+		installcache.Store(cacheDir, mappings)
+	}
+
+	for i, inst := range installations {
+		if strings.EqualFold(inst.Account.Login, org) && (apiTargetType == "" || inst.TargetType == apiTargetType) {
+			if err := checkSuspended(&installations[i]); err != nil {
+				return inst.ID, err
+			}
+			// Best effort: a missing or unwritable config only costs an API call next time.
+			_ = config.RememberInstallation(scope, inst.Account.Login, inst.ID)
 			return inst.ID, nil
 		}
 	}
 
-	available := make([]string, 0, len(installations))
-	for _, inst := range installations {
-		available = append(available, fmt.Sprintf("  %d (%s)", inst.ID, inst.Account.Login))
+	msg := fmt.Sprintf("no installation found for org %q, available", org)
+	if apiTargetType != "" {
+		msg = fmt.Sprintf("no %s installation found for %q, available", targetType, org)
+	}
+	return 0, &installationsError{code: "installation_not_found", msg: msg, installations: installations}
+}
+
+// normalizeTargetType maps the --target-type / target_type values "org" and
+// "user" to the target_type GitHub reports for installations.
+func normalizeTargetType(targetType string) (string, error) {
+	switch strings.ToLower(targetType) {
+	case "":
+		return "", nil
+	case "org", "organization":
+		return "Organization", nil
+	case "user":
+		return "User", nil
+	default:
+		return "", usageErrorf("invalid target type %q: must be org or user", targetType)
 	}
-	return 0, fmt.Errorf("no installation found for org %q, available:\n%s", org, strings.Join(available, "\n"))
 }
 
-func runProxy(args []string) error {
+func runProxy(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
 	// 1. Parse flags (highest precedence)
 	flagOverride, ghArgs := parseInstallationFlags(args)
+	// After a "--" ahead of the command, even exec, shell and env go to gh.
+	passthrough := commandIndex(args) < 0 && slices.Contains(args, "--")
 
-	// 2. Read env vars (middle precedence)
-	envOverride := resolveInstallationFromEnv()
+	// "gha exec <command>" runs any program with the token instead of gh.
+	var execArgs []string
+	if !passthrough && len(ghArgs) > 0 && ghArgs[0] == "exec" {
+		execArgs = ghArgs[1:]
+		if len(execArgs) > 0 && execArgs[0] == "--" {
+			execArgs = execArgs[1:]
+		}
+		if len(execArgs) == 0 {
+			return usageErrorf("usage: gha [flags] exec <command> [args...]")
+		}
+		ghArgs = nil
+	}
+
+	// "gha shell" starts an interactive shell with the token, as exec would.
+	shell := !passthrough && len(ghArgs) > 0 && ghArgs[0] == "shell"
+	if shell {
+		if len(ghArgs) > 1 {
+			return usageErrorf("usage: gha [flags] shell")
+		}
+		execArgs = []string{userShell()}
+		ghArgs = nil
+	}
+
+	// "gha env" prints the token for the current shell instead of running
+	// anything.
+	var envReq *envRequest
+	if !passthrough && len(ghArgs) > 0 && ghArgs[0] == "env" {
+		var err error
+		if envReq, err = parseEnvArgs(ghArgs[1:], &flagOverride, stderr); err != nil {
+			return err
+		}
+		ghArgs = nil
+	}
+
+	// "gha token" prints the token alone, or hands it to a workflow's later
+	// steps.
+	if !passthrough && len(ghArgs) > 0 && ghArgs[0] == "token" {
+		var err error
+		if envReq, err = parseTokenArgs(ghArgs[1:], &flagOverride, stderr); err != nil {
+			return err
+		}
+		ghArgs = nil
+	}
+
+	// "gha status" shows what a command would run as instead of running one.
+	if !passthrough && len(ghArgs) > 0 && ghArgs[0] == "status" {
+		asJSON, err := parseStatusArgs(ghArgs[1:], &flagOverride, stderr)
+		if err != nil {
+			return err
+		}
+		return runStatus(flagOverride, asJSON, stdout)
+	}
+
+	// "gha permissions show" mints a token to show exactly what it can do.
+	if !passthrough && len(ghArgs) > 0 && ghArgs[0] == "permissions" {
+		asJSON, err := parsePermissionsArgs(ghArgs[1:], &flagOverride, stderr)
+		if err != nil {
+			return err
+		}
+		return runPermissionsShow(flagOverride, asJSON, stdout)
+	}
+
+	// A -R/--repo given to gh identifies the installation as precisely as
+	// --repo does, so use it unless gha's own flags already chose one.
+	if flagOverride.id == 0 && flagOverride.org == "" && flagOverride.repo == "" {
+		flagOverride.repo = repoFromGhArgs(ghArgs)
+	}
+
+	// 2. Read env vars (middle precedence), config and .gha.yaml
+	sel, err := selectApp(flagOverride)
+	if err != nil {
+		return err
+	}
+	cfg, project, src, target := sel.cfg, sel.project, sel.src, sel.target
+	if checked, ok := policyArgs(ghArgs, execArgs); ok {
+		if err := checkPolicy(cfg.Policy, checked, !envBool(dryRunEnv), stdin, stderr); err != nil {
+			return err
+		}
+	}
+	scope := &auth.TokenRequest{Permissions: project.Permissions}
+	revoke := cfg.Revoke || envBool(revokeEnv)
+	opts := apiOptions(cfg)
+	var (
+		jwtToken       string
+		installationID int64
+		installToken   *auth.InstallationToken
+		fromAgent      bool
+	)
+	// Dry runs and the Terraform formats sign JWTs of their own. A token the
+	// command revokes is minted for it alone.
+	if !envBool(dryRunEnv) && (envReq == nil || !strings.HasPrefix(envReq.format, "terraform")) {
+		installationID, installToken, fromAgent = tokenFromAgent(cfg, src, scope, revoke, stderr)
+	}
+	if !fromAgent {
+		jwtToken, err = signJWT(cfg)
+		if err != nil {
+			return err
+		}
+
+		// 3. Resolve installation ID with precedence: flag > env > .gha.yaml > git remote > config > auto-detect
+		installationID, err = resolveInstallation(jwtToken, src, opts...)
+		if err != nil {
+			return err
+		}
+		debugf("installation: %d (%s)", installationID, src.origin(installationID))
+	}
+
+	if envBool(dryRunEnv) {
+		argv := append([]string{"gh"}, ghArgs...)
+		if execArgs != nil {
+			argv = execArgs
+		}
+		if envReq != nil {
+			argv = envReq.argv()
+		}
+		dryRun{
+			cfg:            cfg,
+			appSource:      sel.appSource,
+			installationID: installationID,
+			src:            src,
+			permissions:    project.Permissions,
+			argv:           argv,
+			isolated:       cfg.Isolated || envBool(isolatedEnv),
+			revoke:         revoke,
+		}.print(stdout)
+		return nil
+	}
 
-	cfg, err := config.Load()
+	if execArgs == nil && envReq == nil {
+		if err := checkGhVersion(cfg.MinGhVersion); err != nil {
+			return err
+		}
+	}
+	audit, err := openAuditLog(cfg)
 	if err != nil {
 		return err
 	}
 
-	jwtToken, err := auth.GenerateJWT(cfg.AppID, cfg.PrivateKeyPath)
+	if envReq != nil && envReq.format == "terraform-app" {
+		// The provider mints its own tokens from the App's key, so none is
+		// minted here.
+		return printTerraformAppEnv(*envReq, cfg, jwtToken, installationID, audit, opts, stdout, stderr)
+	}
+
+	if !fromAgent {
+		installToken, err = mintInstallationToken(jwtToken, installationID, scope, opts...)
+		if isNotFound(err) && forgetCachedInstallation(src.scope, installationID) {
+			// The cached mapping pointed at a removed installation; resolve afresh.
+			debugf("installation %d is gone; forgot it and resolving again", installationID)
+			for login, id := range src.installations {
+				if id == installationID {
+					delete(src.installations, login)
+				}
+			}
+			installationID, err = resolveInstallation(jwtToken, src, opts...)
+			if err != nil {
+				return err
+			}
+			installToken, err = mintInstallationToken(jwtToken, installationID, scope, opts...)
+		}
+		if err != nil {
+			return fmt.Errorf("getting installation token: %w", err)
+		}
+	}
+	if envReq != nil {
+		if err := audit.record(installationID, envReq.auditArgv(), 0, nil); err != nil {
+			return err
+		}
+		if envReq.command == "token" {
+			return printToken(*envReq, installationID, installToken, stdout, stderr)
+		}
+		token := installToken.Token
+		if !envReq.plain && envReq.outputFile == "" && isTerminal(stdout) {
+			token = maskToken(token)
+			infof(stderr, "gha: the token is masked on a terminal; pass --plain to print it, or run eval \"$(gha env)\"\n")
+		}
+		vars := tokenVars(cfg, token)
+		if envReq.format == "terraform" {
+			owner, err := installationOwner(jwtToken, installationID, opts)
+			if err != nil {
+				return err
+			}
+			vars = terraformTokenVars(cfg, owner, token)
+		}
+		return writeEnv(*envReq, vars, []string{token}, stdout, stderr)
+	}
+
+	hook := hookContext{installationID: installationID, appID: cfg.AppID, host: cfg.Host(), command: append([]string{"gh"}, ghArgs...)}
+	hook.org, _, _ = strings.Cut(target, "/")
+	if execArgs != nil {
+		hook.command = execArgs
+	}
+	if err := runHook("pre_run", cfg.Hooks.PreRun, hook.env(), stderr); err != nil {
+		return err
+	}
+
+	envOpts, cleanup, err := commandEnv(cfg)
 	if err != nil {
-		return fmt.Errorf("generating JWT: %w", err)
+		return err
+	}
+	defer cleanup()
+	isolated := cfg.Isolated || envBool(isolatedEnv)
+	if shell {
+		account := shellAccount(hook.org, src.installations, installationID)
+		envOpts = append(envOpts, proxy.WithEnv(shellEnv(account)...))
 	}
 
-	// 3. Resolve installation ID with precedence: flag > env > config > auto-detect
-	installationID, err := resolveInstallation(jwtToken, flagOverride, envOverride, cfg.InstallationID)
+	refresh := envBool(refreshEnv)
+	showRateLimit := envBool(showRateLimitEnv)
+	if cfg.Hooks.PostRun == "" && !isolated && !refresh && !showRateLimit && !revoke && audit == nil {
+		if execArgs != nil {
+			return proxy.ExecCommand(execArgs[0], execArgs[1:], installToken.Token, envOpts...)
+		}
+		return proxy.Exec(ghArgs, installToken.Token, envOpts...)
+	}
+
+	// A post_run hook, removing the isolated config directory afterwards,
+	// refreshing the token, reporting the rate limit, revoking the token or
+	// auditing the exit code needs gha to outlive the command, so run it as a
+	// child.
+	run := func(token string, extra ...proxy.Option) (int, error) {
+		runOpts := append(envOpts, extra...)
+		if execArgs != nil {
+			return proxy.RunCommand(execArgs[0], execArgs[1:], token, runOpts...)
+		}
+		return proxy.Run(ghArgs, token, runOpts...)
+	}
+	var minted mintedTokens
+	minted.add(installToken)
+	keys := newKeySource(cfg)
+	mint := func() (*auth.InstallationToken, error) {
+		if fromAgent {
+			pinned := installationSources{scope: src.scope, flag: installationOverride{id: installationID}}
+			if _, token, ok := tokenFromAgent(cfg, pinned, scope, revoke, stderr); ok {
+				minted.add(token)
+				return token, nil
+			}
+		}
+		jwtToken, err := keys.sign()
+		if err != nil {
+			return nil, err
+		}
+		token, err := mintInstallationToken(jwtToken, installationID, scope, opts...)
+		if err == nil {
+			minted.add(token)
+		}
+		return token, err
+	}
+	var code int
+	if refresh {
+		code, err = runRefreshing(installToken, mint, cfg.Host(), run, execArgs == nil && repeatable(ghArgs), stderr)
+	} else {
+		code, err = run(installToken.Token)
+	}
+	if auditErr := audit.record(installationID, hook.command, code, err); auditErr != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", auditErr)
+	}
+	if showRateLimit && err == nil {
+		reportRateLimit(stderr, installToken, mint, opts...)
+	}
+	if revoke {
+		minted.revoke(stderr, opts...)
+	}
 	if err != nil {
 		return err
 	}
+	if cfg.Hooks.PostRun != "" {
+		env := append(hook.env(), "GHA_HOOK_EXIT_CODE="+strconv.Itoa(code))
+		if err := runHook("post_run", cfg.Hooks.PostRun, env, stderr); err != nil {
+			fmt.Fprintf(stderr, "warning: %v\n", err)
+		}
+	}
+	if code != 0 {
+		return &proxy.ExitError{Code: code}
+	}
+	return nil
+}
+
+// selection is what a command runs as before its installation is resolved:
+// the App's config, why that App was picked, the org or repository the
+// command targets and where its installation may come from.
+type selection struct {
+	cfg       *config.Config
+	project   *config.Project
+	appSource string
+	target    string
+	src       installationSources
+}
+
+// selectApp reads the environment, the config, the nearest .gha.yaml and,
+// failing those, the git remote, and picks the App for a command given the
+// installation flags in flagOverride.
+func selectApp(flagOverride installationOverride) (*selection, error) {
+	envOverride := resolveInstallationFromEnv()
 
-	installToken, err := auth.GetInstallationToken(jwtToken, installationID)
+	cfg, err := loadHostConfig()
 	if err != nil {
-		return fmt.Errorf("getting installation token: %w", err)
+		return nil, err
 	}
 
-	return proxy.Exec(ghArgs, installToken)
+	project, err := loadProject()
+	if err != nil {
+		return nil, err
+	}
+	projectOverride := installationOverride{id: project.InstallationID, org: project.Org}
+
+	// Only consult the git remote when nothing more explicit was given.
+	var gitRepo string
+	if flagOverride.id == 0 && flagOverride.org == "" && flagOverride.repo == "" &&
+		envOverride == (installationOverride{}) && projectOverride == (installationOverride{}) {
+		gitRepo = gitRemoteRepo(cfg.Host())
+		debugf("git remote: %q", gitRepo)
+	}
+
+	// Pick the App: --app / GHA_APP > .gha.yaml > routes > top level.
+	appName, appSource := os.Getenv(appEnv), "from --app or "+appEnv
+	if appName == "" {
+		appName, appSource = project.App, "from .gha.yaml"
+	}
+	target := routeTarget(flagOverride, envOverride, projectOverride, gitRepo)
+	if appName == "" {
+		appName, appSource = cfg.Route(target), "routed from "+target
+	}
+	if appName == "" {
+		appSource = "top-level config"
+	}
+	debugf("app: %q (%s)", appName, appSource)
+	if cfg, err = cfg.ForApp(appName); err != nil {
+		return nil, &configError{err}
+	}
+
+	src := installationSources{
+		scope:      cfg.Scope(),
+		flag:       flagOverride,
+		env:        envOverride,
+		project:    projectOverride,
+		gitRepo:    gitRepo,
+		configID:   cfg.InstallationID,
+		targetType: cfg.TargetType,
+
+		installations: cfg.Installations,
+	}
+	if flagOverride.targetType != "" {
+		src.targetType = flagOverride.targetType
+	}
+	return &selection{cfg: cfg, project: project, appSource: appSource, target: target, src: src}, nil
+}
+
+// ghNoUpdateNotifierEnv turns off gh's update notice, so it does not compete
+// with gha's.
+const ghNoUpdateNotifierEnv = "GH_NO_UPDATE_NOTIFIER"
+
+// commandEnv returns the options shaping the environment of a command run
+// with cfg's token, and a cleanup func to call once it has exited.
+func commandEnv(cfg *config.Config) ([]proxy.Option, func(), error) {
+	opts := []proxy.Option{proxy.WithHost(cfg.Host()), proxy.WithTokenEnv(cfg.TokenEnv...), proxy.WithScrubEnv(cfg.ScrubEnv...)}
+	if muteGhUpdateNotifier(cfg) {
+		opts = append(opts, proxy.WithEnv(ghNoUpdateNotifierEnv+"=1"))
+	}
+	if !cfg.Isolated && !envBool(isolatedEnv) {
+		return opts, func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "gha-gh-config-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating isolated gh config directory: %w", err)
+	}
+	opts = append(opts, proxy.WithEnv("GH_CONFIG_DIR="+dir))
+	return opts, func() { _ = os.RemoveAll(dir) }, nil
+}
+
+// muteGhUpdateNotifier reports whether gha sets GH_NO_UPDATE_NOTIFIER for
+// the command: unless gh_update_notifier is set or the variable already is.
+func muteGhUpdateNotifier(cfg *config.Config) bool {
+	return !cfg.GhUpdateNotifier && os.Getenv(ghNoUpdateNotifierEnv) == ""
+}
+
+// routeTarget returns the org or owner/repo a command targets, for matching
+// against routing rules, in the same precedence as resolveInstallation.
+func routeTarget(flag, env, project installationOverride, gitRepo string) string {
+	for _, target := range []string{flag.repo, flag.org, env.org, project.org, gitRepo} {
+		if target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+// loadProject returns the .gha.yaml governing the current directory, or an
+// empty Project when there is none.
+func loadProject() (*config.Project, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return &config.Project{}, nil
+	}
+	project, _, err := config.FindProject(wd)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	if project == nil {
+		return &config.Project{}, nil
+	}
+	return project, nil
 }
 
 // resolveInstallation determines the installation ID using the precedence chain:
-// flag > env > config > auto-detect.
-func resolveInstallation(jwtToken string, flag, env installationOverride, configID int64) (int64, error) {
+// flag > env > .gha.yaml > git remote > config > auto-detect. A git remote
+// repository the App is not installed on falls through to the rest of the chain.
+func resolveInstallation(jwtToken string, src installationSources, opts ...auth.Option) (int64, error) {
+	flag, env := src.flag, src.env
+
 	// Flag --installation-id takes highest precedence
 	if flag.id > 0 {
 		return flag.id, nil
 	}
+	// Flag --repo
+	if flag.repo != "" {
+		return resolveInstallationByRepo(jwtToken, flag.repo, opts...)
+	}
 	// Flag --org
 	if flag.org != "" {
-		return resolveInstallationByOrg(jwtToken, flag.org)
+		return resolveOrg(jwtToken, flag.org, src, opts...)
 	}
 	// Env GHA_INSTALLATION_ID
 	if env.id > 0 {
@@ -279,32 +1511,56 @@ func resolveInstallation(jwtToken string, flag, env installationOverride, config
 	}
 	// Env GHA_ORG
 	if env.org != "" {
-		return resolveInstallationByOrg(jwtToken, env.org)
+		return resolveOrg(jwtToken, env.org, src, opts...)
+	}
+	// Project .gha.yaml
+	if src.project.id > 0 {
+		return src.project.id, nil
+	}
+	if src.project.org != "" {
+		return resolveOrg(jwtToken, src.project.org, src, opts...)
+	}
+	// Git remote of the current directory
+	if src.gitRepo != "" {
+		id, err := resolveInstallationByRepo(jwtToken, src.gitRepo, opts...)
+		var notInstalled *notInstalledError
+		if !errors.As(err, &notInstalled) {
+			return id, err
+		}
 	}
 	// Config file
-	if configID > 0 {
-		return configID, nil
+	if src.configID > 0 {
+		return src.configID, nil
 	}
 	// Auto-detect
-	return resolveInstallationID(jwtToken)
+	return resolveInstallationID(jwtToken, opts...)
 }
 
-func resolveInstallationID(jwtToken string) (int64, error) {
-	installations, err := auth.GetInstallations(jwtToken)
+// resolveOrg answers from the config's installations map when possible and
+// otherwise asks the API. The map does not record account types, so it is
+// skipped when a target type is requested.
+func resolveOrg(jwtToken, org string, src installationSources, opts ...auth.Option) (int64, error) {
+	if src.targetType == "" {
+		if id, ok := src.installations[strings.ToLower(org)]; ok {
+			debugf("org %s: installation %d from the installations map in config", org, id)
+			return id, nil
+		}
+	}
+	return resolveInstallationByOrg(jwtToken, src.scope, org, src.targetType, opts...)
+}
+
+func resolveInstallationID(jwtToken string, opts ...auth.Option) (int64, error) {
+	installations, err := auth.GetInstallations(jwtToken, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("listing installations: %w", err)
 	}
 
 	switch len(installations) {
 	case 0:
-		return 0, fmt.Errorf("no installations found for this GitHub App")
+		return 0, &installationsError{code: "no_installations", msg: "no installations found for this GitHub App"}
 	case 1:
-		return installations[0].ID, nil
+		return installations[0].ID, checkSuspended(&installations[0])
 	default:
-		lines := make([]string, 0, len(installations))
-		for _, inst := range installations {
-			lines = append(lines, fmt.Sprintf("  %d (%s)", inst.ID, inst.Account.Login))
-		}
-		return 0, fmt.Errorf("multiple installations found, set installation_id in config:\n%s", strings.Join(lines, "\n"))
+		return 0, &installationsError{code: "multiple_installations", msg: "multiple installations found, set installation_id in config", installations: installations}
 	}
 }
@@ -0,0 +1,691 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const agentUsage = "usage: gha agent <start|stop|status> [--socket PATH]"
+
+// agentSockEnv names the socket of a running gha agent. While it is set,
+// commands get their tokens from the agent instead of signing a JWT and
+// minting a token each.
+const agentSockEnv = "GHA_AGENT_SOCK"
+
+// agentTokenLife is how long a token the agent hands out has left at least:
+// a cached token closer to its expiry is replaced with a new one.
+var agentTokenLife = 30 * time.Minute
+
+// agentStartTimeout is how long gha agent start waits for the agent it
+// starts in the background to listen.
+var agentStartTimeout = 10 * time.Second
+
+func runAgent(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(agentUsage)
+	}
+	switch args[0] {
+	case "start":
+		return runAgentStart(args[1:], stdout, stderr)
+	case "stop":
+		return runAgentStop(args[1:], stdout, stderr)
+	case "status":
+		return runAgentStatus(args[1:], stdout, stderr)
+	default:
+		return usageErrorf("unknown agent command %q - %s", args[0], agentUsage)
+	}
+}
+
+// runAgentStart starts an agent that keeps the App's keys loaded and hands
+// tokens to the commands of the user's shells, as ssh-agent does with SSH
+// keys. Unless --foreground is given, it runs in the background; either
+// way, the GHA_AGENT_SOCK assignment pointing commands at it is printed for
+// the shell to eval.
+func runAgentStart(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("agent start", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	socket := fs.String("socket", "", "Socket to listen on (default $GHA_AGENT_SOCK, or agent.sock in a private directory)")
+	foreground := fs.Bool("foreground", false, "Serve in the foreground until interrupted instead of in the background")
+	shell := fs.String("shell", defaultEnvShell(), "Shell syntax: bash, zsh, fish or pwsh")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if !slices.Contains(envShells, *shell) {
+		return usageErrorf("unsupported shell %q: use bash, zsh, fish or pwsh", *shell)
+	}
+	path, err := agentSocket(*socket)
+	if err != nil {
+		return err
+	}
+	if st, err := newAgentClient(path).status(); err == nil {
+		return fmt.Errorf("an agent (pid %d) is already listening on %s", st.PID, path)
+	}
+
+	if !*foreground {
+		pid, logPath, err := spawnAgent(path)
+		if err != nil {
+			return err
+		}
+		printEnv(stdout, *shell, [][2]string{{agentSockEnv, path}})
+		infof(stderr, "gha agent: pid %d listening on %s (log: %s)\n", pid, path, logPath)
+		return nil
+	}
+
+	cfg, err := loadBaseConfig()
+	if err != nil {
+		return err
+	}
+	a := newAgentServer(cfg, path, stderr)
+	// The key of the App commands run as by default is loaded up front, so
+	// one the agent cannot use is reported now; others on first use.
+	def, err := cfg.ForHost(os.Getenv(ghHostEnv))
+	if err == nil {
+		def, err = def.ForApp(os.Getenv(appEnv))
+	}
+	if err != nil {
+		return &configError{err}
+	}
+	if _, err := a.keySource(def).sign(); err != nil {
+		return err
+	}
+
+	ln, err := listenAgent(path)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: a, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.stop = stop
+	printEnv(stdout, *shell, [][2]string{{agentSockEnv, path}})
+	infof(stderr, "gha agent: pid %d listening on %s\n", os.Getpid(), path)
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ln) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
+
+func runAgentStop(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("agent stop", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	socket := fs.String("socket", "", "Socket of the agent (default $GHA_AGENT_SOCK)")
+	shell := fs.String("shell", defaultEnvShell(), "Shell syntax: bash, zsh, fish or pwsh")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if !slices.Contains(envShells, *shell) {
+		return usageErrorf("unsupported shell %q: use bash, zsh, fish or pwsh", *shell)
+	}
+	path, err := agentSocket(*socket)
+	if err != nil {
+		return err
+	}
+	var st agentStatus
+	if err := newAgentClient(path).do(http.MethodPost, "/stop", nil, &st); err != nil {
+		return err
+	}
+	printUnsetEnv(stdout, *shell, agentSockEnv)
+	infof(stderr, "gha agent: stopped pid %d\n", st.PID)
+	return nil
+}
+
+func runAgentStatus(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("agent status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	socket := fs.String("socket", "", "Socket of the agent (default $GHA_AGENT_SOCK)")
+	asJSON := fs.Bool("json", false, "Print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	path, err := agentSocket(*socket)
+	if err != nil {
+		return err
+	}
+	st, err := newAgentClient(path).status()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	}
+	st.print(stdout)
+	return nil
+}
+
+// agentSocket returns the socket of the agent: socket when given, else
+// $GHA_AGENT_SOCK, else agent.sock in a directory of $XDG_RUNTIME_DIR or,
+// without one, of the config directory.
+func agentSocket(socket string) (string, error) {
+	if socket == "" {
+		socket = os.Getenv(agentSockEnv)
+	}
+	if socket != "" {
+		return filepath.Abs(socket)
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gha", "agent.sock"), nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "agent", "agent.sock"), nil
+}
+
+// privateAgentDir creates the directory of the agent's socket, and refuses
+// one other users may enter: as with ssh-agent, the directory is what keeps
+// them from connecting, since not every system honours a socket's own mode.
+func privateAgentDir(socket string) error {
+	dir := filepath.Dir(socket)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	// Windows has no such modes; its ACLs keep a profile's directories
+	// private.
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is accessible to other users (mode %04o): put the agent's socket in a directory only you can enter", dir, info.Mode().Perm())
+	}
+	return nil
+}
+
+// listenAgent listens on the Unix socket at path, replacing a socket left
+// behind by an agent that is gone.
+func listenAgent(path string) (net.Listener, error) {
+	if err := privateAgentDir(path); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// spawnAgent runs gha agent start --foreground in the background, detached
+// from the terminal, and waits for it to listen on socket. It returns the
+// agent's pid and the log file its diagnostics go to, next to the socket.
+func spawnAgent(socket string) (int, string, error) {
+	if err := privateAgentDir(socket); err != nil {
+		return 0, "", err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, "", err
+	}
+	logPath := strings.TrimSuffix(socket, filepath.Ext(socket)) + ".log"
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, "", err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(exe, "agent", "start", "--foreground", "--socket", socket)
+	cmd.Dir = filepath.Dir(socket)
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcess()
+	if err := cmd.Start(); err != nil {
+		return 0, "", fmt.Errorf("starting the agent: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	client := newAgentClient(socket)
+	deadline := time.After(agentStartTimeout)
+	for {
+		if st, err := client.status(); err == nil && st.PID == cmd.Process.Pid {
+			return st.PID, logPath, nil
+		}
+		select {
+		case err := <-exited:
+			return 0, "", fmt.Errorf("the agent exited (%v): %s", err, lastLogLine(logPath))
+		case <-deadline:
+			return 0, "", fmt.Errorf("the agent (pid %d) did not listen on %s within %s; see %s", cmd.Process.Pid, socket, agentStartTimeout, logPath)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// lastLogLine returns the last line of the agent's log, which says why it
+// exited.
+func lastLogLine(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return strings.TrimPrefix(lines[len(lines)-1], "error: ")
+}
+
+// agentServer is the handler of gha agent: it signs with the keys it keeps
+// loaded, and hands out the tokens it mints until they near expiry.
+type agentServer struct {
+	cfg     *config.Config
+	socket  string
+	started time.Time
+	log     io.Writer
+	// stop shuts the agent down.
+	stop func()
+
+	mu sync.Mutex
+	// keys holds a keySource per config scope, and tokens the tokens minted
+	// for each agentCacheKey.
+	keys   map[string]*keySource
+	tokens map[string]*tokenResponse
+}
+
+func newAgentServer(cfg *config.Config, socket string, log io.Writer) *agentServer {
+	return &agentServer{
+		cfg:     cfg,
+		socket:  socket,
+		started: time.Now(),
+		log:     log,
+		stop:    func() {},
+		keys:    map[string]*keySource{},
+		tokens:  map[string]*tokenResponse{},
+	}
+}
+
+// agentTokenRequest is the body of the agent's POST /token: the App a
+// command runs as, everything its installation is resolved from and the
+// permissions its token asks for. The command resolves these from its own
+// config and directory; the agent only signs and mints.
+type agentTokenRequest struct {
+	Host          string            `json:"host,omitempty"`
+	App           string            `json:"app,omitempty"`
+	AppID         int64             `json:"app_id"`
+	Flag          agentOverride     `json:"flag,omitzero"`
+	Env           agentOverride     `json:"env,omitzero"`
+	Project       agentOverride     `json:"project,omitzero"`
+	GitRepo       string            `json:"git_repo,omitempty"`
+	ConfigID      int64             `json:"config_installation_id,omitempty"`
+	TargetType    string            `json:"target_type,omitempty"`
+	Installations map[string]int64  `json:"installations,omitempty"`
+	Permissions   map[string]string `json:"permissions,omitempty"`
+
+	// Fresh asks for a token minted for this request alone and not handed
+	// to anyone else, as a command that revokes its token needs.
+	Fresh bool `json:"fresh,omitempty"`
+}
+
+// agentOverride is an installationOverride on the wire.
+type agentOverride struct {
+	ID         int64  `json:"id,omitempty"`
+	Org        string `json:"org,omitempty"`
+	Repo       string `json:"repo,omitempty"`
+	TargetType string `json:"target_type,omitempty"`
+}
+
+func newAgentOverride(o installationOverride) agentOverride {
+	return agentOverride{ID: o.id, Org: o.org, Repo: o.repo, TargetType: o.targetType}
+}
+
+func (o agentOverride) override() installationOverride {
+	return installationOverride{id: o.ID, org: o.Org, repo: o.Repo, targetType: o.TargetType}
+}
+
+// agentStatus is what gha agent status shows.
+type agentStatus struct {
+	PID     int       `json:"pid"`
+	Socket  string    `json:"socket"`
+	Started time.Time `json:"started"`
+	// Keys lists the config scopes whose keys are loaded.
+	Keys []string `json:"keys"`
+	// Tokens counts the cached tokens the agent still hands out.
+	Tokens int `json:"tokens"`
+}
+
+// print writes st as aligned "label: value" lines, like gha status.
+func (st agentStatus) print(w io.Writer) {
+	line := func(label, format string, a ...any) {
+		fmt.Fprintf(w, "%-14s%s\n", label+":", fmt.Sprintf(format, a...))
+	}
+	line("socket", "%s", st.Socket)
+	line("pid", "%d (up %s)", st.PID, time.Since(st.Started).Round(time.Second))
+	keys := "none"
+	if len(st.Keys) > 0 {
+		keys = strings.Join(st.Keys, ", ")
+	}
+	line("keys", "%s", keys)
+	line("tokens", "%d cached", st.Tokens)
+}
+
+// staleAgentError is a request for an App the agent's config does not have
+// as the command's does, as after the config was edited.
+type staleAgentError struct {
+	msg string
+}
+
+func (e *staleAgentError) Error() string {
+	return fmt.Sprintf("the agent's config is out of date (%s): restart the agent", e.msg)
+}
+
+func (a *agentServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/token":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			a.fail(w, r, http.StatusMethodNotAllowed, usageErrorf("use POST to get a token"))
+			return
+		}
+		var req agentTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			a.fail(w, r, http.StatusBadRequest, &usageError{err})
+			return
+		}
+		resp, err := a.token(req)
+		if err != nil {
+			status := serveStatus(err)
+			if errors.As(err, new(*staleAgentError)) {
+				status = http.StatusConflict
+			}
+			a.fail(w, r, status, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, resp)
+	case "/status":
+		writeJSON(w, http.StatusOK, a.status())
+	case "/stop":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			a.fail(w, r, http.StatusMethodNotAllowed, usageErrorf("use POST to stop the agent"))
+			return
+		}
+		writeJSON(w, http.StatusOK, a.status())
+		a.stop()
+	default:
+		a.fail(w, r, http.StatusNotFound, usageErrorf("no such endpoint %s", r.URL.Path))
+	}
+}
+
+func (a *agentServer) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	fmt.Fprintf(a.log, "%s %s: %d %v\n", r.Method, r.URL.Path, status, err)
+	writeJSON(w, status, map[string]errorReport{"error": newErrorReport(err)})
+}
+
+// token returns the token req asks for: one cached for the same App,
+// installation and permissions that has agentTokenLife left, or else a new
+// one.
+func (a *agentServer) token(req agentTokenRequest) (*tokenResponse, error) {
+	cfg, err := a.cfg.ForHost(req.Host)
+	if err == nil {
+		cfg, err = cfg.ForApp(req.App)
+	}
+	if err != nil {
+		return nil, &staleAgentError{err.Error()}
+	}
+	if cfg.AppID != req.AppID {
+		return nil, &staleAgentError{fmt.Sprintf("it has app_id %d where the command has %d", cfg.AppID, req.AppID)}
+	}
+	src := installationSources{
+		scope:         cfg.Scope(),
+		flag:          req.Flag.override(),
+		env:           req.Env.override(),
+		project:       req.Project.override(),
+		gitRepo:       req.GitRepo,
+		configID:      req.ConfigID,
+		targetType:    req.TargetType,
+		installations: req.Installations,
+	}
+	jwtToken, err := a.keySource(cfg).sign()
+	if err != nil {
+		return nil, err
+	}
+	opts := apiOptions(cfg)
+	installationID, err := resolveInstallation(jwtToken, src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	key := agentCacheKey(cfg.Scope(), installationID, req.Permissions)
+	if !req.Fresh {
+		if resp := a.cached(key); resp != nil {
+			fmt.Fprintf(a.log, "token: installation %d (%s), cached\n", installationID, scopeName(cfg.Scope()))
+			return resp, nil
+		}
+	}
+	token, err := mintInstallationToken(jwtToken, installationID, &auth.TokenRequest{Permissions: req.Permissions}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("getting installation token: %w", err)
+	}
+	resp := &tokenResponse{
+		Token:               token.Token,
+		ExpiresAt:           token.ExpiresAt,
+		InstallationID:      installationID,
+		Permissions:         token.Permissions,
+		RepositorySelection: token.RepositorySelection,
+	}
+	if !req.Fresh {
+		a.mu.Lock()
+		a.tokens[key] = resp
+		a.mu.Unlock()
+	}
+	fmt.Fprintf(a.log, "token: installation %d (%s), minted\n", installationID, scopeName(cfg.Scope()))
+	return resp, nil
+}
+
+// agentCacheKey identifies the tokens that are interchangeable: those of one
+// config scope's App and installation with the same permissions.
+func agentCacheKey(scope string, installationID int64, perms map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%d", scope, installationID)
+	for _, name := range slices.Sorted(maps.Keys(perms)) {
+		fmt.Fprintf(&b, "|%s:%s", name, perms[name])
+	}
+	return b.String()
+}
+
+// cached returns the token cached under key if it has agentTokenLife left,
+// dropping the tokens that do not.
+func (a *agentServer) cached(key string) *tokenResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pruneTokens()
+	return a.tokens[key]
+}
+
+// pruneTokens drops the cached tokens too close to expiry to hand out.
+// a.mu must be held.
+func (a *agentServer) pruneTokens() {
+	maps.DeleteFunc(a.tokens, func(_ string, t *tokenResponse) bool {
+		return time.Until(t.ExpiresAt) < agentTokenLife
+	})
+}
+
+// keySource returns the keySource of cfg's App.
+func (a *agentServer) keySource(cfg *config.Config) *keySource {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k, ok := a.keys[cfg.Scope()]
+	if !ok {
+		k = newKeySource(cfg)
+		a.keys[cfg.Scope()] = k
+	}
+	return k
+}
+
+func (a *agentServer) status() agentStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pruneTokens()
+	st := agentStatus{PID: os.Getpid(), Socket: a.socket, Started: a.started, Tokens: len(a.tokens), Keys: []string{}}
+	for _, scope := range slices.Sorted(maps.Keys(a.keys)) {
+		st.Keys = append(st.Keys, scopeName(scope))
+	}
+	return st
+}
+
+// scopeName names a config scope for people: "default" for the top level.
+func scopeName(scope string) string {
+	if scope == "" {
+		return "default"
+	}
+	return scope
+}
+
+// agentClient talks to the agent listening on a socket.
+type agentClient struct {
+	socket string
+	http   *http.Client
+}
+
+func newAgentClient(socket string) *agentClient {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &agentClient{socket: socket, http: &http.Client{Transport: transport, Timeout: time.Minute}}
+}
+
+// agentError is an error the agent answered a request with.
+type agentError struct {
+	status int
+	report errorReport
+}
+
+func (e *agentError) Error() string {
+	return "gha agent: " + e.report.Message
+}
+
+// do sends the agent a request with body, if not nil, as JSON, and decodes
+// its answer into v. An answer with an error is returned as an *agentError.
+func (c *agentClient) do(method, path string, body, v any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	// The host is not used: every request goes to the socket.
+	req, err := http.NewRequest(method, "http://gha-agent"+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("no agent is listening on %s: %w", c.socket, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var answer struct {
+			Error errorReport `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil || answer.Error.Message == "" {
+			answer.Error.Message = resp.Status
+		}
+		return &agentError{status: resp.StatusCode, report: answer.Error}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *agentClient) status() (*agentStatus, error) {
+	var st agentStatus
+	if err := c.do(http.MethodGet, "/status", nil, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// tokenFromAgent gets the token a command run as cfg would mint from the
+// agent at $GHA_AGENT_SOCK, for the installation src resolves to. It reports
+// false when the command should sign and mint itself: without an agent, or
+// when the agent cannot help. A request the agent refused is left for the
+// command to fail on as usual, while an agent that is gone or out of date is
+// warned about on stderr.
+func tokenFromAgent(cfg *config.Config, src installationSources, scope *auth.TokenRequest, fresh bool, stderr io.Writer) (int64, *auth.InstallationToken, bool) {
+	socket := os.Getenv(agentSockEnv)
+	if socket == "" {
+		return 0, nil, false
+	}
+	req := agentTokenRequest{
+		Host:          cfg.Host(),
+		App:           cfg.AppName(),
+		AppID:         cfg.AppID,
+		Flag:          newAgentOverride(src.flag),
+		Env:           newAgentOverride(src.env),
+		Project:       newAgentOverride(src.project),
+		GitRepo:       src.gitRepo,
+		ConfigID:      src.configID,
+		TargetType:    src.targetType,
+		Installations: src.installations,
+		Permissions:   scope.Permissions,
+		Fresh:         fresh,
+	}
+	var resp tokenResponse
+	if err := newAgentClient(socket).do(http.MethodPost, "/token", req, &resp); err != nil {
+		var refused *agentError
+		if errors.As(err, &refused) && refused.status != http.StatusConflict {
+			debugf("%v; minting locally", err)
+		} else {
+			fmt.Fprintf(stderr, "warning: %v; signing locally\n", err)
+		}
+		return 0, nil, false
+	}
+	debugf("installation: %d (from the agent at %s)", resp.InstallationID, socket)
+	return resp.InstallationID, &auth.InstallationToken{
+		Token:               resp.Token,
+		ExpiresAt:           resp.ExpiresAt,
+		Permissions:         resp.Permissions,
+		RepositorySelection: resp.RepositorySelection,
+	}, true
+}
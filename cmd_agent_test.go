@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Agent(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	var mints atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/installations/7/access_tokens" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		mints.Add(1)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_agent", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer api.Close()
+	keyPath := generateTestKeyFile(t)
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: keyPath, APIURL: api.URL}},
+	})
+	t.Setenv(ghHostEnv, "ghe.test")
+	socket := filepath.Join(t.TempDir(), "agent", "agent.sock")
+
+	type result struct {
+		stdout, stderr string
+		code           int
+	}
+	done := make(chan result, 1)
+	// The agent is started without run, whose globals commands set while
+	// it serves them.
+	go func() {
+		var stdout, stderr strings.Builder
+		code := 0
+		if err := runAgent([]string{"start", "--foreground", "--socket", socket, "--shell", "bash"}, &stdout, &stderr); err != nil {
+			code = exitCode(err)
+			stderr.WriteString(err.Error())
+		}
+		done <- result{stdout.String(), stderr.String(), code}
+	}()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if _, err := newAgentClient(socket).status(); err == nil {
+			break
+		}
+		select {
+		case r := <-done:
+			t.Fatalf("the agent exited: code = %d, stderr = %s", r.code, r.stderr)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the agent did not start listening")
+		}
+	}
+	if info, err := os.Stat(socket); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+	if _, _, code := runCmd(t, []string{"gha", "agent", "start", "--foreground", "--socket", socket}, ""); code == 0 {
+		t.Error("a second agent started on the same socket")
+	}
+
+	// Commands get the token the agent minted once.
+	t.Setenv(agentSockEnv, socket)
+	for range 2 {
+		stdout, stderr, code := runCmd(t, []string{"gha", "token"}, "")
+		if code != 0 || stdout != "ghs_agent\n" || stderr != "" {
+			t.Errorf("token: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+		}
+	}
+	if n := mints.Load(); n != 1 {
+		t.Errorf("tokens minted = %d, want 1 for both commands", n)
+	}
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "agent", "status"}, "")
+	if code != 0 || !strings.Contains(stdout, "hosts/ghe.test") || !strings.Contains(stdout, "1 cached") {
+		t.Errorf("status: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+	}
+
+	// A command whose config has another App is not served the agent's.
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 2, InstallationID: 7, PrivateKeyPath: keyPath, APIURL: api.URL}},
+	})
+	stdout, stderr, code = runCmd(t, []string{"gha", "token"}, "")
+	if code != 0 || stdout != "ghs_agent\n" || !strings.Contains(stderr, "out of date") {
+		t.Errorf("stale agent: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+	}
+	if n := mints.Load(); n != 2 {
+		t.Errorf("tokens minted = %d, want the command to mint its own", n)
+	}
+
+	stdout, stderr, code = runCmd(t, []string{"gha", "agent", "stop", "--shell", "bash"}, "")
+	if code != 0 || stdout != "unset GHA_AGENT_SOCK\n" {
+		t.Errorf("stop: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+	}
+	select {
+	case r := <-done:
+		if r.code != 0 || !strings.Contains(r.stdout, "export GHA_AGENT_SOCK=") {
+			t.Errorf("start: code = %d, stdout = %q, stderr = %s", r.code, r.stdout, r.stderr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the agent did not stop")
+	}
+
+	// Without the agent, commands warn and mint their own tokens.
+	stdout, stderr, code = runCmd(t, []string{"gha", "token"}, "")
+	if code != 0 || stdout != "ghs_agent\n" || !strings.Contains(stderr, "no agent is listening") {
+		t.Errorf("no agent: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+	}
+	if _, _, code := runCmd(t, []string{"gha", "agent", "status"}, ""); code == 0 {
+		t.Error("status succeeded without an agent")
+	}
+}
+
+func TestListenAgent_SharedDirectory(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no directory modes to check")
+	}
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenAgent(filepath.Join(dir, "agent.sock")); err == nil || !strings.Contains(err.Error(), "accessible to other users") {
+		t.Errorf("err = %v, want the directory refused", err)
+	}
+}
+
+func TestAgentCacheKey(t *testing.T) {
+	a := agentCacheKey("apps/bot", 7, map[string]string{"issues": "write", "contents": "read"})
+	b := agentCacheKey("apps/bot", 7, map[string]string{"contents": "read", "issues": "write"})
+	if a != b || a != "apps/bot|7|contents:read|issues:write" {
+		t.Errorf("keys = %q, %q", a, b)
+	}
+	if agentCacheKey("apps/bot", 7, nil) == a || agentCacheKey("", 7, nil) == agentCacheKey("", 8, nil) {
+		t.Error("tokens of other permissions or installations share a key")
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const aliasUsage = `usage:
+  gha alias set <name> <expansion>
+  gha alias list
+  gha alias delete <name>`
+
+// builtinCommands are handled by gha itself rather than passed to gh, so
+// they cannot be used as alias names.
+var builtinCommands = map[string]bool{
+	"configure": true, "config": true, "doctor": true, "reset": true, "logout": true, "jwt": true,
+	"installations": true, "installation": true, "app": true, "install": true,
+	"alias": true, "exec": true, "shell": true, "env": true, "token": true, "direnv": true, "foreach": true, "git-credential": true,
+	"self-update": true, "completion": true, "generate-docs": true, "status": true, "permissions": true, "revoke-token": true, "setup-git": true, "setup-npm": true, "setup-maven": true, "setup-gradle": true, "setup-go": true, "clone": true, "serve": true, "agent": true, "docker-credential": true, "docker-login": true, "help": true,
+}
+
+var placeholderRE = regexp.MustCompile(`\$[1-9][0-9]*`)
+
+func runAlias(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(aliasUsage)
+	}
+	want := map[string]int{"set": 2, "list": 0, "delete": 1}
+	n, ok := want[args[0]]
+	if !ok {
+		return usageErrorf("unknown alias command %q", args[0])
+	}
+	if len(args)-1 != n {
+		return usageErrorf(aliasUsage)
+	}
+
+	cfg, err := config.LoadPartial()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		names := make([]string, 0, len(cfg.Aliases))
+		for name := range cfg.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stdout, "%s: %s\n", name, cfg.Aliases[name])
+		}
+		return nil
+	case "set":
+		name, expansion := args[1], strings.TrimSpace(args[2])
+		if err := validateAlias(name, expansion); err != nil {
+			return err
+		}
+		verb := "Added"
+		if _, exists := cfg.Aliases[name]; exists {
+			verb = "Changed"
+		}
+		if cfg.Aliases == nil {
+			cfg.Aliases = map[string]string{}
+		}
+		cfg.Aliases[name] = expansion
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+		infof(stderr, "%s alias %s\n", verb, name)
+	case "delete":
+		name := args[1]
+		if _, exists := cfg.Aliases[name]; !exists {
+			return fmt.Errorf("no such alias %s", name)
+		}
+		delete(cfg.Aliases, name)
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+		infof(stderr, "Deleted alias %s\n", name)
+	}
+	return nil
+}
+
+func validateAlias(name, expansion string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return usageErrorf("invalid alias name %q", name)
+	}
+	if builtinCommands[name] {
+		return fmt.Errorf("%q is a gha command and cannot be an alias", name)
+	}
+	if expansion == "" {
+		return fmt.Errorf("alias expansion must not be empty")
+	}
+	if strings.HasPrefix(expansion, "!") {
+		return nil
+	}
+	_, err := splitArgs(expansion)
+	return err
+}
+
+// expandAlias replaces the command word of args with its expansion when it
+// names an alias. gha flags given before it are kept in place.
+func expandAlias(args []string) ([]string, error) {
+	i := commandIndex(args)
+	if i < 0 || builtinCommands[args[i]] {
+		return args, nil
+	}
+	cfg, err := config.LoadPartial()
+	if err != nil {
+		// A broken config is reported by the command itself.
+		return args, nil
+	}
+	expansion, ok := cfg.Aliases[args[i]]
+	if !ok {
+		return args, nil
+	}
+	expanded, err := expandAliasArgs(expansion, args[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("alias %s: %w", args[i], err)
+	}
+	return append(args[:i:i], expanded...), nil
+}
+
+// commandIndex returns the index of the first argument that is neither a gha
+// flag nor a flag's value, or -1 if there is none or a "--" comes first.
+func commandIndex(args []string) int {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return -1
+		case arg == "--installation-id" || arg == "--org" || arg == "--repo" || arg == "--target-type":
+			i++ // skip the value
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+// expandAliasArgs expands an alias with the arguments given after it. $1, $2,
+// ... are replaced by those arguments and the rest are appended. A shell
+// alias ("!...") becomes gha exec sh -c, with the arguments as "$@".
+func expandAliasArgs(expansion string, args []string) ([]string, error) {
+	if script, ok := strings.CutPrefix(expansion, "!"); ok {
+		return append([]string{"exec", "sh", "-c", script, "--"}, args...), nil
+	}
+
+	words, err := splitArgs(expansion)
+	if err != nil {
+		return nil, err
+	}
+	used := 0
+	for _, w := range words {
+		for _, m := range placeholderRE.FindAllString(w, -1) {
+			n, _ := strconv.Atoi(m[1:])
+			used = max(used, n)
+		}
+	}
+	if used > len(args) {
+		return nil, fmt.Errorf("not enough arguments: the expansion uses $%d", used)
+	}
+	for i, w := range words {
+		words[i] = placeholderRE.ReplaceAllStringFunc(w, func(m string) string {
+			n, _ := strconv.Atoi(m[1:])
+			return args[n-1]
+		})
+	}
+	return append(words, args[used:]...), nil
+}
+
+// splitArgs splits s into words the way a POSIX shell would, honouring
+// single and double quotes and backslash escapes.
+func splitArgs(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_AliasSetListDelete(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "alias", "set", "prs", "pr list --limit 50"}, "")
+	if code != 0 || !strings.Contains(stderr, "Added alias prs") {
+		t.Fatalf("set: code = %d, stderr = %q", code, stderr)
+	}
+	_, stderr, code = runCmd(t, []string{"gha", "alias", "set", "prs", "pr list --limit 10"}, "")
+	if code != 0 || !strings.Contains(stderr, "Changed alias prs") {
+		t.Fatalf("change: code = %d, stderr = %q", code, stderr)
+	}
+	if _, _, code := runCmd(t, []string{"gha", "alias", "set", "co", "!gh pr checkout $1"}, ""); code != 0 {
+		t.Fatalf("set shell alias: code = %d", code)
+	}
+
+	stdout, _, code := runCmd(t, []string{"gha", "alias", "list"}, "")
+	if want := "co: !gh pr checkout $1\nprs: pr list --limit 10\n"; code != 0 || stdout != want {
+		t.Errorf("list = %q, want %q", stdout, want)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "alias", "delete", "prs"}, "")
+	if code != 0 || !strings.Contains(stderr, "Deleted alias prs") {
+		t.Errorf("delete: code = %d, stderr = %q", code, stderr)
+	}
+	_, stderr, code = runCmd(t, []string{"gha", "alias", "delete", "prs"}, "")
+	if code != 1 || !strings.Contains(stderr, "no such alias prs") {
+		t.Errorf("delete again: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRun_AliasSetInvalid(t *testing.T) {
+	setupTestEnv(t)
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"set", "jwt", "pr list"}, "is a gha command"},
+		{[]string{"set", "-x", "pr list"}, "invalid alias name"},
+		{[]string{"set", "prs", "  "}, "must not be empty"},
+		{[]string{"set", "prs", `pr list --search "open`}, "unterminated quote"},
+		{[]string{"set", "prs"}, "usage:"},
+		{[]string{"rename", "a", "b"}, "unknown alias command"},
+	}
+	for _, tt := range tests {
+		_, stderr, code := runCmd(t, append([]string{"gha", "alias"}, tt.args...), "")
+		if code == 0 || !strings.Contains(stderr, tt.want) {
+			t.Errorf("alias %v: code = %d, stderr = %q, want %q", tt.args, code, stderr, tt.want)
+		}
+	}
+}
+
+func TestRun_AliasExpandsToGhaCommand(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 12345, Aliases: map[string]string{"claims": "jwt --decode"}})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "claims"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"iss": "12345"`) {
+		t.Errorf("stdout = %q, want decoded JWT claims", stdout)
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, Aliases: map[string]string{
+		"prs":  "pr list --limit 50",
+		"mine": `issue list --assignee "$1" --label "needs review"`,
+		"co":   "!gh pr checkout $1 && git pull",
+	}})
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"plain", []string{"prs", "--state", "closed"}, []string{"pr", "list", "--limit", "50", "--state", "closed"}},
+		{"gha flags kept", []string{"--org", "acme", "prs"}, []string{"--org", "acme", "pr", "list", "--limit", "50"}},
+		{"placeholder", []string{"mine", "octo cat", "-w"}, []string{"issue", "list", "--assignee", "octo cat", "--label", "needs review", "-w"}},
+		{"shell", []string{"co", "42"}, []string{"exec", "sh", "-c", "gh pr checkout $1 && git pull", "--", "42"}},
+		{"not an alias", []string{"pr", "list"}, []string{"pr", "list"}},
+		{"builtin", []string{"jwt"}, []string{"jwt"}},
+		{"no command", []string{"--version"}, []string{"--version"}},
+		{"after separator", []string{"--", "prs"}, []string{"--", "prs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandAlias(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("expandAlias(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := expandAlias([]string{"mine"}); err == nil || !strings.Contains(err.Error(), "not enough arguments") {
+		t.Errorf("err = %v, want not enough arguments", err)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"pr list", []string{"pr", "list"}},
+		{"  pr   list  ", []string{"pr", "list"}},
+		{`api -f 'q=a b' "x\"y"`, []string{"api", "-f", "q=a b", `x"y`}},
+		{`a\ b ''`, []string{"a b", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.in)
+		if err != nil {
+			t.Errorf("splitArgs(%q): %v", tt.in, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := splitArgs(`it's`); err == nil {
+		t.Error("expected error for an unterminated quote")
+	}
+}
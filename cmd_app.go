@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+const appUsage = `usage:
+  gha app create [--org ORG] [--force] <manifest.yaml>
+  gha app show [--json]
+  gha app deliveries list [--limit N] [--json]
+  gha app deliveries redeliver <delivery-id>
+  gha app hook-config get [--json]
+  gha app hook-config set [--url URL] [--secret SECRET|-] [--content-type json|form] [--insecure-ssl]
+  gha app hook ping [--timeout 30s]`
+
+func runApp(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(appUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		return runAppCreate(args[1:], stdout, stderr)
+	case "show":
+		return runAppShow(args[1:], stdout, stderr)
+	case "deliveries":
+		return runAppDeliveries(args[1:], stdout, stderr)
+	case "hook-config":
+		return runAppHookConfig(args[1:], stdin, stdout, stderr)
+	case "hook":
+		return runAppHook(args[1:], stdout, stderr)
+	default:
+		return usageErrorf("unknown app command %q", args[0])
+	}
+}
+
+func runAppShow(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("app show", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "Print the app as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg, jwtToken, err := loadJWT()
+	if err != nil {
+		return err
+	}
+	return showApp(stdout, jwtToken, *asJSON, apiOptions(cfg)...)
+}
+
+// showApp fetches the authenticated app and prints it as text or JSON.
+func showApp(w io.Writer, jwtToken string, asJSON bool, opts ...auth.Option) error {
+	app, err := auth.GetApp(jwtToken, opts...)
+	if err != nil {
+		return fmt.Errorf("fetching app: %w", err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(app)
+	}
+
+	fmt.Fprintf(w, "Name:   %s (%s)\n", app.Name, app.Slug)
+	fmt.Fprintf(w, "ID:     %d\n", app.ID)
+	fmt.Fprintf(w, "Owner:  %s\n", app.Owner.Login)
+	if app.HTMLURL != "" {
+		fmt.Fprintf(w, "URL:    %s\n", app.HTMLURL)
+	}
+
+	fmt.Fprintln(w, "Permissions:")
+	names := make([]string, 0, len(app.Permissions))
+	for name := range app.Permissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %s\n", name, app.Permissions[name])
+	}
+
+	fmt.Fprintln(w, "Events:")
+	for _, event := range app.Events {
+		fmt.Fprintf(w, "  %s\n", event)
+	}
+	return nil
+}
+
+func runAppDeliveries(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(appUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("app deliveries list", flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		limit := fs.Int("limit", 30, "Maximum number of deliveries to show (1-100)")
+		asJSON := fs.Bool("json", false, "Print deliveries as JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return &usageError{err}
+		}
+		if fs.NArg() > 0 {
+			return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+		}
+		if *limit < 1 || *limit > 100 {
+			return usageErrorf("invalid --limit %d: must be between 1 and 100", *limit)
+		}
+
+		cfg, jwtToken, err := loadJWT()
+		if err != nil {
+			return err
+		}
+		return listDeliveries(stdout, jwtToken, *limit, *asJSON, apiOptions(cfg)...)
+	case "redeliver":
+		if len(args) != 2 {
+			return usageErrorf("usage: gha app deliveries redeliver <delivery-id>")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || id <= 0 {
+			return usageErrorf("invalid delivery ID %q: must be a positive integer", args[1])
+		}
+
+		cfg, jwtToken, err := loadJWT()
+		if err != nil {
+			return err
+		}
+		return redeliver(stderr, jwtToken, id, apiOptions(cfg)...)
+	default:
+		return usageErrorf("unknown app deliveries command %q", args[0])
+	}
+}
+
+// listDeliveries prints recent webhook deliveries, one per line, newest first.
+func listDeliveries(w io.Writer, jwtToken string, limit int, asJSON bool, opts ...auth.Option) error {
+	deliveries, err := auth.ListHookDeliveries(jwtToken, limit, opts...)
+	if err != nil {
+		return fmt.Errorf("listing deliveries: %w", err)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(deliveries)
+	}
+
+	for _, d := range deliveries {
+		event := d.Event
+		if d.Action != "" {
+			event += "." + d.Action
+		}
+		redelivery := ""
+		if d.Redelivery {
+			redelivery = "\tredelivery"
+		}
+		code := colorGreen
+		if d.StatusCode < 200 || d.StatusCode >= 300 {
+			code = colorRed
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s%s\n",
+			d.ID, d.DeliveredAt.Format(time.RFC3339), event, paint(w, code, strconv.Itoa(d.StatusCode)), d.Status, redelivery)
+	}
+	return nil
+}
+
+func redeliver(w io.Writer, jwtToken string, deliveryID int64, opts ...auth.Option) error {
+	if err := auth.RedeliverHookDelivery(jwtToken, deliveryID, opts...); err != nil {
+		return fmt.Errorf("redelivering %d: %w", deliveryID, err)
+	}
+	fmt.Fprintf(w, "Redelivery of %d requested\n", deliveryID)
+	return nil
+}
+
+func runAppHookConfig(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(appUsage)
+	}
+
+	switch args[0] {
+	case "get":
+		fs := flag.NewFlagSet("app hook-config get", flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		asJSON := fs.Bool("json", false, "Print the webhook config as JSON")
+		if err := fs.Parse(args[1:]); err != nil {
+			return &usageError{err}
+		}
+		if fs.NArg() > 0 {
+			return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+		}
+
+		cfg, jwtToken, err := loadJWT()
+		if err != nil {
+			return err
+		}
+		hook, err := auth.GetHookConfig(jwtToken, apiOptions(cfg)...)
+		if err != nil {
+			return fmt.Errorf("fetching webhook config: %w", err)
+		}
+		return printHookConfig(stdout, hook, *asJSON)
+	case "set":
+		update, err := parseHookConfigUpdate(args[1:], stdin, stderr)
+		if err != nil {
+			return err
+		}
+
+		cfg, jwtToken, err := loadJWT()
+		if err != nil {
+			return err
+		}
+		hook, err := auth.UpdateHookConfig(jwtToken, update, apiOptions(cfg)...)
+		if err != nil {
+			return fmt.Errorf("updating webhook config: %w", err)
+		}
+		return printHookConfig(stdout, hook, false)
+	default:
+		return usageErrorf("unknown app hook-config command %q", args[0])
+	}
+}
+
+// parseHookConfigUpdate builds a webhook config update from set flags. A
+// secret of "-" is read from the first line of stdin so it stays out of
+// shell history.
+func parseHookConfigUpdate(args []string, stdin io.Reader, stderr io.Writer) (auth.HookConfig, error) {
+	var update auth.HookConfig
+
+	fs := flag.NewFlagSet("app hook-config set", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&update.URL, "url", "", "Webhook payload URL")
+	fs.StringVar(&update.Secret, "secret", "", "Webhook secret, or - to read it from stdin")
+	fs.StringVar(&update.ContentType, "content-type", "", "Payload content type: json or form")
+	insecure := fs.Bool("insecure-ssl", false, "Skip TLS verification of the payload URL")
+	if err := fs.Parse(args); err != nil {
+		return update, &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return update, usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if len(set) == 0 {
+		return update, fmt.Errorf("nothing to update: pass at least one of --url, --secret, --content-type, --insecure-ssl")
+	}
+
+	if update.ContentType != "" && update.ContentType != "json" && update.ContentType != "form" {
+		return update, usageErrorf("invalid --content-type %q: must be json or form", update.ContentType)
+	}
+	if set["insecure-ssl"] {
+		update.InsecureSSL = "0"
+		if *insecure {
+			update.InsecureSSL = "1"
+		}
+	}
+	if update.Secret == "-" {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return update, fmt.Errorf("reading secret from stdin: unexpected end of input")
+		}
+		update.Secret = strings.TrimRight(line, "\r\n")
+		if update.Secret == "" {
+			return update, fmt.Errorf("secret read from stdin is empty")
+		}
+	}
+	return update, nil
+}
+
+func printHookConfig(w io.Writer, cfg *auth.HookConfig, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg)
+	}
+
+	fmt.Fprintf(w, "url:          %s\n", cfg.URL)
+	fmt.Fprintf(w, "content_type: %s\n", cfg.ContentType)
+	fmt.Fprintf(w, "secret:       %s\n", cfg.Secret)
+	fmt.Fprintf(w, "insecure_ssl: %s\n", cfg.InsecureSSL)
+	return nil
+}
+
+func runAppHook(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "ping" {
+		return usageErrorf("usage: gha app hook ping [--timeout 30s]")
+	}
+
+	fs := flag.NewFlagSet("app hook ping", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for the delivery result")
+	if err := fs.Parse(args[1:]); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg, jwtToken, err := loadJWT()
+	if err != nil {
+		return err
+	}
+	return pingHook(stdout, jwtToken, *timeout, 2*time.Second, apiOptions(cfg)...)
+}
+
+// pingHook replays the App's original ping delivery and waits for the new
+// attempt to show up in the delivery log. GitHub has no dedicated ping
+// endpoint for Apps, so redelivering the ping event is the closest
+// equivalent. It returns an error if the receiver did not answer with 2xx.
+func pingHook(w io.Writer, jwtToken string, timeout, interval time.Duration, opts ...auth.Option) error {
+	var newest int64
+	ping, err := auth.FindHookDelivery(jwtToken, func(d auth.HookDelivery) bool {
+		if d.ID > newest {
+			newest = d.ID
+		}
+		return d.Event == "ping"
+	}, opts...)
+	if err != nil {
+		return fmt.Errorf("finding ping delivery: %w", err)
+	}
+	if ping == nil {
+		return fmt.Errorf("no ping delivery found to replay; use 'gha app deliveries redeliver <id>' with another delivery instead")
+	}
+
+	if err := auth.RedeliverHookDelivery(jwtToken, ping.ID, opts...); err != nil {
+		return fmt.Errorf("redelivering ping: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		attempt, err := auth.FindHookDeliveryAfter(jwtToken, newest, func(d auth.HookDelivery) bool {
+			return d.GUID == ping.GUID
+		}, opts...)
+		if err != nil {
+			return fmt.Errorf("checking ping delivery: %w", err)
+		}
+		if attempt != nil {
+			if attempt.StatusCode < 200 || attempt.StatusCode > 299 {
+				return fmt.Errorf("ping delivery %d failed: HTTP %d %s", attempt.ID, attempt.StatusCode, attempt.Status)
+			}
+			fmt.Fprintf(w, "Ping delivered: HTTP %d %s in %.2fs (delivery %d)\n",
+				attempt.StatusCode, attempt.Status, attempt.Duration, attempt.ID)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for ping delivery result", timeout)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const githubWebURL = "https://github.com"
+
+func runAppCreate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("app create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	org := fs.String("org", "", "Create the App owned by this organization instead of your user")
+	force := fs.Bool("force", false, "Overwrite an existing gha configuration")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long to wait for the browser flow to complete")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("usage: gha app create [--org ORG] [--force] <manifest.yaml>")
+	}
+
+	cfgPath, err := config.Path()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(cfgPath); err == nil && !*force {
+		return fmt.Errorf("configuration already exists at %s; pass --force to overwrite it", cfgPath)
+	}
+
+	manifest, err := readManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	app, err := createAppFromManifest(stderr, manifest, *org, *timeout, debugAPIOptions()...)
+	if err != nil {
+		return err
+	}
+
+	keyPath, err := saveCreatedApp(app)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Created GitHub App %s (ID %d): %s\n", app.Name, app.ID, app.HTMLURL)
+	fmt.Fprintf(stdout, "Private key saved to %s\n", keyPath)
+	fmt.Fprintf(stdout, "Configuration saved to %s\n", cfgPath)
+	if app.WebhookSecret != "" {
+		fmt.Fprintf(stdout, "Webhook secret: %s\n", app.WebhookSecret)
+	}
+	fmt.Fprintf(stdout, "Install it with: %s/apps/%s/installations/new\n", githubWebURL, app.Slug)
+	return nil
+}
+
+// readManifest loads an App manifest from a YAML (or JSON) file.
+func readManifest(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	var manifest map[string]any
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if name, _ := manifest["name"].(string); name == "" {
+		return nil, fmt.Errorf("manifest must set name")
+	}
+	if _, ok := manifest["url"]; !ok {
+		return nil, fmt.Errorf("manifest must set url")
+	}
+	return manifest, nil
+}
+
+var manifestFormTmpl = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html><body onload="document.forms[0].submit()">
+<form action="{{.Action}}" method="post">
+<input type="hidden" name="manifest" value="{{.Manifest}}">
+<noscript><button type="submit">Create GitHub App</button></noscript>
+</form>
+</body></html>
+`))
+
+// createAppFromManifest runs the App Manifest flow: it serves a page on
+// localhost that posts the manifest to GitHub, waits for GitHub to redirect
+// back with a temporary code, and exchanges the code for the App credentials.
+func createAppFromManifest(log io.Writer, manifest map[string]any, org string, timeout time.Duration, opts ...auth.Option) (*auth.ManifestApp, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting callback server: %w", err)
+	}
+	base := "http://" + ln.Addr().String()
+
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("generating state: %w", err)
+	}
+	state := hex.EncodeToString(stateBytes)
+
+	manifest["redirect_url"] = base + "/callback"
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	action := githubWebURL + "/settings/apps/new"
+	if org != "" {
+		action = fmt.Sprintf("%s/organizations/%s/settings/apps/new", githubWebURL, url.PathEscape(org))
+	}
+	action += "?state=" + state
+
+	codes := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = manifestFormTmpl.Execute(w, struct{ Action, Manifest string }{action, string(manifestJSON)})
+	})
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("state") != state || q.Get("code") == "" {
+			http.Error(w, "invalid callback", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "GitHub App created. You can close this window and return to the terminal.")
+		select {
+		case codes <- q.Get("code"):
+		default:
+		}
+	})
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(ln) }()
+	defer func() { _ = srv.Close() }()
+
+	fmt.Fprintf(log, "Opening %s in your browser to create the App...\n", base)
+	if err := openBrowser(base + "/"); err != nil {
+		fmt.Fprintf(log, "Could not open a browser (%v); visit %s manually\n", err, base)
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case <-time.After(timeout):
+		return nil, errors.New("timed out waiting for the App to be created in the browser")
+	}
+
+	app, err := auth.ConvertManifest(code, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging manifest code: %w", err)
+	}
+	return app, nil
+}
+
+// saveCreatedApp writes the new App's private key into the config directory
+// and saves a configuration pointing at it.
+func saveCreatedApp(app *auth.ManifestApp) (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating config directory: %w", err)
+	}
+
+	keyPath := filepath.Join(dir, app.Slug+".private-key.pem")
+	if err := os.WriteFile(keyPath, []byte(app.PEM), 0o600); err != nil {
+		return "", fmt.Errorf("writing private key: %w", err)
+	}
+
+	if err := config.Save(&config.Config{AppID: app.ID, PrivateKeyPath: keyPath}); err != nil {
+		return "", fmt.Errorf("saving config: %w", err)
+	}
+	return keyPath, nil
+}
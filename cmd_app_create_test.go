@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadManifest(t *testing.T) {
+	path := writeManifest(t, "name: my-bot\nurl: https://example.com\ndefault_permissions:\n  issues: write\n")
+
+	manifest, err := readManifest(path)
+	if err != nil {
+		t.Fatalf("readManifest: %v", err)
+	}
+	perms, ok := manifest["default_permissions"].(map[string]any)
+	if !ok || perms["issues"] != "write" {
+		t.Errorf("default_permissions = %v, want issues:write", manifest["default_permissions"])
+	}
+}
+
+func TestReadManifest_MissingName(t *testing.T) {
+	path := writeManifest(t, "url: https://example.com\n")
+
+	_, err := readManifest(path)
+	if err == nil || !strings.Contains(err.Error(), "name") {
+		t.Errorf("err = %v, want missing name error", err)
+	}
+}
+
+func TestCreateAppFromManifest(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app-manifests/the-code/conversions" {
+			t.Errorf("path = %s, want conversion of the-code", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 31, "slug": "my-bot", "pem": "PEM"}`))
+	}))
+	defer api.Close()
+
+	// Fake browser: load the form page, check it targets GitHub with the
+	// manifest, then follow the redirect GitHub would make.
+	orig := openBrowser
+	t.Cleanup(func() { openBrowser = orig })
+	openBrowser = func(start string) error {
+		go func() {
+			resp, err := http.Get(start)
+			if err != nil {
+				t.Errorf("GET form: %v", err)
+				return
+			}
+			page, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if !strings.Contains(string(page), "https://github.com/organizations/acme/settings/apps/new?state=") {
+				t.Errorf("form does not target the org App creation page:\n%s", page)
+			}
+			if !strings.Contains(string(page), "redirect_url") {
+				t.Errorf("manifest is missing redirect_url:\n%s", page)
+			}
+			state := regexp.MustCompile(`state=([0-9a-f]+)`).FindStringSubmatch(string(page))[1]
+
+			u, _ := url.Parse(start)
+			resp, err = http.Get(u.Scheme + "://" + u.Host + "/callback?code=the-code&state=" + state)
+			if err != nil {
+				t.Errorf("GET callback: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+
+	manifest := map[string]any{"name": "my-bot", "url": "https://example.com"}
+	app, err := createAppFromManifest(io.Discard, manifest, "acme", 5*time.Second, auth.WithBaseURL(api.URL))
+	if err != nil {
+		t.Fatalf("createAppFromManifest: %v", err)
+	}
+	if app.ID != 31 || app.PEM != "PEM" {
+		t.Errorf("app = %+v", app)
+	}
+}
+
+func TestCreateAppFromManifest_RejectsBadState(t *testing.T) {
+	orig := openBrowser
+	t.Cleanup(func() { openBrowser = orig })
+	openBrowser = func(start string) error {
+		u, _ := url.Parse(start)
+		resp, err := http.Get(u.Scheme + "://" + u.Host + "/callback?code=x&state=forged")
+		if err != nil {
+			t.Errorf("GET callback: %v", err)
+			return nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400 for forged state", resp.StatusCode)
+		}
+		return nil
+	}
+
+	_, err := createAppFromManifest(io.Discard, map[string]any{}, "", 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want timeout after rejected callback", err)
+	}
+}
+
+func TestSaveCreatedApp(t *testing.T) {
+	setupTestEnv(t)
+
+	keyPath, err := saveCreatedApp(&auth.ManifestApp{ID: 31, Slug: "my-bot", PEM: "PEM DATA"})
+	if err != nil {
+		t.Fatalf("saveCreatedApp: %v", err)
+	}
+
+	data, err := os.ReadFile(keyPath)
+	if err != nil || string(data) != "PEM DATA" {
+		t.Errorf("key file = %q, %v", data, err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	if cfg.AppID != 31 || cfg.PrivateKeyPath != keyPath {
+		t.Errorf("cfg = %+v, want app 31 with key %s", cfg, keyPath)
+	}
+}
+
+func TestRun_AppCreateRefusesExistingConfig(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1})
+
+	_, stderr, code := runCmd(t, []string{"gha", "app", "create", writeManifest(t, "name: x\nurl: y\n")}, "")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "--force") {
+		t.Errorf("stderr = %q, want hint about --force", stderr)
+	}
+}
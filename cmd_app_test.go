@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+func newAppServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app" {
+			t.Errorf("path = %s, want /app", r.URL.Path)
+		}
+		w.Write([]byte(`{
+			"id": 9, "slug": "deploy-bot", "name": "Deploy Bot",
+			"owner": {"login": "acme"},
+			"html_url": "https://github.com/apps/deploy-bot",
+			"permissions": {"metadata": "read", "contents": "write"},
+			"events": ["push"]
+		}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestShowApp_Text(t *testing.T) {
+	srv := newAppServer(t)
+
+	var out bytes.Buffer
+	if err := showApp(&out, "fake-jwt", false, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("showApp: %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"Name:   Deploy Bot (deploy-bot)",
+		"Owner:  acme",
+		"  contents: write\n  metadata: read\n",
+		"Events:\n  push\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestShowApp_JSON(t *testing.T) {
+	srv := newAppServer(t)
+
+	var out bytes.Buffer
+	if err := showApp(&out, "fake-jwt", true, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("showApp: %v", err)
+	}
+
+	var app auth.App
+	if err := json.Unmarshal(out.Bytes(), &app); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if app.Slug != "deploy-bot" || app.Permissions["contents"] != "write" {
+		t.Errorf("app = %+v, want deploy-bot with contents:write", app)
+	}
+}
+
+func TestRun_AppUnknownCommand(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "app", "bogus"}, "")
+	if code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr, "unknown app command") {
+		t.Errorf("stderr = %q, want unknown command error", stderr)
+	}
+}
+
+func TestListDeliveries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id": 2, "delivered_at": "2024-05-01T10:00:00Z", "status": "Invalid HTTP Response: 502", "status_code": 502, "event": "issues", "action": "opened", "redelivery": true},
+			{"id": 1, "delivered_at": "2024-05-01T09:00:00Z", "status": "OK", "status_code": 200, "event": "push"}
+		]`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := listDeliveries(&out, "fake-jwt", 30, false, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("listDeliveries: %v", err)
+	}
+
+	want := "2\t2024-05-01T10:00:00Z\tissues.opened\t502\tInvalid HTTP Response: 502\tredelivery\n" +
+		"1\t2024-05-01T09:00:00Z\tpush\t200\tOK\n"
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/hook/deliveries/2/attempts" {
+			t.Errorf("path = %s, want /app/hook/deliveries/2/attempts", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := redeliver(&out, "fake-jwt", 2, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if !strings.Contains(out.String(), "Redelivery of 2 requested") {
+		t.Errorf("output = %q, want confirmation", out.String())
+	}
+}
+
+func TestRun_AppDeliveriesInvalidArgs(t *testing.T) {
+	setupTestEnv(t)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"limit too high", []string{"gha", "app", "deliveries", "list", "--limit", "500"}, "invalid --limit"},
+		{"bad delivery id", []string{"gha", "app", "deliveries", "redeliver", "abc"}, "invalid delivery ID"},
+		{"missing delivery id", []string{"gha", "app", "deliveries", "redeliver"}, "usage"},
+		{"unknown subcommand", []string{"gha", "app", "deliveries", "bogus"}, "unknown app deliveries command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, code := runCmd(t, tt.args, "")
+			if code != exitUsage {
+				t.Errorf("exit code = %d, want %d", code, exitUsage)
+			}
+			if !strings.Contains(stderr, tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHookConfigUpdate(t *testing.T) {
+	var errBuf bytes.Buffer
+	update, err := parseHookConfigUpdate(
+		[]string{"--url", "https://x.ngrok.app/hook", "--content-type", "json", "--insecure-ssl=false"},
+		strings.NewReader(""), &errBuf)
+	if err != nil {
+		t.Fatalf("parseHookConfigUpdate: %v", err)
+	}
+	if update.URL != "https://x.ngrok.app/hook" || update.ContentType != "json" {
+		t.Errorf("update = %+v", update)
+	}
+	if update.InsecureSSL != "0" {
+		t.Errorf("InsecureSSL = %q, want %q", update.InsecureSSL, "0")
+	}
+	if update.Secret != "" {
+		t.Errorf("Secret = %q, want empty (not passed)", update.Secret)
+	}
+}
+
+func TestParseHookConfigUpdate_SecretFromStdin(t *testing.T) {
+	var errBuf bytes.Buffer
+	update, err := parseHookConfigUpdate([]string{"--secret", "-"}, strings.NewReader("s3cret\r\n"), &errBuf)
+	if err != nil {
+		t.Fatalf("parseHookConfigUpdate: %v", err)
+	}
+	if update.Secret != "s3cret" {
+		t.Errorf("Secret = %q, want %q", update.Secret, "s3cret")
+	}
+}
+
+func TestParseHookConfigUpdate_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		wantErr string
+	}{
+		{"no flags", nil, "", "nothing to update"},
+		{"bad content type", []string{"--content-type", "xml"}, "", "invalid --content-type"},
+		{"empty stdin secret", []string{"--secret", "-"}, "\n", "empty"},
+		{"stdin EOF", []string{"--secret", "-"}, "", "end of input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errBuf bytes.Buffer
+			_, err := parseHookConfigUpdate(tt.args, strings.NewReader(tt.stdin), &errBuf)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrintHookConfig(t *testing.T) {
+	var out bytes.Buffer
+	cfg := &auth.HookConfig{URL: "https://example.com/hook", ContentType: "json", Secret: "********", InsecureSSL: "0"}
+	if err := printHookConfig(&out, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "url:          https://example.com/hook\n") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func TestPingHook(t *testing.T) {
+	var redelivered bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/app/hook/deliveries/1/attempts":
+			redelivered = true
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/app/hook/deliveries" && !redelivered:
+			w.Write([]byte(`[{"id": 5, "event": "push"}, {"id": 1, "guid": "g-ping", "event": "ping"}]`))
+		case r.URL.Path == "/app/hook/deliveries":
+			w.Write([]byte(`[{"id": 6, "guid": "g-ping", "event": "ping", "status": "OK", "status_code": 200, "duration": 0.25, "redelivery": true},
+				{"id": 5, "event": "push"}, {"id": 1, "guid": "g-ping", "event": "ping"}]`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := pingHook(&out, "fake-jwt", time.Second, time.Millisecond, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("pingHook: %v", err)
+	}
+	if !strings.Contains(out.String(), "Ping delivered: HTTP 200 OK") {
+		t.Errorf("output = %q, want success message", out.String())
+	}
+}
+
+func TestPingHook_ReceiverFailure(t *testing.T) {
+	listed := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		listed++
+		if listed == 1 {
+			w.Write([]byte(`[{"id": 1, "guid": "g-ping", "event": "ping"}]`))
+			return
+		}
+		w.Write([]byte(`[{"id": 2, "guid": "g-ping", "event": "ping", "status": "Invalid HTTP Response: 502", "status_code": 502}]`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	err := pingHook(&out, "fake-jwt", time.Second, time.Millisecond, auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "HTTP 502") {
+		t.Errorf("err = %v, want HTTP 502 failure", err)
+	}
+}
+
+func TestPingHook_NoPingDelivery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 5, "event": "push"}]`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	err := pingHook(&out, "fake-jwt", time.Second, time.Millisecond, auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "no ping delivery") {
+		t.Errorf("err = %v, want no ping delivery error", err)
+	}
+}
+
+func TestPingHook_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Write([]byte(`[{"id": 1, "guid": "g-ping", "event": "ping"}]`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	err := pingHook(&out, "fake-jwt", 10*time.Millisecond, time.Millisecond, auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want timeout", err)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/installcache"
+	"github.com/haribote-lab/github-app-cli/internal/update"
+)
+
+const cacheUsage = "usage: gha cache <clear|status>"
+
+// cacheCommands are the gha cache subcommands gha handles itself; the rest
+// of gh cache, which manages Actions caches, still goes to gh.
+var cacheCommands = map[string]bool{"clear": true, "status": true}
+
+func runCache(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(cacheUsage)
+	}
+	switch args[0] {
+	case "clear":
+		return runCacheClear(args[1:], stderr)
+	case "status":
+		return runCacheStatus(args[1:], stdout, stderr)
+	default:
+		return usageErrorf("unknown cache command %q - %s", args[0], cacheUsage)
+	}
+}
+
+func runCacheClear(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("cache clear", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	installations := fs.Bool("installations", false, "Clear the installation IDs looked up for orgs and users")
+	updateCheck := fs.Bool("update-check", false, "Clear the remembered gha release and gh version checks")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	// Without a flag, clear everything.
+	if !*installations && !*updateCheck {
+		*installations, *updateCheck = true, true
+	}
+
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	if *installations {
+		candidates = append(candidates, installationCacheFiles(dir)...)
+	}
+	if *updateCheck {
+		candidates = append(candidates, updateCheckFiles(dir)...)
+	}
+	files := existingFiles(candidates)
+	if len(files) == 0 {
+		infof(stderr, "Nothing to clear.\n")
+		return nil
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing %s: %w", f, err)
+		}
+		infof(stderr, "Removed %s\n", f)
+	}
+	return nil
+}
+
+// cacheReport is what gha cache status shows: every cache gha keeps and
+// how long its entries are trusted.
+type cacheReport struct {
+	TokenCached   bool                 `json:"token_cached"`
+	Installations []cachedInstallation `json:"installations"`
+	UpdateCheck   *updateCheckReport   `json:"update_check"`
+	GhVersion     *ghVersionCache      `json:"gh_version"`
+}
+
+// cachedInstallation is an installation cache entry. Scope is the host or
+// App scope whose cache holds it, empty for the default one.
+type cachedInstallation struct {
+	Scope      string    `json:"scope,omitempty"`
+	Login      string    `json:"login"`
+	ID         int64     `json:"id"`
+	TargetType string    `json:"target_type,omitempty"`
+	CachedAt   time.Time `json:"cached_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Expired    bool      `json:"expired"`
+}
+
+type updateCheckReport struct {
+	LatestVersion   string    `json:"latest_version,omitempty"`
+	Channel         string    `json:"channel"`
+	CheckedAt       time.Time `json:"checked_at"`
+	NextCheck       time.Time `json:"next_check"`
+	NotifiedVersion string    `json:"notified_version,omitempty"`
+	NotifiedAt      time.Time `json:"notified_at,omitzero"`
+}
+
+func runCacheStatus(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("cache status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "Print the cache contents as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+
+	report := cacheReport{Installations: []cachedInstallation{}}
+	now := time.Now()
+	for _, path := range installationCacheFiles(dir) {
+		scope, _ := filepath.Rel(dir, filepath.Dir(path))
+		if scope == "." {
+			scope = ""
+		}
+		for _, e := range installcache.Entries(filepath.Dir(path)) {
+			report.Installations = append(report.Installations, cachedInstallation{
+				Scope:      filepath.ToSlash(scope),
+				Login:      e.Login,
+				ID:         e.ID,
+				TargetType: e.TargetType,
+				CachedAt:   e.CachedAt,
+				ExpiresAt:  e.ExpiresAt,
+				Expired:    !now.Before(e.ExpiresAt),
+			})
+		}
+	}
+	if s := update.ReadCheck(dir); s != nil {
+		report.UpdateCheck = &updateCheckReport{
+			LatestVersion:   s.LatestVersion,
+			Channel:         s.Channel,
+			CheckedAt:       s.CheckedAt,
+			NextCheck:       s.NextCheck,
+			NotifiedVersion: s.NotifiedVersion,
+			NotifiedAt:      s.NotifiedAt,
+		}
+	}
+	var gh ghVersionCache
+	if data, err := os.ReadFile(filepath.Join(dir, ghVersionCacheFile)); err == nil && json.Unmarshal(data, &gh) == nil {
+		report.GhVersion = &gh
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.print(stdout)
+	return nil
+}
+
+func (r cacheReport) print(w io.Writer) {
+	line := func(label, format string, a ...any) {
+		fmt.Fprintf(w, "%-15s%s\n", label+":", fmt.Sprintf(format, a...))
+	}
+	stamp := func(t time.Time) string { return t.Local().Format(time.RFC3339) }
+
+	line("token", "not cached; each command mints a fresh installation token")
+	if len(r.Installations) == 0 {
+		line("installations", "none cached")
+	} else {
+		line("installations", "%d cached", len(r.Installations))
+	}
+	for _, inst := range r.Installations {
+		name := inst.Login
+		if inst.Scope != "" {
+			name = inst.Scope + " " + name
+		}
+		if inst.TargetType != "" {
+			name += " (" + inst.TargetType + ")"
+		}
+		state := "expires"
+		if inst.Expired {
+			state = "expired"
+		}
+		fmt.Fprintf(w, "  %s: %d, %s %s\n", name, inst.ID, state, stamp(inst.ExpiresAt))
+	}
+	switch u := r.UpdateCheck; {
+	case u == nil:
+		line("update check", "never run")
+	case u.LatestVersion == "":
+		line("update check", "no release found on %s, checked %s, next check after %s", u.Channel, stamp(u.CheckedAt), stamp(u.NextCheck))
+	default:
+		line("update check", "v%s on %s, checked %s, next check after %s", u.LatestVersion, u.Channel, stamp(u.CheckedAt), stamp(u.NextCheck))
+	}
+	if r.GhVersion != nil {
+		line("gh version", "%s for %s, until the binary changes", r.GhVersion.Version, r.GhVersion.Path)
+	} else {
+		line("gh version", "not cached")
+	}
+}
+
+// installationCacheFiles returns the installation caches under the config
+// directory dir, for the default scope and every host and App scope.
+func installationCacheFiles(dir string) []string {
+	files := []string{installcache.Path(dir)}
+	for _, kind := range []string{"hosts", "apps"} {
+		scopeCaches, _ := filepath.Glob(installcache.Path(filepath.Join(dir, kind, "*")))
+		files = append(files, scopeCaches...)
+	}
+	return files
+}
+
+// updateCheckFiles returns the files remembering the last gha release check
+// and the gh version last checked.
+func updateCheckFiles(dir string) []string {
+	return []string{update.CachePath(dir), filepath.Join(dir, ghVersionCacheFile)}
+}
+
+// existingFiles returns those of paths that exist, each followed by its lock
+// file if there is one.
+func existingFiles(paths []string) []string {
+	var files []string
+	for _, f := range paths {
+		// Lock files sit beside the file they guard.
+		for _, f := range []string{f, f + ".lock"} {
+			if _, err := os.Stat(f); err == nil {
+				files = append(files, filepath.Clean(f))
+			}
+		}
+	}
+	return files
+}
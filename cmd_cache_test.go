@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/installcache"
+	"github.com/haribote-lab/github-app-cli/internal/update"
+)
+
+func TestRun_CacheClear(t *testing.T) {
+	setupTestEnv(t)
+	dir, _ := seedState(t)
+	scoped := filepath.Join(dir, "apps", "bot")
+	installcache.Store(scoped, []installcache.Mapping{{Login: "acme", ID: 2}})
+
+	_, stderr, code := runCmd(t, []string{"gha", "cache", "clear", "--installations"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, f := range []string{installcache.Path(dir), installcache.Path(scoped)} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", f)
+		}
+	}
+	if _, err := os.Stat(update.CachePath(dir)); err != nil {
+		t.Errorf("--installations must keep the update check: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.yaml")); err != nil {
+		t.Errorf("the config must be kept: %v", err)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "cache", "clear"}, "")
+	if code != 0 || !strings.Contains(stderr, "Removed "+update.CachePath(dir)) {
+		t.Fatalf("code = %d, stderr = %q", code, stderr)
+	}
+	_, stderr, code = runCmd(t, []string{"gha", "cache", "clear"}, "")
+	if code != 0 || !strings.Contains(stderr, "Nothing to clear") {
+		t.Errorf("code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRun_CacheUsage(t *testing.T) {
+	setupTestEnv(t)
+
+	if _, _, code := runCmd(t, []string{"gha", "cache", "clear", "extra"}, ""); code != exitUsage {
+		t.Errorf("extra argument: code = %d, want %d", code, exitUsage)
+	}
+	if _, _, code := runCmd(t, []string{"gha", "cache", "clear", "--tokens"}, ""); code != exitUsage {
+		t.Errorf("unknown flag: code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRun_CacheStatus(t *testing.T) {
+	setupTestEnv(t)
+	dir, _ := seedState(t)
+	installcache.Store(filepath.Join(dir, "apps", "bot"), []installcache.Mapping{{Login: "acme", TargetType: "Organization", ID: 2}})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "cache", "status"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{"token:", "installations: 2 cached", "  acme: 1, expires ", "  apps/bot acme (Organization): 2, expires ", "update check:"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, code = runCmd(t, []string{"gha", "cache", "status", "--json"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	var report cacheReport
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout)
+	}
+	if report.TokenCached || len(report.Installations) != 2 || report.Installations[1].Scope != "apps/bot" || report.Installations[1].Expired {
+		t.Errorf("report = %+v", report)
+	}
+	if report.UpdateCheck == nil || report.GhVersion != nil {
+		t.Errorf("update_check = %+v, gh_version = %+v; want only the update check", report.UpdateCheck, report.GhVersion)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+const cloneUsage = "usage: gha [--hostname <host>] clone <owner>/<repo> [<directory>] [-- <git clone flags>...]"
+
+// runClone clones a repository with a token minted for it alone. The token
+// reaches git through a credential helper that reads it from a private,
+// temporary file, never through the URL, so the clone's origin is the plain
+// https URL and no token is left in .git/config.
+func runClone(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	var gitArgs []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, gitArgs = args[:i], args[i+1:]
+	}
+	if len(args) < 1 || len(args) > 2 || strings.HasPrefix(args[0], "-") {
+		return usageErrorf(cloneUsage)
+	}
+	host := packagesHost()
+	repo := args[0]
+	if remoteHost, remoteRepo, ok := parseRemoteURL(repo); ok && strings.Contains(repo, ":") {
+		host, repo = strings.ToLower(remoteHost), remoteRepo
+		if err := os.Setenv(ghHostEnv, host); err != nil {
+			return err
+		}
+	}
+	owner, name, err := parseRepo(repo)
+	if err != nil {
+		return err
+	}
+
+	sel, err := selectApp(installationOverride{repo: repo})
+	if err != nil {
+		return err
+	}
+	cfg := sel.cfg
+	jwtToken, err := signJWT(cfg)
+	if err != nil {
+		return err
+	}
+	opts := apiOptions(cfg)
+	installationID, err := resolveInstallation(jwtToken, sel.src, opts...)
+	if err != nil {
+		return err
+	}
+	debugf("installation: %d (%s)", installationID, sel.src.origin(installationID))
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		return err
+	}
+	scope := &auth.TokenRequest{Permissions: sel.project.Permissions, Repositories: []string{name}}
+	keys := newKeySource(cfg)
+	mint := func() (*auth.InstallationToken, error) {
+		jwtToken, err := keys.sign()
+		if err != nil {
+			return nil, err
+		}
+		return mintInstallationToken(jwtToken, installationID, scope, opts...)
+	}
+	token, err := mintInstallationToken(jwtToken, installationID, scope, opts...)
+	if err != nil {
+		return fmt.Errorf("getting installation token: %w", err)
+	}
+
+	// A large clone may outlast the token; the refresher keeps the file
+	// the helper reads current.
+	r, err := startRefresher(token, mint, stderr)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+	env, err := r.env(host)
+	if err != nil {
+		return err
+	}
+
+	argv := append([]string{"git", "clone", "https://" + host + "/" + owner + "/" + name + ".git"}, args[1:]...)
+	argv = append(argv, gitArgs...)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Env = append(proxy.WithoutCredentials(os.Environ()), env...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, rawOutput(stderr)
+	err = cmd.Run()
+	code := 0
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		code, err = exitErr.ExitCode(), nil
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("git not found in PATH")
+	case err != nil:
+		return fmt.Errorf("running git clone: %w", err)
+	}
+	if err := audit.record(installationID, argv, code, nil); err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+	if code != 0 {
+		// git already reported the failure; pass its exit code on.
+		return &proxy.ExitError{Code: code}
+	}
+	infof(stderr, "The clone holds no token; run gha setup-git to fetch and push with the App later.\n")
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Clone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake git uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	var scope auth.TokenRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/installation":
+			w.Write([]byte(`{"id": 42, "account": {"login": "acme"}}`))
+		case "/app/installations/42/access_tokens":
+			if err := json.NewDecoder(r.Body).Decode(&scope); err != nil {
+				t.Errorf("decoding token request: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "ghs_forclone", "expires_at": "2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	// The fake git records its arguments, the helper git would run and the
+	// token that helper would answer with.
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "git.log")
+	script := "#!/bin/sh\n{ echo \"$@\"; echo \"$GIT_CONFIG_VALUE_1\"; cat \"$GHA_TOKEN_FILE\"; } > " + logFile + "\nexit ${FAKE_GIT_EXIT:-0}\n"
+	if err := os.WriteFile(filepath.Join(binDir, "git"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GIT_CONFIG_COUNT", "")
+
+	if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "clone", "acme/app", "src", "--", "--depth", "1"}, ""); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("git log = %q", data)
+	}
+	if want := "clone https://ghe.test/acme/app.git src --depth 1"; lines[0] != want {
+		t.Errorf("git args = %q, want %q", lines[0], want)
+	}
+	if !strings.Contains(lines[1], "git-credential --token-file") {
+		t.Errorf("credential helper = %q", lines[1])
+	}
+	if lines[2] != "ghs_forclone" {
+		t.Errorf("token file = %q, want ghs_forclone", lines[2])
+	}
+	if len(scope.Repositories) != 1 || scope.Repositories[0] != "app" {
+		t.Errorf("token repositories = %v, want [app]", scope.Repositories)
+	}
+
+	t.Setenv("FAKE_GIT_EXIT", "128")
+	if _, _, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "clone", "acme/app"}, ""); code != 128 {
+		t.Errorf("failed clone: exit code = %d, want git's 128", code)
+	}
+	if _, _, code := runCmd(t, []string{"gha", "clone"}, ""); code != exitUsage {
+		t.Errorf("no repository: exit code = %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/installcache"
+)
+
+const completionUsage = "usage: gha completion <bash|zsh|fish>"
+
+// completeCommand is the hidden command completion scripts call for flag
+// values that depend on the config: gha __complete <org|installation-id|app>.
+const completeCommand = "__complete"
+
+// usageFlag is one entry of the Flags section of the usage text, which the
+// completion scripts are generated from.
+type usageFlag struct {
+	long, short, arg, desc string
+}
+
+// usageFlags parses the Flags section of the usage text.
+func usageFlags() []usageFlag {
+	_, section, _ := strings.Cut(usage, "\nFlags:\n")
+	section, _, _ = strings.Cut(section, "\n\n")
+	var flags []usageFlag
+	for _, line := range strings.Split(section, "\n") {
+		spec, desc, _ := strings.Cut(strings.TrimSpace(line), "  ")
+		f := usageFlag{desc: strings.TrimSpace(desc)}
+		for _, part := range strings.Split(spec, ", ") {
+			name, arg, _ := strings.Cut(part, " ")
+			if long, ok := strings.CutPrefix(name, "--"); ok {
+				f.long, f.arg = long, arg
+			} else {
+				f.short = strings.TrimPrefix(name, "-")
+			}
+		}
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// completionCommands returns gha's own commands, sorted.
+func completionCommands() []string {
+	commands := make([]string, 0, len(builtinCommands))
+	for name := range builtinCommands {
+		commands = append(commands, name)
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+const bashCompletion = `# bash completion for gha
+_gha() {
+  local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+  case "$prev" in
+    --org) COMPREPLY=($(compgen -W "$(gha __complete org 2>/dev/null)" -- "$cur")); return ;;
+    --installation-id) COMPREPLY=($(compgen -W "$(gha __complete installation-id 2>/dev/null | cut -f1)" -- "$cur")); return ;;
+    --app) COMPREPLY=($(compgen -W "$(gha __complete app 2>/dev/null)" -- "$cur")); return ;;
+    --target-type) COMPREPLY=($(compgen -W "org user" -- "$cur")); return ;;
+  esac
+  if [[ "$cur" == -* ]]; then
+    COMPREPLY=($(compgen -W "{{flags}}" -- "$cur"))
+  elif [[ $COMP_CWORD -eq 1 ]]; then
+    COMPREPLY=($(compgen -W "{{commands}}" -- "$cur"))
+  fi
+}
+complete -o default -F _gha gha
+`
+
+const zshCompletion = `#compdef gha
+_gha() {
+  local -a values
+  case "${words[CURRENT-1]}" in
+    --org) values=(${(f)"$(gha __complete org 2>/dev/null)"}); compadd -a values; return ;;
+    --installation-id)
+      values=(${(f)"$(gha __complete installation-id 2>/dev/null)"})
+      values=(${values//$'\t'/:})
+      _describe installation values; return ;;
+    --app) values=(${(f)"$(gha __complete app 2>/dev/null)"}); compadd -a values; return ;;
+    --target-type) compadd org user; return ;;
+  esac
+  if [[ "$PREFIX" == -* ]]; then
+    compadd -- {{flags}}
+  elif (( CURRENT == 2 )); then
+    compadd -- {{commands}}
+  else
+    _files
+  fi
+}
+compdef _gha gha
+`
+
+const fishCompletion = `# fish completion for gha
+complete -c gha -n __fish_use_subcommand -x -a '{{commands}}'
+{{fish_flags}}`
+
+// dynamicFlags are the flags whose values gha __complete lists.
+var dynamicFlags = map[string]bool{"org": true, "installation-id": true, "app": true}
+
+func runCompletion(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErrorf(completionUsage)
+	}
+	var flagNames []string
+	for _, f := range usageFlags() {
+		if f.short != "" {
+			flagNames = append(flagNames, "-"+f.short)
+		}
+		flagNames = append(flagNames, "--"+f.long)
+	}
+	r := strings.NewReplacer(
+		"{{flags}}", strings.Join(flagNames, " "),
+		"{{commands}}", strings.Join(completionCommands(), " "),
+		"{{fish_flags}}", fishFlags(),
+	)
+	switch args[0] {
+	case "bash":
+		fmt.Fprint(stdout, r.Replace(bashCompletion))
+	case "zsh":
+		fmt.Fprint(stdout, r.Replace(zshCompletion))
+	case "fish":
+		fmt.Fprint(stdout, r.Replace(fishCompletion))
+	default:
+		return usageErrorf("unsupported shell %q - %s", args[0], completionUsage)
+	}
+	return nil
+}
+
+// fishFlags returns a complete line per flag, with values for the flags
+// gha __complete knows about.
+func fishFlags() string {
+	var b strings.Builder
+	for _, f := range usageFlags() {
+		fmt.Fprintf(&b, "complete -c gha -l %s", f.long)
+		if f.short != "" {
+			fmt.Fprintf(&b, " -s %s", f.short)
+		}
+		switch {
+		case dynamicFlags[f.long]:
+			fmt.Fprintf(&b, " -x -a '(gha __complete %s 2>/dev/null)'", f.long)
+		case f.long == "target-type":
+			b.WriteString(" -x -a 'org user'")
+		case f.arg != "":
+			b.WriteString(" -r")
+		}
+		fmt.Fprintf(&b, " -d %s\n", shellQuote(f.desc))
+	}
+	return b.String()
+}
+
+// runComplete prints the candidate values for a flag, one per line. An
+// installation ID is followed by a tab and its account login.
+func runComplete(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErrorf("usage: gha %s <org|installation-id|app>", completeCommand)
+	}
+	switch args[0] {
+	case "app":
+		cfg, err := loadHostConfig()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(cfg.Apps))
+		for name := range cfg.Apps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintln(stdout, name)
+		}
+	case "org", "installation-id":
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		mappings, err := installationCandidates(cfg)
+		if err != nil {
+			return err
+		}
+		for _, m := range mappings {
+			if args[0] == "org" {
+				fmt.Fprintln(stdout, m.Login)
+			} else {
+				fmt.Fprintf(stdout, "%d\t%s\n", m.ID, m.Login)
+			}
+		}
+	default:
+		return fmt.Errorf("cannot complete %q", args[0])
+	}
+	return nil
+}
+
+// installationCandidates returns the installations known for cfg's scope,
+// from the installation cache and the config's installations map, sorted by
+// login. Only when neither knows any are they listed through the API, which
+// also fills the cache for next time.
+func installationCandidates(cfg *config.Config) ([]installcache.Mapping, error) {
+	byLogin := map[string]int64{}
+	dir, dirErr := cacheDir(cfg.Scope())
+	if dirErr == nil {
+		for _, m := range installcache.All(dir) {
+			byLogin[m.Login] = m.ID
+		}
+	}
+	for login, id := range cfg.Installations {
+		byLogin[strings.ToLower(login)] = id
+	}
+
+	if len(byLogin) == 0 {
+		jwtToken, err := signJWT(cfg)
+		if err != nil {
+			return nil, err
+		}
+		installations, err := auth.GetInstallations(jwtToken, apiOptions(cfg)...)
+		if err != nil {
+			return nil, fmt.Errorf("listing installations: %w", err)
+		}
+		mappings := make([]installcache.Mapping, 0, len(installations))
+		for _, inst := range installations {
+			mappings = append(mappings, installcache.Mapping{Login: inst.Account.Login, TargetType: inst.TargetType, ID: inst.ID})
+			byLogin[strings.ToLower(inst.Account.Login)] = inst.ID
+		}
+		if dirErr == nil {
+			installcache.Store(dir, mappings)
+		}
+	}
+
+	mappings := make([]installcache.Mapping, 0, len(byLogin))
+	for login, id := range byLogin {
+		mappings = append(mappings, installcache.Mapping{Login: login, ID: id})
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Login < mappings[j].Login })
+	return mappings, nil
+}
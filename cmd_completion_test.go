@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/installcache"
+)
+
+func TestUsageFlags(t *testing.T) {
+	flags := map[string]usageFlag{}
+	for _, f := range usageFlags() {
+		flags[f.long] = f
+	}
+	if f := flags["quiet"]; f.short != "q" || f.arg != "" {
+		t.Errorf("quiet = %+v, want short q and no argument", f)
+	}
+	if f := flags["installation-id"]; f.arg != "<id>" || f.desc == "" {
+		t.Errorf("installation-id = %+v, want an <id> argument and a description", f)
+	}
+}
+
+func TestRun_Completion(t *testing.T) {
+	setupTestEnv(t)
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		stdout, stderr, code := runCmd(t, []string{"gha", "completion", shell}, "")
+		if code != 0 {
+			t.Fatalf("%s: code = %d, stderr = %q", shell, code, stderr)
+		}
+		for _, want := range []string{"__complete org", "__complete installation-id", "self-update", "installation-id"} {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: script lacks %q", shell, want)
+			}
+		}
+		if shell == "bash" {
+			if bash, err := exec.LookPath("bash"); err == nil {
+				cmd := exec.Command(bash, "-n")
+				cmd.Stdin = strings.NewReader(stdout)
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Errorf("bash -n: %v\n%s", err, out)
+				}
+			}
+		}
+	}
+	if _, _, code := runCmd(t, []string{"gha", "completion", "tcsh"}, ""); code != exitUsage {
+		t.Errorf("tcsh: code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRun_CompleteInstallations(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, Installations: map[string]int64{"pinned": 7}})
+	dir, err := config.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	installcache.Store(dir, []installcache.Mapping{{Login: "Acme", ID: 11}})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", completeCommand, "org"}, "")
+	if code != 0 || stdout != "acme\npinned\n" {
+		t.Errorf("org: stdout = %q, stderr = %q, code = %d", stdout, stderr, code)
+	}
+	stdout, _, _ = runCmd(t, []string{"gha", completeCommand, "installation-id"}, "")
+	if stdout != "11\tacme\n7\tpinned\n" {
+		t.Errorf("installation-id: stdout = %q", stdout)
+	}
+}
+
+func TestRun_CompleteInstallations_FromAPI(t *testing.T) {
+	setupTestEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 5, "account": {"login": "Acme"}, "target_type": "Organization"}]`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{AppID: 1, Hosts: map[string]config.Host{
+		"ghe.example.com": {AppID: 2, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL},
+	}})
+	t.Setenv(ghHostEnv, "ghe.example.com")
+
+	stdout, stderr, code := runCmd(t, []string{"gha", completeCommand, "org"}, "")
+	if code != 0 || stdout != "acme\n" {
+		t.Fatalf("stdout = %q, stderr = %q, code = %d", stdout, stderr, code)
+	}
+	dir, err := cacheDir("hosts/ghe.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := installcache.Lookup(dir, "acme", ""); !ok || id != 5 {
+		t.Errorf("cache = %d, %v; want the listing stored", id, ok)
+	}
+}
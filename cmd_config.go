@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const configUsage = `usage:
+  gha config get <key>
+  gha config set <key> <value>
+  gha config unset <key>
+  gha config list
+  gha config edit
+  gha config check [--json]
+  gha config encrypt [--recipient <age-recipient>]...
+  gha config decrypt
+  gha config convert <yaml|toml|json>
+  gha config schema`
+
+func runConfig(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(configUsage)
+	}
+	switch args[0] {
+	case "check":
+		return runConfigCheck(args[1:], stdout, stderr)
+	case "edit":
+		return runConfigEdit(args[1:], stderr)
+	case "encrypt":
+		return runConfigEncrypt(args[1:], stderr)
+	case "decrypt":
+		return runConfigDecrypt(args[1:], stderr)
+	case "schema":
+		if len(args) != 1 {
+			return usageErrorf("unexpected arguments: %s", strings.Join(args[1:], " "))
+		}
+		schema, err := config.Schema()
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(schema)
+		return err
+	case "convert":
+		if len(args) != 2 {
+			return usageErrorf(configUsage)
+		}
+		from, to, err := config.Convert(args[1])
+		if err != nil {
+			return err
+		}
+		infof(stderr, "Converted %s to %s\n", from, to)
+		if os.Getenv(config.PathEnv) != "" {
+			infof(stderr, "Point %s at the new file.\n", config.PathEnv)
+		}
+		return nil
+	}
+
+	want := map[string]int{"get": 1, "set": 2, "unset": 1, "list": 0}
+	n, ok := want[args[0]]
+	if !ok {
+		return usageErrorf("unknown config command %q", args[0])
+	}
+	if len(args)-1 != n {
+		return usageErrorf(configUsage)
+	}
+
+	cfg, err := config.LoadPartial()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		value, set, err := cfg.Get(args[1])
+		if err != nil {
+			return err
+		}
+		if !set {
+			return fmt.Errorf("%s is not set", args[1])
+		}
+		fmt.Fprintln(stdout, value)
+		return nil
+	case "list":
+		for _, kv := range cfg.List() {
+			fmt.Fprintf(stdout, "%s=%s\n", kv[0], kv[1])
+		}
+		return nil
+	case "set":
+		if err := cfg.Set(args[1], args[2]); err != nil {
+			return err
+		}
+	case "unset":
+		if err := cfg.Unset(args[1]); err != nil {
+			return err
+		}
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	return nil
+}
+
+// runConfigEncrypt turns on encryption at rest, with age recipients when
+// given and with $GHA_CONFIG_PASSPHRASE otherwise.
+func runConfigEncrypt(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("config encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var recipients []string
+	fs.Func("recipient", "Encrypt to this age recipient (repeatable)", func(r string) error {
+		recipients = append(recipients, r)
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg, err := config.LoadPartial()
+	if err != nil {
+		return err
+	}
+	cfg.Encryption = &config.Encryption{Passphrase: len(recipients) == 0, AgeRecipients: recipients}
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if len(recipients) == 0 {
+		infof(stderr, "Configuration encrypted; set %s to use it\n", config.PassphraseEnv)
+	} else {
+		infof(stderr, "Configuration encrypted to %d age recipient(s); set %s to use it\n", len(recipients), config.AgeIdentityEnv)
+	}
+	return nil
+}
+
+func runConfigDecrypt(args []string, stderr io.Writer) error {
+	if len(args) > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+	cfg, err := config.LoadPartial()
+	if err != nil {
+		return err
+	}
+	if cfg.Encryption == nil {
+		return fmt.Errorf("the config file is not encrypted")
+	}
+	cfg.Encryption = nil
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	infof(stderr, "Configuration decrypted\n")
+	return nil
+}
+
+func runConfigCheck(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("config check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	lookup, err := config.LookupOrder()
+	if err != nil {
+		return err
+	}
+	checks := checkConfig()
+	failed := 0
+	for _, c := range checks {
+		if c.Status == checkFail {
+			failed++
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			OK     bool            `json:"ok"`
+			Lookup []config.Source `json:"lookup"`
+			Checks []configCheck   `json:"checks"`
+		}{failed == 0, lookup, checks}); err != nil {
+			return err
+		}
+	} else {
+		printLookupOrder(stdout, lookup)
+		for _, c := range checks {
+			status := fmt.Sprintf("%-4s", strings.ToUpper(c.Status))
+			fmt.Fprintf(stdout, "%s  %-13s %s\n", paint(stdout, checkColors[c.Status], status), c.Name, c.Detail)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// printLookupOrder lists where gha looks for its config, first to last,
+// marking the source in effect.
+func printLookupOrder(w io.Writer, lookup []config.Source) {
+	fmt.Fprintln(w, "Config lookup order:")
+	for _, s := range lookup {
+		detail := "not set"
+		switch {
+		case s.Set && s.Path != "":
+			detail = s.Path
+		case s.Set:
+			detail = "set"
+		}
+		if s.Used {
+			detail += " (in use)"
+		}
+		fmt.Fprintf(w, "  %-21s %s\n", s.Name, detail)
+	}
+	fmt.Fprintln(w)
+}
+
+const (
+	checkPass = "pass"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// checkColors color each check's status in gha config check's output.
+var checkColors = map[string]string{checkPass: colorGreen, checkFail: colorRed, checkSkip: colorYellow}
+
+type configCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// checkConfig runs each configuration check in turn. A check whose
+// prerequisite failed is reported as skipped rather than failing again.
+func checkConfig(opts ...auth.Option) []configCheck {
+	var checks []configCheck
+	add := func(name, status, format string, a ...any) {
+		checks = append(checks, configCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, a...)})
+	}
+	skip := func(names ...string) []configCheck {
+		for _, name := range names {
+			add(name, checkSkip, "skipped")
+		}
+		return checks
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		if paths, perr := config.DefaultPaths(); perr == nil && os.Getenv(config.PathEnv) == "" && os.Getenv(config.AppIDEnv) == "" {
+			add("config", checkFail, "%v (looked for %s)", err, strings.Join(paths, ", "))
+		} else {
+			add("config", checkFail, "%v", err)
+		}
+		return skip("key file", "key parses", "app", "installation")
+	}
+	if host := cfg.Host(); host != "" && cfg.FromEnvironment() {
+		add("config", checkPass, "read from %s for host %s", config.AppIDEnv, host)
+	} else if host != "" {
+		path, _ := config.Path()
+		add("config", checkPass, "%s (hosts.%s)", path, host)
+	} else if name := cfg.AppName(); name != "" {
+		path, _ := config.Path()
+		add("config", checkPass, "%s (apps.%s)", path, name)
+	} else if cfg.FromEnvironment() {
+		add("config", checkPass, "read from %s", config.AppIDEnv)
+	} else {
+		path, _ := config.Path()
+		add("config", checkPass, "%s", path)
+	}
+
+	if cfg.PrivateKey != "" {
+		add("key file", checkPass, "inline key from %s", config.PrivateKeyEnv)
+	} else if _, err := os.Stat(cfg.KeyPath()); err != nil {
+		add("key file", checkFail, "%v", err)
+		return skip("key parses", "app", "installation")
+	} else if problem := cfg.KeyProblem(); problem != "" {
+		add("key file", checkFail, "%s", problem)
+	} else {
+		add("key file", checkPass, "%s", cfg.KeyPath())
+	}
+
+	// The key file check above already reports who can read the key, so
+	// sign regardless, to report on the key itself too.
+	jwtToken, err := generateJWT(cfg)
+	if err != nil {
+		add("key parses", checkFail, "%v", err)
+		return skip("app", "installation")
+	}
+	add("key parses", checkPass, "signed a JWT for app %d", cfg.AppID)
+
+	opts = append(apiOptions(cfg), opts...)
+	app, err := auth.GetApp(jwtToken, opts...)
+	if err != nil {
+		add("app", checkFail, "%v", err)
+		return skip("installation")
+	}
+	add("app", checkPass, "authenticated as %s (owner %s)", app.Slug, app.Owner.Login)
+
+	if cfg.InstallationID == 0 {
+		add("installation", checkSkip, "installation_id not set; resolved at runtime")
+		return checks
+	}
+	inst, err := auth.GetInstallation(jwtToken, cfg.InstallationID, opts...)
+	if err != nil {
+		add("installation", checkFail, "installation %d: %v", cfg.InstallationID, err)
+		return checks
+	}
+	if err := checkSuspended(inst); err != nil {
+		add("installation", checkFail, "%v", err)
+		return checks
+	}
+	add("installation", checkPass, "%d on %s", inst.ID, inst.Account.Login)
+	return checks
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// editErrorPrefix marks the comment lines gha adds above the config when it
+// reopens the editor after a failed validation. They are stripped on save.
+const editErrorPrefix = "# gha: "
+
+// newConfigTemplates seed the editor, by format, when no config exists yet.
+var newConfigTemplates = map[string]string{
+	"yaml": `# gha configuration. Keys: app_id, installation_id, private_key_path,
+# target_type, installations.
+app_id:
+private_key_path:
+`,
+	"toml": `# gha configuration. Keys: app_id, installation_id, private_key_path,
+# target_type, installations.
+app_id = 0
+private_key_path = ""
+`,
+	"json": `{
+  "app_id": 0,
+  "private_key_path": ""
+}
+`,
+}
+
+func runConfigEdit(args []string, stderr io.Writer) error {
+	if len(args) > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(args, " "))
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	format := config.FormatOf(path)
+	original, err := config.ReadRaw()
+	if os.IsNotExist(err) {
+		original = []byte(newConfigTemplates[format])
+	} else if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "gha-config-*."+format)
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+	keepTmp := false
+	defer func() {
+		if !keepTmp {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	content := original
+	var lastErr error
+	for {
+		if err := os.WriteFile(tmpPath, withEditErrors(content, lastErr), 0o600); err != nil {
+			return fmt.Errorf("writing temporary file: %w", err)
+		}
+		if err := openEditor(tmpPath); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return fmt.Errorf("reading edited config: %w", err)
+		}
+		edited = stripEditErrors(edited)
+
+		if bytes.Equal(edited, original) {
+			infof(stderr, "Edit cancelled, no changes made.\n")
+			return nil
+		}
+		if lastErr != nil && bytes.Equal(edited, content) {
+			// Saved again without fixing anything: give up like kubectl edit.
+			keepTmp = true
+			_ = os.WriteFile(tmpPath, edited, 0o600)
+			return fmt.Errorf("%v\nyour changes were kept in %s", lastErr, tmpPath)
+		}
+
+		if err := config.SaveRaw(edited); err != nil {
+			content, lastErr = edited, err
+			fmt.Fprintf(stderr, "error: %v\nReopening the editor...\n", err)
+			continue
+		}
+		infof(stderr, "Configuration saved to %s\n", path)
+		return nil
+	}
+}
+
+// withEditErrors prefixes data with comment lines describing err.
+func withEditErrors(data []byte, err error) []byte {
+	if err == nil {
+		return data
+	}
+	var b bytes.Buffer
+	b.WriteString(editErrorPrefix + "the config was not saved because it is invalid:\n")
+	for _, line := range strings.Split(err.Error(), "\n") {
+		b.WriteString(editErrorPrefix + "  " + line + "\n")
+	}
+	b.Write(data)
+	return b.Bytes()
+}
+
+func stripEditErrors(data []byte) []byte {
+	for bytes.HasPrefix(data, []byte(editErrorPrefix)) {
+		_, rest, ok := bytes.Cut(data, []byte("\n"))
+		if !ok {
+			return nil
+		}
+		data = rest
+	}
+	return data
+}
+
+// openEditor runs $VISUAL or $EDITOR (falling back to vi, or notepad on
+// Windows) on path, attached to the terminal.
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// Allow editors with arguments, such as "code --wait".
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], filepath.Clean(path))...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running editor %q: %w", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// fakeEditor installs a shell script as $EDITOR. On its Nth invocation the
+// script copies contents[N-1] over the edited file (or leaves it alone when
+// that entry is empty) and appends the file it was shown to seen.log.
+func fakeEditor(t *testing.T, contents ...string) (seenLog string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake editor is a shell script")
+	}
+	dir := t.TempDir()
+	for i, c := range contents {
+		if err := os.WriteFile(filepath.Join(dir, "edit"+string(rune('1'+i))), []byte(c), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := `#!/bin/sh
+dir="$(dirname "$0")"
+n=$(( $(cat "$dir/count" 2>/dev/null || echo 0) + 1 ))
+echo "$n" > "$dir/count"
+cat "$1" >> "$dir/seen.log"
+if [ -s "$dir/edit$n" ]; then cp "$dir/edit$n" "$1"; fi
+`
+	editor := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(editor, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+	return filepath.Join(dir, "seen.log")
+}
+
+func TestRun_ConfigEdit(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+	fakeEditor(t, "# my bot\napp_id: 2\nprivate_key_path: /k.pem\n")
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "edit"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	path, _ := config.Path()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# my bot\n") {
+		t.Errorf("config = %q, want comments preserved", data)
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.AppID != 2 {
+		t.Errorf("Load = %+v, %v; want app_id 2", cfg, err)
+	}
+}
+
+func TestRun_ConfigEditReopensOnInvalid(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+	seen := fakeEditor(t,
+		"app_id: 1\nprivate_key_path: /k.pem\ncolour: blue\n",
+		"app_id: 1\nprivate_key_path: /k.pem\ntarget_type: org\n",
+	)
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "edit"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	log, _ := os.ReadFile(seen)
+	if !strings.Contains(string(log), editErrorPrefix) || !strings.Contains(string(log), "colour") {
+		t.Errorf("second editor session should show the error above the rejected edit, saw:\n%s", log)
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.TargetType != "org" {
+		t.Errorf("Load = %+v, %v; want the corrected config", cfg, err)
+	}
+}
+
+func TestRun_ConfigEditGivesUpWhenUnfixed(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+	fakeEditor(t, "app_id: -4\nprivate_key_path: /k.pem\n")
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "edit"}, "")
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "app_id must be a positive integer") || !strings.Contains(stderr, "your changes were kept in") {
+		t.Errorf("stderr = %q", stderr)
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.AppID != 1 {
+		t.Errorf("original config should be untouched: %+v, %v", cfg, err)
+	}
+}
+
+func TestRun_ConfigEditUnchanged(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+	fakeEditor(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "edit"}, "")
+	if code != 0 || !strings.Contains(stderr, "Edit cancelled") {
+		t.Errorf("code = %d, stderr = %q; want cancelled", code, stderr)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_ConfigSetGetFromScratch(t *testing.T) {
+	setupTestEnv(t)
+
+	for _, args := range [][]string{
+		{"gha", "config", "set", "app_id", "123"},
+		{"gha", "config", "set", "private_key_path", "/keys/app.pem"},
+		{"gha", "config", "set", "installation_id", "456"},
+	} {
+		if _, stderr, code := runCmd(t, args, ""); code != 0 {
+			t.Fatalf("%v: exit code = %d, stderr = %s", args, code, stderr)
+		}
+	}
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "config", "get", "installation_id"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if stdout != "456\n" {
+		t.Errorf("stdout = %q, want %q", stdout, "456\n")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config written by set should load: %v", err)
+	}
+	if cfg.AppID != 123 || cfg.PrivateKeyPath != "/keys/app.pem" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestRun_ConfigListAndUnset(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem", InstallationID: 9,
+		Installations: map[string]int64{"zeta": 2, "acme": 3}})
+
+	if _, stderr, code := runCmd(t, []string{"gha", "config", "unset", "installation_id"}, ""); code != 0 {
+		t.Fatalf("unset: exit code = %d, stderr = %s", code, stderr)
+	}
+
+	stdout, _, code := runCmd(t, []string{"gha", "config", "list"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	want := "app_id=1\nprivate_key_path=/k.pem\ninstallations.acme=3\ninstallations.zeta=2\n"
+	if stdout != want {
+		t.Errorf("list = %q, want %q", stdout, want)
+	}
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "get", "installation_id"}, "")
+	if code != 1 || !strings.Contains(stderr, "installation_id is not set") {
+		t.Errorf("get unset key: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRun_ConfigEncryptDecrypt(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+	path, _ := config.Path()
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "encrypt"}, "")
+	if code != 1 || !strings.Contains(stderr, config.PassphraseEnv) {
+		t.Errorf("encrypt without passphrase: code = %d, stderr = %q", code, stderr)
+	}
+
+	t.Setenv(config.PassphraseEnv, "s3cret")
+	if _, stderr, code := runCmd(t, []string{"gha", "config", "encrypt"}, ""); code != 0 {
+		t.Fatalf("encrypt: code = %d, stderr = %s", code, stderr)
+	}
+	if data, _ := os.ReadFile(path); !config.IsEncrypted(data) {
+		t.Fatalf("config not encrypted:\n%s", data)
+	}
+	stdout, _, code := runCmd(t, []string{"gha", "config", "get", "app_id"}, "")
+	if code != 0 || stdout != "1\n" {
+		t.Errorf("get from encrypted config: code = %d, stdout = %q", code, stdout)
+	}
+
+	if _, stderr, code := runCmd(t, []string{"gha", "config", "decrypt"}, ""); code != 0 {
+		t.Fatalf("decrypt: code = %d, stderr = %s", code, stderr)
+	}
+	if data, _ := os.ReadFile(path); config.IsEncrypted(data) || !strings.Contains(string(data), "app_id: 1") {
+		t.Errorf("config not decrypted:\n%s", data)
+	}
+	_, stderr, code = runCmd(t, []string{"gha", "config", "decrypt"}, "")
+	if code != 1 || !strings.Contains(stderr, "not encrypted") {
+		t.Errorf("decrypt twice: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRun_ConfigConvert(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+	from, _ := config.Path()
+
+	_, stderr, code := runCmd(t, []string{"gha", "config", "convert", "toml"}, "")
+	if code != 0 {
+		t.Fatalf("convert: code = %d, stderr = %s", code, stderr)
+	}
+	to, _ := config.Path()
+	if filepath.Ext(to) != ".toml" || !strings.Contains(stderr, "Converted "+from+" to "+to) {
+		t.Errorf("path = %s, stderr = %q", to, stderr)
+	}
+	stdout, _, code := runCmd(t, []string{"gha", "config", "get", "app_id"}, "")
+	if code != 0 || stdout != "1\n" {
+		t.Errorf("get after convert: code = %d, stdout = %q", code, stdout)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "config", "convert"}, "")
+	if code != exitUsage || !strings.Contains(stderr, "usage:") {
+		t.Errorf("convert without format: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRun_ConfigErrors(t *testing.T) {
+	setupTestEnv(t)
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"gha", "config"}, "usage:"},
+		{[]string{"gha", "config", "get"}, "usage:"},
+		{[]string{"gha", "config", "frob"}, `unknown config command "frob"`},
+		{[]string{"gha", "config", "get", "color"}, `unknown config key "color"`},
+		{[]string{"gha", "config", "set", "app_id", "abc"}, "app_id must be a positive integer"},
+		{[]string{"gha", "config", "set", "target_type", "team"}, "target_type must be org or user"},
+	}
+	for _, tt := range tests {
+		_, stderr, code := runCmd(t, tt.args, "")
+		if code == 0 || !strings.Contains(stderr, tt.wantErr) {
+			t.Errorf("%v: code = %d, stderr = %q, want %q", tt.args, code, stderr, tt.wantErr)
+		}
+	}
+}
+
+func checkStatuses(checks []configCheck) map[string]string {
+	got := map[string]string{}
+	for _, c := range checks {
+		got[c.Name] = c.Status
+	}
+	return got
+}
+
+func TestCheckConfig_AllPass(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 5})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			w.Write([]byte(`{"id": 1, "slug": "my-bot", "owner": {"login": "acme"}}`))
+		case "/app/installations/5":
+			w.Write([]byte(`{"id": 5, "account": {"login": "acme"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	checks := checkConfig(auth.WithBaseURL(srv.URL))
+	for _, c := range checks {
+		if c.Status != checkPass {
+			t.Errorf("%s = %s (%s), want pass", c.Name, c.Status, c.Detail)
+		}
+	}
+	if len(checks) != 5 {
+		t.Errorf("len(checks) = %d, want 5", len(checks))
+	}
+}
+
+func TestRun_Doctor(t *testing.T) {
+	tmp := setupTestEnv(t)
+
+	stdout, _, code := runCmd(t, []string{"gha", "doctor"}, "")
+	if code == 0 {
+		t.Error("doctor passed without a config")
+	}
+	dir := filepath.Join(tmp, ".config", "github-app-cli")
+	for _, want := range []string{"Config lookup order:", "GHA_CONFIG", "XDG_CONFIG_HOME       not set", dir + " (in use)", "FAIL  config"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+
+	custom := filepath.Join(tmp, "ci.yaml")
+	t.Setenv(config.PathEnv, custom)
+	stdout, _, _ = runCmd(t, []string{"gha", "config", "check"}, "")
+	if !strings.Contains(stdout, custom+" (in use)") || strings.Contains(stdout, dir+" (in use)") {
+		t.Errorf("stdout = %s, want %s in use", stdout, custom)
+	}
+}
+
+func TestCheckConfig_MissingConfigSkipsRest(t *testing.T) {
+	setupTestEnv(t)
+
+	got := checkStatuses(checkConfig())
+	want := map[string]string{"config": checkFail, "key file": checkSkip, "key parses": checkSkip, "app": checkSkip, "installation": checkSkip}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s = %q, want %q", name, got[name], status)
+		}
+	}
+	if detail := checkConfig()[0].Detail; !strings.Contains(detail, "config.toml") {
+		t.Errorf("config detail = %q, want the paths looked for", detail)
+	}
+}
+
+func TestCheckConfig_KeyPermissionsAndBadApp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	setupTestEnv(t)
+	keyPath := generateTestKeyFile(t)
+	if err := os.Chmod(keyPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: keyPath})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"A JSON web token could not be decoded"}`))
+	}))
+	defer srv.Close()
+
+	got := checkStatuses(checkConfig(auth.WithBaseURL(srv.URL)))
+	want := map[string]string{"config": checkPass, "key file": checkFail, "key parses": checkPass, "app": checkFail, "installation": checkSkip}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s = %q, want %q", name, got[name], status)
+		}
+	}
+}
+
+func TestRun_ConfigCheckJSON(t *testing.T) {
+	setupTestEnv(t)
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "config", "check", "--json"}, "")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "1 of 5 checks failed") {
+		t.Errorf("stderr = %q, want failure summary", stderr)
+	}
+
+	var report struct {
+		OK     bool          `json:"ok"`
+		Checks []configCheck `json:"checks"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout)
+	}
+	if report.OK || len(report.Checks) != 5 {
+		t.Errorf("report = %+v, want ok=false with 5 checks", report)
+	}
+}
+
+func TestRun_ConfigSchema(t *testing.T) {
+	setupTestEnv(t)
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "config", "schema"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if schema["$schema"] == nil || schema["properties"] == nil {
+		t.Errorf("schema = %v", schema)
+	}
+}
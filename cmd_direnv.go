@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const direnvUsage = "usage: gha direnv hook"
+
+// direnvHook defines use_gha for direnv's stdlib, so an .envrc can load a
+// token with "use gha [flags]". direnv reverts the variables when the
+// directory is left.
+const direnvHook = `# gha: load an installation token with "use gha [flags]" in .envrc
+use_gha() {
+  watch_file .gha.yaml
+  local gha_env
+  if ! gha_env=$(gha "$@" env --shell bash); then
+    log_error "gha: could not load an installation token"
+    return 1
+  fi
+  eval "$gha_env"
+}
+`
+
+func runDirenv(args []string, stdout io.Writer) error {
+	if len(args) != 1 || args[0] != "hook" {
+		return usageErrorf(direnvUsage)
+	}
+	fmt.Fprint(stdout, strings.ReplaceAll(direnvHook, "gha \"$@\"", progName+" \"$@\""))
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun_DirenvHook(t *testing.T) {
+	setupTestEnv(t)
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "direnv", "hook"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if _, _, code := runCmd(t, []string{"gha", "direnv"}, ""); code == 0 {
+		t.Error("gha direnv without hook succeeded")
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	// A fake gha echoing its arguments stands in for gha env.
+	binDir := t.TempDir()
+	gha := "#!/bin/sh\necho \"export GH_TOKEN=ghs_direnv GHA_ARGS='$*'\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "gha"), []byte(gha), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := "watch_file() { :; }\nlog_error() { :; }\n" + stdout + "use_gha --org acme && echo \"$GH_TOKEN $GHA_ARGS\"\n"
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Env = append(os.Environ(), "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("use_gha: %v", err)
+	}
+	if want := "ghs_direnv --org acme env --shell bash\n"; string(out) != want {
+		t.Errorf("use_gha output = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const dockerCredentialUsage = "usage: gha docker-credential <get|store|erase|list>"
+
+// dockerCredentialProgram is the name Docker runs a credential helper
+// configured as "gha" by. Invoked under it, gha is gha docker-credential.
+const dockerCredentialProgram = "docker-credential-gha"
+
+// errDockerCredentialsNotFound is what Docker's credential helper protocol
+// has a helper print when it has no credentials for a registry, so that
+// Docker goes on without them rather than failing.
+const errDockerCredentialsNotFound = "credentials not found in native keychain"
+
+// ghcrHost is GitHub's Container registry for github.com.
+const ghcrHost = "ghcr.io"
+
+// dockerCredential is the answer to a get request.
+type dockerCredential struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// isDockerCredentialHelper reports whether gha was invoked as
+// docker-credential-gha.
+func isDockerCredentialHelper(arg0 string) bool {
+	return strings.TrimSuffix(filepath.Base(arg0), ".exe") == dockerCredentialProgram
+}
+
+// runDockerCredential implements Docker's credential helper protocol for
+// the Container registry: get answers with an installation token for the
+// server URL read from stdin. The tokens are minted on demand, so store and
+// erase have nothing to keep or forget, and list has nothing to show.
+func runDockerCredential(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) != 1 {
+		return usageErrorf(dockerCredentialUsage)
+	}
+	switch args[0] {
+	case "get":
+	case "store", "erase":
+		_, _ = io.Copy(io.Discard, stdin)
+		return nil
+	case "list":
+		fmt.Fprintln(stdout, "{}")
+		return nil
+	default:
+		return usageErrorf(dockerCredentialUsage)
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return fmt.Errorf("reading server URL: %w", err)
+	}
+	server := strings.TrimSpace(string(data))
+	host, ok := registryHost(server)
+	if !ok {
+		// Docker reads a helper's error from stdout.
+		fmt.Fprintln(stdout, errDockerCredentialsNotFound)
+		return fmt.Errorf("%s is not a GitHub Container registry", server)
+	}
+	token, err := mintHostToken(host, "")
+	if err != nil {
+		fmt.Fprintln(stdout, err)
+		return err
+	}
+	enc := json.NewEncoder(stdout)
+	return enc.Encode(dockerCredential{ServerURL: server, Username: "x-access-token", Secret: token.Token})
+}
+
+// registryHost returns the GitHub host whose Container registry server is:
+// github.com for ghcr.io, and HOST for a GitHub Enterprise Server's
+// containers.HOST.
+func registryHost(server string) (string, bool) {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == ghcrHost {
+		return config.DefaultHost, true
+	}
+	if ghes, ok := strings.CutPrefix(host, "containers."); ok && ghes != "" {
+		return ghes, true
+	}
+	return "", false
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+		ok     bool
+	}{
+		{"ghcr.io", "github.com", true},
+		{"https://ghcr.io", "github.com", true},
+		{"https://GHCR.IO/v2/", "github.com", true},
+		{"containers.ghe.example.com", "ghe.example.com", true},
+		{"https://index.docker.io/v1/", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := registryHost(tt.server); got != tt.want || ok != tt.ok {
+			t.Errorf("registryHost(%q) = %q, %v; want %q, %v", tt.server, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRun_DockerCredential(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/installations/7/access_tokens" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_forghcr", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	// Docker runs the helper by the name docker-credential-gha.
+	stdout, stderr, code := runCmd(t, []string{"/usr/local/bin/docker-credential-gha", "get"}, "containers.ghe.test\n")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	want := `{"ServerURL":"containers.ghe.test","Username":"x-access-token","Secret":"ghs_forghcr"}` + "\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	stdout, _, code = runCmd(t, []string{"gha", "docker-credential", "get"}, "https://index.docker.io/v1/")
+	if code == 0 || strings.TrimSpace(stdout) != errDockerCredentialsNotFound {
+		t.Errorf("other registry: code = %d, stdout = %q", code, stdout)
+	}
+
+	for _, op := range []string{"store", "erase"} {
+		if stdout, stderr, code := runCmd(t, []string{"docker-credential-gha", op}, `{"ServerURL":"ghcr.io"}`); code != 0 || stdout != "" {
+			t.Errorf("%s: code = %d, stdout = %q, stderr = %s", op, code, stdout, stderr)
+		}
+	}
+	if stdout, _, code := runCmd(t, []string{"docker-credential-gha", "list"}, ""); code != 0 || stdout != "{}\n" {
+		t.Errorf("list: code = %d, stdout = %q", code, stdout)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+const dockerLoginUsage = "usage: gha [--hostname <host>] docker-login [--registry <registry>] [--print [--plain]]"
+
+// runDockerLogin logs docker in to the Container registry with an
+// installation token, for machines without the docker-credential-gha
+// helper. With --print it writes the auths entry for ~/.docker/config.json
+// to stdout instead of running docker.
+func runDockerLogin(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("docker-login", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	registry := fs.String("registry", "", "Registry to log in to (default ghcr.io, or containers.<host> for --hostname)")
+	printEntry := fs.Bool("print", false, "Print the docker config.json auths entry instead of running docker login")
+	plain := fs.Bool("plain", false, "With --print, print the entry even to a terminal, unmasked")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 || *plain && !*printEntry {
+		return usageErrorf(dockerLoginUsage)
+	}
+	if *registry == "" {
+		*registry = ghcrHost
+		if host := strings.ToLower(os.Getenv(ghHostEnv)); host != "" && host != config.DefaultHost {
+			*registry = "containers." + host
+		}
+	}
+	host, ok := registryHost(*registry)
+	if !ok {
+		return usageErrorf("%s is not a GitHub Container registry: use ghcr.io or containers.<host>", *registry)
+	}
+
+	token, err := mintHostToken(host, "")
+	if err != nil {
+		return err
+	}
+	const username = "x-access-token"
+
+	if *printEntry {
+		auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + token.Token))
+		masked := !*plain && isTerminal(stdout)
+		if masked {
+			auth = maskToken(auth)
+		}
+		entry := map[string]map[string]map[string]string{"auths": {*registry: {"auth": auth}}}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+		if masked {
+			infof(stderr, "gha: the token is masked on a terminal; pass --plain to print it\n")
+		}
+		return nil
+	}
+
+	cmd := exec.Command("docker", "login", *registry, "--username", username, "--password-stdin")
+	cmd.Stdin = strings.NewReader(token.Token)
+	cmd.Stdout, cmd.Stderr = stdout, rawOutput(stderr)
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("docker not found in PATH; use --print to write the config.json entry instead")
+	case errors.As(err, &exitErr):
+		// docker already reported the failure; pass its exit code on.
+		return &proxy.ExitError{Code: exitErr.ExitCode()}
+	case err != nil:
+		return fmt.Errorf("running docker login: %w", err)
+	}
+	infof(stderr, "The token expires at %s; run gha docker-login again after that.\n", token.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
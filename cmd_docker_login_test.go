@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_DockerLogin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake docker uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_fordocker", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	binDir := t.TempDir()
+	logFile := filepath.Join(binDir, "docker.log")
+	script := "#!/bin/sh\necho \"$@\" > " + logFile + "\ncat >> " + logFile + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "docker-login"}, ""); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	got, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "login containers.ghe.test --username x-access-token --password-stdin\nghs_fordocker"; string(got) != want {
+		t.Errorf("docker ran with %q, want %q", got, want)
+	}
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "docker-login", "--print"}, "")
+	if code != 0 {
+		t.Fatalf("--print: exit code = %d, stderr = %s", code, stderr)
+	}
+	var entry struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &entry); err != nil {
+		t.Fatalf("--print output %q: %v", stdout, err)
+	}
+	auth, _ := base64.StdEncoding.DecodeString(entry.Auths["containers.ghe.test"].Auth)
+	if string(auth) != "x-access-token:ghs_fordocker" {
+		t.Errorf("auth = %q, want x-access-token:ghs_fordocker", auth)
+	}
+
+	for _, args := range [][]string{
+		{"gha", "docker-login", "--registry", "docker.io"},
+		{"gha", "docker-login", "--plain"},
+	} {
+		if _, stderr, code := runCmd(t, args, ""); code != exitUsage || !strings.Contains(stderr, "error:") {
+			t.Errorf("%q: exit code = %d, stderr = %s", args[1:], code, stderr)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
+)
+
+// usageSection is a titled block of the usage text: its entries, each a
+// command or flag with its description, or plain lines such as examples.
+// Paragraphs between sections are kept as text of the section before them.
+type usageSection struct {
+	title   string
+	entries [][2]string
+	lines   []string
+	text    []string
+}
+
+// parseUsage splits the usage text and help topics into the summary line
+// and sections, so generated docs follow exactly what gha --help and gha
+// help <topic> print.
+func parseUsage() (summary string, sections []usageSection) {
+	blocks := strings.Split(referenceText(), "\n\n")
+	summary = blocks[0]
+	for _, block := range blocks[1:] {
+		lines := strings.Split(block, "\n")
+		title, ok := strings.CutSuffix(lines[0], ":")
+		if !ok || strings.HasPrefix(lines[0], " ") || len(lines) < 2 || !strings.HasPrefix(lines[1], "  ") {
+			if len(sections) > 0 {
+				last := &sections[len(sections)-1]
+				last.text = append(last.text, strings.Join(lines, " "))
+			}
+			continue
+		}
+		s := usageSection{title: title}
+		for _, line := range lines[1:] {
+			line = strings.TrimSpace(line)
+			spec, desc, ok := strings.Cut(line, "  ")
+			if ok && s.lines == nil {
+				s.entries = append(s.entries, [2]string{spec, strings.TrimSpace(desc)})
+			} else {
+				s.lines = append(s.lines, line)
+			}
+		}
+		sections = append(sections, s)
+	}
+	return summary, sections
+}
+
+// manSectionNames renames usage sections to the usual man page headings.
+var manSectionNames = map[string]string{"Usage": "COMMANDS", "Flags": "OPTIONS", "Environment Variables": "ENVIRONMENT"}
+
+func runGenerateDocs(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("generate-docs", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "markdown", "Output format: man or markdown")
+	dir := fs.String("dir", "", "Write gha.1 or gha.md into this directory instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	var doc, name string
+	switch *format {
+	case "man":
+		doc, name = manPage(), "gha.1"
+	case "markdown":
+		doc, name = markdownReference(), "gha.md"
+	default:
+		return usageErrorf("--format must be man or markdown, got %q", *format)
+	}
+	if *dir == "" {
+		_, err := io.WriteString(stdout, doc)
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(*dir, name)
+	if err := fsutil.WriteFile(path, []byte(doc), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	infof(stderr, "Wrote %s\n", path)
+	return nil
+}
+
+func manPage() string {
+	summary, sections := parseUsage()
+	_, description, _ := strings.Cut(summary, " - ")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH GHA 1 \"\" \"gha %s\" \"User Commands\"\n", roff(version))
+	fmt.Fprintf(&b, ".SH NAME\ngha \\- %s\n", roff(description))
+	b.WriteString(".SH SYNOPSIS\n.B gha\n[\\fIflags\\fR] \\fIcommand\\fR [\\fIargs\\fR...]\n")
+	for _, s := range sections {
+		heading := manSectionNames[s.title]
+		if heading == "" {
+			heading = strings.ToUpper(s.title)
+		}
+		fmt.Fprintf(&b, ".SH %q\n", roff(heading))
+		for _, e := range s.entries {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roff(e[0]), roff(e[1]))
+		}
+		if len(s.lines) > 0 {
+			b.WriteString(".PP\n.nf\n")
+			for _, line := range s.lines {
+				fmt.Fprintf(&b, "%s\n", roff(line))
+			}
+			b.WriteString(".fi\n")
+		}
+		for _, text := range s.text {
+			fmt.Fprintf(&b, ".PP\n%s\n", roff(text))
+		}
+	}
+	return b.String()
+}
+
+// roff escapes s for a man page line.
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+func markdownReference() string {
+	summary, sections := parseUsage()
+	_, description, _ := strings.Cut(summary, " - ")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# gha\n\n%s.\n", upperFirst(description))
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.title)
+		for _, e := range s.entries {
+			fmt.Fprintf(&b, "- `%s`: %s\n", e[0], e[1])
+		}
+		if len(s.lines) > 0 {
+			if len(s.entries) > 0 {
+				b.WriteString("\n")
+			}
+			if numbered(s.lines) {
+				b.WriteString(strings.Join(s.lines, "\n") + "\n")
+			} else {
+				b.WriteString("```\n" + strings.Join(s.lines, "\n") + "\n```\n")
+			}
+		}
+		for _, text := range s.text {
+			fmt.Fprintf(&b, "\n%s\n", text)
+		}
+	}
+	return b.String()
+}
+
+// numbered reports whether every line is an item of a numbered list.
+func numbered(lines []string) bool {
+	for _, line := range lines {
+		n, _, ok := strings.Cut(line, ". ")
+		if !ok || n == "" || strings.IndexFunc(n, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_GenerateDocs(t *testing.T) {
+	setupTestEnv(t)
+	for _, tt := range []struct {
+		format string
+		want   []string
+	}{
+		{"man", []string{".TH GHA 1", ".SH NAME", ".SH \"OPTIONS\"", `.B \-\-installation\-id <id>`, ".B GHA_NO_UPDATE_CHECK", `.B gha self\-update`}},
+		{"markdown", []string{"# gha", "## Flags", "- `--installation-id <id>`: Use specific installation", "- `GHA_NO_UPDATE_CHECK`:", "1. --installation-id", "```\ngha configure\n"}},
+	} {
+		stdout, stderr, code := runCmd(t, []string{"gha", "generate-docs", "--format", tt.format}, "")
+		if code != 0 {
+			t.Fatalf("%s: code = %d, stderr = %q", tt.format, code, stderr)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("%s: output lacks %q", tt.format, want)
+			}
+		}
+	}
+}
+
+func TestRun_GenerateDocs_Dir(t *testing.T) {
+	setupTestEnv(t)
+	dir := filepath.Join(t.TempDir(), "manpages")
+	if _, stderr, code := runCmd(t, []string{"gha", "generate-docs", "--format", "man", "--dir", dir}, ""); code != 0 {
+		t.Fatalf("code = %d, stderr = %q", code, stderr)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "gha.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), ".TH GHA 1") {
+		t.Errorf("gha.1 = %q, want a man page", data[:min(len(data), 40)])
+	}
+}
+
+func TestRun_GenerateDocs_BadFormat(t *testing.T) {
+	setupTestEnv(t)
+	_, stderr, code := runCmd(t, []string{"gha", "generate-docs", "--format", "html"}, "")
+	if code != exitUsage || !strings.Contains(stderr, "man or markdown") {
+		t.Errorf("code = %d, stderr = %q, want a format error", code, stderr)
+	}
+}
+
+func TestRoff(t *testing.T) {
+	if got := roff(`.gha --flag C:\dir`); got != `\&.gha \-\-flag C:\edir` {
+		t.Errorf("roff = %q", got)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+const envUsage = "usage: gha [flags] env [--org ORG] [--installation-id ID] [--shell bash|zsh|fish|pwsh] [--format gh|terraform|terraform-app] [--plain] [--output-file PATH | --github-actions]"
+
+// envShells are the syntaxes gha env can print.
+var envShells = []string{"bash", "zsh", "fish", "pwsh"}
+
+// envFormats are the sets of variables gha env can print: gh's, the
+// Terraform GitHub provider's with a token, and the provider's with the
+// App's own credentials, for it to mint tokens with.
+var envFormats = []string{"gh", "terraform", "terraform-app"}
+
+// envRequest is what gha env or gha token was asked to print.
+type envRequest struct {
+	command       string
+	shell         string
+	format        string
+	plain         bool
+	githubActions bool
+
+	// outputFile is the env file gha env appends the variables to instead
+	// of printing them.
+	outputFile string
+}
+
+// argv returns the command line that prints r.
+func (r envRequest) argv() []string {
+	if r.command == "env" {
+		return slices.Insert(r.auditArgv(), 2, "--shell", r.shell)
+	}
+	return r.auditArgv()
+}
+
+// auditArgv returns the command line the audit log records for r, which
+// leaves out the shell as it makes no difference to what is printed.
+func (r envRequest) auditArgv() []string {
+	argv := []string{"gha", r.command}
+	if r.format != "" && r.format != "gh" {
+		argv = append(argv, "--format", r.format)
+	}
+	if r.githubActions {
+		argv = append(argv, "--github-actions")
+	} else if r.outputFile != "" {
+		argv = append(argv, "--output-file", r.outputFile)
+	}
+	return argv
+}
+
+// parseEnvArgs parses gha env's arguments: the shell to print for,
+// defaulting to the one in $SHELL, the variables to print and whether
+// --plain was given. As gha env is gha's own command, the installation flags
+// may also follow it; they update override.
+func parseEnvArgs(args []string, override *installationOverride, stderr io.Writer) (*envRequest, error) {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	shell := fs.String("shell", defaultEnvShell(), "Shell syntax: bash, zsh, fish or pwsh")
+	format := fs.String("format", "gh", "Variables to print: gh, terraform or terraform-app")
+	plain := fs.Bool("plain", false, "Print the token even to a terminal, unmasked")
+	outputFile := fs.String("output-file", "", "Append KEY=VALUE lines to this env file instead of printing exports")
+	actions := fs.Bool("github-actions", false, "Mask the token in the workflow log and append the variables to $GITHUB_ENV")
+	fs.Int64Var(&override.id, "installation-id", override.id, "Installation ID to use")
+	fs.StringVar(&override.org, "org", override.org, "Account to find the installation for")
+	fs.StringVar(&override.repo, "repo", override.repo, "Repository to find the installation for")
+	fs.StringVar(&override.targetType, "target-type", override.targetType, "Only match an org or user account")
+	if err := fs.Parse(args); err != nil {
+		return nil, &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return nil, usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if override.id < 0 {
+		return nil, usageErrorf("invalid installation ID %d: must be a positive integer", override.id)
+	}
+	if !slices.Contains(envFormats, *format) {
+		return nil, usageErrorf("unsupported format %q - %s", *format, envUsage)
+	}
+	if !slices.Contains(envShells, *shell) {
+		return nil, usageErrorf("unsupported shell %q - %s", *shell, envUsage)
+	}
+	if *actions {
+		if *outputFile != "" {
+			return nil, usageErrorf("--output-file and --github-actions cannot be combined - %s", envUsage)
+		}
+		if *outputFile = os.Getenv(githubEnvEnv); *outputFile == "" {
+			return nil, fmt.Errorf("--github-actions: $%s is not set; run it in a GitHub Actions step", githubEnvEnv)
+		}
+	}
+	return &envRequest{command: "env", shell: *shell, format: *format, plain: *plain, githubActions: *actions, outputFile: *outputFile}, nil
+}
+
+func defaultEnvShell() string {
+	name := strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe")
+	switch name {
+	case "zsh", "fish", "pwsh":
+		return name
+	case "powershell":
+		return "pwsh"
+	}
+	if os.Getenv("SHELL") == "" && runtime.GOOS == "windows" {
+		return "pwsh"
+	}
+	return "bash"
+}
+
+// tokenVars returns the variables gha env exports for token on cfg's host,
+// in the order they are printed.
+func tokenVars(cfg *config.Config, token string) [][2]string {
+	vars := [][2]string{{"GH_TOKEN", token}, {"GITHUB_TOKEN", token}}
+	if proxy.IsEnterprise(cfg.Host()) {
+		vars = append(vars, [2]string{"GH_ENTERPRISE_TOKEN", token})
+	}
+	for _, name := range cfg.TokenEnv {
+		if name != "GH_TOKEN" && name != "GITHUB_TOKEN" && name != "GH_ENTERPRISE_TOKEN" {
+			vars = append(vars, [2]string{name, token})
+		}
+	}
+	if host := cfg.Host(); host != "" {
+		vars = append(vars, [2]string{ghHostEnv, host})
+	}
+	return vars
+}
+
+// terraformTokenVars returns the variables the Terraform GitHub provider
+// reads to act on owner's resources with token.
+func terraformTokenVars(cfg *config.Config, owner, token string) [][2]string {
+	return append([][2]string{{"GITHUB_TOKEN", token}}, terraformOwnerVars(cfg, owner)...)
+}
+
+// terraformAppVars returns the variables the Terraform GitHub provider
+// reads to mint its own tokens for the installation as the App, which
+// signs with pem, the private key itself rather than a path to it.
+func terraformAppVars(cfg *config.Config, owner string, installationID int64, pem string) [][2]string {
+	vars := [][2]string{
+		{"GITHUB_APP_ID", strconv.FormatInt(cfg.AppID, 10)},
+		{"GITHUB_APP_INSTALLATION_ID", strconv.FormatInt(installationID, 10)},
+		{"GITHUB_APP_PEM_FILE", pem},
+	}
+	return append(vars, terraformOwnerVars(cfg, owner)...)
+}
+
+// terraformOwnerVars returns the provider's variables for the account it
+// manages and, off github.com, the API it talks to.
+func terraformOwnerVars(cfg *config.Config, owner string) [][2]string {
+	vars := [][2]string{{"GITHUB_OWNER", owner}}
+	if api := cfg.APIURL(); api != "" {
+		vars = append(vars, [2]string{"GITHUB_BASE_URL", strings.TrimSuffix(api, "/") + "/"})
+	}
+	return vars
+}
+
+// printTerraformAppEnv prints the Terraform GitHub provider's App
+// credentials for installationID. The private key is masked on a terminal
+// unless r.plain is set.
+func printTerraformAppEnv(r envRequest, cfg *config.Config, jwtToken string, installationID int64, audit *auditLog, opts []auth.Option, stdout, stderr io.Writer) error {
+	owner, err := installationOwner(jwtToken, installationID, opts)
+	if err != nil {
+		return err
+	}
+	pem := "****"
+	if r.plain || r.outputFile != "" || !isTerminal(stdout) {
+		if pem, err = readPrivateKey(cfg); err != nil {
+			return err
+		}
+	} else {
+		infof(stderr, "gha: the private key is masked on a terminal; pass --plain to print it\n")
+	}
+	if err := audit.record(installationID, r.auditArgv(), 0, nil); err != nil {
+		return err
+	}
+	return writeEnv(r, terraformAppVars(cfg, owner, installationID, pem), []string{pem}, stdout, stderr)
+}
+
+// writeEnv prints vars as r.shell's exports or, given r.outputFile, appends
+// them to it. In a GitHub Actions step, the secrets among the values are
+// registered with the runner first, so it redacts them from the log.
+func writeEnv(r envRequest, vars [][2]string, secrets []string, stdout, stderr io.Writer) error {
+	if r.outputFile == "" {
+		printEnv(stdout, r.shell, vars)
+		return nil
+	}
+	if r.githubActions || os.Getenv("GITHUB_ACTIONS") == "true" {
+		addMasks(stdout, secrets...)
+	}
+	if err := appendGitHubFile(r.outputFile, vars); err != nil {
+		return err
+	}
+	names := make([]string, len(vars))
+	for i, v := range vars {
+		names[i] = v[0]
+	}
+	infof(stderr, "Wrote %s to %s.\n", strings.Join(names, ", "), r.outputFile)
+	return nil
+}
+
+// installationOwner returns the login of the account installationID is
+// installed on.
+func installationOwner(jwtToken string, installationID int64, opts []auth.Option) (string, error) {
+	inst, err := auth.GetInstallation(jwtToken, installationID, opts...)
+	if err != nil {
+		return "", fmt.Errorf("looking up installation %d: %w", installationID, err)
+	}
+	return inst.Account.Login, nil
+}
+
+// readPrivateKey returns the PEM contents of cfg's private key.
+func readPrivateKey(cfg *config.Config) (string, error) {
+	if cfg.PrivateKey != "" {
+		return cfg.PrivateKey, nil
+	}
+	data, err := os.ReadFile(cfg.KeyPath())
+	if err != nil {
+		return "", fmt.Errorf("reading private key %s: %w", cfg.KeyPath(), err)
+	}
+	return string(data), nil
+}
+
+// printUnsetEnv writes to w the command removing name from the environment,
+// in shell's syntax.
+func printUnsetEnv(w io.Writer, shell, name string) {
+	switch shell {
+	case "fish":
+		fmt.Fprintf(w, "set -e %s;\n", name)
+	case "pwsh":
+		fmt.Fprintf(w, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", name)
+	default:
+		fmt.Fprintf(w, "unset %s\n", name)
+	}
+}
+
+// printEnv writes vars to w as assignments in shell's syntax.
+func printEnv(w io.Writer, shell string, vars [][2]string) {
+	for _, v := range vars {
+		switch shell {
+		case "fish":
+			fmt.Fprintf(w, "set -gx %s '%s';\n", v[0], strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v[1]))
+		case "pwsh":
+			fmt.Fprintf(w, "$env:%s = '%s'\n", v[0], strings.ReplaceAll(v[1], "'", "''"))
+		default:
+			fmt.Fprintf(w, "export %s=%s\n", v[0], shellQuote(v[1]))
+		}
+	}
+}
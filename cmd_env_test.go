@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Env(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_env", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	saveTestConfig(t, &config.Config{
+		AppID:    1,
+		TokenEnv: []string{"GITHUB_TOKEN", "TF_VAR_github_token"},
+		Hosts:    map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "env", "--installation-id", "7", "--shell", "bash"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	want := "export GH_TOKEN=ghs_env\n" +
+		"export GITHUB_TOKEN=ghs_env\n" +
+		"export GH_ENTERPRISE_TOKEN=ghs_env\n" +
+		"export TF_VAR_github_token=ghs_env\n" +
+		"export GH_HOST=ghe.test\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "env", "--shell", "tcsh"}, "")
+	if code == 0 {
+		t.Errorf("unsupported shell accepted, stderr = %s", stderr)
+	}
+}
+
+func TestRun_EnvTerraform(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	var minted bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/7":
+			w.Write([]byte(`{"id": 7, "account": {"login": "acme"}}`))
+		case "/app/installations/7/access_tokens":
+			minted = true
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "ghs_tf", "expires_at": "2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	keyPath := generateTestKeyFile(t)
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 3, InstallationID: 7, PrivateKeyPath: keyPath, APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "env", "--shell", "bash", "--format", "terraform"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	want := "export GITHUB_TOKEN=ghs_tf\n" +
+		"export GITHUB_OWNER=acme\n" +
+		"export GITHUB_BASE_URL=" + srv.URL + "/\n"
+	if stdout != want {
+		t.Errorf("terraform: stdout = %q, want %q", stdout, want)
+	}
+
+	minted = false
+	stdout, stderr, code = runCmd(t, []string{"gha", "--hostname", "ghe.test", "env", "--shell", "bash", "--format", "terraform-app"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if minted {
+		t.Error("terraform-app minted a token")
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"export GITHUB_APP_ID=3\n",
+		"export GITHUB_APP_INSTALLATION_ID=7\n",
+		"export GITHUB_APP_PEM_FILE='" + string(key) + "'\n",
+		"export GITHUB_OWNER=acme\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("terraform-app: stdout = %q, want it to contain %q", stdout, want)
+		}
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "env", "--format", "hcl"}, "")
+	if code != exitUsage {
+		t.Errorf("unsupported format: exit code = %d, want %d, stderr = %s", code, exitUsage, stderr)
+	}
+}
+
+func TestRun_EnvOutputFile(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_envfile", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv(githubEnvEnv, "")
+
+	envFile := filepath.Join(t.TempDir(), "ci.env")
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "env", "--output-file", envFile}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("--output-file: stdout = %q, want nothing", stdout)
+	}
+	data, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "GH_TOKEN=ghs_envfile\nGITHUB_TOKEN=ghs_envfile\nGH_ENTERPRISE_TOKEN=ghs_envfile\nGH_HOST=ghe.test\n"
+	if string(data) != want {
+		t.Errorf("env file = %q, want %q", data, want)
+	}
+
+	if _, _, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "env", "--github-actions"}, ""); code == 0 {
+		t.Error("--github-actions succeeded without $GITHUB_ENV")
+	}
+	githubEnv := filepath.Join(t.TempDir(), "github_env")
+	t.Setenv(githubEnvEnv, githubEnv)
+	stdout, stderr, code = runCmd(t, []string{"gha", "--hostname", "ghe.test", "env", "--github-actions"}, "")
+	if code != 0 {
+		t.Fatalf("--github-actions: exit code = %d, stderr = %s", code, stderr)
+	}
+	if stdout != "::add-mask::ghs_envfile\n" {
+		t.Errorf("--github-actions: stdout = %q, want the mask command", stdout)
+	}
+	if data, err := os.ReadFile(githubEnv); err != nil || string(data) != want {
+		t.Errorf("$GITHUB_ENV = %q (%v), want %q", data, err, want)
+	}
+}
+
+func TestPrintEnv(t *testing.T) {
+	vars := [][2]string{{"GH_TOKEN", "ghs_a'b"}}
+	for shell, want := range map[string]string{
+		"bash": "export GH_TOKEN='ghs_a'\\''b'\n",
+		"zsh":  "export GH_TOKEN='ghs_a'\\''b'\n",
+		"fish": "set -gx GH_TOKEN 'ghs_a\\'b';\n",
+		"pwsh": "$env:GH_TOKEN = 'ghs_a''b'\n",
+	} {
+		var buf bytes.Buffer
+		printEnv(&buf, shell, vars)
+		if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", shell, buf.String(), want)
+		}
+	}
+}
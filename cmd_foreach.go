@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+const foreachUsage = "usage: gha foreach --all|--orgs <org>,... [--parallel N] [--json] [--] <gh args...>"
+
+// foreachResult is the outcome of the command for one installation.
+type foreachResult struct {
+	inst auth.Installation
+	code int
+	err  error
+	out  bytes.Buffer // stdout, captured for --json
+}
+
+// foreachJSON is one installation's entry in --json output.
+type foreachJSON struct {
+	InstallationID int64           `json:"installation_id"`
+	Account        string          `json:"account"`
+	ExitCode       int             `json:"exit_code"`
+	Error          string          `json:"error,omitempty"`
+	Output         json.RawMessage `json:"output,omitempty"`
+}
+
+// runForeach runs a gh command once per installation of the App, each with
+// its own token, prefixing every line of output with the account's login.
+// A failure for one installation does not stop the others; the failures are
+// summarised at the end.
+func runForeach(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("foreach", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	all := fs.Bool("all", false, "Run for every installation of the App")
+	orgs := fs.String("orgs", "", "Comma-separated accounts to run for")
+	parallel := fs.Int("parallel", 1, "Run for up to N installations at once")
+	asJSON := fs.Bool("json", false, "Merge each installation's JSON output into one array")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	ghArgs := fs.Args()
+	if *all == (*orgs != "") || len(ghArgs) == 0 {
+		return usageErrorf(foreachUsage)
+	}
+	if *parallel < 1 {
+		return fmt.Errorf("--parallel must be at least 1")
+	}
+
+	cfg, jwtToken, err := loadJWT()
+	if err != nil {
+		return err
+	}
+	// Asked once, the answer stands for every installation.
+	if err := checkPolicy(cfg.Policy, ghArgs, true, stdin, stderr); err != nil {
+		return err
+	}
+	opts := apiOptions(cfg)
+	targets, err := foreachTargets(jwtToken, *all, *orgs, stderr, opts...)
+	if err != nil {
+		return err
+	}
+	if err := checkGhVersion(cfg.MinGhVersion); err != nil {
+		return err
+	}
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		return err
+	}
+	envOpts, cleanup, err := commandEnv(cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Lines from concurrent commands must not interleave mid-line. A jsonLog
+	// already writes a record at a time.
+	stdout = &lockedWriter{w: stdout}
+	if _, ok := stderr.(*jsonLog); !ok {
+		stderr = &lockedWriter{w: stderr}
+	}
+	results := make([]*foreachResult, len(targets))
+	sem := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for i, inst := range targets {
+		res := &foreachResult{inst: inst}
+		results[i] = res
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			login := inst.Account.Login
+			token, err := mintInstallationToken(jwtToken, inst.ID, nil, opts...)
+			if err != nil {
+				res.err = fmt.Errorf("getting installation token: %w", err)
+				return
+			}
+			out, errOut := &prefixWriter{w: stdout, prefix: login + ": "}, &prefixWriter{w: rawOutput(stderr), prefix: login + ": "}
+			if *asJSON {
+				out = &prefixWriter{w: &res.out}
+			}
+			runOpts := append(slices.Clip(envOpts), proxy.WithStdin(nil), proxy.WithStdout(out), proxy.WithStderr(errOut))
+			res.code, res.err = proxy.Run(ghArgs, token.Token, runOpts...)
+			out.flush()
+			errOut.flush()
+			if err := audit.record(inst.ID, append([]string{"gh"}, ghArgs...), res.code, res.err); err != nil {
+				fmt.Fprintf(stderr, "warning: %v\n", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if *asJSON {
+		if err := printForeachJSON(stdout, results); err != nil {
+			return err
+		}
+	}
+	var failed []string
+	for _, res := range results {
+		switch {
+		case res.err != nil:
+			failed = append(failed, fmt.Sprintf("%s (%v)", res.inst.Account.Login, res.err))
+		case res.code != 0:
+			failed = append(failed, fmt.Sprintf("%s (exit %d)", res.inst.Account.Login, res.code))
+		}
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(stderr, "gha: %d of %d installations failed: %s\n", len(failed), len(results), strings.Join(failed, ", "))
+		return &proxy.ExitError{Code: 1}
+	}
+	return nil
+}
+
+// printForeachJSON writes the results as one JSON array in target order.
+func printForeachJSON(w io.Writer, results []*foreachResult) error {
+	entries := make([]foreachJSON, 0, len(results))
+	for _, res := range results {
+		entry := foreachJSON{InstallationID: res.inst.ID, Account: res.inst.Account.Login, ExitCode: res.code}
+		out := bytes.TrimSpace(res.out.Bytes())
+		switch {
+		case res.err != nil:
+			entry.Error = res.err.Error()
+		case len(out) == 0:
+		case json.Valid(out):
+			entry.Output = out
+		default:
+			entry.Error = "output is not JSON - pass --json to the gh command too"
+		}
+		entries = append(entries, entry)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding results: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// foreachTargets returns the installations to run for: every active one
+// when all is set, sorted by login, or those of the comma-separated orgs in
+// the order given.
+func foreachTargets(jwtToken string, all bool, orgs string, stderr io.Writer, opts ...auth.Option) ([]auth.Installation, error) {
+	installations, err := auth.GetInstallations(jwtToken, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("listing installations: %w", err)
+	}
+
+	if all {
+		var targets []auth.Installation
+		for _, inst := range installations {
+			if inst.SuspendedAt != nil {
+				infof(stderr, "gha: skipping suspended installation %d (%s)\n", inst.ID, inst.Account.Login)
+				continue
+			}
+			targets = append(targets, inst)
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("no active installations found for this GitHub App")
+		}
+		sort.Slice(targets, func(i, j int) bool {
+			return strings.ToLower(targets[i].Account.Login) < strings.ToLower(targets[j].Account.Login)
+		})
+		return targets, nil
+	}
+
+	byLogin := make(map[string]auth.Installation, len(installations))
+	for _, inst := range installations {
+		byLogin[strings.ToLower(inst.Account.Login)] = inst
+	}
+	var targets []auth.Installation
+	for _, org := range strings.Split(orgs, ",") {
+		if org = strings.TrimSpace(org); org == "" {
+			continue
+		}
+		inst, ok := byLogin[strings.ToLower(org)]
+		if !ok {
+			return nil, fmt.Errorf("the App is not installed on %s", org)
+		}
+		targets = append(targets, inst)
+	}
+	if len(targets) == 0 {
+		return nil, usageErrorf(foreachUsage)
+	}
+	return targets, nil
+}
+
+// prefixWriter writes each line written to it to w with prefix in front.
+// A trailing partial line is held until the next write or flush.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if _, err := io.WriteString(p.w, p.prefix+string(p.buf[:i+1])); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// flush writes any trailing partial line, ending it with a newline.
+func (p *prefixWriter) flush() {
+	if len(p.buf) > 0 {
+		_, _ = io.WriteString(p.w, p.prefix+string(p.buf)+"\n")
+		p.buf = nil
+	}
+}
+
+// lockedWriter serialises writes to w from concurrent goroutines.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// foreachServer serves three installations and mints ghs_<id> tokens.
+func foreachServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/app/installations":
+			w.Write([]byte(`[
+				{"id": 1, "account": {"login": "zeta"}},
+				{"id": 2, "account": {"login": "Acme"}},
+				{"id": 3, "account": {"login": "old"}, "suspended_at": "2024-01-01T00:00:00Z"}
+			]`))
+		case strings.HasSuffix(r.URL.Path, "/access_tokens"):
+			var id int
+			fmt.Sscanf(r.URL.Path, "/app/installations/%d/access_tokens", &id)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"token": "ghs_%d", "expires_at": "2099-01-01T00:00:00Z"}`, id)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun_Foreach(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+	srv := foreachServer(t)
+
+	binDir := t.TempDir()
+	gh := "#!/bin/sh\necho \"$* $GH_TOKEN\"\nprintf partial >&2\n[ \"$GH_TOKEN\" != ghs_1 ] || exit 4\n"
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(ghHostEnv, "ghe.test")
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "foreach", "--orgs", "acme", "--", "repo", "list"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if stdout != "Acme: repo list ghs_2\n" || stderr != "Acme: partial\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+
+	// --all runs in login order, skips suspended installations and carries
+	// on past a failure, summarising it at the end.
+	stdout, stderr, code = runCmd(t, []string{"gha", "foreach", "--all", "repo", "list"}, "")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1 after a failure", code)
+	}
+	if want := "Acme: repo list ghs_2\nzeta: repo list ghs_1\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "skipping suspended installation 3 (old)") {
+		t.Errorf("stderr = %q, want a note about the suspended installation", stderr)
+	}
+	if !strings.Contains(stderr, "gha: 1 of 2 installations failed: zeta (exit 4)") {
+		t.Errorf("stderr = %q, want a failure summary", stderr)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "foreach", "--orgs", "nope", "repo", "list"}, "")
+	if code != 1 || !strings.Contains(stderr, "not installed on nope") {
+		t.Errorf("unknown org: code = %d, stderr = %s", code, stderr)
+	}
+	if _, _, code := runCmd(t, []string{"gha", "foreach", "repo", "list"}, ""); code != exitUsage {
+		t.Errorf("neither --all nor --orgs: code = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRun_ForeachParallelJSON(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+	srv := foreachServer(t)
+
+	binDir := t.TempDir()
+	gh := "#!/bin/sh\ncase $GH_TOKEN in\nghs_1) echo '[{\"n\": 1}]' ;;\n*) echo oops; exit 2 ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(ghHostEnv, "ghe.test")
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "foreach", "--all", "--parallel", "2", "--json", "--", "repo", "list", "--json", "name"}, "")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1 after a failure", code)
+	}
+	var got []foreachJSON
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("stdout is not a JSON array: %v\n%s", err, stdout)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2:\n%s", len(got), stdout)
+	}
+	if got[0].Account != "Acme" || got[0].ExitCode != 2 || got[0].Error == "" {
+		t.Errorf("Acme entry = %+v, want exit 2 with an error", got[0])
+	}
+	var output bytes.Buffer
+	_ = json.Compact(&output, got[1].Output)
+	if got[1].InstallationID != 1 || got[1].ExitCode != 0 || output.String() != `[{"n":1}]` {
+		t.Errorf("zeta entry = %+v, want its JSON output", got[1])
+	}
+	if !strings.Contains(stderr, "1 of 2 installations failed: Acme (exit 2)") {
+		t.Errorf("stderr = %q, want a failure summary", stderr)
+	}
+}
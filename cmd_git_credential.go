@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+const gitCredentialUsage = "usage: gha git-credential [--token-file <path>] <get|store|erase>"
+
+// runGitCredential implements git's credential helper protocol. For get it
+// answers with the installation token in the --token-file that --refresh
+// keeps current or, as set up by gha setup-git, with one minted for the
+// request; store and erase are accepted and ignored.
+func runGitCredential(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("git-credential", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	tokenFile := fs.String("token-file", "", "Read the token from this file")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf(gitCredentialUsage)
+	}
+
+	// Git writes the request as key=value lines ended by a blank line.
+	request := map[string]string{}
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() && scanner.Text() != "" {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			request[key] = value
+		}
+	}
+
+	switch fs.Arg(0) {
+	case "get":
+	case "store", "erase":
+		return nil
+	default:
+		return usageErrorf(gitCredentialUsage)
+	}
+	if p := request["protocol"]; p != "" && p != "https" {
+		return nil
+	}
+
+	if *tokenFile == "" {
+		token, err := mintGitCredential(request)
+		if err != nil || token == nil {
+			return err
+		}
+		// Git 2.41 and later stop reusing a credential once it expires.
+		fmt.Fprintf(stdout, "username=x-access-token\npassword=%s\npassword_expiry_utc=%d\n", token.Token, token.ExpiresAt.Unix())
+		return nil
+	}
+	token, err := os.ReadFile(*tokenFile)
+	if err != nil {
+		return fmt.Errorf("reading token: %w", err)
+	}
+	fmt.Fprintf(stdout, "username=x-access-token\npassword=%s\n", strings.TrimSpace(string(token)))
+	return nil
+}
+
+// mintGitCredential mints an installation token for a git request's host,
+// finding the installation from the repository in its path when git sends
+// one (credential.useHttpPath, which gha setup-git turns on) and otherwise
+// as a proxied command would. It returns nil for a request without a host.
+func mintGitCredential(request map[string]string) (*auth.InstallationToken, error) {
+	host := request["host"]
+	if host == "" {
+		return nil, nil
+	}
+	var repo string
+	if owner, rest, ok := strings.Cut(request["path"], "/"); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		if name = strings.TrimSuffix(name, ".git"); owner != "" && name != "" {
+			repo = owner + "/" + name
+		}
+	}
+	return mintHostToken(host, repo)
+}
+
+// mintHostToken mints an installation token for host, for a credential
+// helper or a package manager's config: for repo as --repo would find it, or
+// without one, as a command run in the current directory would.
+func mintHostToken(host, repo string) (*auth.InstallationToken, error) {
+	// The host is passed on as --hostname would pass it.
+	if err := os.Setenv(ghHostEnv, host); err != nil {
+		return nil, err
+	}
+	sel, err := selectApp(installationOverride{repo: repo})
+	if err != nil {
+		return nil, err
+	}
+	scope := &auth.TokenRequest{Permissions: sel.project.Permissions}
+	if _, token, ok := tokenFromAgent(sel.cfg, sel.src, scope, false, logOutput); ok {
+		return token, nil
+	}
+	jwtToken, err := signJWT(sel.cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts := apiOptions(sel.cfg)
+	installationID, err := resolveInstallation(jwtToken, sel.src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	debugf("installation: %d (%s)", installationID, sel.src.origin(installationID))
+	token, err := mintInstallationToken(jwtToken, installationID, scope, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("getting installation token: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func runInstall(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	org := fs.String("org", "", "Organization or user to install the App on")
+	wait := fs.Bool("wait", false, "Wait until the new installation appears")
+	timeout := fs.Duration("timeout", 10*time.Minute, "How long --wait waits for the installation")
+	pin := fs.Bool("pin", false, "With --wait, pin the new installation in config without prompting")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg, jwtToken, err := loadJWT()
+	if err != nil {
+		return err
+	}
+
+	opts := apiOptions(cfg)
+	app, err := auth.GetApp(jwtToken, opts...)
+	if err != nil {
+		return fmt.Errorf("fetching app: %w", err)
+	}
+
+	var targetID int64
+	if *org != "" {
+		account, err := auth.GetAccount(*org, opts...)
+		if err != nil {
+			return fmt.Errorf("looking up %s: %w", *org, err)
+		}
+		targetID = account.ID
+	}
+
+	var known map[int64]bool
+	if *wait {
+		known, err = installationIDs(jwtToken, opts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := installURL(webURL(cfg), app.Slug, targetID)
+	fmt.Fprintf(stderr, "Opening %s in your browser...\n", u)
+	if err := openBrowser(u); err != nil {
+		fmt.Fprintf(stderr, "Could not open a browser (%v); visit the URL above manually\n", err)
+	}
+	if !*wait {
+		return nil
+	}
+
+	infof(stderr, "Waiting for the installation to complete...\n")
+	inst, err := waitForInstallation(jwtToken, *org, known, *timeout, 3*time.Second, opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Installed on %s (installation %d)\n", inst.Account.Login, inst.ID)
+
+	if cfg.FromEnvironment() {
+		fmt.Fprintf(stderr, "Configured from %s; set GHA_INSTALLATION_ID=%d to use this installation\n", config.AppIDEnv, inst.ID)
+		return nil
+	}
+	if scope := cfg.Scope(); scope != "" {
+		key := strings.Replace(scope, "/", ".", 1)
+		fmt.Fprintf(stderr, "Set %s.installation_id to %d in the config file to pin this installation\n", key, inst.ID)
+		return nil
+	}
+
+	if !*pin {
+		answer, err := prompt(bufio.NewReader(stdin), stderr, fmt.Sprintf("Pin installation %d in config? [y/N]: ", inst.ID))
+		if err != nil || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			return nil
+		}
+	}
+
+	cfg.InstallationID = inst.ID
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	infof(stderr, "installation_id set to %d\n", inst.ID)
+	return nil
+}
+
+// installURL returns the page on web where the App is installed. With a
+// non-zero targetID the page preselects that account.
+func installURL(web, slug string, targetID int64) string {
+	if targetID == 0 {
+		return fmt.Sprintf("%s/apps/%s/installations/new", web, slug)
+	}
+	return fmt.Sprintf("%s/apps/%s/installations/new/permissions?target_id=%d", web, slug, targetID)
+}
+
+func installationIDs(jwtToken string, opts ...auth.Option) (map[int64]bool, error) {
+	installations, err := auth.GetInstallations(jwtToken, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("listing installations: %w", err)
+	}
+	ids := make(map[int64]bool, len(installations))
+	for _, inst := range installations {
+		ids[inst.ID] = true
+	}
+	return ids, nil
+}
+
+// waitForInstallation polls the App's installations until one that is not in
+// known appears, restricted to org when it is set.
+func waitForInstallation(jwtToken, org string, known map[int64]bool, timeout, interval time.Duration, opts ...auth.Option) (*auth.Installation, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		installations, err := auth.GetInstallations(jwtToken, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("listing installations: %w", err)
+		}
+		for i, inst := range installations {
+			if known[inst.ID] {
+				continue
+			}
+			if org == "" || strings.EqualFold(inst.Account.Login, org) {
+				return &installations[i], nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for the installation", timeout)
+		}
+		time.Sleep(interval)
+	}
+}
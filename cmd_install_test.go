@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+func TestInstallURL(t *testing.T) {
+	if got, want := installURL(githubWebURL, "my-bot", 0), "https://github.com/apps/my-bot/installations/new"; got != want {
+		t.Errorf("installURL = %q, want %q", got, want)
+	}
+	if got, want := installURL(githubWebURL, "my-bot", 42), "https://github.com/apps/my-bot/installations/new/permissions?target_id=42"; got != want {
+		t.Errorf("installURL = %q, want %q", got, want)
+	}
+}
+
+func TestWaitForInstallation(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Write([]byte(`[{"id": 1, "account": {"login": "old-org"}}]`))
+			return
+		}
+		w.Write([]byte(`[{"id": 1, "account": {"login": "old-org"}}, {"id": 2, "account": {"login": "other"}}, {"id": 3, "account": {"login": "Acme"}}]`))
+	}))
+	defer srv.Close()
+
+	known := map[int64]bool{1: true}
+	inst, err := waitForInstallation("fake-jwt", "acme", known, time.Second, time.Millisecond, auth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("waitForInstallation: %v", err)
+	}
+	if inst.ID != 3 {
+		t.Errorf("ID = %d, want 3 (new installation on acme)", inst.ID)
+	}
+}
+
+func TestWaitForInstallation_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 1, "account": {"login": "old-org"}}]`))
+	}))
+	defer srv.Close()
+
+	_, err := waitForInstallation("fake-jwt", "", map[int64]bool{1: true}, 10*time.Millisecond, time.Millisecond, auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want timeout", err)
+	}
+}
+
+func TestInstallationIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 5, "account": {"login": "a"}}, {"id": 6, "account": {"login": "b"}}]`))
+	}))
+	defer srv.Close()
+
+	ids, err := installationIDs("fake-jwt", auth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("installationIDs: %v", err)
+	}
+	if len(ids) != 2 || !ids[5] || !ids[6] {
+		t.Errorf("ids = %v, want {5, 6}", ids)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+const installationsUsage = `usage:
+  gha installations repos <installation-id>
+  gha installation check <owner>/<repo>`
+
+func runInstallations(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf(installationsUsage)
+	}
+
+	switch args[0] {
+	case "repos":
+		if len(args) != 2 {
+			return usageErrorf("usage: gha installations repos <installation-id>")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || id <= 0 {
+			return usageErrorf("invalid installation ID %q: must be a positive integer", args[1])
+		}
+
+		cfg, jwtToken, err := loadJWT()
+		if err != nil {
+			return err
+		}
+		return listInstallationRepos(stdout, jwtToken, id, apiOptions(cfg)...)
+	case "check":
+		if len(args) != 2 {
+			return usageErrorf("usage: gha installation check <owner>/<repo>")
+		}
+		owner, repo, err := parseRepo(args[1])
+		if err != nil {
+			return err
+		}
+
+		cfg, jwtToken, err := loadJWT()
+		if err != nil {
+			return err
+		}
+		return checkRepoInstallation(stdout, jwtToken, owner, repo, apiOptions(cfg)...)
+	default:
+		return usageErrorf("unknown installations command %q", args[0])
+	}
+}
+
+// listInstallationRepos mints a token for the installation and prints every
+// repository it can reach.
+func listInstallationRepos(w io.Writer, jwtToken string, installationID int64, opts ...auth.Option) error {
+	installToken, err := mintInstallationToken(jwtToken, installationID, nil, opts...)
+	if err != nil {
+		return fmt.Errorf("getting installation token: %w", err)
+	}
+
+	repos, err := auth.ListInstallationRepos(installToken.Token, opts...)
+	if err != nil {
+		return fmt.Errorf("listing repositories: %w", err)
+	}
+
+	for _, repo := range repos {
+		visibility := "public"
+		if repo.Private {
+			visibility = paint(w, colorYellow, "private")
+		}
+		fmt.Fprintf(w, "%s\t%s\n", repo.FullName, visibility)
+	}
+	return nil
+}
+
+// checkRepoInstallation reports whether the App is installed on owner/repo
+// and, if so, which installation covers it and what it was granted. It
+// returns an error when the App is not installed.
+func checkRepoInstallation(w io.Writer, jwtToken, owner, repo string, opts ...auth.Option) error {
+	inst, err := auth.GetRepoInstallation(jwtToken, owner, repo, opts...)
+	if isNotFound(err) {
+		return &notInstalledError{repo: owner + "/" + repo}
+	}
+	if err != nil {
+		return fmt.Errorf("looking up installation for %s/%s: %w", owner, repo, err)
+	}
+
+	fmt.Fprintf(w, "%s/%s: %s\n", owner, repo, paint(w, colorGreen, "installed"))
+	fmt.Fprintf(w, "Installation:         %d (%s)\n", inst.ID, inst.Account.Login)
+	if inst.RepositorySelection != "" {
+		fmt.Fprintf(w, "Repository selection: %s\n", inst.RepositorySelection)
+	}
+	fmt.Fprintln(w, "Permissions:")
+	names := make([]string, 0, len(inst.Permissions))
+	for name := range inst.Permissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %s\n", name, inst.Permissions[name])
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+func TestListInstallationRepos(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/77/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"token": "ghs_repo_token"})
+		case "/installation/repositories":
+			if got := r.Header.Get("Authorization"); got != "Bearer ghs_repo_token" {
+				t.Errorf("Authorization = %q, want installation token", got)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"total_count": 2,
+				"repositories": []map[string]any{
+					{"full_name": "org/public-repo", "private": false},
+					{"full_name": "org/secret-repo", "private": true},
+				},
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := listInstallationRepos(&out, "fake-jwt", 77, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("listInstallationRepos: %v", err)
+	}
+
+	want := "org/public-repo\tpublic\norg/secret-repo\tprivate\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_InstallationsReposInvalidID(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "installations", "repos", "abc"}, "")
+	if code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr, "invalid installation ID") {
+		t.Errorf("stderr = %q, want invalid installation ID error", stderr)
+	}
+}
+
+func TestRun_InstallationsUnknownCommand(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "installations", "bogus"}, "")
+	if code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr, "unknown installations command") {
+		t.Errorf("stderr = %q, want unknown command error", stderr)
+	}
+}
+
+func TestCheckRepoInstallation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/widgets/installation" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"id": 55, "account": {"login": "acme"}, "repository_selection": "all",
+			"permissions": {"metadata": "read", "contents": "write"}}`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	if err := checkRepoInstallation(&out, "fake-jwt", "acme", "widgets", auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("checkRepoInstallation: %v", err)
+	}
+	for _, want := range []string{
+		"acme/widgets: installed",
+		"Installation:         55 (acme)",
+		"Repository selection: all",
+		"  contents: write\n  metadata: read\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	err := checkRepoInstallation(&out, "fake-jwt", "acme", "other", auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "not installed on acme/other") {
+		t.Errorf("err = %v, want not installed error", err)
+	}
+}
+
+func TestParseRepo(t *testing.T) {
+	owner, repo, err := parseRepo("acme/widgets")
+	if err != nil || owner != "acme" || repo != "widgets" {
+		t.Errorf("parseRepo = %q, %q, %v", owner, repo, err)
+	}
+	for _, bad := range []string{"acme", "/widgets", "acme/", "a/b/c", ""} {
+		if _, _, err := parseRepo(bad); err == nil {
+			t.Errorf("parseRepo(%q): expected error", bad)
+		}
+	}
+}
+
+func TestRun_InstallationCheckInvalidRepo(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "installation", "check", "not-a-repo"}, "")
+	if code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr, "invalid repository") {
+		t.Errorf("stderr = %q, want invalid repository error", stderr)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func runJWT(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("jwt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	decode := fs.Bool("decode", false, "Also print the decoded JWT header and claims")
+	plain := fs.Bool("plain", false, "Print the JWT even to a terminal, unmasked")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	_, token, err := loadJWT()
+	if err != nil {
+		return err
+	}
+
+	if !*plain && isTerminal(stdout) {
+		fmt.Fprintln(stdout, maskToken(token))
+		infof(stderr, "gha: the JWT is masked on a terminal; pass --plain to print it\n")
+	} else {
+		fmt.Fprintln(stdout, token)
+	}
+	if !*decode {
+		return nil
+	}
+
+	decoded, err := decodeJWT(token)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding claims: %w", err)
+	}
+	fmt.Fprintln(stdout, string(out))
+	return nil
+}
+
+type decodedJWT struct {
+	Header map[string]any `json:"header"`
+	Claims map[string]any `json:"claims"`
+}
+
+// decodeJWT splits a compact JWT and decodes its header and claims without
+// verifying the signature.
+func decodeJWT(token string) (*decodedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT: expected 3 segments, got %d", len(parts))
+	}
+
+	var d decodedJWT
+	if err := decodeSegment(parts[0], &d.Header); err != nil {
+		return nil, fmt.Errorf("decoding JWT header: %w", err)
+	}
+	if err := decodeSegment(parts[1], &d.Claims); err != nil {
+		return nil, fmt.Errorf("decoding JWT claims: %w", err)
+	}
+	return &d, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
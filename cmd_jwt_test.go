@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func saveTestConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	if cfg.PrivateKeyPath == "" {
+		cfg.PrivateKeyPath = generateTestKeyFile(t)
+	}
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("config.Save: %v", err)
+	}
+}
+
+func TestRun_JWT(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 4242})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "jwt"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+
+	token := strings.TrimSpace(stdout)
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("stdout = %q, want a compact JWT", stdout)
+	}
+
+	decoded, err := decodeJWT(token)
+	if err != nil {
+		t.Fatalf("decodeJWT: %v", err)
+	}
+	if decoded.Claims["iss"] != "4242" {
+		t.Errorf("iss = %v, want %q", decoded.Claims["iss"], "4242")
+	}
+	if decoded.Header["alg"] != "RS256" {
+		t.Errorf("alg = %v, want RS256", decoded.Header["alg"])
+	}
+}
+
+func TestRun_JWTDecode(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 4242})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "jwt", "--decode"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+
+	token, rest, ok := strings.Cut(stdout, "\n")
+	if !ok || strings.Count(token, ".") != 2 {
+		t.Fatalf("first line = %q, want a compact JWT", token)
+	}
+
+	var got decodedJWT
+	if err := json.Unmarshal([]byte(rest), &got); err != nil {
+		t.Fatalf("decoded output is not JSON: %v\n%s", err, rest)
+	}
+	for _, claim := range []string{"iss", "iat", "exp"} {
+		if _, ok := got.Claims[claim]; !ok {
+			t.Errorf("claims missing %q: %v", claim, got.Claims)
+		}
+	}
+}
+
+func TestRun_JWTFromEnv(t *testing.T) {
+	setupTestEnv(t)
+	keyData, err := os.ReadFile(generateTestKeyFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.AppIDEnv, "9001")
+	t.Setenv(config.PrivateKeyEnv, strings.ReplaceAll(string(keyData), "\n", `\n`))
+	t.Setenv(config.PrivateKeyPathEnv, "")
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "jwt", "--decode"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"iss": "9001"`) {
+		t.Errorf("stdout = %q, want JWT issued for GHA_APP_ID without a config file", stdout)
+	}
+}
+
+func TestRun_JWTWithoutConfig(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "jwt"}, "")
+	if code != exitConfig {
+		t.Errorf("exit code = %d, want %d", code, exitConfig)
+	}
+	if !strings.Contains(stderr, "configuration not found") {
+		t.Errorf("stderr = %q, want config not found error", stderr)
+	}
+}
+
+func TestRun_JWTUnexpectedArgs(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "jwt", "extra"}, "")
+	if code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+	if !strings.Contains(stderr, "unexpected arguments") {
+		t.Errorf("stderr = %q, want unexpected arguments error", stderr)
+	}
+}
+
+func TestDecodeJWT_Malformed(t *testing.T) {
+	if _, err := decodeJWT("not-a-jwt"); err == nil {
+		t.Error("expected error for malformed JWT")
+	}
+	if _, err := decodeJWT("!!.!!.!!"); err == nil {
+		t.Error("expected error for invalid base64 segments")
+	}
+}
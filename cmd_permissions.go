@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+const permissionsUsage = "usage: gha [flags] permissions show [--json] [--org ORG] [--installation-id ID]"
+
+// permissionsReport is what gha permissions show prints: what a token minted
+// for the installation can do, as GitHub granted it, beside what the
+// installation itself was granted.
+type permissionsReport struct {
+	InstallationID      int64             `json:"installation_id"`
+	Account             string            `json:"account"`
+	RepositorySelection string            `json:"repository_selection"`
+	Repositories        []string          `json:"repositories,omitempty"`
+	Permissions         map[string]string `json:"permissions"`
+	Installation        map[string]string `json:"installation_permissions"`
+	Narrowed            bool              `json:"narrowed"`
+	ExpiresAt           time.Time         `json:"expires_at"`
+}
+
+// parsePermissionsArgs parses gha permissions show's arguments and reports
+// whether --json was given. Like gha status, it takes the installation flags
+// after the command too; they update override.
+func parsePermissionsArgs(args []string, override *installationOverride, stderr io.Writer) (bool, error) {
+	if len(args) == 0 || args[0] != "show" {
+		return false, usageErrorf(permissionsUsage)
+	}
+	fs := flag.NewFlagSet("permissions show", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "Print the permissions as JSON")
+	fs.Int64Var(&override.id, "installation-id", override.id, "Installation ID to use")
+	fs.StringVar(&override.org, "org", override.org, "Account to find the installation for")
+	fs.StringVar(&override.repo, "repo", override.repo, "Repository to find the installation for")
+	fs.StringVar(&override.targetType, "target-type", override.targetType, "Only match an org or user account")
+	if err := fs.Parse(args[1:]); err != nil {
+		return false, &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return false, usageErrorf(permissionsUsage)
+	}
+	return *asJSON, nil
+}
+
+// runPermissionsShow mints a token as a command given flagOverride would,
+// permissions: in .gha.yaml included, and prints what GitHub granted it.
+func runPermissionsShow(flagOverride installationOverride, asJSON bool, stdout io.Writer) error {
+	sel, err := selectApp(flagOverride)
+	if err != nil {
+		return err
+	}
+	cfg := sel.cfg
+	jwtToken, err := signJWT(cfg)
+	if err != nil {
+		return err
+	}
+	opts := apiOptions(cfg)
+	installationID, err := resolveInstallation(jwtToken, sel.src, opts...)
+	if err != nil {
+		return err
+	}
+	debugf("installation: %d (%s)", installationID, sel.src.origin(installationID))
+
+	inst, err := auth.GetInstallation(jwtToken, installationID, opts...)
+	if err != nil {
+		return err
+	}
+	if err := checkSuspended(inst); err != nil {
+		return err
+	}
+	token, err := mintInstallationToken(jwtToken, installationID, &auth.TokenRequest{Permissions: sel.project.Permissions}, opts...)
+	if err != nil {
+		return fmt.Errorf("getting installation token: %w", err)
+	}
+
+	report := permissionsReport{
+		InstallationID:      installationID,
+		Account:             inst.Account.Login,
+		RepositorySelection: token.RepositorySelection,
+		Permissions:         token.Permissions,
+		Installation:        inst.Permissions,
+		Narrowed:            len(sel.project.Permissions) > 0,
+		ExpiresAt:           token.ExpiresAt,
+	}
+	if report.RepositorySelection == "" {
+		report.RepositorySelection = inst.RepositorySelection
+	}
+	// With all repositories granted, listing them says nothing more.
+	if report.RepositorySelection == "selected" {
+		repos, err := auth.ListInstallationRepos(token.Token, opts...)
+		if err != nil {
+			return err
+		}
+		for _, r := range repos {
+			report.Repositories = append(report.Repositories, r.FullName)
+		}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	report.print(stdout)
+	return nil
+}
+
+// print writes r as aligned "label: value" lines, followed by one line per
+// permission, noting where the installation was granted more.
+func (r permissionsReport) print(w io.Writer) {
+	line := func(label, format string, a ...any) {
+		fmt.Fprintf(w, "%-14s%s\n", label+":", fmt.Sprintf(format, a...))
+	}
+	line("installation", "%d (%s)", r.InstallationID, r.Account)
+	if r.RepositorySelection == "selected" {
+		line("repositories", "%d selected", len(r.Repositories))
+		for _, name := range r.Repositories {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+	} else {
+		line("repositories", "%s", r.RepositorySelection)
+	}
+	line("expires", "%s", r.ExpiresAt.Local().Format(time.RFC3339))
+	if r.Narrowed {
+		line("permissions", "narrowed by permissions: in .gha.yaml")
+	} else {
+		line("permissions", "all granted to the installation")
+	}
+
+	names := make([]string, 0, len(r.Installation))
+	for name := range r.Installation {
+		names = append(names, name)
+	}
+	for name := range r.Permissions {
+		if _, ok := r.Installation[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		level, granted := r.Permissions[name], r.Installation[name]
+		switch {
+		case level == "":
+			fmt.Fprintf(w, "  %s: %s (installation: %s)\n", name, paint(w, colorDim, "none"), granted)
+		case granted != "" && granted != level:
+			fmt.Fprintf(w, "  %s: %s (installation: %s)\n", name, level, granted)
+		default:
+			fmt.Fprintf(w, "  %s: %s\n", name, level)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_PermissionsShow(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(".gha.yaml", []byte("permissions:\n  contents: read\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/7":
+			w.Write([]byte(`{"id": 7, "account": {"login": "acme"}, "repository_selection": "selected",
+				"permissions": {"contents": "write", "issues": "write", "metadata": "read"}}`))
+		case "/app/installations/7/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "ghs_perm", "expires_at": "2099-01-01T00:00:00Z",
+				"permissions": {"contents": "read", "metadata": "read"}, "repository_selection": "selected"}`))
+		case "/installation/repositories":
+			w.Write([]byte(`{"total_count": 1, "repositories": [{"id": 1, "full_name": "acme/api"}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "permissions", "show", "--installation-id", "7"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{
+		"installation: 7 (acme)",
+		"repositories: 1 selected\n  acme/api\n",
+		"permissions:  narrowed by permissions: in .gha.yaml",
+		"  contents: read (installation: write)\n  issues: none (installation: write)\n  metadata: read\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+
+	stdout, stderr, code = runCmd(t, []string{"gha", "--hostname", "ghe.test", "--installation-id", "7", "permissions", "show", "--json"}, "")
+	if code != 0 {
+		t.Fatalf("--json: exit code = %d, stderr = %s", code, stderr)
+	}
+	var report permissionsReport
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, stdout)
+	}
+	if report.Permissions["contents"] != "read" || report.Installation["contents"] != "write" || !report.Narrowed || len(report.Repositories) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestRun_PermissionsUsage(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1})
+	for _, args := range [][]string{{"permissions"}, {"permissions", "list"}, {"permissions", "show", "extra"}} {
+		if _, _, code := runCmd(t, append([]string{"gha"}, args...), ""); code != exitUsage {
+			t.Errorf("%v: exit code = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func runReset(name string, args []string, stdin io.Reader, stderr io.Writer) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	force := fs.Bool("force", false, "Remove files without asking for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	files, err := stateFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		infof(stderr, "Nothing to remove.\n")
+		return nil
+	}
+
+	fmt.Fprintln(stderr, "This removes:")
+	for _, f := range files {
+		fmt.Fprintf(stderr, "  %s\n", f)
+	}
+	if !*force {
+		answer, err := prompt(bufio.NewReader(stdin), stderr, "Continue? [y/N]: ")
+		if err != nil || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+			return fmt.Errorf("aborted")
+		}
+	}
+
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing %s: %w", f, err)
+		}
+	}
+	if dir, err := config.Dir(); err == nil {
+		// Only succeeds when nothing else, such as a private key, is left.
+		for _, kind := range []string{"hosts", "apps"} {
+			scopeDirs, _ := filepath.Glob(filepath.Join(dir, kind, "*"))
+			for _, d := range scopeDirs {
+				_ = os.Remove(d)
+			}
+			_ = os.Remove(filepath.Join(dir, kind))
+		}
+		_ = os.Remove(dir)
+	}
+
+	infof(stderr, "Removed gha configuration and caches. Private keys were left in place.\n")
+	return nil
+}
+
+// stateFiles lists the existing files gha has written: the config file and
+// every cache kept in the config directory.
+func stateFiles() ([]string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	// Without $GHA_CONFIG, remove the config file in every format.
+	candidates, err := config.DefaultPaths()
+	if err != nil {
+		return nil, err
+	}
+	if path := os.Getenv(config.PathEnv); path != "" {
+		candidates = []string{path}
+	}
+	candidates = append(candidates, installationCacheFiles(dir)...)
+	candidates = append(candidates, updateCheckFiles(dir)...)
+	return existingFiles(candidates), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/installcache"
+	"github.com/haribote-lab/github-app-cli/internal/update"
+)
+
+func seedState(t *testing.T) (dir string, keyPath string) {
+	t.Helper()
+	dir, err := config.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath = filepath.Join(dir, "bot.private-key.pem")
+	writeTestKey(t, keyPath)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: keyPath})
+	installcache.Store(dir, []installcache.Mapping{{Login: "acme", ID: 1}})
+	if err := os.WriteFile(update.CachePath(dir), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return dir, keyPath
+}
+
+func TestRun_ResetForce(t *testing.T) {
+	setupTestEnv(t)
+	dir, keyPath := seedState(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "reset", "--force"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, f := range []string{filepath.Join(dir, "config.yaml"), installcache.Path(dir), update.CachePath(dir)} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", f)
+		}
+	}
+	if _, err := os.Stat(keyPath); err != nil {
+		t.Errorf("private key should be kept: %v", err)
+	}
+}
+
+func TestRun_LogoutPrompt(t *testing.T) {
+	setupTestEnv(t)
+	dir, _ := seedState(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "logout"}, "n\n")
+	if code != 1 || !strings.Contains(stderr, "aborted") {
+		t.Errorf("declined: code = %d, stderr = %q", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.yaml")); err != nil {
+		t.Errorf("declining must keep the config: %v", err)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "logout"}, "y\n")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.yaml")); !os.IsNotExist(err) {
+		t.Error("config should be removed after confirming")
+	}
+}
+
+func TestRun_ResetNothing(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "reset"}, "")
+	if code != 0 || !strings.Contains(stderr, "Nothing to remove") {
+		t.Errorf("code = %d, stderr = %q", code, stderr)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const revokeTokenUsage = "usage: gha revoke-token [--env] [<token> | -]"
+
+// installationTokenPrefix starts every installation access token.
+const installationTokenPrefix = "ghs_"
+
+// runRevokeToken revokes an installation token that may have leaked: the
+// one given, the one read from stdin, or with --env the one this shell got
+// from gha shell, gha env or --refresh. gha keeps no token on disk, so there
+// is no other to find.
+func runRevokeToken(args []string, stdin io.Reader, stderr io.Writer) error {
+	fs := flag.NewFlagSet("revoke-token", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fromEnv := fs.Bool("env", false, "Revoke the token in "+tokenFileEnv+" or GH_TOKEN")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 1 || *fromEnv && fs.NArg() > 0 {
+		return usageErrorf(revokeTokenUsage)
+	}
+
+	var token string
+	switch {
+	case *fromEnv:
+		var err error
+		if token, err = envToken(); err != nil {
+			return err
+		}
+	case fs.NArg() == 1 && fs.Arg(0) != "-":
+		token = fs.Arg(0)
+	case fs.NArg() == 0 && isTerminal(stdin):
+		return usageErrorf("%s - pass the token on stdin, or use --env", revokeTokenUsage)
+	default:
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading token: %w", err)
+		}
+		token = line
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return usageErrorf("no token to revoke")
+	}
+	if !strings.HasPrefix(token, installationTokenPrefix) {
+		return usageErrorf("not an installation token: installation tokens start with %s", installationTokenPrefix)
+	}
+
+	// The token authenticates itself, but the host it belongs to still
+	// comes from the config, when there is one.
+	var opts []auth.Option
+	cfg, err := loadHostConfig()
+	switch {
+	case err == nil:
+		opts = apiOptions(cfg)
+	case errors.Is(err, config.ErrNotFound):
+		opts = debugAPIOptions()
+	default:
+		return err
+	}
+
+	err = auth.RevokeInstallationToken(token, opts...)
+	var apiErr *auth.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		infof(stderr, "The token %s is no longer valid: it has already expired or been revoked.\n", maskToken(token))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	infof(stderr, "Revoked the installation token %s.\n", maskToken(token))
+	return nil
+}
+
+// envToken returns the token this shell was given: the current one in
+// GHA_TOKEN_FILE under --refresh, or GH_TOKEN.
+func envToken() (string, error) {
+	if path := os.Getenv(tokenFileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading %s: %w", tokenFileEnv, err)
+		}
+		return string(data), nil
+	}
+	if token := os.Getenv("GH_TOKEN"); token != "" {
+		return token, nil
+	}
+	return "", usageErrorf("neither %s nor GH_TOKEN is set; run gha revoke-token from the shell that has the token", tokenFileEnv)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_RevokeToken(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv(tokenFileEnv, "")
+	t.Setenv("GH_TOKEN", "")
+
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/installation/token" {
+			t.Errorf("request = %s %s, want DELETE /installation/token", r.Method, r.URL.Path)
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		revoked = append(revoked, token)
+		if token == "ghs_expired0" {
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	_, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "revoke-token"}, "ghs_fromstdin\n")
+	if code != 0 || !strings.Contains(stderr, "Revoked the installation token ghs_****") {
+		t.Fatalf("stdin: code = %d, stderr = %q", code, stderr)
+	}
+	if strings.Contains(stderr, "ghs_fromstdin") {
+		t.Errorf("stderr shows the whole token: %q", stderr)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("ghs_refreshed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(tokenFileEnv, tokenFile)
+	t.Setenv("GH_TOKEN", "ghs_started")
+	if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "revoke-token", "--env"}, ""); code != 0 {
+		t.Fatalf("--env: code = %d, stderr = %s", code, stderr)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "--hostname", "ghe.test", "revoke-token", "ghs_expired0"}, "")
+	if code != 0 || !strings.Contains(stderr, "no longer valid") {
+		t.Errorf("expired: code = %d, stderr = %q", code, stderr)
+	}
+	if got := strings.Join(revoked, ","); got != "ghs_fromstdin,ghs_refreshed,ghs_expired0" {
+		t.Errorf("revoked %s", got)
+	}
+}
+
+func TestRun_RevokeTokenUsage(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv(tokenFileEnv, "")
+	t.Setenv("GH_TOKEN", "")
+
+	for _, tc := range []struct {
+		args  []string
+		input string
+	}{
+		{[]string{"revoke-token"}, ""},
+		{[]string{"revoke-token", "gho_usertoken"}, ""},
+		{[]string{"revoke-token", "--env"}, ""},
+		{[]string{"revoke-token", "--env", "ghs_x"}, ""},
+		{[]string{"revoke-token", "ghs_a", "ghs_b"}, ""},
+	} {
+		if _, _, code := runCmd(t, append([]string{"gha"}, tc.args...), tc.input); code != exitUsage {
+			t.Errorf("%v: exit code = %d, want %d", tc.args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/update"
+)
+
+// executable locates the running binary; tests point it at a stand-in.
+var executable = os.Executable
+
+// releaseOptions configure where releases are fetched from; tests point
+// them at a local server.
+var releaseOptions []update.Option
+
+func runSelfUpdate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	prerelease := fs.Bool("prerelease", false, "Update to the newest release, including release candidates")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if progName != "gha" {
+		return fmt.Errorf("%s is managed by gh - run gh extension upgrade instead", progName)
+	}
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("locating the gha binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	// Leave package-managed installs to their package manager.
+	switch method := update.DetectInstall(exe); {
+	case method == update.InstallManual:
+	case method.UpgradeCommand() != "":
+		return fmt.Errorf("%s is managed by a package manager - run %s instead", exe, method.UpgradeCommand())
+	default:
+		return fmt.Errorf("%s was installed from a .deb package - install the new one from the releases page instead", exe)
+	}
+
+	cfg, _ := config.LoadPartial()
+	release, err := update.LatestRelease(updateOptions(cfg, *prerelease)...)
+	if err != nil {
+		return err
+	}
+	if !update.Newer(release.Version, version) {
+		fmt.Fprintf(stdout, "gha %s is already the latest version\n", version)
+		return nil
+	}
+	infof(stderr, "Downloading gha v%s...\n", release.Version)
+	if err := update.SelfUpdate(release, exe); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Updated %s to v%s\n", exe, release.Version)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/update"
+)
+
+func stubExecutable(t *testing.T, path string) {
+	t.Helper()
+	orig := executable
+	executable = func() (string, error) { return path, nil }
+	t.Cleanup(func() { executable = orig })
+}
+
+func stubLatestRelease(t *testing.T, tag string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"tag_name": tag, "assets": []any{}})
+	}))
+	t.Cleanup(srv.Close)
+	orig := releaseOptions
+	releaseOptions = []update.Option{update.WithBaseURL(srv.URL)}
+	t.Cleanup(func() { releaseOptions = orig })
+}
+
+func TestRun_SelfUpdate_UpToDate(t *testing.T) {
+	setupTestEnv(t)
+	exe := filepath.Join(t.TempDir(), "gha")
+	if err := os.WriteFile(exe, []byte("bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stubExecutable(t, exe)
+	stubLatestRelease(t, "v"+version)
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "self-update"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %q", code, stderr)
+	}
+	if !strings.Contains(stdout, "already the latest version") {
+		t.Errorf("stdout = %q, want an up-to-date message", stdout)
+	}
+}
+
+func TestRun_SelfUpdate_Homebrew(t *testing.T) {
+	setupTestEnv(t)
+	exe := filepath.Join(t.TempDir(), "Cellar", "gha", "1.0.0", "bin", "gha")
+	if err := os.MkdirAll(filepath.Dir(exe), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(exe, []byte("bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stubExecutable(t, exe)
+	stubLatestRelease(t, "v999.0.0")
+
+	_, stderr, code := runCmd(t, []string{"gha", "self-update"}, "")
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stderr, "brew upgrade gha") {
+		t.Errorf("stderr = %q, want a brew upgrade hint", stderr)
+	}
+	if got, _ := os.ReadFile(exe); string(got) != "bin" {
+		t.Errorf("executable = %q, want it untouched", got)
+	}
+}
+
+func TestRun_SelfUpdate_Prerelease(t *testing.T) {
+	tmp := setupTestEnv(t)
+	exe := filepath.Join(t.TempDir(), "gha")
+	if err := os.WriteFile(exe, []byte("bin"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stubExecutable(t, exe)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/latest":
+			json.NewEncoder(w).Encode(map[string]any{"tag_name": "v999.0.0"})
+		case "/releases":
+			json.NewEncoder(w).Encode([]map[string]any{{"tag_name": "v999.1.0-rc.1", "prerelease": true}, {"tag_name": "v999.0.0"}})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	orig := releaseOptions
+	releaseOptions = []update.Option{update.WithBaseURL(srv.URL + "/releases")}
+	t.Cleanup(func() { releaseOptions = orig })
+
+	// The release is not signed for this build, so each attempt stops after
+	// announcing the version it chose.
+	_, stderr, _ := runCmd(t, []string{"gha", "self-update"}, "")
+	if !strings.Contains(stderr, "v999.0.0") {
+		t.Errorf("stable: stderr = %q, want v999.0.0", stderr)
+	}
+	_, stderr, _ = runCmd(t, []string{"gha", "self-update", "--prerelease"}, "")
+	if !strings.Contains(stderr, "v999.1.0-rc.1") {
+		t.Errorf("--prerelease: stderr = %q, want v999.1.0-rc.1", stderr)
+	}
+
+	cfgPath := filepath.Join(tmp, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("channel: prerelease\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.PathEnv, cfgPath)
+	_, stderr, _ = runCmd(t, []string{"gha", "self-update"}, "")
+	if !strings.Contains(stderr, "v999.1.0-rc.1") {
+		t.Errorf("channel: prerelease: stderr = %q, want v999.1.0-rc.1", stderr)
+	}
+
+	// update_base_url points at a mirror of the releases API.
+	releaseOptions = nil
+	if err := os.WriteFile(cfgPath, []byte("update_base_url: "+srv.URL+"/releases/\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, stderr, _ = runCmd(t, []string{"gha", "self-update"}, "")
+	if !strings.Contains(stderr, "v999.0.0") {
+		t.Errorf("update_base_url: stderr = %q, want v999.0.0 from the mirror", stderr)
+	}
+}
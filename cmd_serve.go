@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const serveUsage = "usage: gha [--hostname <host>] [--app <name>] serve [--listen ADDR] [--allow-anyone]"
+
+// runServe runs an HTTP service that mints installation tokens on request,
+// so tools that need GitHub credentials can get them from one place instead
+// of each holding the App's key. It serves until interrupted.
+func runServe(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	listen := fs.String("listen", "127.0.0.1:8080", "Address to listen on")
+	allowAnyone := fs.Bool("allow-anyone", false, "Serve without serve.clients, minting tokens for anyone who can connect")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf(serveUsage)
+	}
+
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return err
+	}
+	conf := cfg.Serve
+	// Even on loopback, every local user could mint tokens.
+	if len(conf.Clients) == 0 && !*allowAnyone {
+		return &configError{fmt.Errorf("serve.clients is empty, so anyone who can connect could mint tokens for the App: list the clients in the config, or pass --allow-anyone")}
+	}
+	s := newTokenServer(cfg, os.Getenv(appEnv), stderr)
+
+	// A client that trickles its request or never reads the response must
+	// not hold a connection open. Minting can take several API calls, each
+	// allowed 30 seconds, so the handler gets longer to write.
+	srv := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      2 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+	if conf.TLSCert != "" {
+		if srv.TLSConfig, err = serveTLSConfig(cfg); err != nil {
+			return &configError{err}
+		}
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	if host, port, _ := net.SplitHostPort(ln.Addr().String()); net.ParseIP(host).IsLoopback() {
+		// A web page that rebinds its name to loopback reaches the server
+		// under that name, so only the loopback names are accepted.
+		s.hosts = map[string]bool{ln.Addr().String(): true, net.JoinHostPort("localhost", port): true}
+	} else {
+		switch {
+		case len(conf.Clients) == 0:
+			fmt.Fprintf(stderr, "warning: listening on %s without serve.clients: anyone who can reach it can mint tokens for the App\n", ln.Addr())
+		case conf.TLSCert == "":
+			fmt.Fprintf(stderr, "warning: listening on %s without serve.tls_cert: bearer tokens and minted tokens cross the network in the clear\n", ln.Addr())
+		}
+	}
+	scheme := "http"
+	if conf.TLSCert != "" {
+		scheme = "https"
+	}
+	infof(stderr, "gha serve: listening on %s://%s\n", scheme, ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	done := make(chan error, 1)
+	go func() {
+		if conf.TLSCert != "" {
+			done <- srv.ServeTLS(ln, cfg.Resolve(conf.TLSCert), cfg.Resolve(conf.TLSKey))
+			return
+		}
+		done <- srv.Serve(ln)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdown)
+}
+
+// serveTLSConfig returns the TLS configuration of gha serve: with
+// serve.client_ca, client certificates are asked for and verified against
+// it. They are not required, so clients may also authenticate with bearer
+// tokens.
+func serveTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.Serve.ClientCA == "" {
+		return conf, nil
+	}
+	path := cfg.Resolve(cfg.Serve.ClientCA)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading serve.client_ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("serve.client_ca: no certificates found in %s", path)
+	}
+	conf.ClientCAs, conf.ClientAuth = pool, tls.VerifyClientCertIfGiven
+	return conf, nil
+}
+
+// tokenServer is the handler of gha serve.
+type tokenServer struct {
+	cfg *config.Config
+	// app is the App given with --app or GHA_APP; without one, each
+	// request is routed as a command would be.
+	app string
+	log io.Writer
+	// hosts, when set, are the Host headers requests may carry.
+	hosts map[string]bool
+
+	mu sync.Mutex
+	// keys holds a keySource per App, so each key is read once and
+	// watched for rotation rather than read for every request.
+	keys map[string]*keySource
+}
+
+func newTokenServer(cfg *config.Config, app string, log io.Writer) *tokenServer {
+	return &tokenServer{cfg: cfg, app: app, log: log, keys: map[string]*keySource{}}
+}
+
+// tokenResponse is the body of a successful POST /token.
+type tokenResponse struct {
+	Token               string            `json:"token"`
+	ExpiresAt           time.Time         `json:"expires_at"`
+	InstallationID      int64             `json:"installation_id"`
+	Permissions         map[string]string `json:"permissions,omitempty"`
+	RepositorySelection string            `json:"repository_selection,omitempty"`
+}
+
+// maxTokenRequest bounds the body of a POST /token, whose form only names
+// an installation, permissions and repositories.
+const maxTokenRequest = 64 << 10
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkOrigin(r); err != nil {
+		s.fail(w, r, http.StatusForbidden, err)
+		return
+	}
+	switch r.URL.Path {
+	case "/healthz":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case "/token":
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			s.fail(w, r, http.StatusMethodNotAllowed, usageErrorf("use POST to mint a token"))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxTokenRequest)
+		client, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.fail(w, r, serveStatus(err), err)
+			return
+		}
+		resp, err := s.mint(r, client)
+		if err != nil {
+			s.fail(w, r, serveStatus(err), err)
+			return
+		}
+		fmt.Fprintf(s.log, "%s %s %s: installation %d for %s\n", r.RemoteAddr, r.Method, r.URL.RequestURI(), resp.InstallationID, clientName(client))
+		writeJSON(w, http.StatusCreated, resp)
+	default:
+		s.fail(w, r, http.StatusNotFound, usageErrorf("no such endpoint %s: use POST /token", r.URL.Path))
+	}
+}
+
+// checkOrigin refuses requests a web browser made: those carrying an Origin
+// header, which browsers send with every POST, and those for a Host other
+// than the server's, as after DNS rebinding.
+func (s *tokenServer) checkOrigin(r *http.Request) error {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return &accessError{code: "forbidden", msg: fmt.Sprintf("requests from web pages are not served (Origin %s)", origin)}
+	}
+	if s.hosts != nil && !s.hosts[strings.ToLower(r.Host)] {
+		return &accessError{code: "forbidden", msg: fmt.Sprintf("unexpected Host %q", r.Host)}
+	}
+	return nil
+}
+
+// authenticate returns the client that made r, by its bearer token or its
+// verified client certificate. Without serve.clients, anyone may request
+// tokens, and the client is nil.
+func (s *tokenServer) authenticate(r *http.Request) (*config.ServeClient, error) {
+	clients := s.cfg.Serve.Clients
+	if len(clients) == 0 {
+		return nil, nil
+	}
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		sum := sha256.Sum256([]byte(strings.TrimSpace(bearer)))
+		digest := []byte(hex.EncodeToString(sum[:]))
+		for i, c := range clients {
+			if subtle.ConstantTimeCompare(digest, []byte(strings.ToLower(c.TokenSHA256))) == 1 {
+				return &clients[i], nil
+			}
+		}
+		return nil, &accessError{code: "unauthenticated", msg: "unknown bearer token"}
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for i, c := range clients {
+			if c.CommonName != "" && c.CommonName == cn {
+				return &clients[i], nil
+			}
+		}
+		return nil, &accessError{code: "unauthenticated", msg: fmt.Sprintf("no client with common_name %q", cn)}
+	}
+	return nil, &accessError{code: "unauthenticated", msg: "a bearer token or client certificate is required"}
+}
+
+// clientName names client in the log.
+func clientName(client *config.ServeClient) string {
+	if client == nil {
+		return "anyone"
+	}
+	return "client " + client.Name
+}
+
+// mint mints the token r asks for on behalf of client. The query or form
+// takes org, repo or installation_id to pick the installation as the flags
+// of the same names do, and permissions and repositories to narrow the
+// token, within what client may have.
+func (s *tokenServer) mint(r *http.Request, client *config.ServeClient) (*tokenResponse, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, &usageError{err}
+	}
+	override := installationOverride{org: r.Form.Get("org"), repo: r.Form.Get("repo")}
+	if raw := r.Form.Get("installation_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, usageErrorf("invalid installation_id %q: must be a positive integer", raw)
+		}
+		override.id = id
+	}
+	if override.repo != "" {
+		if _, _, err := parseRepo(override.repo); err != nil {
+			return nil, err
+		}
+	}
+	perms, err := config.ParsePermissions(r.Form.Get("permissions"))
+	if err != nil {
+		return nil, &usageError{err}
+	}
+	if client != nil {
+		if perms, err = client.NarrowPermissions(perms); err != nil {
+			return nil, &accessError{code: "forbidden", client: client.Name, msg: err.Error()}
+		}
+	}
+	scope := &auth.TokenRequest{Permissions: perms}
+	for _, name := range strings.Split(r.Form.Get("repositories"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			scope.Repositories = append(scope.Repositories, name)
+		}
+	}
+
+	appName := s.app
+	if appName == "" {
+		appName = s.cfg.Route(routeTarget(override, installationOverride{}, installationOverride{}, ""))
+	}
+	cfg, err := s.cfg.ForApp(appName)
+	if err != nil {
+		return nil, &configError{err}
+	}
+	// Unlike a command, a request is not resolved against the server's
+	// environment, working directory or git remote.
+	src := installationSources{
+		scope:         cfg.Scope(),
+		flag:          override,
+		configID:      cfg.InstallationID,
+		targetType:    cfg.TargetType,
+		installations: maps.Clone(cfg.Installations),
+	}
+	jwtToken, err := s.keySource(cfg).sign()
+	if err != nil {
+		return nil, err
+	}
+	opts := apiOptions(cfg)
+	installationID, err := resolveInstallation(jwtToken, src, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// The installation's own account is checked, however it was picked.
+	if client != nil && len(client.Orgs) > 0 {
+		owner, err := installationOwner(jwtToken, installationID, opts)
+		if err != nil {
+			return nil, err
+		}
+		if !client.AllowsOrg(owner) {
+			return nil, &accessError{code: "forbidden", client: client.Name, msg: fmt.Sprintf("installation %d on %s is outside its orgs", installationID, owner)}
+		}
+	}
+	audit, err := openAuditLog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	token, err := mintInstallationToken(jwtToken, installationID, scope, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("getting installation token: %w", err)
+	}
+	if err := audit.record(installationID, serveArgv(r, client), 0, nil); err != nil {
+		fmt.Fprintf(s.log, "warning: %v\n", err)
+	}
+	return &tokenResponse{
+		Token:               token.Token,
+		ExpiresAt:           token.ExpiresAt,
+		InstallationID:      installationID,
+		Permissions:         token.Permissions,
+		RepositorySelection: token.RepositorySelection,
+	}, nil
+}
+
+// keySource returns the keySource of cfg's App.
+func (s *tokenServer) keySource(cfg *config.Config) *keySource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[cfg.Scope()]
+	if !ok {
+		k = newKeySource(cfg)
+		s.keys[cfg.Scope()] = k
+	}
+	return k
+}
+
+// serveArgv returns the audit log's argv for r, made by client.
+func serveArgv(r *http.Request, client *config.ServeClient) []string {
+	argv := []string{"gha", "serve", r.Method + " " + r.URL.RequestURI()}
+	if client != nil {
+		argv = append(argv, "client="+client.Name)
+	}
+	return argv
+}
+
+// fail logs err and answers r with it, as the JSON errorReport that
+// --error-format json prints. A denied request is logged as a warning and
+// recorded in the audit log too.
+func (s *tokenServer) fail(w http.ResponseWriter, r *http.Request, status int, err error) {
+	var access *accessError
+	if errors.As(err, &access) {
+		fmt.Fprintf(s.log, "warning: %s %s %s: %d %v\n", r.RemoteAddr, r.Method, r.URL.RequestURI(), status, err)
+		argv := []string{"gha", "serve", r.Method + " " + r.URL.RequestURI(), "remote=" + r.RemoteAddr}
+		if audit, auditErr := openAuditLog(s.cfg); auditErr != nil {
+			fmt.Fprintf(s.log, "warning: %v\n", auditErr)
+		} else if auditErr := audit.record(0, argv, status, err); auditErr != nil {
+			fmt.Fprintf(s.log, "warning: %v\n", auditErr)
+		}
+	} else {
+		fmt.Fprintf(s.log, "%s %s %s: %d %v\n", r.RemoteAddr, r.Method, r.URL.RequestURI(), status, err)
+	}
+	writeJSON(w, status, map[string]errorReport{"error": newErrorReport(err)})
+}
+
+// serveStatus returns the HTTP status gha serve fails a request with for err.
+func serveStatus(err error) int {
+	var (
+		installations *installationsError
+		access        *accessError
+	)
+	switch {
+	case errors.As(err, &access):
+		if access.code == "unauthenticated" {
+			return http.StatusUnauthorized
+		}
+		return http.StatusForbidden
+	case errors.As(err, new(*notInstalledError)):
+		return http.StatusNotFound
+	case errors.As(err, &installations):
+		if installations.code == "multiple_installations" {
+			// The request has to name one.
+			return http.StatusBadRequest
+		}
+		return http.StatusNotFound
+	case errors.As(err, new(*suspendedError)):
+		return http.StatusForbidden
+	case errors.As(err, new(*http.MaxBytesError)):
+		return http.StatusRequestEntityTooLarge
+	}
+	switch exitCode(err) {
+	case exitUsage:
+		return http.StatusBadRequest
+	case exitAPI:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
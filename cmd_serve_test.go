@@ -0,0 +1,337 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestTokenServer(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	var scope auth.TokenRequest
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/installation":
+			w.Write([]byte(`{"id": 42, "account": {"login": "acme"}}`))
+		case "/app/installations/42/access_tokens":
+			scope = auth.TokenRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&scope); err != nil {
+				t.Errorf("decoding token request: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "ghs_served", "expires_at": "2099-01-01T00:00:00Z", "permissions": {"contents": "read"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: api.URL}},
+	})
+	t.Setenv(ghHostEnv, "ghe.test")
+	cfg, err := loadHostConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	srv := httptest.NewServer(newTokenServer(cfg, "", &log))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/token?repo=acme/app&permissions=contents:read&repositories=app", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got tokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /token: status = %d, err = %v", resp.StatusCode, err)
+	}
+	if got.Token != "ghs_served" || got.InstallationID != 42 || got.Permissions["contents"] != "read" {
+		t.Errorf("response = %+v", got)
+	}
+	if scope.Permissions["contents"] != "read" || len(scope.Repositories) != 1 || scope.Repositories[0] != "app" {
+		t.Errorf("token request = %+v", scope)
+	}
+	if !strings.Contains(log.String(), "installation 42") {
+		t.Errorf("log = %q, want the request logged", log.String())
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/token", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/token?permissions=contents:all", http.StatusBadRequest},
+		{http.MethodPost, "/token?installation_id=x", http.StatusBadRequest},
+		{http.MethodGet, "/healthz", http.StatusOK},
+		{http.MethodGet, "/nope", http.StatusNotFound},
+	} {
+		req, err := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s %s: status = %d, want %d", tc.method, tc.path, resp.StatusCode, tc.status)
+		}
+	}
+
+	body := "repositories=" + strings.Repeat("a", maxTokenRequest)
+	resp, err = http.Post(srv.URL+"/token", "application/x-www-form-urlencoded", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized POST /token: status = %d, want 413", resp.StatusCode)
+	}
+}
+
+func TestTokenServer_BrowserRequests(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: generateTestKeyFile(t)})
+	cfg, err := loadHostConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	s := newTokenServer(cfg, "", &log)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	s.hosts = map[string]bool{srv.Listener.Addr().String(): true}
+
+	for _, tc := range []struct {
+		name, host, origin string
+		status             int
+	}{
+		{"listen address", "", "", http.StatusOK},
+		{"origin", "", "https://evil.example", http.StatusForbidden},
+		{"rebound host", "evil.example:8080", "", http.StatusForbidden},
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/healthz", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.host != "" {
+			req.Host = tc.host
+		}
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, resp.StatusCode, tc.status)
+		}
+	}
+}
+
+func TestRun_ServeWithoutClients(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: generateTestKeyFile(t)})
+	_, stderr, code := runCmd(t, []string{"gha", "serve", "--listen", "127.0.0.1:0"}, "")
+	if code == 0 || !strings.Contains(stderr, "--allow-anyone") {
+		t.Errorf("code = %d, stderr = %s, want serving without clients refused", code, stderr)
+	}
+}
+
+func TestTokenServer_Clients(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	var scope auth.TokenRequest
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/42":
+			w.Write([]byte(`{"id": 42, "account": {"login": "acme"}}`))
+		case "/app/installations/42/access_tokens":
+			scope = auth.TokenRequest{}
+			if err := json.NewDecoder(r.Body).Decode(&scope); err != nil && err != io.EOF {
+				t.Errorf("decoding token request: %v", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "ghs_served", "expires_at": "2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	ca, caPEM := generateTestCA(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	digest := func(token string) string {
+		sum := sha256.Sum256([]byte(token))
+		return hex.EncodeToString(sum[:])
+	}
+	auditFile := filepath.Join(t.TempDir(), "audit.jsonl")
+	saveTestConfig(t, &config.Config{
+		AppID:        1,
+		AuditLogPath: auditFile,
+		Hosts:        map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 42, PrivateKeyPath: generateTestKeyFile(t), APIURL: api.URL}},
+		Serve: config.Serve{
+			TLSCert:  "unused.pem",
+			TLSKey:   "unused-key.pem",
+			ClientCA: caFile,
+			Clients: []config.ServeClient{
+				{Name: "ci", TokenSHA256: digest("s3cret"), Permissions: map[string]string{"contents": "read"}},
+				{Name: "other", TokenSHA256: digest("elsewhere"), Orgs: []string{"other-*"}},
+				{Name: "deploy", CommonName: "deploy.internal", Orgs: []string{"acme"}},
+			},
+		},
+	})
+	t.Setenv(ghHostEnv, "ghe.test")
+	cfg, err := loadHostConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	srv := httptest.NewUnstartedServer(newTokenServer(cfg, "", &log))
+	if srv.TLS, err = serveTLSConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	post := func(client *http.Client, path, bearer string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tc := range []struct {
+		name, path, bearer string
+		status             int
+	}{
+		{"no credentials", "/token", "", http.StatusUnauthorized},
+		{"unknown token", "/token", "guess", http.StatusUnauthorized},
+		{"permission beyond the client's", "/token?permissions=contents:write", "s3cret", http.StatusForbidden},
+		{"installation outside the client's orgs", "/token", "elsewhere", http.StatusForbidden},
+		{"allowed", "/token", "s3cret", http.StatusCreated},
+	} {
+		if status := post(srv.Client(), tc.path, tc.bearer); status != tc.status {
+			t.Errorf("%s: status = %d, want %d", tc.name, status, tc.status)
+		}
+	}
+	if len(scope.Permissions) != 1 || scope.Permissions["contents"] != "read" {
+		t.Errorf("token request = %+v, want the client's permissions", scope)
+	}
+	if n := strings.Count(log.String(), "warning: "); n != 4 {
+		t.Errorf("log = %q, want the 4 denied requests as warnings", log.String())
+	}
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "denied"); n != 4 {
+		t.Errorf("audit log = %s, want the 4 denied requests", data)
+	}
+
+	// A client certificate signed by client_ca identifies the client too.
+	withCert := func(commonName string) *http.Client {
+		tr := srv.Client().Transport.(*http.Transport).Clone()
+		tr.TLSClientConfig.Certificates = []tls.Certificate{generateTestClientCert(t, ca, commonName)}
+		return &http.Client{Transport: tr}
+	}
+	if status := post(withCert("deploy.internal"), "/token", ""); status != http.StatusCreated {
+		t.Errorf("client certificate: status = %d, want %d", status, http.StatusCreated)
+	}
+	if status := post(withCert("stranger"), "/token", ""); status != http.StatusUnauthorized {
+		t.Errorf("unknown certificate: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+// testCA is a certificate authority for client certificates in tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func generateTestCA(t *testing.T) (*testCA, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func generateTestClientCert(t *testing.T, ca *testCA, commonName string) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
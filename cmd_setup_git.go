@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const setupGitUsage = "usage: gha [--hostname <host>] [--app <name>] setup-git [--remove]"
+
+// runSetupGit points git's credential helper for each configured host, or
+// only the one --hostname selects, at gha git-credential in the global git
+// config, as gh auth setup-git does for gh. With --remove it takes out the
+// helpers it set up again.
+func runSetupGit(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("setup-git", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	remove := fs.Bool("remove", false, "Remove the credential helpers gha set up")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return usageErrorf(setupGitUsage)
+	}
+	if *remove {
+		return removeGitHelpers(os.Getenv(ghHostEnv), stderr)
+	}
+
+	hosts, err := setupGitHosts()
+	if err != nil {
+		return err
+	}
+	for _, host := range hosts {
+		if err := addGitHelper(host); err != nil {
+			return err
+		}
+		infof(stderr, "Configured git to fetch credentials for https://%s from gha.\n", host)
+	}
+	return nil
+}
+
+// addGitHelper makes gha git-credential, with the App --app selects, the
+// only credential helper for host in the global git config.
+func addGitHelper(host string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating gha for the git credential helper: %w", err)
+	}
+	helper := "!" + shellQuote(exe)
+	if app := os.Getenv(appEnv); app != "" {
+		helper += " --app " + shellQuote(app)
+	}
+	helper += " git-credential"
+
+	key := "credential.https://" + host
+	// An empty helper resets the list, so gha answers for the host rather
+	// than any helper configured before it.
+	if _, err := gitConfig("--replace-all", key+".helper", ""); err != nil {
+		return err
+	}
+	if _, err := gitConfig("--add", key+".helper", helper); err != nil {
+		return err
+	}
+	// With the repository in the request, the helper finds the installation
+	// for it rather than for the current directory.
+	_, err = gitConfig(key+".useHttpPath", "true")
+	return err
+}
+
+// setupGitHosts returns the host --hostname selects, or every host in the
+// config.
+func setupGitHosts() ([]string, error) {
+	cfg, err := loadHostConfig()
+	if err != nil {
+		return nil, err
+	}
+	if os.Getenv(ghHostEnv) != "" || len(cfg.Hosts) == 0 {
+		host := cfg.Host()
+		if host == "" {
+			host = config.DefaultHost
+		}
+		return []string{host}, nil
+	}
+	var hosts []string
+	if cfg.AppID > 0 {
+		hosts = append(hosts, config.DefaultHost)
+	}
+	names := make([]string, 0, len(cfg.Hosts))
+	for name := range cfg.Hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append(hosts, names...), nil
+}
+
+// removeGitHelpers removes the credential helpers gha setup-git configured,
+// for host or, when it is empty, for every host. A host left with no other
+// helper loses the empty entry and useHttpPath setup-git added with it.
+func removeGitHelpers(host string, stderr io.Writer) error {
+	out, err := gitConfig("--get-regexp", `^credential\..*\.helper$`)
+	if err != nil {
+		return err
+	}
+	helpers := map[string][]string{}
+	var keys []string
+	for _, line := range strings.Split(strings.TrimRight(out, "\n"), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if key == "" {
+			continue
+		}
+		if _, ok := helpers[key]; !ok {
+			keys = append(keys, key)
+		}
+		helpers[key] = append(helpers[key], value)
+	}
+
+	removed := 0
+	for _, key := range keys {
+		prefix := strings.TrimSuffix(key, ".helper")
+		if host != "" && !strings.EqualFold(prefix, "credential.https://"+host) {
+			continue
+		}
+		others := 0
+		for _, value := range helpers[key] {
+			switch {
+			case isGhaHelper(value):
+				if _, err := gitConfig("--unset-all", key, "^"+regexp.QuoteMeta(value)+"$"); err != nil {
+					return err
+				}
+				removed++
+				infof(stderr, "Removed gha's credential helper for %s.\n", strings.TrimPrefix(prefix, "credential."))
+			case value != "":
+				others++
+			}
+		}
+		if others == 0 && slices.ContainsFunc(helpers[key], isGhaHelper) {
+			if _, err := gitConfig("--unset-all", key); err != nil {
+				return err
+			}
+			if _, err := gitConfig("--unset", prefix+".useHttpPath"); err != nil {
+				return err
+			}
+		}
+	}
+	if removed == 0 {
+		infof(stderr, "No credential helper set up by gha was found.\n")
+	}
+	return nil
+}
+
+// isGhaHelper reports whether a credential.helper value runs gha
+// git-credential, as gha setup-git configures it, rather than another
+// helper such as gh's.
+func isGhaHelper(value string) bool {
+	fields := strings.Fields(value)
+	return strings.HasPrefix(value, "!") && len(fields) >= 2 && fields[len(fields)-1] == "git-credential" &&
+		fields[len(fields)-2] != "auth"
+}
+
+// gitConfig runs git config --global with args and returns its output. A
+// key that is not set is not an error.
+func gitConfig(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"config", "--global"}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0:
+		return "", nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 5:
+		// --unset of a key that is not set.
+		return "", nil
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git config: %s", msg)
+		}
+		return "", fmt.Errorf("running git config: %w", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// setupGitTestEnv points git's global config at a file of the test's own.
+func setupGitTestEnv(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+}
+
+func gitHelpers(t *testing.T, host string) []string {
+	t.Helper()
+	out, err := gitConfig("--get-all", "credential.https://"+host+".helper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+func TestRun_SetupGit(t *testing.T) {
+	setupTestEnv(t)
+	setupGitTestEnv(t)
+	saveTestConfig(t, &config.Config{
+		AppID:          1,
+		PrivateKeyPath: "/k.pem",
+		Hosts:          map[string]config.Host{"ghe.test": {AppID: 2, PrivateKeyPath: "/k.pem"}},
+	})
+	if _, err := gitConfig("--add", "credential.https://gitlab.test.helper", "!/usr/bin/gh auth git-credential"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Running it twice leaves one helper per host.
+	for range 2 {
+		if _, stderr, code := runCmd(t, []string{"gha", "setup-git"}, ""); code != 0 {
+			t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+		}
+	}
+	for _, host := range []string{"github.com", "ghe.test"} {
+		helpers := gitHelpers(t, host)
+		if len(helpers) != 2 || helpers[0] != "" || !isGhaHelper(helpers[1]) {
+			t.Errorf("%s helpers = %q, want a reset and gha's", host, helpers)
+		}
+		if out, _ := gitConfig("--get", "credential.https://"+host+".useHttpPath"); strings.TrimSpace(out) != "true" {
+			t.Errorf("%s useHttpPath = %q, want true", host, out)
+		}
+	}
+
+	if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "setup-git", "--remove"}, ""); code != 0 {
+		t.Fatalf("--remove: exit code = %d, stderr = %s", code, stderr)
+	}
+	if helpers := gitHelpers(t, "ghe.test"); helpers != nil {
+		t.Errorf("ghe.test helpers after --remove = %q, want none", helpers)
+	}
+	if helpers := gitHelpers(t, "github.com"); len(helpers) != 2 {
+		t.Errorf("github.com helpers = %q, want them kept", helpers)
+	}
+
+	t.Setenv(ghHostEnv, "")
+	_, stderr, code := runCmd(t, []string{"gha", "setup-git", "--remove"}, "")
+	if code != 0 || !strings.Contains(stderr, "Removed gha's credential helper for https://github.com") {
+		t.Fatalf("--remove: exit code = %d, stderr = %s", code, stderr)
+	}
+	if helpers := gitHelpers(t, "github.com"); helpers != nil {
+		t.Errorf("github.com helpers after --remove = %q, want none", helpers)
+	}
+	if helpers := gitHelpers(t, "gitlab.test"); len(helpers) != 1 {
+		t.Errorf("another tool's helper = %q, want it kept", helpers)
+	}
+}
+
+func TestIsGhaHelper(t *testing.T) {
+	for value, want := range map[string]bool{
+		"!'/opt/my tools/gha' git-credential":          true,
+		"!/usr/local/bin/gha --app bot git-credential": true,
+		"!/usr/bin/gh auth git-credential":             false,
+		"osxkeychain":                                  false,
+		"":                                             false,
+	} {
+		if got := isGhaHelper(value); got != want {
+			t.Errorf("isGhaHelper(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestRun_GitCredentialMints(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app/installation":
+			w.Write([]byte(`{"id": 42, "account": {"login": "acme"}}`))
+		case "/app/installations/42/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token": "ghs_forgit", "expires_at": "2099-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "git-credential", "get"}, "protocol=https\nhost=ghe.test\npath=acme/app.git\n\n")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	want := "username=x-access-token\npassword=ghs_forgit\npassword_expiry_utc=4070908800\n"
+	if stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+const setupGoUsage = "usage: gha [--hostname <host>] [--app <name>] setup-go [--private <pattern>,...]"
+
+// runSetupGo lets the go command fetch private modules from the App's
+// host: it adds them to GOPRIVATE, so they bypass the module proxy and
+// checksum database, and sets up gha git-credential as setup-git does, so
+// the git that go runs authenticates with an installation token.
+func runSetupGo(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("setup-go", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	private := flags.String("private", "", "Comma-separated module path patterns to treat as private (default: <host>/<owner> of the git remote)")
+	if err := flags.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if flags.NArg() > 0 {
+		return usageErrorf(setupGoUsage)
+	}
+
+	host := packagesHost()
+	var patterns []string
+	for _, p := range strings.Split(*private, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	if len(patterns) == 0 {
+		owner, _, _ := strings.Cut(gitRemoteRepo(host), "/")
+		if owner == "" {
+			return usageErrorf("no git remote to take the modules' owner from: pass --private %s/<owner>", host)
+		}
+		patterns = []string{host + "/" + owner}
+	}
+
+	out, err := goEnv("GOPRIVATE", "GONOSUMDB")
+	if err != nil {
+		return err
+	}
+	current := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	goPrivate, noSumDB := current[0], ""
+	if len(current) > 1 {
+		noSumDB = current[1]
+	}
+	if err := addGoPatterns("GOPRIVATE", goPrivate, patterns); err != nil {
+		return err
+	}
+	// GONOSUMDB follows GOPRIVATE unless it was set apart from it.
+	if noSumDB != goPrivate {
+		if err := addGoPatterns("GONOSUMDB", noSumDB, patterns); err != nil {
+			return err
+		}
+	}
+	if err := addGitHelper(host); err != nil {
+		return err
+	}
+	infof(stderr, "Configured go to fetch %s with credentials from gha.\n", strings.Join(patterns, ", "))
+	return nil
+}
+
+// addGoPatterns adds patterns missing from value, the current setting of
+// the go environment variable name, with go env -w.
+func addGoPatterns(name, value string, patterns []string) error {
+	var list []string
+	for _, p := range strings.Split(value, ",") {
+		if p != "" {
+			list = append(list, p)
+		}
+	}
+	n := len(list)
+	for _, p := range patterns {
+		if !slices.Contains(list, p) {
+			list = append(list, p)
+		}
+	}
+	if len(list) == n {
+		return nil
+	}
+	_, err := goEnv("-w", name+"="+strings.Join(list, ","))
+	return err
+}
+
+// goEnv runs go env with args and returns its output.
+func goEnv(args ...string) (string, error) {
+	cmd := exec.Command("go", append([]string{"env"}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("go env: %s", msg)
+		}
+		return "", fmt.Errorf("running go env: %w", err)
+	}
+	return string(out), nil
+}
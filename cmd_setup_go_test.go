@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_SetupGo(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	setupTestEnv(t)
+	setupGitTestEnv(t)
+	t.Setenv("GOENV", filepath.Join(t.TempDir(), "goenv"))
+	t.Setenv("GOPRIVATE", "")
+	t.Setenv("GONOSUMDB", "")
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "/k.pem"})
+
+	if _, err := goEnv("-w", "GOPRIVATE=example.com/internal"); err != nil {
+		t.Fatal(err)
+	}
+	// Running it twice adds the pattern once.
+	for range 2 {
+		if _, stderr, code := runCmd(t, []string{"gha", "setup-go", "--private", "github.com/acme"}, ""); code != 0 {
+			t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+		}
+	}
+	out, err := goEnv("GOPRIVATE", "GONOSUMDB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "example.com/internal,github.com/acme\nexample.com/internal,github.com/acme\n"; out != want {
+		t.Errorf("GOPRIVATE, GONOSUMDB = %q, want %q", out, want)
+	}
+	if helpers := gitHelpers(t, "github.com"); len(helpers) != 2 || !isGhaHelper(helpers[1]) {
+		t.Errorf("github.com helpers = %q, want gha's", helpers)
+	}
+
+	t.Chdir(t.TempDir())
+	if _, stderr, code := runCmd(t, []string{"gha", "setup-go"}, ""); code != exitUsage || !strings.Contains(stderr, "pass --private") {
+		t.Errorf("no git remote: exit code = %d, stderr = %s", code, stderr)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const (
+	setupMavenUsage  = "usage: gha [--hostname <host>] setup-maven [--id <server-id>] [--env] [--plain]"
+	setupGradleUsage = "usage: gha [--hostname <host>] setup-gradle [--plain]"
+)
+
+// mavenTokenPlaceholder is what setup-maven --env writes instead of a
+// token: Maven expands it from the environment gha exec or gha env sets up.
+const mavenTokenPlaceholder = "${env.GH_TOKEN}"
+
+// packagesUsername is the user name GitHub Packages takes an installation
+// token with.
+const packagesUsername = "x-access-token"
+
+// runSetupMaven prints a settings.xml whose server entry authenticates to
+// the GitHub Packages Maven registry with an installation token or, with
+// --env, with GH_TOKEN from the environment.
+func runSetupMaven(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("setup-maven", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	id := flags.String("id", "github", "The server id the pom's repository uses")
+	fromEnv := flags.Bool("env", false, "Write "+mavenTokenPlaceholder+" instead of a token")
+	plain := flags.Bool("plain", false, "Print the token even to a terminal, unmasked")
+	if err := flags.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if flags.NArg() > 0 || *id == "" {
+		return usageErrorf(setupMavenUsage)
+	}
+
+	host := packagesHost()
+	password, masked := mavenTokenPlaceholder, false
+	if !*fromEnv {
+		var err error
+		if password, masked, err = packagesToken(host, *plain, stdout); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stdout, `<settings xmlns="http://maven.apache.org/SETTINGS/1.0.0">
+  <servers>
+    <server>
+      <id>%s</id>
+      <username>%s</username>
+      <password>%s</password>
+    </server>
+  </servers>
+</settings>
+`, xmlEscape(*id), packagesUsername, xmlEscape(password))
+
+	if masked {
+		infof(stderr, "gha: the token is masked on a terminal; pass --plain to print it\n")
+	}
+	infof(stderr, "Give the pom a repository with id %s and url %s, and run mvn -s with this file.\n", *id, packagesRepoURL("maven", host))
+	return nil
+}
+
+// runSetupGradle prints gradle.properties entries holding an installation
+// token for the GitHub Packages Maven registry, under the gpr.user and
+// gpr.key names GitHub's documentation uses.
+func runSetupGradle(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("setup-gradle", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	plain := flags.Bool("plain", false, "Print the token even to a terminal, unmasked")
+	if err := flags.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if flags.NArg() > 0 {
+		return usageErrorf(setupGradleUsage)
+	}
+
+	host := packagesHost()
+	token, masked, err := packagesToken(host, *plain, stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "gpr.user=%s\ngpr.key=%s\n", packagesUsername, token)
+
+	if masked {
+		infof(stderr, "gha: the token is masked on a terminal; pass --plain to print it\n")
+	}
+	infof(stderr, "Read them in the credentials of a maven repository with url %s.\n", packagesRepoURL("maven", host))
+	return nil
+}
+
+// packagesHost returns the GitHub host --hostname selects.
+func packagesHost() string {
+	if host := strings.ToLower(os.Getenv(ghHostEnv)); host != "" {
+		return host
+	}
+	return config.DefaultHost
+}
+
+// packagesToken mints a token for host's GitHub Packages, masked when it is
+// to be printed to a terminal without --plain.
+func packagesToken(host string, plain bool, stdout io.Writer) (token string, masked bool, err error) {
+	minted, err := mintHostToken(host, "")
+	if err != nil {
+		return "", false, err
+	}
+	if !plain && isTerminal(stdout) {
+		return maskToken(minted.Token), true, nil
+	}
+	return minted.Token, false, nil
+}
+
+// packagesRepoURL returns the URL of the GitHub Packages registry of kind,
+// such as maven, for the current directory's repository on host, with
+// placeholders when there is no git remote to take it from.
+func packagesRepoURL(kind, host string) string {
+	repo := gitRemoteRepo(host)
+	if repo == "" {
+		repo = "OWNER/REPOSITORY"
+	}
+	if host == config.DefaultHost {
+		return "https://" + kind + ".pkg.github.com/" + repo
+	}
+	return "https://" + kind + "." + host + "/" + repo
+}
+
+// xmlEscape escapes s for XML character data.
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
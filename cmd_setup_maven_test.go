@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_SetupMavenGradle(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_forjvm", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "setup-maven", "--id", "gpr"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	var settings struct {
+		Servers []struct {
+			ID       string `xml:"id"`
+			Username string `xml:"username"`
+			Password string `xml:"password"`
+		} `xml:"servers>server"`
+	}
+	if err := xml.Unmarshal([]byte(stdout), &settings); err != nil {
+		t.Fatalf("settings.xml %q: %v", stdout, err)
+	}
+	if len(settings.Servers) != 1 || settings.Servers[0].ID != "gpr" || settings.Servers[0].Username != "x-access-token" || settings.Servers[0].Password != "ghs_forjvm" {
+		t.Errorf("servers = %+v", settings.Servers)
+	}
+	if !strings.Contains(stderr, "https://maven.ghe.test/OWNER/REPOSITORY") {
+		t.Errorf("stderr = %q, want the repository URL", stderr)
+	}
+
+	stdout, _, code = runCmd(t, []string{"gha", "setup-maven", "--env"}, "")
+	if code != 0 || !strings.Contains(stdout, "<password>${env.GH_TOKEN}</password>") {
+		t.Errorf("--env: code = %d, stdout = %s", code, stdout)
+	}
+
+	stdout, stderr, code = runCmd(t, []string{"gha", "--hostname", "ghe.test", "setup-gradle"}, "")
+	if code != 0 || stdout != "gpr.user=x-access-token\ngpr.key=ghs_forjvm\n" {
+		t.Errorf("gradle: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+	}
+}
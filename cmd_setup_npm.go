@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+const setupNpmUsage = "usage: gha [--hostname <host>] setup-npm [--scope @<owner>] [--file <path>] [--env]"
+
+// npmTokenPlaceholder is what setup-npm --env writes instead of a token: npm
+// expands it from the environment gha exec or gha env sets up.
+const npmTokenPlaceholder = "${GH_TOKEN}"
+
+// runSetupNpm points a scope at the GitHub Packages npm registry in .npmrc
+// and gives npm a token for it: a freshly minted installation token or,
+// with --env, a reference to GH_TOKEN for gha exec or gha env to fill in.
+// Other lines in the file are kept.
+func runSetupNpm(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("setup-npm", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	scope := flags.String("scope", "", "Scope to install from GitHub Packages (default: the git remote's owner)")
+	path := flags.String("file", ".npmrc", "The .npmrc file to write")
+	fromEnv := flags.Bool("env", false, "Write "+npmTokenPlaceholder+" instead of a token")
+	if err := flags.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if flags.NArg() > 0 {
+		return usageErrorf(setupNpmUsage)
+	}
+
+	host := packagesHost()
+	if *scope == "" {
+		owner, _, _ := strings.Cut(gitRemoteRepo(host), "/")
+		if owner == "" {
+			return usageErrorf("no git remote to take the scope from: pass --scope @<owner>")
+		}
+		*scope = owner
+	}
+	*scope = "@" + strings.ToLower(strings.TrimPrefix(*scope, "@"))
+
+	registry := npmRegistry(host)
+	token := npmTokenPlaceholder
+	if !*fromEnv {
+		minted, err := mintHostToken(host, "")
+		if err != nil {
+			return err
+		}
+		token = minted.Token
+	}
+	authKey := "//" + strings.TrimPrefix(registry, "https://") + ":_authToken"
+	if err := updateNpmrc(*path, []npmrcEntry{{*scope + ":registry", registry}, {authKey, token}}, !*fromEnv); err != nil {
+		return err
+	}
+
+	infof(stderr, "Configured %s to install %s packages from %s.\n", *path, *scope, registry)
+	if *fromEnv {
+		infof(stderr, "Run npm with the token in GH_TOKEN, e.g. gha exec npm ci, or after eval \"$(gha env)\".\n")
+	} else {
+		infof(stderr, "The token expires within the hour; run gha setup-npm again, or use --env for longer-lived setups. Keep %s out of version control.\n", *path)
+	}
+	return nil
+}
+
+// npmRegistry returns the GitHub Packages npm registry for host.
+func npmRegistry(host string) string {
+	if host == config.DefaultHost {
+		return "https://npm.pkg.github.com/"
+	}
+	return "https://npm." + host + "/"
+}
+
+// npmrcEntry is a key=value line of an .npmrc.
+type npmrcEntry struct {
+	key, value string
+}
+
+// updateNpmrc sets entries in the .npmrc at path, replacing the lines that
+// set the same keys and keeping the rest. A file holding a token is made
+// readable only by its owner.
+func updateNpmrc(path string, entries []npmrcEntry, secret bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+
+	values := map[string]string{}
+	for _, e := range entries {
+		values[e.key] = e.value
+	}
+	written := map[string]bool{}
+	kept := lines[:0]
+	for _, line := range lines {
+		key, _, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		value, set := values[key]
+		switch {
+		case !ok || !set:
+			kept = append(kept, line)
+		case !written[key]:
+			kept = append(kept, key+"="+value)
+			written[key] = true
+		}
+	}
+	for _, e := range entries {
+		if !written[e.key] {
+			kept = append(kept, e.key+"="+e.value)
+		}
+	}
+
+	mode := fs.FileMode(0o644)
+	if secret {
+		mode = 0o600
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), mode); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if secret {
+		// WriteFile leaves an existing file's mode alone.
+		if err := os.Chmod(path, 0o600); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_SetupNpm(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_fornpm", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+	existing := "save-exact=true\n//npm.ghe.test/:_authToken=ghs_old\n"
+	if err := os.WriteFile(".npmrc", []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "setup-npm", "--scope", "Acme"}, ""); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	got, err := os.ReadFile(".npmrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "save-exact=true\n//npm.ghe.test/:_authToken=ghs_fornpm\n@acme:registry=https://npm.ghe.test/\n"
+	if string(got) != want {
+		t.Errorf(".npmrc = %q, want %q", got, want)
+	}
+	if info, err := os.Stat(".npmrc"); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf(".npmrc mode = %o, want 600 once it holds a token", info.Mode().Perm())
+	}
+
+	t.Setenv(ghHostEnv, "")
+	if _, stderr, code := runCmd(t, []string{"gha", "setup-npm", "--scope", "@acme", "--env", "--file", "ci.npmrc"}, ""); code != 0 {
+		t.Fatalf("--env: exit code = %d, stderr = %s", code, stderr)
+	}
+	got, _ = os.ReadFile("ci.npmrc")
+	if want := "@acme:registry=https://npm.pkg.github.com/\n//npm.pkg.github.com/:_authToken=${GH_TOKEN}\n"; string(got) != want {
+		t.Errorf("ci.npmrc = %q, want %q", got, want)
+	}
+
+	_, stderr, code := runCmd(t, []string{"gha", "setup-npm", "--env"}, "")
+	if code != exitUsage || !strings.Contains(stderr, "pass --scope") {
+		t.Errorf("no scope: exit code = %d, stderr = %s", code, stderr)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// statusReport is what gha status shows: what a command would run as, and
+// why. Installation and key failures are reported rather than returned, so
+// the rest still shows.
+type statusReport struct {
+	Config             string `json:"config"`
+	Host               string `json:"host"`
+	APIURL             string `json:"api_url,omitempty"`
+	App                string `json:"app"`
+	AppID              int64  `json:"app_id"`
+	AppSource          string `json:"app_source"`
+	Key                string `json:"key"`
+	KeySource          string `json:"key_source"`
+	KeyError           string `json:"key_error,omitempty"`
+	InstallationID     int64  `json:"installation_id,omitempty"`
+	InstallationSource string `json:"installation_source,omitempty"`
+	InstallationError  string `json:"installation_error,omitempty"`
+	TokenCached        bool   `json:"token_cached"`
+}
+
+// parseStatusArgs parses gha status's arguments and reports whether --json
+// was given. Like gha env, it takes the installation flags after the command
+// too; they update override.
+func parseStatusArgs(args []string, override *installationOverride, stderr io.Writer) (bool, error) {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "Print the status as JSON")
+	fs.Int64Var(&override.id, "installation-id", override.id, "Installation ID to use")
+	fs.StringVar(&override.org, "org", override.org, "Account to find the installation for")
+	fs.StringVar(&override.repo, "repo", override.repo, "Repository to find the installation for")
+	fs.StringVar(&override.targetType, "target-type", override.targetType, "Only match an org or user account")
+	if err := fs.Parse(args); err != nil {
+		return false, &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return false, usageErrorf("usage: gha [flags] status [--json] [--org ORG] [--installation-id ID]")
+	}
+	return *asJSON, nil
+}
+
+// runStatus resolves the App and installation as a command given
+// flagOverride would, without minting a token, and prints the result.
+func runStatus(flagOverride installationOverride, asJSON bool, stdout io.Writer) error {
+	sel, err := selectApp(flagOverride)
+	if err != nil {
+		return err
+	}
+	cfg := sel.cfg
+	st := statusReport{Host: cfg.Host(), APIURL: cfg.APIURL(), App: cfg.AppName(), AppID: cfg.AppID, AppSource: sel.appSource}
+	if st.Host == "" {
+		st.Host = config.DefaultHost
+	}
+	if st.App == "" {
+		st.App = "default"
+	}
+	if cfg.FromEnvironment() {
+		st.Config = "environment (" + config.AppIDEnv + ")"
+	} else if st.Config, err = config.Path(); err != nil {
+		return err
+	}
+	switch {
+	case cfg.PrivateKey != "":
+		st.Key, st.KeySource = config.PrivateKeyEnv, "environment"
+	case cfg.FromEnvironment():
+		st.Key, st.KeySource = cfg.KeyPath(), config.PrivateKeyPathEnv
+	default:
+		st.Key, st.KeySource = cfg.KeyPath(), "private_key_path in config"
+	}
+
+	jwtToken, err := signJWT(cfg)
+	if err != nil {
+		st.KeyError = err.Error()
+		st.InstallationError = "not resolved without a usable key"
+	} else if id, err := resolveInstallation(jwtToken, sel.src, apiOptions(cfg)...); err != nil {
+		st.InstallationError = err.Error()
+	} else {
+		st.InstallationID, st.InstallationSource = id, sel.src.origin(id)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(st)
+	}
+	st.print(stdout)
+	return nil
+}
+
+// print writes st as aligned "label: value" lines, like a dry run.
+func (st statusReport) print(w io.Writer) {
+	line := func(label, format string, a ...any) {
+		fmt.Fprintf(w, "%-14s%s\n", label+":", fmt.Sprintf(format, a...))
+	}
+	line("config", "%s", st.Config)
+	if st.APIURL != "" {
+		line("host", "%s (%s)", st.Host, st.APIURL)
+	} else {
+		line("host", "%s", st.Host)
+	}
+	line("app", "%s (app_id %d, %s)", st.App, st.AppID, st.AppSource)
+	if st.KeyError != "" {
+		line("key", "%s (%s): %s", st.Key, st.KeySource, st.KeyError)
+	} else {
+		line("key", "%s (%s)", st.Key, st.KeySource)
+	}
+	if st.InstallationError != "" {
+		line("installation", "unresolved: %s", st.InstallationError)
+	} else {
+		line("installation", "%d (%s)", st.InstallationID, st.InstallationSource)
+	}
+	line("token", "not cached; each command mints a fresh installation token")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Status(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 7})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "status"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{
+		"host:         github.com",
+		"app:          default (app_id 1, top-level config)",
+		"(private_key_path in config)",
+		"installation: 7 (from installation_id in config)",
+		"token:        not cached",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+
+	// Installation flags work after the command, as with gha env.
+	stdout, stderr, code = runCmd(t, []string{"gha", "status", "--json", "--installation-id", "9"}, "")
+	if code != 0 {
+		t.Fatalf("--json: exit code = %d, stderr = %s", code, stderr)
+	}
+	var st statusReport
+	if err := json.Unmarshal([]byte(stdout), &st); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, stdout)
+	}
+	if st.InstallationID != 9 || st.InstallationSource != "from --installation-id" || st.AppID != 1 || st.TokenCached {
+		t.Errorf("status = %+v", st)
+	}
+	if !strings.HasSuffix(st.Config, "config.yaml") {
+		t.Errorf("config = %q, want the config file", st.Config)
+	}
+}
+
+func TestRun_StatusBadKey(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	t.Setenv(config.AppIDEnv, "5")
+	t.Setenv(config.PrivateKeyPathEnv, filepath.Join(t.TempDir(), "missing.pem"))
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "status", "--json"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	var st statusReport
+	if err := json.Unmarshal([]byte(stdout), &st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Config != "environment (GHA_APP_ID)" || st.KeySource != config.PrivateKeyPathEnv || st.KeyError == "" || st.InstallationError == "" {
+		t.Errorf("status = %+v, want the key error reported", st)
+	}
+}
+
+func TestRun_StatusUnexpectedArgs(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1})
+	if _, _, code := runCmd(t, []string{"gha", "status", "extra"}, ""); code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
+	}
+}
@@ -6,12 +6,20 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/haribote-lab/github-app-cli/internal/auth"
 	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/update"
 )
 
 func setupTestEnv(t *testing.T) string {
@@ -19,7 +27,35 @@ func setupTestEnv(t *testing.T) string {
 	tmp := t.TempDir()
 	t.Setenv("HOME", tmp)
 	t.Setenv("USERPROFILE", tmp)
+	t.Setenv("APPDATA", filepath.Join(tmp, ".config"))
 	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(config.PathEnv, "")
+	t.Setenv(config.AppIDEnv, "")
+	t.Setenv(ghHostEnv, "")
+	t.Setenv(appEnv, "")
+	t.Setenv(config.PassphraseEnv, "")
+	t.Setenv(config.AgeIdentityEnv, "")
+	t.Setenv(dryRunEnv, "")
+	t.Setenv(isolatedEnv, "")
+	t.Setenv(refreshEnv, "")
+	t.Setenv(quietEnv, "")
+	t.Setenv(debugEnv, "")
+	t.Setenv(debugFileEnv, "")
+	t.Setenv(logFormatEnv, "")
+	t.Setenv(errorJSONEnv, "")
+	t.Setenv(noUpdateCheckEnv, "")
+	t.Setenv(ghNoUpdateNotifierEnv, "")
+	t.Setenv(skipVersionCheckEnv, "")
+	t.Setenv(showRateLimitEnv, "")
+	t.Setenv(revokeEnv, "")
+	t.Setenv(insecureKeyPermsEnv, "")
+	t.Setenv(noColorEnv, "")
+	t.Setenv(colorForceEnv, "")
+	t.Setenv(agentSockEnv, "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	for _, name := range append([]string{"CI"}, ciEnvVars...) {
+		t.Setenv(name, "")
+	}
 	return tmp
 }
 
@@ -32,8 +68,8 @@ func runCmd(t *testing.T, args []string, input string) (stdout, stderr string, c
 
 func TestRun_NoArgs(t *testing.T) {
 	stdout, _, code := runCmd(t, []string{"gha"}, "")
-	if code != 1 {
-		t.Errorf("exit code = %d, want 1", code)
+	if code != exitUsage {
+		t.Errorf("exit code = %d, want %d", code, exitUsage)
 	}
 	if !strings.Contains(stdout, "Usage:") {
 		t.Errorf("stdout = %q, want usage info", stdout)
@@ -222,8 +258,8 @@ func TestRun_ProxyWithoutConfig(t *testing.T) {
 	setupTestEnv(t)
 
 	_, stderr, code := runCmd(t, []string{"gha", "pr", "list"}, "")
-	if code != 1 {
-		t.Errorf("exit code = %d, want 1", code)
+	if code != exitConfig {
+		t.Errorf("exit code = %d, want %d", code, exitConfig)
 	}
 	if !strings.Contains(stderr, "configuration not found") {
 		t.Errorf("stderr = %q, want config not found error", stderr)
@@ -254,6 +290,72 @@ func TestRun_ConfigureTildeExpansion(t *testing.T) {
 	}
 }
 
+func TestRun_ConfigureRelativeKeyPath(t *testing.T) {
+	tmp := setupTestEnv(t)
+	writeTestKey(t, filepath.Join(tmp, "app.pem"))
+	t.Chdir(tmp)
+
+	if _, stderr, code := runCmd(t, []string{"gha", "configure"}, "1\n\napp.pem\n"); code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PrivateKeyPath != filepath.Join(tmp, "app.pem") {
+		t.Errorf("PrivateKeyPath = %q, want it made absolute", cfg.PrivateKeyPath)
+	}
+}
+
+func TestRun_JWTRelativeKeyPath(t *testing.T) {
+	tmp := setupTestEnv(t)
+	dir, _ := config.Dir()
+	writeTestKey(t, filepath.Join(dir, "app.pem"))
+	saveTestConfig(t, &config.Config{AppID: 1, PrivateKeyPath: "app.pem"})
+	t.Chdir(tmp) // not the config directory
+
+	if _, stderr, code := runCmd(t, []string{"gha", "jwt"}, ""); code != 0 {
+		t.Errorf("exit code = %d, stderr = %s", code, stderr)
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not checked on Windows")
+	}
+	setupTestEnv(t)
+	keyPath := generateTestKeyFile(t)
+	if err := os.Chmod(keyPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	cfg := &config.Config{AppID: 1, PrivateKeyPath: keyPath}
+	if err := checkPermissions(cfg, &stderr); err == nil || !strings.Contains(err.Error(), "--insecure-key-perms") {
+		t.Errorf("default: err = %v, want the key refused", err)
+	}
+	if _, err := signJWT(cfg); err == nil {
+		t.Error("signJWT should refuse a key other users can read")
+	}
+
+	t.Setenv(insecureKeyPermsEnv, "1")
+	if err := checkPermissions(cfg, &stderr); err != nil {
+		t.Fatalf("--insecure-key-perms: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "warning: private key "+keyPath+" is accessible by other users") {
+		t.Errorf("stderr = %q, want a warning", stderr.String())
+	}
+
+	// strict_permissions cannot be overridden.
+	cfg.StrictPermissions = true
+	if err := checkPermissions(cfg, &stderr); err == nil || !strings.Contains(err.Error(), "strict_permissions") {
+		t.Errorf("strict: err = %v", err)
+	}
+	if _, err := signJWT(cfg); err == nil {
+		t.Error("signJWT should refuse an exposed key in strict mode")
+	}
+}
+
 // --- Tests for parseInstallationFlags ---
 
 func TestParseInstallationFlags_InstallationID(t *testing.T) {
@@ -319,6 +421,16 @@ func TestParseInstallationFlags_NoFlags(t *testing.T) {
 	}
 }
 
+func TestParseInstallationFlags_AfterCommand(t *testing.T) {
+	override, remaining := parseInstallationFlags([]string{"--installation-id", "5", "secret", "list", "--org", "myorg", "--installation-id", "6"})
+	if override.id != 5 || override.org != "" {
+		t.Errorf("override = %+v, want id 5 and no org", override)
+	}
+	if want := "secret list --org myorg --installation-id 6"; strings.Join(remaining, " ") != want {
+		t.Errorf("remaining = %v, want [%s]", remaining, want)
+	}
+}
+
 func TestParseInstallationFlags_InvalidID(t *testing.T) {
 	override, remaining := parseInstallationFlags([]string{"--installation-id", "notanumber", "pr", "list"})
 	if override.id != 0 {
@@ -339,6 +451,25 @@ func TestParseInstallationFlags_FlagAtEnd(t *testing.T) {
 	}
 }
 
+func TestParseInstallationFlags_Separator(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantOrg string
+		want    []string
+	}{
+		{[]string{"--", "secret", "list", "--org", "myorg"}, "", []string{"secret", "list", "--org", "myorg"}},
+		{[]string{"--org", "acme", "--", "secret", "list", "--org", "myorg"}, "acme", []string{"secret", "list", "--org", "myorg"}},
+		{[]string{"exec", "--", "tool", "--org", "myorg"}, "", []string{"exec", "--", "tool", "--org", "myorg"}},
+		{[]string{"api", "--org", "acme", "--", "--org"}, "", []string{"api", "--org", "acme", "--", "--org"}},
+	}
+	for _, tt := range tests {
+		override, remaining := parseInstallationFlags(tt.args)
+		if override.org != tt.wantOrg || strings.Join(remaining, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("parseInstallationFlags(%q) = %q, %q; want %q, %q", tt.args, override.org, remaining, tt.wantOrg, tt.want)
+		}
+	}
+}
+
 // --- Tests for resolveInstallationFromEnv ---
 
 func TestResolveInstallationFromEnv_ID(t *testing.T) {
@@ -384,7 +515,7 @@ func TestResolveInstallation_FlagIDWins(t *testing.T) {
 	env := installationOverride{id: 200}
 	configID := int64(300)
 
-	id, err := resolveInstallation("fake-jwt", flag, env, configID)
+	id, err := resolveInstallation("fake-jwt", installationSources{flag: flag, env: env, configID: configID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -398,7 +529,7 @@ func TestResolveInstallation_EnvIDWins(t *testing.T) {
 	env := installationOverride{id: 200}
 	configID := int64(300)
 
-	id, err := resolveInstallation("fake-jwt", flag, env, configID)
+	id, err := resolveInstallation("fake-jwt", installationSources{flag: flag, env: env, configID: configID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -412,7 +543,7 @@ func TestResolveInstallation_ConfigIDFallback(t *testing.T) {
 	env := installationOverride{}
 	configID := int64(300)
 
-	id, err := resolveInstallation("fake-jwt", flag, env, configID)
+	id, err := resolveInstallation("fake-jwt", installationSources{flag: flag, env: env, configID: configID})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -431,9 +562,9 @@ func TestRun_HelpContainsFlags(t *testing.T) {
 	for _, want := range []string{
 		"--installation-id",
 		"--org",
-		"GHA_INSTALLATION_ID",
-		"GHA_ORG",
-		"Resolution Order",
+		"Help Topics",
+		"environment",
+		"resolution",
 	} {
 		if !strings.Contains(stdout, want) {
 			t.Errorf("help missing %q", want)
@@ -466,3 +597,841 @@ func writeTestKey(t *testing.T, path string) {
 		t.Fatal(err)
 	}
 }
+
+// --- Tests for --repo ---
+
+func TestParseInstallationFlags_TargetType(t *testing.T) {
+	override, remaining := parseInstallationFlags([]string{"--org", "acme", "--target-type", "user", "repo", "list"})
+	if override.targetType != "user" {
+		t.Errorf("targetType = %q, want %q", override.targetType, "user")
+	}
+	if len(remaining) != 2 || remaining[0] != "repo" || remaining[1] != "list" {
+		t.Errorf("remaining = %v, want [repo list]", remaining)
+	}
+
+	override, _ = parseInstallationFlags([]string{"--target-type=org", "repo", "list"})
+	if override.targetType != "org" {
+		t.Errorf("targetType = %q, want %q", override.targetType, "org")
+	}
+}
+
+func TestParseInstallationFlags_Repo(t *testing.T) {
+	override, remaining := parseInstallationFlags([]string{"--repo", "acme/widgets", "pr", "list"})
+	if override.repo != "acme/widgets" {
+		t.Errorf("repo = %q, want %q", override.repo, "acme/widgets")
+	}
+	if len(remaining) != 2 || remaining[0] != "pr" || remaining[1] != "list" {
+		t.Errorf("remaining = %v, want [pr list]", remaining)
+	}
+}
+
+func TestParseInstallationFlags_RepoEquals(t *testing.T) {
+	override, remaining := parseInstallationFlags([]string{"--org", "x", "--repo=acme/widgets", "pr", "list"})
+	if override.repo != "acme/widgets" {
+		t.Errorf("repo = %q, want %q", override.repo, "acme/widgets")
+	}
+	if len(remaining) != 2 {
+		t.Errorf("remaining = %v, want [pr list]", remaining)
+	}
+}
+
+func TestParseInstallationFlags_RepoAfterSubcommandPassedThrough(t *testing.T) {
+	override, remaining := parseInstallationFlags([]string{"pr", "list", "--repo=acme/widgets"})
+	if override.repo != "" {
+		t.Errorf("repo = %q, want empty (belongs to gh)", override.repo)
+	}
+	if len(remaining) != 3 || remaining[2] != "--repo=acme/widgets" {
+		t.Errorf("remaining = %v, want [pr list --repo=acme/widgets]", remaining)
+	}
+}
+
+func TestResolveInstallationByRepo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/widgets/installation":
+			w.Write([]byte(`{"id": 808, "account": {"login": "acme"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	id, err := resolveInstallationByRepo("fake-jwt", "acme/widgets", auth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("resolveInstallationByRepo: %v", err)
+	}
+	if id != 808 {
+		t.Errorf("id = %d, want 808", id)
+	}
+
+	_, err = resolveInstallationByRepo("fake-jwt", "acme/missing", auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "not installed on acme/missing") {
+		t.Errorf("err = %v, want not installed error", err)
+	}
+
+	_, err = resolveInstallationByRepo("fake-jwt", "bogus", auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "invalid repository") {
+		t.Errorf("err = %v, want invalid repository error", err)
+	}
+}
+
+func TestResolveInstallation_FlagIDWinsOverRepo(t *testing.T) {
+	flag := installationOverride{id: 100, repo: "acme/widgets"}
+
+	id, err := resolveInstallation("fake-jwt", installationSources{flag: flag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 100 {
+		t.Errorf("id = %d, want 100 (--installation-id should win over --repo)", id)
+	}
+}
+
+// --- Tests for --config ---
+
+func TestExtractGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantPath string
+		wantRest []string
+	}{
+		{"separate value", []string{"--config", "/tmp/c.yaml", "pr", "list"}, "/tmp/c.yaml", []string{"pr", "list"}},
+		{"equals", []string{"--config=/tmp/c.yaml", "jwt"}, "/tmp/c.yaml", []string{"jwt"}},
+		{"after other flags", []string{"--org", "acme", "--config", "c.yaml", "pr", "list"}, "c.yaml", []string{"--org", "acme", "pr", "list"}},
+		{"after command belongs to gh", []string{"api", "--config", "x"}, "", []string{"api", "--config", "x"}},
+		{"absent", []string{"pr", "list"}, "", []string{"pr", "list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globals, rest, err := extractGlobalFlags(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if globals.config != tt.wantPath {
+				t.Errorf("config = %q, want %q", globals.config, tt.wantPath)
+			}
+			if strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+				t.Errorf("rest = %v, want %v", rest, tt.wantRest)
+			}
+		})
+	}
+
+	if _, _, err := extractGlobalFlags([]string{"--config"}); err == nil {
+		t.Error("expected error for --config without a path")
+	}
+
+	globals, rest, err := extractGlobalFlags([]string{"--hostname=ghe.example.com", "--config", "c.yaml", "pr", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if globals.hostname != "ghe.example.com" || globals.config != "c.yaml" || len(rest) != 2 {
+		t.Errorf("globals = %+v, rest = %v", globals, rest)
+	}
+
+	globals, rest, err = extractGlobalFlags([]string{"--app", "partner", "--org", "acme", "pr", "list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if globals.app != "partner" || strings.Join(rest, " ") != "--org acme pr list" {
+		t.Errorf("globals = %+v, rest = %v", globals, rest)
+	}
+}
+
+func TestRun_ConfigFlag(t *testing.T) {
+	setupTestEnv(t)
+	cfgPath := filepath.Join(t.TempDir(), "alt.yaml")
+	t.Setenv(config.PathEnv, cfgPath)
+	saveTestConfig(t, &config.Config{AppID: 5151})
+	t.Setenv(config.PathEnv, "")
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--config", cfgPath, "jwt", "--decode"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"iss": "5151"`) {
+		t.Errorf("stdout = %q, want JWT issued for the app in --config", stdout)
+	}
+}
+
+func TestRun_HostnameSelectsHostConfig(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, Hosts: map[string]config.Host{
+		"ghe.example.com": {AppID: 808, PrivateKeyPath: generateTestKeyFile(t)},
+	}})
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.example.com", "jwt", "--decode"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"iss": "808"`) {
+		t.Errorf("stdout = %q, want JWT for the ghe.example.com App", stdout)
+	}
+	if got := os.Getenv(ghHostEnv); got != "ghe.example.com" {
+		t.Errorf("GH_HOST = %q, want it exported for gh", got)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "--hostname", "other.example.com", "jwt"}, "")
+	if code != exitConfig || !strings.Contains(stderr, "no configuration for host other.example.com") {
+		t.Errorf("unknown host: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestLoadConfig_GHHost(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, Hosts: map[string]config.Host{
+		"ghe.example.com": {AppID: 2, PrivateKeyPath: "/k.pem"},
+	}})
+	t.Setenv(ghHostEnv, "ghe.example.com")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 2 {
+		t.Errorf("AppID = %d, want 2", cfg.AppID)
+	}
+	opts := apiOptions(cfg)
+	if len(opts) != 1 {
+		t.Fatalf("apiOptions = %d options, want a base URL override", len(opts))
+	}
+	if got := webURL(cfg); got != "https://ghe.example.com" {
+		t.Errorf("webURL = %q", got)
+	}
+	dir, _ := cacheDir(cfg.Scope())
+	if !strings.HasSuffix(dir, filepath.Join("hosts", "ghe.example.com")) {
+		t.Errorf("cacheDir = %q, want a per-host directory", dir)
+	}
+}
+
+func TestLoadConfig_App(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, Apps: map[string]config.App{
+		"partner": {AppID: 2, PrivateKeyPath: "/k.pem"},
+	}})
+	t.Setenv(appEnv, "partner")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 2 {
+		t.Errorf("AppID = %d, want 2", cfg.AppID)
+	}
+	dir, _ := cacheDir(cfg.Scope())
+	if !strings.HasSuffix(dir, filepath.Join("apps", "partner")) {
+		t.Errorf("cacheDir = %q, want a per-app directory", dir)
+	}
+
+	t.Setenv(appEnv, "unknown")
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "no app named unknown") {
+		t.Errorf("err = %v, want unknown app error", err)
+	}
+}
+
+func TestRouteTarget(t *testing.T) {
+	tests := []struct {
+		name               string
+		flag, env, project installationOverride
+		gitRepo            string
+		want               string
+	}{
+		{name: "flag repo", flag: installationOverride{repo: "acme/api", org: "x"}, env: installationOverride{org: "y"}, want: "acme/api"},
+		{name: "flag org", flag: installationOverride{org: "acme"}, env: installationOverride{org: "y"}, want: "acme"},
+		{name: "env org", env: installationOverride{org: "acme"}, project: installationOverride{org: "y"}, want: "acme"},
+		{name: "project org", project: installationOverride{org: "acme"}, gitRepo: "y/z", want: "acme"},
+		{name: "git remote", gitRepo: "acme/api", want: "acme/api"},
+		{name: "none", flag: installationOverride{id: 5}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeTarget(tt.flag, tt.env, tt.project, tt.gitRepo); got != tt.want {
+				t.Errorf("routeTarget = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// --- Tests for repoFromGhArgs ---
+
+func TestRepoFromGhArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"short flag", []string{"pr", "list", "-R", "acme/widgets"}, "acme/widgets"},
+		{"long flag", []string{"issue", "list", "--repo", "acme/widgets"}, "acme/widgets"},
+		{"long flag equals", []string{"pr", "view", "1", "--repo=acme/widgets"}, "acme/widgets"},
+		{"short flag attached", []string{"pr", "list", "-Racme/widgets"}, "acme/widgets"},
+		{"short flag equals", []string{"pr", "list", "-R=acme/widgets"}, "acme/widgets"},
+		{"host prefix", []string{"pr", "list", "-R", "github.example.com/acme/widgets"}, "acme/widgets"},
+		{"last one wins", []string{"-R", "a/b", "pr", "list", "-R", "c/d"}, "c/d"},
+		{"no repo", []string{"pr", "list"}, ""},
+		{"owner only", []string{"pr", "list", "-R", "acme"}, ""},
+		{"after separator", []string{"api", "--", "-R", "acme/widgets"}, ""},
+		{"flag without value", []string{"pr", "list", "-R"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repoFromGhArgs(tt.args); got != tt.want {
+				t.Errorf("repoFromGhArgs(%v) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveInstallation_GitRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/acme/widgets/installation" {
+			w.Write([]byte(`{"id": 404404}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	id, err := resolveInstallation("fake-jwt", installationSources{gitRepo: "acme/widgets", configID: 300}, auth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 404404 {
+		t.Errorf("id = %d, want 404404 (git remote should win over config)", id)
+	}
+
+	id, err = resolveInstallation("fake-jwt", installationSources{gitRepo: "me/dotfiles", configID: 300}, auth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 300 {
+		t.Errorf("id = %d, want 300 (uninstalled git remote should fall back to config)", id)
+	}
+}
+
+func TestResolveInstallation_ProjectBetweenEnvAndGitRemote(t *testing.T) {
+	src := installationSources{
+		project:  installationOverride{id: 150},
+		gitRepo:  "acme/widgets",
+		configID: 300,
+	}
+	id, err := resolveInstallation("fake-jwt", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 150 {
+		t.Errorf("id = %d, want 150 (.gha.yaml should win over git remote and config)", id)
+	}
+
+	src.env = installationOverride{id: 200}
+	id, err = resolveInstallation("fake-jwt", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 200 {
+		t.Errorf("id = %d, want 200 (env should win over .gha.yaml)", id)
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, config.ProjectFile), []byte("org: acme\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(sub)
+
+	project, err := loadProject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Org != "acme" {
+		t.Errorf("Org = %q, want acme", project.Org)
+	}
+}
+
+func TestResolveInstallation_EnvWinsOverGitRemote(t *testing.T) {
+	id, err := resolveInstallation("fake-jwt", installationSources{env: installationOverride{id: 200}, gitRepo: "acme/widgets", configID: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 200 {
+		t.Errorf("id = %d, want 200 (env should win over git remote)", id)
+	}
+}
+
+func TestResolveInstallationByOrg_Cached(t *testing.T) {
+	setupTestEnv(t)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`[{"id": 1, "account": {"login": "acme"}}, {"id": 2, "account": {"login": "other"}}]`))
+	}))
+	defer srv.Close()
+
+	for _, org := range []string{"acme", "ACME", "other"} {
+		if _, err := resolveInstallationByOrg("fake-jwt", "", org, "", auth.WithBaseURL(srv.URL)); err != nil {
+			t.Fatalf("resolveInstallationByOrg(%s): %v", org, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("installation listings = %d, want 1 (later lookups served from cache)", calls)
+	}
+
+	id, _ := resolveInstallationByOrg("fake-jwt", "", "other", "", auth.WithBaseURL(srv.URL))
+	if id != 2 {
+		t.Errorf("id = %d, want 2", id)
+	}
+}
+
+func TestResolveInstallationByOrg_MissRefetches(t *testing.T) {
+	setupTestEnv(t)
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`[{"id": 1, "account": {"login": "acme"}}]`))
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := resolveInstallationByOrg("fake-jwt", "", "unknown", "", auth.WithBaseURL(srv.URL)); err == nil {
+			t.Fatal("expected error for unknown org")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("installation listings = %d, want 2 (misses are not cached)", calls)
+	}
+}
+
+func TestForgetCachedInstallation(t *testing.T) {
+	setupTestEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 7, "account": {"login": "acme"}}]`))
+	}))
+	defer srv.Close()
+
+	if _, err := resolveInstallationByOrg("fake-jwt", "", "acme", "", auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if !forgetCachedInstallation("", 7) {
+		t.Error("forgetCachedInstallation(7) = false, want true")
+	}
+	if forgetCachedInstallation("", 7) {
+		t.Error("second forgetCachedInstallation(7) = true, want false")
+	}
+}
+
+func TestResolveInstallationByOrg_RemembersInConfig(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 5, "account": {"login": "Acme"}}]`))
+	}))
+	defer srv.Close()
+
+	if _, err := resolveInstallationByOrg("fake-jwt", "", "acme", "", auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := cfg.InstallationFor("acme"); !ok || id != 5 {
+		t.Errorf("installations[acme] = %d, %v; want 5, true", id, ok)
+	}
+}
+
+func TestResolveInstallation_ConfigInstallationsMap(t *testing.T) {
+	src := installationSources{
+		flag:          installationOverride{org: "ACME"},
+		installations: map[string]int64{"acme": 77},
+	}
+
+	// No API server: the map must answer on its own.
+	id, err := resolveInstallation("fake-jwt", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 77 {
+		t.Errorf("id = %d, want 77 (from installations map)", id)
+	}
+}
+
+func TestResolveInstallationByOrg_TargetType(t *testing.T) {
+	setupTestEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 1, "account": {"login": "acme"}, "target_type": "User"},
+			{"id": 2, "account": {"login": "other"}, "target_type": "Organization"}]`))
+	}))
+	defer srv.Close()
+
+	id, err := resolveInstallationByOrg("fake-jwt", "", "acme", "user", auth.WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 1 {
+		t.Errorf("id = %d, want 1", id)
+	}
+
+	// The cached User entry must not satisfy an org lookup.
+	_, err = resolveInstallationByOrg("fake-jwt", "", "acme", "org", auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), `no org installation found for "acme"`) {
+		t.Errorf("err = %v, want no org installation error", err)
+	}
+
+	_, err = resolveInstallationByOrg("fake-jwt", "", "acme", "team", auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "invalid target type") {
+		t.Errorf("err = %v, want invalid target type error", err)
+	}
+}
+
+// --- Tests for suspended installations ---
+
+func TestMintInstallationToken_Suspended(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/9/access_tokens":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"This installation has been suspended"}`))
+		case "/app/installations/9":
+			w.Write([]byte(`{"id": 9, "account": {"login": "acme"}, "suspended_at": "2024-03-01T12:00:00Z",
+				"html_url": "https://github.com/organizations/acme/settings/installations/9"}`))
+		}
+	}))
+	defer srv.Close()
+
+	_, err := mintInstallationToken("fake-jwt", 9, nil, auth.WithBaseURL(srv.URL))
+	var suspended *suspendedError
+	if !errors.As(err, &suspended) {
+		t.Fatalf("err = %v, want *suspendedError", err)
+	}
+	want := "installation 9 is suspended since 2024-03-01 — unsuspend it at https://github.com/organizations/acme/settings/installations/9"
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestMintInstallationToken_OtherForbidden(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/9/access_tokens":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Resource not accessible"}`))
+		case "/app/installations/9":
+			w.Write([]byte(`{"id": 9, "account": {"login": "acme"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	_, err := mintInstallationToken("fake-jwt", 9, nil, auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("err = %v, want original 403 error", err)
+	}
+}
+
+func TestResolveInstallationByOrg_Suspended(t *testing.T) {
+	setupTestEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 3, "account": {"login": "acme"}, "suspended_at": "2024-01-02T00:00:00Z"}]`))
+	}))
+	defer srv.Close()
+
+	_, err := resolveInstallationByOrg("fake-jwt", "", "acme", "", auth.WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "suspended since 2024-01-02") {
+		t.Errorf("err = %v, want suspended error", err)
+	}
+	if !strings.Contains(err.Error(), "https://github.com/settings/installations/3") {
+		t.Errorf("err = %v, want fallback unsuspend URL", err)
+	}
+}
+
+func TestRun_Isolated(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_isolated", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	seen := filepath.Join(t.TempDir(), "seen")
+	binDir := t.TempDir()
+	gh := "#!/bin/sh\necho \"$GH_CONFIG_DIR\" > " + seen + "\n[ -d \"$GH_CONFIG_DIR\" ] || exit 9\n"
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GH_CONFIG_DIR", "/home/me/.config/gh")
+
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	_, stderr, code := runCmd(t, []string{"gha", "--isolated", "--hostname", "ghe.test", "--installation-id", "7", "pr", "list"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	data, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := strings.TrimSpace(string(data))
+	if dir == "" || dir == "/home/me/.config/gh" {
+		t.Fatalf("GH_CONFIG_DIR = %q, want a temporary directory", dir)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("isolated config directory %s was not removed", dir)
+	}
+}
+
+func TestRun_Separator(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_sep", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	seen := filepath.Join(t.TempDir(), "seen")
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte("#!/bin/sh\necho \"gh $*\" > "+seen+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	// --isolated runs gh as a child so the test process survives.
+	for args, want := range map[string]string{
+		"-- secret list --org myorg": "gh secret list --org myorg\n",
+		"-- config list":             "gh config list\n",
+		"-- exec ls":                 "gh exec ls\n",
+	} {
+		argv := append([]string{"gha", "--isolated", "--hostname", "ghe.test", "--installation-id", "7"}, strings.Fields(args)...)
+		_ = os.Remove(seen)
+		_, stderr, code := runCmd(t, argv, "")
+		got, _ := os.ReadFile(seen)
+		if code != 0 || string(got) != want {
+			t.Errorf("gha %s: code = %d, gh saw %q, stderr = %s; want %q", args, code, got, stderr, want)
+		}
+	}
+}
+
+func TestRun_Quiet(t *testing.T) {
+	setupTestEnv(t)
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 1})
+
+	_, stderr, code := runCmd(t, []string{"gha", "alias", "set", "prs", "pr list"}, "")
+	if code != 0 || stderr == "" {
+		t.Fatalf("code = %d, stderr = %q; want a confirmation", code, stderr)
+	}
+	_, stderr, code = runCmd(t, []string{"gha", "-q", "alias", "set", "prs", "pr list --limit 5"}, "")
+	if code != 0 || stderr != "" {
+		t.Errorf("--quiet: code = %d, stderr = %q; want no output", code, stderr)
+	}
+	t.Setenv(quietEnv, "")
+
+	// A cached newer release makes checkForUpdate print a notice.
+	dir, err := config.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := fmt.Sprintf(`{"latest_version": "99.0.0", "checked_at": %q}`, time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(update.CachePath(dir), []byte(cache), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldVersion := version
+	version = "1.0.0"
+	t.Cleanup(func() { version = oldVersion })
+
+	var notice bytes.Buffer
+	checkForUpdate(&notice)
+	if !strings.Contains(notice.String(), "99.0.0") {
+		t.Fatalf("notice = %q, want the newer version", notice.String())
+	}
+	notice.Reset()
+	t.Setenv(quietEnv, "1")
+	checkForUpdate(&notice)
+	if notice.Len() != 0 {
+		t.Errorf("quiet notice = %q, want none", notice.String())
+	}
+}
+
+func TestCheckForUpdate_Disabled(t *testing.T) {
+	tmp := setupTestEnv(t)
+	dir, err := config.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	cache := fmt.Sprintf(`{"latest_version": "99.0.0", "checked_at": %q}`, time.Now().Format(time.RFC3339))
+	if err := os.WriteFile(update.CachePath(dir), []byte(cache), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldVersion := version
+	version = "1.0.0"
+	t.Cleanup(func() { version = oldVersion })
+
+	var notice bytes.Buffer
+	t.Setenv(noUpdateCheckEnv, "1")
+	checkForUpdate(&notice)
+	if notice.Len() != 0 {
+		t.Errorf("%s: notice = %q, want none", noUpdateCheckEnv, notice.String())
+	}
+	t.Setenv(noUpdateCheckEnv, "")
+
+	cfgPath := filepath.Join(tmp, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("disable_update_check: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.PathEnv, cfgPath)
+	checkForUpdate(&notice)
+	if notice.Len() != 0 {
+		t.Errorf("disable_update_check: notice = %q, want none", notice.String())
+	}
+	t.Setenv(config.PathEnv, "")
+
+	for name, value := range map[string]string{"CI": "true", "GITHUB_ACTIONS": "true", "JENKINS_URL": "https://ci.example.com"} {
+		t.Setenv(name, value)
+		checkForUpdate(&notice)
+		if notice.Len() != 0 {
+			t.Errorf("%s=%s: notice = %q, want none", name, value, notice.String())
+		}
+		t.Setenv(name, "")
+	}
+	t.Setenv("CI", "false")
+	checkForUpdate(&notice)
+	if notice.Len() == 0 {
+		t.Error("CI=false: want the notice")
+	}
+}
+
+func TestCheckForUpdate_Background(t *testing.T) {
+	setupTestEnv(t)
+	oldVersion := version
+	version = "1.0.0"
+	t.Cleanup(func() { version = oldVersion })
+	started := 0
+	oldStart := startUpdateCheck
+	startUpdateCheck = func() error { started++; return nil }
+	t.Cleanup(func() { startUpdateCheck = oldStart })
+
+	// With no cache, the check starts in the background and prints nothing.
+	var notice bytes.Buffer
+	checkForUpdate(&notice)
+	if notice.Len() != 0 || started != 1 {
+		t.Fatalf("notice = %q, started = %d; want no notice and one background check", notice.String(), started)
+	}
+
+	// The background command fills the cache for the next run.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name": "v99.0.0"}`)
+	}))
+	t.Cleanup(srv.Close)
+	oldOpts := releaseOptions
+	releaseOptions = []update.Option{update.WithBaseURL(srv.URL)}
+	t.Cleanup(func() { releaseOptions = oldOpts })
+	if _, stderr, code := runCmd(t, []string{"gha", updateCheckCommand}, ""); code != 0 {
+		t.Fatalf("%s: code = %d, stderr = %q", updateCheckCommand, code, stderr)
+	}
+
+	checkForUpdate(&notice)
+	if !strings.Contains(notice.String(), "99.0.0") || started != 1 {
+		t.Errorf("notice = %q, started = %d; want the cached notice and no new check", notice.String(), started)
+	}
+
+	// Each release is announced once.
+	notice.Reset()
+	checkForUpdate(&notice)
+	if notice.Len() != 0 {
+		t.Errorf("repeat notice = %q, want none", notice.String())
+	}
+}
+
+func TestRun_AsGhExtension(t *testing.T) {
+	setupTestEnv(t)
+	t.Cleanup(func() { progName = "gha" })
+
+	for arg0, want := range map[string]string{
+		"gha":                "gha",
+		"/usr/local/bin/gha": "gha",
+		"gh-app-auth.exe":    "gh app-auth",
+		"gh-":                "gha",
+		"/home/me/.local/share/gh/extensions/gh-app-auth/gh-app-auth": "gh app-auth",
+	} {
+		if got := invokedAs(filepath.FromSlash(arg0)); got != want {
+			t.Errorf("invokedAs(%q) = %q, want %q", arg0, got, want)
+		}
+	}
+
+	stdout, _, _ := runCmd(t, []string{"gh-app-auth", "--help"}, "")
+	if !strings.Contains(stdout, "\n  gh app-auth pr list\n") {
+		t.Errorf("usage does not use the extension name:\n%s", stdout)
+	}
+	stdout, _, _ = runCmd(t, []string{"gh-app-auth", "--version"}, "")
+	if !strings.HasPrefix(stdout, "gh app-auth ") {
+		t.Errorf("version = %q", stdout)
+	}
+}
+
+func TestRun_GhUpdateNotifier(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_n", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	seen := filepath.Join(t.TempDir(), "seen")
+	binDir := t.TempDir()
+	gh := "#!/bin/sh\necho \"[$GH_NO_UPDATE_NOTIFIER]\" > " + seen + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	for _, keep := range []bool{false, true} {
+		saveTestConfig(t, &config.Config{
+			AppID:            1,
+			GhUpdateNotifier: keep,
+			Hosts:            map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+		})
+		// --isolated runs gh as a child so the test process survives.
+		if _, stderr, code := runCmd(t, []string{"gha", "--isolated", "--hostname", "ghe.test", "--installation-id", "7", "pr", "list"}, ""); code != 0 {
+			t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+		}
+		want := "[1]\n"
+		if keep {
+			want = "[]\n"
+		}
+		if data, _ := os.ReadFile(seen); string(data) != want {
+			t.Errorf("gh_update_notifier %v: gh saw GH_NO_UPDATE_NOTIFIER %q, want %q", keep, data, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+const tokenUsage = "usage: gha [flags] token [--org ORG] [--installation-id ID] [--plain] [--github-actions]"
+
+// githubOutputEnv and githubEnvEnv name the files a GitHub Actions step
+// writes its outputs, and the environment of the job's later steps, to.
+const (
+	githubOutputEnv = "GITHUB_OUTPUT"
+	githubEnvEnv    = "GITHUB_ENV"
+)
+
+// parseTokenArgs parses gha token's arguments. Like gha env, gha token also
+// takes the installation flags after it; they update override.
+func parseTokenArgs(args []string, override *installationOverride, stderr io.Writer) (*envRequest, error) {
+	fs := flag.NewFlagSet("token", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	plain := fs.Bool("plain", false, "Print the token even to a terminal, unmasked")
+	actions := fs.Bool("github-actions", false, "Mask the token in the workflow log and set it as the step's token output")
+	fs.Int64Var(&override.id, "installation-id", override.id, "Installation ID to use")
+	fs.StringVar(&override.org, "org", override.org, "Account to find the installation for")
+	fs.StringVar(&override.repo, "repo", override.repo, "Repository to find the installation for")
+	fs.StringVar(&override.targetType, "target-type", override.targetType, "Only match an org or user account")
+	if err := fs.Parse(args); err != nil {
+		return nil, &usageError{err}
+	}
+	if fs.NArg() > 0 {
+		return nil, usageErrorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if override.id < 0 {
+		return nil, usageErrorf("invalid installation ID %d: must be a positive integer", override.id)
+	}
+	if *actions && *plain {
+		return nil, usageErrorf("--plain has no effect with --github-actions - %s", tokenUsage)
+	}
+	if *actions && os.Getenv(githubOutputEnv) == "" {
+		return nil, fmt.Errorf("--github-actions: $%s is not set; run it in a GitHub Actions step", githubOutputEnv)
+	}
+	return &envRequest{command: "token", plain: *plain, githubActions: *actions}, nil
+}
+
+// printToken prints token for gha token: on its own, masked on a terminal
+// unless r.plain is set, or with r.githubActions as the step's outputs.
+func printToken(r envRequest, installationID int64, token *auth.InstallationToken, stdout, stderr io.Writer) error {
+	if !r.githubActions {
+		if !r.plain && isTerminal(stdout) {
+			fmt.Fprintln(stdout, maskToken(token.Token))
+			infof(stderr, "gha: the token is masked on a terminal; pass --plain to print it\n")
+			return nil
+		}
+		fmt.Fprintln(stdout, token.Token)
+		return nil
+	}
+
+	addMasks(stdout, token.Token)
+	if err := appendGitHubFile(os.Getenv(githubOutputEnv), [][2]string{
+		{"token", token.Token},
+		{"expires-at", token.ExpiresAt.UTC().Format(time.RFC3339)},
+		{"installation-id", strconv.FormatInt(installationID, 10)},
+	}); err != nil {
+		return err
+	}
+	infof(stderr, "Set the step's token output; it expires at %s.\n", token.ExpiresAt.UTC().Format(time.RFC3339))
+	return nil
+}
+
+// addMasks has the GitHub Actions runner redact secrets from the job's log.
+// The runner reads workflow commands from stdout, and each mask must be in
+// place before its secret can reach the log through a later step. A mask
+// covers a single line, so a secret spanning lines is masked line by line.
+func addMasks(stdout io.Writer, secrets ...string) {
+	for _, s := range secrets {
+		for line := range strings.Lines(s) {
+			if line = strings.TrimRight(line, "\r\n"); line != "" {
+				fmt.Fprintf(stdout, "::add-mask::%s\n", line)
+			}
+		}
+	}
+}
+
+// appendGitHubFile appends vars to path, one of the files GitHub Actions
+// reads a step's outputs or environment from. A value spanning lines is
+// written between delimiters that cannot occur in it.
+func appendGitHubFile(path string, vars [][2]string) error {
+	var b strings.Builder
+	for _, v := range vars {
+		if !strings.ContainsAny(v[1], "\r\n") {
+			fmt.Fprintf(&b, "%s=%s\n", v[0], v[1])
+			continue
+		}
+		delim := "ghadelimiter_" + rand.Text()
+		for strings.Contains(v[1], delim) {
+			delim = "ghadelimiter_" + rand.Text()
+		}
+		fmt.Fprintf(&b, "%s<<%s\n%s\n%s\n", v[0], delim, v[1], delim)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
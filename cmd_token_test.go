@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Token(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_fortoken", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, InstallationID: 7, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+	t.Setenv(githubOutputEnv, "")
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "token"}, "")
+	if code != 0 || stdout != "ghs_fortoken\n" {
+		t.Errorf("token: code = %d, stdout = %q, stderr = %s", code, stdout, stderr)
+	}
+
+	if _, _, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "token", "--github-actions"}, ""); code == 0 {
+		t.Error("--github-actions succeeded without $GITHUB_OUTPUT")
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputFile, []byte("earlier=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(githubOutputEnv, outputFile)
+	stdout, stderr, code = runCmd(t, []string{"gha", "--hostname", "ghe.test", "token", "--github-actions"}, "")
+	if code != 0 {
+		t.Fatalf("--github-actions: exit code = %d, stderr = %s", code, stderr)
+	}
+	if stdout != "::add-mask::ghs_fortoken\n" {
+		t.Errorf("--github-actions: stdout = %q, want the mask command", stdout)
+	}
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "earlier=1\ntoken=ghs_fortoken\nexpires-at=2099-01-01T00:00:00Z\ninstallation-id=7\n"
+	if string(data) != want {
+		t.Errorf("$GITHUB_OUTPUT = %q, want %q", data, want)
+	}
+}
+
+func TestAppendGitHubFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env")
+	if err := appendGitHubFile(path, [][2]string{{"ONE", "1"}, {"KEY", "line 1\nline 2"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var delim string
+	if _, err := fmt.Sscanf(string(data), "ONE=1\nKEY<<%s\n", &delim); err != nil {
+		t.Fatalf("%q: %v", data, err)
+	}
+	if want := "ONE=1\nKEY<<" + delim + "\nline 1\nline 2\n" + delim + "\n"; string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// noColorEnv, set by --no-color, turns off colored output, for gha and for
+// gh alike. See https://no-color.org.
+const noColorEnv = "NO_COLOR"
+
+// colorForceEnv, set to anything but 0, colors output even when it is not
+// a terminal.
+const colorForceEnv = "CLICOLOR_FORCE"
+
+// SGR codes gha colors its output with.
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorDim    = "2"
+)
+
+// colorEnabled reports whether output written to w may be colored: never
+// with NO_COLOR set or under --log-format json, always with CLICOLOR_FORCE
+// set, and otherwise only when w is a terminal.
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv(noColorEnv) != "" {
+		return false
+	}
+	switch w.(type) {
+	case *jsonLog:
+		return false
+	case colorLog:
+		return true
+	}
+	if force := os.Getenv(colorForceEnv); force != "" && force != "0" {
+		return true
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether stream, such as stdin or stdout, is a terminal
+// (a character device).
+func isTerminal(stream any) bool {
+	f, ok := stream.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint returns s in the color code when output to w may be colored, and
+// s unchanged otherwise.
+func paint(w io.Writer, code, s string) string {
+	if !colorEnabled(w) {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// logColors are the colors of gha's message prefixes, the same prefixes
+// jsonLog takes levels from.
+var logColors = []struct {
+	prefix string
+	code   string
+}{
+	{"error: ", colorRed},
+	{"warning: ", colorYellow},
+	{"debug: ", colorDim},
+}
+
+// colorLog colors the "error: ", "warning: " and "debug: " prefixes of the
+// messages written to it, which gha's own stderr is when it is colored.
+type colorLog struct {
+	w io.Writer
+}
+
+func (l colorLog) Write(p []byte) (int, error) {
+	for _, lc := range logColors {
+		if rest, ok := bytes.CutPrefix(p, []byte(lc.prefix)); ok {
+			colored := "\x1b[" + lc.code + "m" + lc.prefix[:len(lc.prefix)-1] + "\x1b[0m "
+			if _, err := l.w.Write(append([]byte(colored), rest...)); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+	return l.w.Write(p)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	setupTestEnv(t)
+	var buf bytes.Buffer
+	if colorEnabled(&buf) {
+		t.Error("colored output that is not a terminal")
+	}
+	t.Setenv(colorForceEnv, "0")
+	if colorEnabled(&buf) {
+		t.Error("CLICOLOR_FORCE=0 should not force color")
+	}
+	t.Setenv(colorForceEnv, "1")
+	if !colorEnabled(&buf) {
+		t.Error("CLICOLOR_FORCE=1 should force color")
+	}
+	if colorEnabled(newJSONLog(&buf)) {
+		t.Error("colored JSON log records")
+	}
+	t.Setenv(noColorEnv, "1")
+	if colorEnabled(&buf) {
+		t.Error("NO_COLOR should win over CLICOLOR_FORCE")
+	}
+	if got := paint(&buf, colorRed, "x"); got != "x" {
+		t.Errorf("paint = %q, want it unchanged under NO_COLOR", got)
+	}
+}
+
+func TestColorLog(t *testing.T) {
+	var buf bytes.Buffer
+	l := colorLog{&buf}
+	l.Write([]byte("warning: key is readable\n"))
+	l.Write([]byte("gh output\n"))
+	want := "\x1b[33mwarning:\x1b[0m key is readable\ngh output\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRun_Color(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv(colorForceEnv, "1")
+
+	_, stderr, _ := runCmd(t, []string{"gha", "cache", "clear", "extra"}, "")
+	if !strings.HasPrefix(stderr, "\x1b[31merror:\x1b[0m ") {
+		t.Errorf("stderr = %q, want a red error prefix", stderr)
+	}
+	_, stderr, _ = runCmd(t, []string{"gha", "--no-color", "cache", "clear", "extra"}, "")
+	if !strings.HasPrefix(stderr, "error: ") {
+		t.Errorf("stderr = %q, want no color with --no-color", stderr)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+// debugEnv, set by --debug, traces how gha picks the App and installation
+// and every GitHub API request it makes, with tokens redacted, and masked
+// should any slip through.
+const debugEnv = "GHA_DEBUG"
+
+// debugFileEnv names a file the trace is appended to instead of stderr.
+const debugFileEnv = "GHA_DEBUG_FILE"
+
+// debugLog receives the trace, or is nil when --debug is off.
+var debugLog io.Writer
+
+// openDebugLog points debugLog at stderr or GHA_DEBUG_FILE when --debug is
+// in effect, and returns a func to close it again.
+func openDebugLog(stderr io.Writer) (func(), error) {
+	if !envBool(debugEnv) {
+		debugLog = nil
+		return func() {}, nil
+	}
+	path := os.Getenv(debugFileEnv)
+	if path == "" {
+		debugLog = maskedWriter{stderr}
+		return func() { debugLog = nil }, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening debug log: %w", err)
+	}
+	debugLog = maskedWriter{f}
+	if _, ok := stderr.(*jsonLog); ok {
+		debugLog = maskedWriter{newJSONLog(f)}
+	}
+	return func() {
+		debugLog = nil
+		_ = f.Close()
+	}, nil
+}
+
+// debugAPIOptions traces API calls when --debug is in effect.
+func debugAPIOptions() []auth.Option {
+	if debugLog == nil {
+		return nil
+	}
+	return []auth.Option{auth.WithDebug(debugLog)}
+}
+
+// debugf writes a line of the trace when --debug is in effect.
+func debugf(format string, a ...any) {
+	if debugLog != nil {
+		fmt.Fprintf(debugLog, "debug: "+format+"\n", a...)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Debug(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 7})
+
+	_, stderr, code := runCmd(t, []string{"gha", "--debug", "--dry-run", "pr", "list"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{
+		"debug: config: ",
+		`debug: app: "" (top-level config)`,
+		"debug: installation: 7 (from installation_id in config)",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr lacks %q:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestRun_DebugFile(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 7})
+	path := filepath.Join(t.TempDir(), "debug.log")
+	t.Setenv(debugEnv, "1")
+	t.Setenv(debugFileEnv, path)
+
+	_, stderr, code := runCmd(t, []string{"gha", "--dry-run", "pr", "list"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if strings.Contains(stderr, "debug:") {
+		t.Errorf("trace went to stderr:\n%s", stderr)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "debug: installation: 7") {
+		t.Errorf("debug log = %q, want the resolved installation", data)
+	}
+	if debugLog != nil {
+		t.Error("debugLog still set after run returned")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+// dryRunEnv, set by --dry-run, makes gha print how it would run a command
+// instead of running it.
+const dryRunEnv = "GHA_DRY_RUN"
+
+// dryRun describes everything runProxy resolved for a command.
+type dryRun struct {
+	cfg            *config.Config
+	appSource      string
+	installationID int64
+	src            installationSources
+	permissions    map[string]string
+	argv           []string
+	isolated       bool
+	revoke         bool
+}
+
+// print writes d to w as aligned "label: value" lines. The token is never
+// minted, so the environment shows where it would go.
+func (d dryRun) print(w io.Writer) {
+	line := func(label, format string, a ...any) {
+		fmt.Fprintf(w, "%-14s%s\n", label+":", fmt.Sprintf(format, a...))
+	}
+
+	app := "default"
+	if name := d.cfg.AppName(); name != "" {
+		app = name
+	}
+	line("app", "%s (app_id %d, %s)", app, d.cfg.AppID, d.appSource)
+
+	host := d.cfg.Host()
+	if host == "" {
+		host = config.DefaultHost
+	}
+	if api := d.cfg.APIURL(); api != "" {
+		line("host", "%s (%s)", host, api)
+	} else {
+		line("host", "%s", host)
+	}
+	line("installation", "%d (%s)", d.installationID, d.src.origin(d.installationID))
+
+	if len(d.permissions) == 0 {
+		line("permissions", "all granted to the installation")
+	} else {
+		names := make([]string, 0, len(d.permissions))
+		for name := range d.permissions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			names[i] = name + "=" + d.permissions[name]
+		}
+		line("permissions", "%s", strings.Join(names, ", "))
+	}
+
+	var hooks []string
+	if d.cfg.Hooks.PreRun != "" {
+		hooks = append(hooks, "pre_run")
+	}
+	if d.cfg.Hooks.PostRun != "" {
+		hooks = append(hooks, "post_run")
+	}
+	if len(hooks) > 0 {
+		line("hooks", "%s", strings.Join(hooks, ", "))
+	}
+	if d.revoke {
+		line("token", "revoked once the command exits")
+	}
+
+	quoted := make([]string, len(d.argv))
+	for i, arg := range d.argv {
+		quoted[i] = shellQuote(arg)
+	}
+	line("command", "%s", strings.Join(quoted, " "))
+
+	tokenEnv := []string{"GH_TOKEN"}
+	if proxy.IsEnterprise(d.cfg.Host()) {
+		tokenEnv = append(tokenEnv, "GH_ENTERPRISE_TOKEN")
+	}
+	var env []string
+	for _, name := range append(tokenEnv, d.cfg.TokenEnv...) {
+		env = append(env, name+"=<redacted>")
+	}
+	if v := os.Getenv(ghHostEnv); v != "" {
+		env = append(env, ghHostEnv+"="+v)
+	}
+	if muteGhUpdateNotifier(d.cfg) {
+		env = append(env, ghNoUpdateNotifierEnv+"=1")
+	}
+	if d.isolated {
+		env = append(env, "GH_CONFIG_DIR=<temporary directory>")
+	}
+	for _, name := range append([]string{"GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}, d.cfg.ScrubEnv...) {
+		if _, ok := os.LookupEnv(name); ok && !slices.Contains(tokenEnv, name) {
+			env = append(env, name+" removed")
+		}
+	}
+	line("env", "%s", strings.Join(env, ", "))
+}
+
+// origin names the setting that selected installation id, following the
+// precedence of resolveInstallation.
+func (src installationSources) origin(id int64) string {
+	switch {
+	case src.flag.id > 0:
+		return "from --installation-id"
+	case src.flag.repo != "":
+		return "from repository " + src.flag.repo
+	case src.flag.org != "":
+		return "from --org " + src.flag.org
+	case src.env.id > 0:
+		return "from GHA_INSTALLATION_ID"
+	case src.env.org != "":
+		return "from GHA_ORG " + src.env.org
+	case src.project.id > 0:
+		return "from installation_id in .gha.yaml"
+	case src.project.org != "":
+		return "from org " + src.project.org + " in .gha.yaml"
+	case src.gitRepo != "" && !(src.configID > 0 && id == src.configID):
+		return "from git remote " + src.gitRepo
+	case src.configID > 0:
+		return "from installation_id in config"
+	default:
+		return "auto-detected"
+	}
+}
+
+// shellQuote quotes s for a POSIX shell when it contains anything but
+// characters that are safe unquoted.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./=:,@%+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
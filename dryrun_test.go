@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_DryRun(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	t.Setenv("GITHUB_TOKEN", "ghp_user")
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 7})
+
+	// No API server is running: a dry run must not mint a token.
+	stdout, stderr, code := runCmd(t, []string{"gha", "--dry-run", "pr", "create", "--title", "a b"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	for _, want := range []string{
+		"app:          default (app_id 1, top-level config)",
+		"host:         github.com",
+		"installation: 7 (from installation_id in config)",
+		"permissions:  all granted to the installation",
+		"command:      gh pr create --title 'a b'",
+		"env:          GH_TOKEN=<redacted>, GH_NO_UPDATE_NOTIFIER=1, GITHUB_TOKEN removed",
+	} {
+		if !strings.Contains(stdout, want+"\n") {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestInstallationSourcesOrigin(t *testing.T) {
+	tests := []struct {
+		src  installationSources
+		id   int64
+		want string
+	}{
+		{installationSources{flag: installationOverride{id: 3}}, 3, "from --installation-id"},
+		{installationSources{env: installationOverride{org: "acme"}, configID: 9}, 4, "from GHA_ORG acme"},
+		{installationSources{gitRepo: "acme/app", configID: 9}, 5, "from git remote acme/app"},
+		{installationSources{gitRepo: "acme/app", configID: 9}, 9, "from installation_id in config"},
+		{installationSources{}, 6, "auto-detected"},
+	}
+	for _, tt := range tests {
+		if got := tt.src.origin(tt.id); got != tt.want {
+			t.Errorf("origin(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"pr":         "pr",
+		"--title=a":  "--title=a",
+		"a b":        "'a b'",
+		"it's":       `'it'\''s'`,
+		"":           "''",
+		"owner/repo": "owner/repo",
+		"$HOME":      "'$HOME'",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
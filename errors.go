@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+// Exit codes of gha's own failures. Once gh or another command has run, gha
+// exits with its code instead.
+const (
+	exitFailure = 1 // any other failure
+	exitUsage   = 2 // unknown flag, missing or extra argument
+	exitConfig  = 3 // no, invalid or unusable configuration
+	exitAPI     = 4 // the GitHub API refused or could not be reached
+	exitNoGh    = 5 // gh is not installed
+)
+
+// usageError is a failure caused by how gha was invoked.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, a ...any) error {
+	return &usageError{fmt.Errorf(format, a...)}
+}
+
+// configError is a failure to load or use the configuration, including the
+// App's private key.
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string { return e.err.Error() }
+func (e *configError) Unwrap() error { return e.err }
+
+// accessError is a gha serve request refused for who made it: code is
+// unauthenticated when the caller is not a known client, and forbidden when
+// the client may not have what it asked for.
+type accessError struct {
+	code   string
+	client string
+	msg    string
+}
+
+func (e *accessError) Error() string {
+	if e.client != "" {
+		return fmt.Sprintf("denied to client %s: %s", e.client, e.msg)
+	}
+	return "denied: " + e.msg
+}
+
+// errorJSONEnv, set by --error-format json, makes gha report a failure as a
+// JSON object on stderr instead of an "error:" line.
+const errorJSONEnv = "GHA_ERROR_JSON"
+
+// installationsError reports that gha could not pick an installation by
+// itself, with the installations to choose from.
+type installationsError struct {
+	code          string
+	msg           string
+	installations []auth.Installation
+}
+
+func (e *installationsError) Error() string {
+	if len(e.installations) == 0 {
+		return e.msg
+	}
+	lines := make([]string, 0, len(e.installations))
+	for _, inst := range e.installations {
+		lines = append(lines, fmt.Sprintf("  %d (%s, %s)", inst.ID, inst.Account.Login, inst.TargetType))
+	}
+	return e.msg + ":\n" + strings.Join(lines, "\n")
+}
+
+// errorReport is the JSON form of a failure. Code is stable for tooling to
+// branch on; Message is the text gha would otherwise print.
+type errorReport struct {
+	Code          string                  `json:"code"`
+	Message       string                  `json:"message"`
+	ExitCode      int                     `json:"exit_code"`
+	Status        int                     `json:"status,omitempty"`
+	Repository    string                  `json:"repository,omitempty"`
+	Installations []installationCandidate `json:"installations,omitempty"`
+}
+
+// installationCandidate is an installation listed in an errorReport.
+type installationCandidate struct {
+	ID         int64  `json:"id"`
+	Account    string `json:"account"`
+	TargetType string `json:"target_type,omitempty"`
+}
+
+// newErrorReport classifies err.
+func newErrorReport(err error) errorReport {
+	r := errorReport{Code: "error", Message: err.Error(), ExitCode: exitCode(err)}
+	var (
+		installations *installationsError
+		notInstalled  *notInstalledError
+		suspended     *suspendedError
+		apiErr        *auth.APIError
+		access        *accessError
+	)
+	switch {
+	case errors.Is(err, config.ErrNotFound):
+		r.Code = "config_not_found"
+	case errors.As(err, &installations):
+		r.Code = installations.code
+		for _, inst := range installations.installations {
+			r.Installations = append(r.Installations, installationCandidate{ID: inst.ID, Account: inst.Account.Login, TargetType: inst.TargetType})
+		}
+	case errors.As(err, &notInstalled):
+		r.Code, r.Repository = "not_installed", notInstalled.repo
+	case errors.As(err, &suspended):
+		r.Code = "installation_suspended"
+		r.Installations = []installationCandidate{{ID: suspended.id}}
+	case errors.As(err, &apiErr):
+		r.Code, r.Status = "api_error", apiErr.StatusCode
+	case errors.Is(err, proxy.ErrGhNotFound):
+		r.Code = "gh_not_found"
+	case errors.As(err, new(*policyError)):
+		r.Code = "policy_denied"
+	case errors.As(err, &access):
+		r.Code = access.code
+	case errors.As(err, new(*usageError)):
+		r.Code = "usage"
+	case errors.As(err, new(*configError)):
+		r.Code = "config"
+	}
+	return r
+}
+
+// exitCode returns the exit code gha fails with for err.
+func exitCode(err error) int {
+	var (
+		installations *installationsError
+		urlErr        *url.Error
+	)
+	switch {
+	case errors.As(err, new(*usageError)):
+		return exitUsage
+	case errors.Is(err, proxy.ErrGhNotFound):
+		return exitNoGh
+	case errors.As(err, &installations):
+		if installations.code == "multiple_installations" {
+			return exitConfig
+		}
+		return exitAPI
+	case errors.As(err, new(*configError)):
+		return exitConfig
+	case errors.As(err, new(*auth.APIError)), errors.As(err, new(*notInstalledError)),
+		errors.As(err, new(*suspendedError)), errors.As(err, &urlErr):
+		return exitAPI
+	}
+	return exitFailure
+}
+
+// reportError writes err to stderr, as an "error:" line or as
+// {"error": {...}} with --error-format json, and returns the exit code to
+// fail with.
+func reportError(stderr io.Writer, err error) int {
+	if !envBool(errorJSONEnv) {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+	} else {
+		data, _ := json.Marshal(map[string]errorReport{"error": newErrorReport(err)})
+		fmt.Fprintf(rawOutput(stderr), "%s\n", data)
+	}
+	return exitCode(err)
+}
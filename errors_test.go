@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+func TestRun_ErrorFormatJSON(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "--error-format", "json", "pr", "list"}, "")
+	if code != exitConfig {
+		t.Fatalf("exit code = %d, want %d", code, exitConfig)
+	}
+	var out struct {
+		Error errorReport `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(stderr), &out); err != nil {
+		t.Fatalf("stderr is not JSON: %q", stderr)
+	}
+	if out.Error.Code != "config_not_found" || !strings.Contains(out.Error.Message, "gha configure") {
+		t.Errorf("error = %+v, want config_not_found", out.Error)
+	}
+}
+
+func TestRun_ErrorFormatEnv(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv(errorJSONEnv, "1")
+
+	_, stderr, _ := runCmd(t, []string{"gha", "pr", "list"}, "")
+	if !strings.HasPrefix(stderr, `{"error":{"code":"config_not_found"`) {
+		t.Errorf("stderr = %q, want a JSON error", stderr)
+	}
+	_, stderr, _ = runCmd(t, []string{"gha", "--error-format=text", "pr", "list"}, "")
+	if !strings.HasPrefix(stderr, "error: configuration not found") {
+		t.Errorf("stderr = %q, want --error-format text to win over %s", stderr, errorJSONEnv)
+	}
+}
+
+func TestRun_ErrorFormatInvalid(t *testing.T) {
+	setupTestEnv(t)
+	_, stderr, code := runCmd(t, []string{"gha", "--error-format", "xml", "pr", "list"}, "")
+	if code != exitUsage || !strings.Contains(stderr, "text or json") {
+		t.Errorf("code = %d, stderr = %q, want a format error", code, stderr)
+	}
+}
+
+func TestNewErrorReport(t *testing.T) {
+	var a, b auth.Installation
+	a.ID, a.Account.Login, a.TargetType = 1, "acme", "Organization"
+	b.ID, b.Account.Login, b.TargetType = 2, "octocat", "User"
+	multiple := &installationsError{code: "multiple_installations", msg: "multiple installations found", installations: []auth.Installation{a, b}}
+
+	r := newErrorReport(fmt.Errorf("resolving: %w", multiple))
+	if r.Code != "multiple_installations" || len(r.Installations) != 2 || r.Installations[1] != (installationCandidate{ID: 2, Account: "octocat", TargetType: "User"}) {
+		t.Errorf("report = %+v", r)
+	}
+	if !strings.Contains(r.Message, "  1 (acme, Organization)\n  2 (octocat, User)") {
+		t.Errorf("message = %q, want the installations listed", r.Message)
+	}
+
+	r = newErrorReport(fmt.Errorf("getting installation token: %w", &auth.APIError{StatusCode: 401, Body: "Bad credentials"}))
+	if r.Code != "api_error" || r.Status != 401 {
+		t.Errorf("report = %+v, want api_error 401", r)
+	}
+	if r = newErrorReport(&notInstalledError{repo: "acme/app"}); r.Code != "not_installed" || r.Repository != "acme/app" {
+		t.Errorf("report = %+v, want not_installed", r)
+	}
+	if r = newErrorReport(fmt.Errorf("boom")); r.Code != "error" {
+		t.Errorf("report = %+v, want the generic code", r)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{usageErrorf("unexpected arguments: x"), exitUsage},
+		{fmt.Errorf("resolving: %w", &configError{config.ErrNotFound}), exitConfig},
+		{&installationsError{code: "multiple_installations"}, exitConfig},
+		{&installationsError{code: "installation_not_found"}, exitAPI},
+		{fmt.Errorf("listing installations: %w", &auth.APIError{StatusCode: 401}), exitAPI},
+		{&url.Error{Op: "Get", URL: "https://api.github.com", Err: errors.New("dial tcp: refused")}, exitAPI},
+		{fmt.Errorf("%w: exec: not found", proxy.ErrGhNotFound), exitNoGh},
+		{errors.New("pre_run hook: exit status 1"), exitFailure},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+// skipVersionCheckEnv, set by --skip-version-check, turns off the
+// min_gh_version check.
+const skipVersionCheckEnv = "GHA_SKIP_VERSION_CHECK"
+
+// ghVersionCacheFile remembers the version of the gh binary last checked,
+// so gh --version only runs again once the binary changes.
+const ghVersionCacheFile = "gh-version.json"
+
+type ghVersionCache struct {
+	Path    string    `json:"path"`
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+	Version string    `json:"version"`
+}
+
+// checkGhVersion fails when the gh in PATH is older than minimum. An empty
+// minimum, or --skip-version-check, skips the check.
+func checkGhVersion(minimum string) error {
+	if minimum == "" || envBool(skipVersionCheckEnv) {
+		return nil
+	}
+	path, err := proxy.LookGh()
+	if err != nil {
+		return err
+	}
+	v, err := ghVersion(path)
+	if err != nil {
+		return err
+	}
+	if compareVersions(v, minimum) < 0 {
+		return fmt.Errorf("gh %s at %s is older than min_gh_version %s and may not honour GH_TOKEN - upgrade it from https://cli.github.com, or pass --skip-version-check", v, path, minimum)
+	}
+	return nil
+}
+
+// ghVersion returns the version of the gh at path, from the cache when the
+// binary has not changed since it was last asked.
+func ghVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	dir, dirErr := config.Dir()
+	cachePath := filepath.Join(dir, ghVersionCacheFile)
+	if dirErr == nil {
+		var cached ghVersionCache
+		if data, err := os.ReadFile(cachePath); err == nil && json.Unmarshal(data, &cached) == nil &&
+			cached.Path == path && cached.ModTime.Equal(info.ModTime()) && cached.Size == info.Size() && cached.Version != "" {
+			return cached.Version, nil
+		}
+	}
+
+	v, err := proxy.GhVersion(path)
+	if err != nil {
+		return "", err
+	}
+	if dirErr == nil {
+		// Best effort: a missing cache only costs another gh --version.
+		data, _ := json.Marshal(ghVersionCache{Path: path, ModTime: info.ModTime(), Size: info.Size(), Version: v})
+		if os.MkdirAll(dir, 0o700) == nil {
+			if unlock, err := fsutil.Lock(cachePath); err == nil {
+				_ = fsutil.WriteFile(cachePath, data, 0o600)
+				unlock()
+			}
+		}
+	}
+	return v, nil
+}
+
+// compareVersions compares dotted versions such as 2.40.1 numerically,
+// ignoring a leading "v" and any pre-release suffix, and returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range 3 {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) [3]int {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	var parts [3]int
+	for i, p := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(p)
+	}
+	return parts
+}
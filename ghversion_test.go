@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.40.1", "2.40.1", 0},
+		{"2.40.1", "2.9.0", 1},
+		{"1.14.0", "2.0", -1},
+		{"v2.1", "2.1.0", 0},
+		{"2.41.0-rc1", "2.41.0", 0},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGhVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+
+	calls := filepath.Join(t.TempDir(), "calls")
+	binDir := t.TempDir()
+	gh := "#!/bin/sh\necho x >> " + calls + "\necho 'gh version 1.14.0 (2021-08-04)'\n"
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte(gh), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+
+	if err := checkGhVersion(""); err != nil {
+		t.Errorf("no minimum: %v", err)
+	}
+	if err := checkGhVersion("1.10"); err != nil {
+		t.Errorf("older minimum: %v", err)
+	}
+	err := checkGhVersion("2.0.0")
+	if err == nil || !strings.Contains(err.Error(), "gh 1.14.0") || !strings.Contains(err.Error(), "--skip-version-check") {
+		t.Errorf("err = %v, want an old-gh error mentioning --skip-version-check", err)
+	}
+	if data, _ := os.ReadFile(calls); strings.Count(string(data), "x") != 1 {
+		t.Errorf("gh --version ran %d times, want once thanks to the cache", strings.Count(string(data), "x"))
+	}
+
+	t.Setenv(skipVersionCheckEnv, "1")
+	if err := checkGhVersion("2.0.0"); err != nil {
+		t.Errorf("skipped check: %v", err)
+	}
+}
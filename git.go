@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// gitRemoteRepo returns the OWNER/REPO of the current directory's origin
+// remote when it points at host ("" for github.com), or "" otherwise (not a
+// git checkout, no origin, git not installed, or another host).
+func gitRemoteRepo(host string) string {
+	if host == "" {
+		host = config.DefaultHost
+	}
+	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return ""
+	}
+	remoteHost, repo, ok := parseRemoteURL(strings.TrimSpace(string(out)))
+	if !ok || !strings.EqualFold(remoteHost, host) {
+		return ""
+	}
+	return repo
+}
+
+// parseRemoteURL extracts the host and OWNER/REPO from a git remote URL in
+// https, ssh, git or scp-like (git@host:owner/repo.git) form.
+func parseRemoteURL(raw string) (host, repo string, ok bool) {
+	var path string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return "", "", false
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// scp-like syntax: [user@]host:owner/repo.git
+		userHost, p, found := strings.Cut(raw, ":")
+		if !found {
+			return "", "", false
+		}
+		if i := strings.LastIndex(userHost, "@"); i >= 0 {
+			userHost = userHost[i+1:]
+		}
+		host, path = userHost, p
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, name, found := strings.Cut(path, "/")
+	if host == "" || !found || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return host, owner + "/" + name, true
+}
@@ -5,3 +5,5 @@ go 1.25.5
 require gopkg.in/yaml.v3 v3.0.1
 
 require github.com/golang-jwt/jwt/v5 v5.3.1
+
+require github.com/BurntSushi/toml v1.6.0
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// helpTopic is a focused explanation gha help <topic> prints. Its text is
+// written like the usage text, so generated docs include it too.
+type helpTopic struct {
+	name string
+	text string
+}
+
+// helpTopics are listed under Help Topics in the usage text, in this order.
+var helpTopics = []helpTopic{
+	{"auth", authHelp},
+	{"resolution", resolutionHelp},
+	{"environment", environmentHelp},
+	{"config", configHelp},
+	{"exit-codes", exitCodesHelp},
+}
+
+const authHelp = `Authentication:
+  1. gha signs a JWT for the App with its private key, valid for ten minutes
+  2. It exchanges the JWT for an access token of the chosen installation
+  3. It runs the command with the token in GH_TOKEN (and GH_ENTERPRISE_TOKEN)
+  4. The token expires an hour after it was issued
+
+The private key is read from private_key_path in the config, from the path in
+GHA_PRIVATE_KEY_PATH, or from the PEM in GHA_PRIVATE_KEY. Like ssh, gha
+refuses a key file other users can read or another user owns, unless
+--insecure-key-perms is given.
+
+The token can do what the installation was granted, on the repositories it
+was granted; permissions: in the nearest .gha.yaml narrows it further. Tokens
+are not cached: each command gets a fresh one. For commands running over an
+hour, --refresh re-issues the token before it expires; --revoke (or revoke:
+true in the config) revokes it as soon as the command exits.
+
+Checking the Credentials:
+  gha config check
+  gha jwt --decode
+  gha status
+`
+
+const resolutionHelp = `Resolution Order (highest to lowest precedence):
+  1. --installation-id / --repo / --org flag
+  2. GHA_INSTALLATION_ID / GHA_ORG environment variable
+  3. installation_id / org in the nearest .gha.yaml
+  4. origin remote of the current git repository (github.com only)
+  5. installation_id in config.yaml
+  6. Auto-detect (works only with single installation)
+
+A -R/--repo flag passed to the gh subcommand selects the installation for
+that repository unless --installation-id, --repo or --org is given.
+
+The App is chosen by --app / GHA_APP, then app in the nearest .gha.yaml, then
+the first routes: rule matching the target org or repository, and is the
+top-level App otherwise.
+
+An org's installation ID is cached for a day; gha cache status shows what is
+cached and gha cache clear forgets it.
+
+Checking the Resolution:
+  gha status
+  gha --org myorg status
+  gha --dry-run --repo myorg/myrepo pr list
+  gha --debug pr list
+`
+
+const environmentHelp = `Environment Variables:
+  GHA_INSTALLATION_ID       Installation ID (overrides config, overridden by flags)
+  GHA_ORG                   Org/user name to resolve (overrides config, overridden by flags)
+  GHA_CONFIG                Path of the config file
+  GH_HOST                   Host to target; selects an entry under hosts: in config
+  GHA_APP                   App to use; selects an entry under apps: in config
+  GHA_APP_ID                App ID; with a key below, no config file is read
+  GHA_PRIVATE_KEY           PEM private key contents (\n escapes allowed)
+  GHA_PRIVATE_KEY_PATH      Path to the PEM private key
+  GHA_CONFIG_PASSPHRASE     Passphrase of a config encrypted with gha config encrypt
+  GHA_AGE_IDENTITY          age identity file for a config encrypted to age recipients
+  GHA_AGENT_SOCK            Socket of a gha agent to get tokens from
+  GHA_DRY_RUN               Same as --dry-run when true
+  GHA_ISOLATED              Same as --isolated when true
+  GHA_REFRESH               Same as --refresh when true
+  GHA_SKIP_VERSION_CHECK    Same as --skip-version-check when true
+  GHA_SHOW_RATE_LIMIT       Same as --show-rate-limit when true
+  GHA_REVOKE                Same as --revoke when true
+  GHA_INSECURE_KEY_PERMS    Same as --insecure-key-perms when true
+  GHA_QUIET                 Same as --quiet when true
+  GHA_DEBUG                 Same as --debug when true
+  GHA_DEBUG_FILE            Append the --debug trace to this file instead of stderr
+  GHA_LOG_FORMAT            Same as --log-format
+  GHA_ERROR_JSON            Same as --error-format json when true
+  GHA_NO_UPDATE_CHECK       Never check for a newer gha release when true
+  NO_COLOR                  Same as --no-color when set to anything
+  CLICOLOR_FORCE            Color output even when it is not a terminal, unless 0
+
+A flag given before the command wins over its environment variable, which
+wins over the config file.
+
+Using Environment Variables:
+  GHA_ORG=myorg gha pr list
+  GHA_APP_ID=12345 GHA_PRIVATE_KEY_PATH=bot.pem gha api /app
+  GHA_DEBUG=1 GHA_DEBUG_FILE=gha.log gha pr list
+`
+
+const configHelp = `Configuration Files:
+  config.yaml               App ID, private key, installation and gha's settings
+  config.toml, config.json  The same in another format; see gha config convert
+  .gha.yaml                 Per-project installation, App and token permissions
+
+Configuration is stored in ~/.config/github-app-cli/config.yaml
+(%APPDATA%\github-app-cli on Windows, $XDG_CONFIG_HOME/github-app-cli when
+set), or config.toml / config.json beside it; use --config <path> (before
+the command) or GHA_CONFIG to point at another file. GHA_APP_ID, set with a
+key, wins over both, and no file is read. gha config check (or gha doctor)
+prints this lookup order and which source is in use.
+
+gha config manages gha's own settings; run gh config directly for gh's. A
+.gha.yaml is looked for in the current directory and its parents.
+
+Editing the Configuration:
+  gha configure
+  gha config set installation_id 12345
+  gha config edit
+  gha config check
+`
+
+const exitCodesHelp = `Exit Status:
+  0                         Success
+  1                         Any other failure in gha itself
+  2                         Usage error: unknown flag or command, bad argument
+  3                         Configuration error: missing, invalid, or no usable key
+  4                         GitHub API error: refused, unreachable, not installed
+  5                         gh is not installed
+  other                     Once gh or the command runs, its own exit code
+
+With --error-format json, a failure of gha's own is also reported on stderr
+as a JSON object with a stable error code.
+
+Checking the Exit Status:
+  gha pr list || echo "exit status $?"
+  gha --error-format json pr list 2> error.json
+`
+
+// referenceText is the usage text followed by every help topic, the whole
+// reference generated docs are made from.
+func referenceText() string {
+	texts := []string{strings.TrimSpace(usage)}
+	for _, t := range helpTopics {
+		texts = append(texts, strings.TrimSpace(t.text))
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// runHelp prints the usage text, or the help topic named by args.
+func runHelp(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		printUsage(stdout)
+		return nil
+	}
+	if len(args) > 1 {
+		return usageErrorf("usage: gha help [topic]")
+	}
+	names := make([]string, 0, len(helpTopics))
+	for _, t := range helpTopics {
+		if t.name == args[0] {
+			fmt.Fprint(stdout, strings.ReplaceAll(t.text, "gha ", progName+" "))
+			return nil
+		}
+		names = append(names, t.name)
+	}
+	return usageErrorf("unknown help topic %q - topics are %s; run gh help %s for gh's own help", args[0], strings.Join(names, ", "), args[0])
+}
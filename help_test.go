@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRun_HelpTopics(t *testing.T) {
+	setupTestEnv(t)
+	for topic, want := range map[string]string{
+		"auth":        "Authentication:",
+		"resolution":  "Resolution Order",
+		"environment": "GHA_INSTALLATION_ID",
+		"config":      "config.yaml",
+		"exit-codes":  "Exit Status:",
+	} {
+		stdout, stderr, code := runCmd(t, []string{"gha", "help", topic}, "")
+		if code != 0 || !strings.Contains(stdout, want) {
+			t.Errorf("help %s: code = %d, stdout lacks %q (stderr %q)", topic, code, want, stderr)
+		}
+	}
+
+	stdout, _, code := runCmd(t, []string{"gha", "help"}, "")
+	if code != 0 || !strings.Contains(stdout, "Usage:") {
+		t.Errorf("help: code = %d, stdout = %q, want the usage text", code, stdout)
+	}
+	_, stderr, code := runCmd(t, []string{"gha", "help", "pr"}, "")
+	if code != exitUsage || !strings.Contains(stderr, "gh help pr") {
+		t.Errorf("unknown topic: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+// Every topic's text is written like the usage text, so it must start with
+// a section generated docs can title.
+func TestHelpTopics_AreSections(t *testing.T) {
+	_, sections := parseUsage()
+	titles := map[string]bool{}
+	for _, s := range sections {
+		titles[s.title] = true
+	}
+	for _, topic := range helpTopics {
+		title, _, _ := strings.Cut(topic.text, ":\n")
+		if !titles[title] {
+			t.Errorf("topic %s: section %q is missing from the generated docs", topic.name, title)
+		}
+		if !strings.Contains(usage, "\n  "+topic.name+" ") {
+			t.Errorf("topic %s is not listed under Help Topics", topic.name)
+		}
+	}
+}
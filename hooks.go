@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+// hookContext describes a proxied command to the pre_run and post_run hooks.
+type hookContext struct {
+	org            string // account the command targets, if known
+	installationID int64
+	appID          int64
+	host           string
+	command        []string
+}
+
+// env returns the GHA_HOOK_* variables describing h.
+func (h hookContext) env() []string {
+	host := h.host
+	if host == "" {
+		host = "github.com"
+	}
+	return []string{
+		"GHA_HOOK_ORG=" + h.org,
+		"GHA_HOOK_INSTALLATION_ID=" + strconv.FormatInt(h.installationID, 10),
+		"GHA_HOOK_APP_ID=" + strconv.FormatInt(h.appID, 10),
+		"GHA_HOOK_HOST=" + host,
+		"GHA_HOOK_COMMAND=" + strings.Join(h.command, " "),
+	}
+}
+
+// runHook runs a configured hook through the shell with env added to gha's
+// environment, less the App's credentials. Its output goes to stderr so it never mixes with the proxied
+// command's output.
+func runHook(name, command string, env []string, stderr io.Writer) error {
+	if command == "" {
+		return nil
+	}
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.Command(shell, flag, command)
+	cmd.Env = append(proxy.WithoutCredentials(os.Environ()), env...)
+	cmd.Stdout = rawOutput(stderr)
+	cmd.Stderr = rawOutput(stderr)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook: %w", name, err)
+	}
+	return nil
+}
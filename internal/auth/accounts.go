@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Account is a GitHub user or organization.
+type Account struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Type  string `json:"type"`
+}
+
+// GetAccount looks up a user or organization by login. The endpoint is public,
+// so the request is sent without credentials.
+func GetAccount(login string, opts ...Option) (*Account, error) {
+	o := buildOpts(opts)
+
+	path := "/users/" + url.PathEscape(login)
+	body, _, err := o.do("looking up account", http.MethodGet, path, "", nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var account Account
+	if err := json.Unmarshal(body, &account); err != nil {
+		return nil, fmt.Errorf("parsing account response: %w", err)
+	}
+	return &account, nil
+}
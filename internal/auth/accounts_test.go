@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetAccount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/acme" {
+			t.Errorf("path = %s, want /users/acme", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Authorization = %q, want none", got)
+		}
+		w.Write([]byte(`{"id": 1001, "login": "acme", "type": "Organization"}`))
+	}))
+	defer srv.Close()
+
+	account, err := GetAccount("acme", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if account.ID != 1001 || account.Type != "Organization" {
+		t.Errorf("account = %+v", account)
+	}
+}
+
+func TestGetAccount_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := GetAccount("ghost", WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want 404", err)
+	}
+}
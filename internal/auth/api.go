@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const maxResponseBytes = 1 << 20
+
+// APIError is returned when the GitHub API responds with an unexpected status.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API error (HTTP %d): %s", e.StatusCode, e.Body)
+}
+
+// do sends a request to the GitHub API and returns the response body and
+// headers when the status code equals want. The request is unauthenticated
+// when token is empty. what describes the operation in transport error
+// messages.
+func (o options) do(what, method, path, token string, reqBody any, want int) ([]byte, http.Header, error) {
+	var body io.Reader
+	var data []byte
+	if reqBody != nil {
+		var err error
+		if data, err = json.Marshal(reqBody); err != nil {
+			return nil, nil, fmt.Errorf("encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, o.baseURL+path, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if o.debug != nil {
+		traceRequest(o.debug, req, data)
+	}
+	start := time.Now()
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		if o.debug != nil {
+			fmt.Fprintf(o.debug, "debug: < %v\n", err)
+		}
+		return nil, nil, fmt.Errorf("%s: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading response: %w", err)
+	}
+	if o.debug != nil {
+		traceResponse(o.debug, resp, data, time.Since(start))
+	}
+
+	if resp.StatusCode != want {
+		return nil, nil, &APIError{StatusCode: resp.StatusCode, Body: string(data)}
+	}
+
+	return data, resp.Header, nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// App describes the authenticated GitHub App.
+type App struct {
+	ID    int64  `json:"id"`
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	HTMLURL     string            `json:"html_url"`
+	Permissions map[string]string `json:"permissions"`
+	Events      []string          `json:"events"`
+}
+
+// GetApp returns the GitHub App authenticated by the JWT.
+func GetApp(jwtToken string, opts ...Option) (*App, error) {
+	o := buildOpts(opts)
+
+	body, _, err := o.do("fetching app", http.MethodGet, "/app", jwtToken, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var app App
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, fmt.Errorf("parsing app response: %w", err)
+	}
+	return &app, nil
+}
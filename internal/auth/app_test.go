@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetApp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/app" {
+			t.Errorf("request = %s %s, want GET /app", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer fake-jwt" {
+			t.Errorf("Authorization = %q, want JWT bearer", got)
+		}
+		w.Write([]byte(`{
+			"id": 1, "slug": "my-app", "name": "My App",
+			"owner": {"login": "acme"},
+			"permissions": {"contents": "read", "issues": "write"},
+			"events": ["push", "pull_request"]
+		}`))
+	}))
+	defer srv.Close()
+
+	app, err := GetApp("fake-jwt", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetApp: %v", err)
+	}
+	if app.Slug != "my-app" || app.Owner.Login != "acme" {
+		t.Errorf("app = %+v, want slug my-app owned by acme", app)
+	}
+	if app.Permissions["issues"] != "write" {
+		t.Errorf("permissions = %v, want issues:write", app.Permissions)
+	}
+	if len(app.Events) != 2 {
+		t.Errorf("events = %v, want 2 entries", app.Events)
+	}
+}
+
+func TestGetApp_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"A JSON web token could not be decoded"}`))
+	}))
+	defer srv.Close()
+
+	_, err := GetApp("bad-jwt", WithBaseURL(srv.URL))
+	if err == nil {
+		t.Fatal("expected error for 401 response")
+	}
+	if !strings.Contains(err.Error(), "401") {
+		t.Errorf("error = %q, want substring %q", err.Error(), "401")
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"bytes"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/json"
@@ -8,7 +9,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
@@ -17,8 +17,11 @@ import (
 
 const defaultBaseURL = "https://api.github.com"
 
+const installationsPerPage = 100
+
 type options struct {
 	baseURL string
+	debug   io.Writer
 }
 
 // Option configures auth behaviour.
@@ -38,16 +41,26 @@ func buildOpts(opts []Option) options {
 }
 
 // GenerateJWT creates a JWT signed with the GitHub App's RSA private key.
+// The key is read into locked memory where the platform allows it, and
+// wiped as soon as the JWT is signed.
 func GenerateJWT(appID int64, privateKeyPath string) (string, error) {
-	keyData, err := os.ReadFile(privateKeyPath)
+	keyData, release, err := ReadKeyFile(privateKeyPath)
 	if err != nil {
 		return "", fmt.Errorf("reading private key %s: %w", privateKeyPath, err)
 	}
+	defer release()
+	return GenerateJWTFromPEM(appID, keyData)
+}
 
+// GenerateJWTFromPEM is like GenerateJWT but takes the PEM-encoded key itself.
+// It wipes the key it parses from keyData once signed; keyData itself is
+// left to the caller.
+func GenerateJWTFromPEM(appID int64, keyData []byte) (string, error) {
 	key, err := findRSAKey(keyData)
 	if err != nil {
 		return "", err
 	}
+	defer wipeKey(key)
 
 	now := time.Now()
 	claims := jwt.RegisteredClaims{
@@ -71,6 +84,10 @@ var keyBlockTypes = map[string]bool{
 }
 
 func findRSAKey(pemData []byte) (*rsa.PrivateKey, error) {
+	pemData, flattened := normalizePEM(pemData)
+	if flattened {
+		defer clear(pemData)
+	}
 	rest := pemData
 	for {
 		var block *pem.Block
@@ -79,11 +96,38 @@ func findRSAKey(pemData []byte) (*rsa.PrivateKey, error) {
 			return nil, fmt.Errorf("no RSA private key PEM block found")
 		}
 		if keyBlockTypes[block.Type] {
+			// The DER is only needed to parse the key from.
+			defer clear(block.Bytes)
 			return parsePKCS1OrPKCS8(block.Bytes)
 		}
 	}
 }
 
+// utf8BOM is the byte order mark some Windows editors start a file with.
+var utf8BOM = []byte("\ufeff")
+
+// normalizePEM undoes what editors and secret stores do to a key file: a
+// leading byte order mark, and newlines flattened to \n escapes, which
+// leave pem.Decode nothing to find. Line endings, CRLF included, and
+// trailing blank lines need no help. The result shares pemData's memory
+// unless flattened reports that it is a copy with the escapes expanded.
+func normalizePEM(pemData []byte) (data []byte, flattened bool) {
+	pemData = bytes.TrimPrefix(pemData, utf8BOM)
+	if bytes.IndexByte(pemData, '\n') >= 0 || !bytes.Contains(pemData, []byte(`\n`)) {
+		return pemData, false
+	}
+	data = make([]byte, 0, len(pemData))
+	for i := 0; i < len(pemData); i++ {
+		if pemData[i] == '\\' && i+1 < len(pemData) && pemData[i+1] == 'n' {
+			data = append(data, '\n')
+			i++
+			continue
+		}
+		data = append(data, pemData[i])
+	}
+	return data, true
+}
+
 func parsePKCS1OrPKCS8(der []byte) (*rsa.PrivateKey, error) {
 	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
 		return key, nil
@@ -107,91 +151,120 @@ type Installation struct {
 	Account struct {
 		Login string `json:"login"`
 	} `json:"account"`
+	TargetType          string            `json:"target_type,omitempty"`
+	RepositorySelection string            `json:"repository_selection,omitempty"`
+	Permissions         map[string]string `json:"permissions,omitempty"`
+	HTMLURL             string            `json:"html_url,omitempty"`
+	SuspendedAt         *time.Time        `json:"suspended_at,omitempty"`
 }
 
-// GetInstallations lists all installations for the authenticated GitHub App.
-func GetInstallations(jwtToken string, opts ...Option) ([]Installation, error) {
+// GetInstallation returns a single installation of the authenticated App.
+func GetInstallation(jwtToken string, installationID int64, opts ...Option) (*Installation, error) {
 	o := buildOpts(opts)
 
-	url := fmt.Sprintf("%s/app/installations", o.baseURL)
-
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	path := fmt.Sprintf("/app/installations/%d", installationID)
+	body, _, err := o.do("fetching installation", http.MethodGet, path, jwtToken, nil, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+jwtToken)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("listing installations: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+	var inst Installation
+	if err := json.Unmarshal(body, &inst); err != nil {
+		return nil, fmt.Errorf("parsing installation response: %w", err)
 	}
+	return &inst, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API error (HTTP %d): %s", resp.StatusCode, string(body))
-	}
+// GetInstallations lists all installations for the authenticated GitHub App,
+// following pagination until all pages have been read.
+func GetInstallations(jwtToken string, opts ...Option) ([]Installation, error) {
+	o := buildOpts(opts)
 
 	var installations []Installation
-	if err := json.Unmarshal(body, &installations); err != nil {
-		return nil, fmt.Errorf("parsing installations response: %w", err)
-	}
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/app/installations?per_page=%d&page=%d", installationsPerPage, page)
+		body, _, err := o.do("listing installations", http.MethodGet, path, jwtToken, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
 
-	return installations, nil
+		var batch []Installation
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, fmt.Errorf("parsing installations response: %w", err)
+		}
+		installations = append(installations, batch...)
+		if len(batch) < installationsPerPage {
+			return installations, nil
+		}
+	}
 }
 
-type installationTokenResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+// InstallationToken is an installation access token, the time it expires,
+// an hour after it was issued, and exactly what it was granted.
+type InstallationToken struct {
+	Token               string            `json:"token"`
+	ExpiresAt           time.Time         `json:"expires_at"`
+	Permissions         map[string]string `json:"permissions,omitempty"`
+	RepositorySelection string            `json:"repository_selection,omitempty"`
 }
 
-const maxResponseBytes = 1 << 20
+// TokenRequest narrows an installation access token. A zero value requests
+// everything the installation has been granted.
+type TokenRequest struct {
+	Permissions map[string]string `json:"permissions,omitempty"`
+
+	// Repositories limits the token to these repositories of the
+	// installation's account, by name without the owner.
+	Repositories []string `json:"repositories,omitempty"`
+}
 
 // GetInstallationToken exchanges a JWT for a GitHub App installation access token.
 func GetInstallationToken(jwtToken string, installationID int64, opts ...Option) (string, error) {
-	o := buildOpts(opts)
-
-	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", o.baseURL, installationID)
+	return CreateInstallationToken(jwtToken, installationID, nil, opts...)
+}
 
-	req, err := http.NewRequest(http.MethodPost, url, nil)
+// CreateInstallationToken exchanges a JWT for an installation access token
+// restricted as described by req, which may be nil.
+func CreateInstallationToken(jwtToken string, installationID int64, req *TokenRequest, opts ...Option) (string, error) {
+	token, err := IssueInstallationToken(jwtToken, installationID, req, opts...)
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+jwtToken)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	return token.Token, nil
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("requesting installation token: %w", err)
+// IssueInstallationToken is CreateInstallationToken, also returning when the
+// token expires.
+func IssueInstallationToken(jwtToken string, installationID int64, req *TokenRequest, opts ...Option) (*InstallationToken, error) {
+	o := buildOpts(opts)
+
+	var reqBody any
+	if req != nil && (len(req.Permissions) > 0 || len(req.Repositories) > 0) {
+		reqBody = req
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", installationID)
+	body, _, err := o.do("requesting installation token", http.MethodPost, path, jwtToken, reqBody, http.StatusCreated)
 	if err != nil {
-		return "", fmt.Errorf("reading response: %w", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("GitHub API error (HTTP %d): %s", resp.StatusCode, string(body))
+	var token InstallationToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("parsing token response: %w", err)
 	}
 
-	var tokenResp installationTokenResponse
-	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return "", fmt.Errorf("parsing token response: %w", err)
+	if token.Token == "" {
+		return nil, fmt.Errorf("GitHub API returned empty token")
 	}
 
-	if tokenResp.Token == "" {
-		return "", fmt.Errorf("GitHub API returned empty token")
-	}
+	return &token, nil
+}
 
-	return tokenResp.Token, nil
+// RevokeInstallationToken revokes an installation access token before it
+// expires. The token authenticates its own revocation.
+func RevokeInstallationToken(installToken string, opts ...Option) error {
+	o := buildOpts(opts)
+	_, _, err := o.do("revoking installation token", http.MethodDelete, "/installation/token", installToken, nil, http.StatusNoContent)
+	return err
 }
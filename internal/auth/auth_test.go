@@ -105,6 +105,53 @@ func TestGenerateJWT_PKCS8(t *testing.T) {
 	}
 }
 
+func TestGenerateJWTFromPEM(t *testing.T) {
+	keyPath, privKey := generateTestKey(t)
+	pemData, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := GenerateJWTFromPEM(777, pemData)
+	if err != nil {
+		t.Fatalf("GenerateJWTFromPEM: %v", err)
+	}
+	parsed, err := jwt.Parse(token, func(tok *jwt.Token) (any, error) {
+		return &privKey.PublicKey, nil
+	})
+	if err != nil {
+		t.Fatalf("parsing JWT: %v", err)
+	}
+	if iss, _ := parsed.Claims.GetIssuer(); iss != "777" {
+		t.Errorf("issuer = %q, want %q", iss, "777")
+	}
+
+	if _, err := GenerateJWTFromPEM(1, []byte("not a pem")); err == nil {
+		t.Error("expected error for invalid PEM")
+	}
+}
+
+func TestGenerateJWT_MangledKeyFile(t *testing.T) {
+	keyPath, _ := generateTestKey(t)
+	pemData, err := os.ReadFile(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		"crlf":      strings.ReplaceAll(string(pemData), "\n", "\r\n") + "\r\n\r\n",
+		"bom":       "\ufeff" + string(pemData),
+		"flattened": strings.ReplaceAll(string(pemData), "\n", `\n`),
+	} {
+		path := filepath.Join(t.TempDir(), "key.pem")
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := GenerateJWT(1, path); err != nil {
+			t.Errorf("%s: GenerateJWT: %v", name, err)
+		}
+	}
+}
+
 func TestGenerateJWT_FileNotFound(t *testing.T) {
 	_, err := GenerateJWT(1, "/nonexistent/key.pem")
 	if err == nil {
@@ -162,6 +209,29 @@ func TestGetInstallations(t *testing.T) {
 	}
 }
 
+func TestGetInstallations_Paginated(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := installationsPerPage
+		if r.URL.Query().Get("page") == "2" {
+			n = 5
+		}
+		installations := make([]map[string]any, n)
+		for i := range installations {
+			installations[i] = map[string]any{"id": i + 1}
+		}
+		json.NewEncoder(w).Encode(installations)
+	}))
+	defer srv.Close()
+
+	got, err := GetInstallations("fake-jwt", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetInstallations: %v", err)
+	}
+	if len(got) != installationsPerPage+5 {
+		t.Errorf("len = %d, want %d", len(got), installationsPerPage+5)
+	}
+}
+
 func TestGetInstallations_Empty(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -270,3 +340,100 @@ func TestGetInstallationToken_EmptyToken(t *testing.T) {
 		t.Errorf("error = %q, want substring %q", err.Error(), "empty token")
 	}
 }
+
+func TestCreateInstallationToken_Permissions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req TokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Permissions["contents"] != "read" {
+			t.Errorf("permissions = %v, want contents: read", req.Permissions)
+		}
+		if len(req.Repositories) != 1 || req.Repositories[0] != "app" {
+			t.Errorf("repositories = %v, want [app]", req.Repositories)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_scoped", "permissions": {"contents": "read", "metadata": "read"}, "repository_selection": "selected"}`))
+	}))
+	defer srv.Close()
+
+	req := &TokenRequest{Permissions: map[string]string{"contents": "read"}, Repositories: []string{"app"}}
+	got, err := CreateInstallationToken("jwt", 1, req, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("CreateInstallationToken: %v", err)
+	}
+	if got != "ghs_scoped" {
+		t.Errorf("token = %q, want %q", got, "ghs_scoped")
+	}
+
+	token, err := IssueInstallationToken("jwt", 1, req, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("IssueInstallationToken: %v", err)
+	}
+	if token.RepositorySelection != "selected" || len(token.Permissions) != 2 || token.Permissions["contents"] != "read" {
+		t.Errorf("token = %+v, want the granted permissions and repository selection", token)
+	}
+}
+
+func TestRevokeInstallationToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/installation/token" {
+			t.Errorf("request = %s %s, want DELETE /installation/token", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer ghs_live" {
+			t.Errorf("Authorization = %q, want the token itself", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := RevokeInstallationToken("ghs_live", WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("RevokeInstallationToken: %v", err)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+	})
+	if err := RevokeInstallationToken("ghs_gone", WithBaseURL(srv.URL)); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want the 401 reported", err)
+	}
+}
+
+func TestGetInstallation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/installations/42" {
+			t.Errorf("path = %s, want /app/installations/42", r.URL.Path)
+		}
+		w.Write([]byte(`{"id": 42, "account": {"login": "acme"},
+			"html_url": "https://github.com/organizations/acme/settings/installations/42",
+			"suspended_at": "2024-03-01T12:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	inst, err := GetInstallation("fake-jwt", 42, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetInstallation: %v", err)
+	}
+	if inst.SuspendedAt == nil || !inst.SuspendedAt.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("SuspendedAt = %v, want 2024-03-01T12:00:00Z", inst.SuspendedAt)
+	}
+	if inst.HTMLURL == "" {
+		t.Error("HTMLURL not parsed")
+	}
+}
+
+func TestGetInstallations_NotSuspended(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 1, "account": {"login": "a"}, "suspended_at": null}]`))
+	}))
+	defer srv.Close()
+
+	got, err := GetInstallations("fake-jwt", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].SuspendedAt != nil {
+		t.Errorf("SuspendedAt = %v, want nil", got[0].SuspendedAt)
+	}
+}
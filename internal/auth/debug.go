@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDebugBody caps how much of a request or response body is traced.
+const maxDebugBody = 4 << 10
+
+// redacted replaces secrets in traced requests and responses.
+const redacted = "<redacted>"
+
+// secretFields are the JSON fields whose values never appear in a trace:
+// installation tokens, and the credentials of an App created from a manifest.
+var secretFields = map[string]bool{"token": true, "client_secret": true, "webhook_secret": true, "pem": true, "secret": true}
+
+// WithDebug traces every API request and response to w, with the
+// Authorization header and secrets in bodies redacted.
+func WithDebug(w io.Writer) Option {
+	return func(o *options) { o.debug = w }
+}
+
+// traceRequest writes req and its body to w.
+func traceRequest(w io.Writer, req *http.Request, body []byte) {
+	fmt.Fprintf(w, "debug: > %s %s\n", req.Method, req.URL)
+	traceHeader(w, ">", req.Header)
+	traceBody(w, ">", body)
+}
+
+// traceResponse writes resp, its body and how long the request took to w.
+func traceResponse(w io.Writer, resp *http.Response, body []byte, elapsed time.Duration) {
+	fmt.Fprintf(w, "debug: < %s (%s)\n", resp.Status, elapsed.Round(time.Millisecond))
+	traceHeader(w, "<", resp.Header)
+	traceBody(w, "<", body)
+}
+
+func traceHeader(w io.Writer, dir string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := strings.Join(h[name], ", ")
+		if strings.EqualFold(name, "Authorization") {
+			value = redacted
+		}
+		fmt.Fprintf(w, "debug: %s %s: %s\n", dir, name, value)
+	}
+}
+
+func traceBody(w io.Writer, dir string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	s := string(redactBody(body))
+	if len(s) > maxDebugBody {
+		s = fmt.Sprintf("%s... (%d bytes)", s[:maxDebugBody], len(s))
+	}
+	fmt.Fprintf(w, "debug: %s %s\n", dir, s)
+}
+
+// redactBody returns a JSON body with the values of secretFields replaced,
+// at any depth. Anything that is not JSON is withheld entirely, since it
+// cannot be told apart from a secret.
+func redactBody(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return []byte(fmt.Sprintf("<%d bytes, not JSON>", len(body)))
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redactValue(v)); err != nil {
+		return []byte(redacted)
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if secretFields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
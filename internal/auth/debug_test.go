@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithDebug_RedactsSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-Request-Id", "ABCD:1234")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"token":"ghs_secret","expires_at":"2030-01-01T00:00:00Z","permissions":{"contents":"read"}}`)
+	}))
+	defer srv.Close()
+
+	var trace bytes.Buffer
+	req := &TokenRequest{Permissions: map[string]string{"contents": "read"}}
+	if _, err := CreateInstallationToken("jwt.secret", 42, req, WithBaseURL(srv.URL), WithDebug(&trace)); err != nil {
+		t.Fatal(err)
+	}
+	got := trace.String()
+	for _, want := range []string{
+		"debug: > POST " + srv.URL + "/app/installations/42/access_tokens",
+		"debug: > Authorization: <redacted>",
+		`debug: > {"permissions":{"contents":"read"}}`,
+		"debug: < 201 Created",
+		"debug: < X-Github-Request-Id: ABCD:1234",
+		`"token":"<redacted>"`,
+		`"permissions":{"contents":"read"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace lacks %q:\n%s", want, got)
+		}
+	}
+	for _, secret := range []string{"jwt.secret", "ghs_secret"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("trace leaks %q:\n%s", secret, got)
+		}
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"id":123456789012,"pem":"-----BEGIN","nested":[{"client_secret":"x"}]}`: `{"id":123456789012,"nested":[{"client_secret":"<redacted>"}],"pem":"<redacted>"}`,
+		`[1,2]`:       `[1,2]`,
+		`token=ghs_x`: `<11 bytes, not JSON>`,
+	} {
+		if got := string(redactBody([]byte(in))); got != want {
+			t.Errorf("redactBody(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HookDelivery is a webhook delivery attempt made for the GitHub App.
+type HookDelivery struct {
+	ID             int64     `json:"id"`
+	GUID           string    `json:"guid"`
+	DeliveredAt    time.Time `json:"delivered_at"`
+	Redelivery     bool      `json:"redelivery"`
+	Duration       float64   `json:"duration"`
+	Status         string    `json:"status"`
+	StatusCode     int       `json:"status_code"`
+	Event          string    `json:"event"`
+	Action         string    `json:"action"`
+	InstallationID int64     `json:"installation_id"`
+	RepositoryID   int64     `json:"repository_id"`
+}
+
+// ListHookDeliveries returns the most recent webhook deliveries for the App,
+// newest first.
+func ListHookDeliveries(jwtToken string, limit int, opts ...Option) ([]HookDelivery, error) {
+	o := buildOpts(opts)
+
+	path := fmt.Sprintf("/app/hook/deliveries?per_page=%d", limit)
+	body, _, err := o.do("listing webhook deliveries", http.MethodGet, path, jwtToken, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []HookDelivery
+	if err := json.Unmarshal(body, &deliveries); err != nil {
+		return nil, fmt.Errorf("parsing deliveries response: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RedeliverHookDelivery asks GitHub to attempt the given delivery again.
+func RedeliverHookDelivery(jwtToken string, deliveryID int64, opts ...Option) error {
+	o := buildOpts(opts)
+
+	path := fmt.Sprintf("/app/hook/deliveries/%d/attempts", deliveryID)
+	_, _, err := o.do("redelivering webhook", http.MethodPost, path, jwtToken, nil, http.StatusAccepted)
+	return err
+}
+
+// HookConfig is the App's webhook configuration. GitHub masks the secret in
+// responses.
+type HookConfig struct {
+	URL         string `json:"url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Secret      string `json:"secret,omitempty"`
+	InsecureSSL string `json:"insecure_ssl,omitempty"`
+}
+
+// GetHookConfig returns the App's webhook configuration.
+func GetHookConfig(jwtToken string, opts ...Option) (*HookConfig, error) {
+	o := buildOpts(opts)
+
+	body, _, err := o.do("fetching webhook config", http.MethodGet, "/app/hook/config", jwtToken, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg HookConfig
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing webhook config response: %w", err)
+	}
+	return &cfg, nil
+}
+
+// UpdateHookConfig changes the non-empty fields of update and returns the
+// resulting webhook configuration.
+func UpdateHookConfig(jwtToken string, update HookConfig, opts ...Option) (*HookConfig, error) {
+	o := buildOpts(opts)
+
+	body, _, err := o.do("updating webhook config", http.MethodPatch, "/app/hook/config", jwtToken, update, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg HookConfig
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing webhook config response: %w", err)
+	}
+	return &cfg, nil
+}
+
+// FindHookDelivery pages through the App's webhook deliveries, newest first,
+// and returns the first one accepted by match, or nil if none is.
+func FindHookDelivery(jwtToken string, match func(HookDelivery) bool, opts ...Option) (*HookDelivery, error) {
+	return FindHookDeliveryAfter(jwtToken, 0, match, opts...)
+}
+
+// FindHookDeliveryAfter is FindHookDelivery among the deliveries with IDs
+// above after. As deliveries come newest first, it stops paging at the first
+// older one, so polling for a new delivery costs a page per poll rather than
+// the App's whole delivery history.
+func FindHookDeliveryAfter(jwtToken string, after int64, match func(HookDelivery) bool, opts ...Option) (*HookDelivery, error) {
+	o := buildOpts(opts)
+
+	path := "/app/hook/deliveries?per_page=100"
+	for {
+		body, header, err := o.do("listing webhook deliveries", http.MethodGet, path, jwtToken, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+
+		var deliveries []HookDelivery
+		if err := json.Unmarshal(body, &deliveries); err != nil {
+			return nil, fmt.Errorf("parsing deliveries response: %w", err)
+		}
+		for i := range deliveries {
+			if deliveries[i].ID <= after {
+				return nil, nil
+			}
+			if match(deliveries[i]) {
+				return &deliveries[i], nil
+			}
+		}
+
+		cursor := nextCursor(header.Get("Link"))
+		if cursor == "" || len(deliveries) == 0 {
+			return nil, nil
+		}
+		path = "/app/hook/deliveries?per_page=100&cursor=" + url.QueryEscape(cursor)
+	}
+}
+
+// nextCursor extracts the cursor parameter of the rel="next" link from a Link
+// header, or returns "" when there is no next page.
+func nextCursor(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+		if err != nil {
+			return ""
+		}
+		return u.Query().Get("cursor")
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListHookDeliveries(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/hook/deliveries" {
+			t.Errorf("path = %s, want /app/hook/deliveries", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("per_page"); got != "5" {
+			t.Errorf("per_page = %q, want 5", got)
+		}
+		w.Write([]byte(`[
+			{"id": 12, "guid": "g-12", "delivered_at": "2024-05-01T10:00:00Z", "status": "OK", "status_code": 200, "event": "push"},
+			{"id": 11, "guid": "g-11", "delivered_at": "2024-05-01T09:00:00Z", "status": "Invalid HTTP Response: 502", "status_code": 502, "event": "issues", "action": "opened", "redelivery": true}
+		]`))
+	}))
+	defer srv.Close()
+
+	got, err := ListHookDeliveries("fake-jwt", 5, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("ListHookDeliveries: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2", len(got))
+	}
+	if got[1].StatusCode != 502 || got[1].Action != "opened" || !got[1].Redelivery {
+		t.Errorf("got[1] = %+v, want failed redelivered issues.opened", got[1])
+	}
+	if got[0].DeliveredAt.IsZero() {
+		t.Error("delivered_at not parsed")
+	}
+}
+
+func TestRedeliverHookDelivery(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.Method != http.MethodPost || r.URL.Path != "/app/hook/deliveries/42/attempts" {
+			t.Errorf("request = %s %s, want POST /app/hook/deliveries/42/attempts", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	if err := RedeliverHookDelivery("fake-jwt", 42, WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("RedeliverHookDelivery: %v", err)
+	}
+	if !called {
+		t.Error("server was not called")
+	}
+}
+
+func TestRedeliverHookDelivery_NotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer srv.Close()
+
+	err := RedeliverHookDelivery("fake-jwt", 1, WithBaseURL(srv.URL))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("err = %v, want 404 API error", err)
+	}
+}
+
+func TestGetHookConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/app/hook/config" {
+			t.Errorf("request = %s %s, want GET /app/hook/config", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"url":"https://example.com/hook","content_type":"json","secret":"********","insecure_ssl":"0"}`))
+	}))
+	defer srv.Close()
+
+	cfg, err := GetHookConfig("fake-jwt", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetHookConfig: %v", err)
+	}
+	if cfg.URL != "https://example.com/hook" || cfg.ContentType != "json" || cfg.InsecureSSL != "0" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestUpdateHookConfig_SendsOnlySetFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("method = %s, want PATCH", r.Method)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if len(body) != 1 || body["url"] != "https://abc.ngrok.app/hook" {
+			t.Errorf("body = %v, want only url", body)
+		}
+		w.Write([]byte(`{"url":"https://abc.ngrok.app/hook","content_type":"json"}`))
+	}))
+	defer srv.Close()
+
+	cfg, err := UpdateHookConfig("fake-jwt", HookConfig{URL: "https://abc.ngrok.app/hook"}, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("UpdateHookConfig: %v", err)
+	}
+	if cfg.URL != "https://abc.ngrok.app/hook" {
+		t.Errorf("URL = %q", cfg.URL)
+	}
+}
+
+func TestFindHookDelivery_FollowsCursor(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("Link", `<`+srvURL+`/app/hook/deliveries?per_page=100&cursor=v1_page2>; rel="next"`)
+			w.Write([]byte(`[{"id": 3, "event": "push"}, {"id": 2, "event": "issues"}]`))
+		case "v1_page2":
+			w.Write([]byte(`[{"id": 1, "guid": "ping-guid", "event": "ping"}]`))
+		default:
+			t.Errorf("unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	got, err := FindHookDelivery("fake-jwt", func(d HookDelivery) bool { return d.Event == "ping" }, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("FindHookDelivery: %v", err)
+	}
+	if got == nil || got.GUID != "ping-guid" {
+		t.Errorf("got = %+v, want ping delivery", got)
+	}
+}
+
+func TestFindHookDelivery_NoMatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": 3, "event": "push"}]`))
+	}))
+	defer srv.Close()
+
+	got, err := FindHookDelivery("fake-jwt", func(d HookDelivery) bool { return d.Event == "ping" }, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("FindHookDelivery: %v", err)
+	}
+	if got != nil {
+		t.Errorf("got = %+v, want nil", got)
+	}
+}
+
+func TestFindHookDeliveryAfter_StopsAtOlder(t *testing.T) {
+	var srvURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cursor") != "" {
+			t.Errorf("fetched page %q past the deliveries older than the one asked after", r.URL.Query().Get("cursor"))
+		}
+		w.Header().Set("Link", `<`+srvURL+`/app/hook/deliveries?per_page=100&cursor=v1_page2>; rel="next"`)
+		w.Write([]byte(`[{"id": 5, "event": "push"}, {"id": 4, "event": "ping"}, {"id": 3, "event": "push"}]`))
+	}))
+	defer srv.Close()
+	srvURL = srv.URL
+
+	got, err := FindHookDeliveryAfter("fake-jwt", 4, func(d HookDelivery) bool { return d.Event == "ping" }, WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("FindHookDeliveryAfter: %v", err)
+	}
+	if got != nil {
+		t.Errorf("got = %+v, want nil: delivery 4 is not after 4", got)
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{`<https://api.github.com/app/hook/deliveries?cursor=abc&per_page=100>; rel="next"`, "abc"},
+		{`<https://api.github.com/app/hook/deliveries?per_page=100>; rel="first", <https://api.github.com/app/hook/deliveries?cursor=xyz>; rel="next"`, "xyz"},
+		{`<https://api.github.com/app/hook/deliveries?per_page=100>; rel="first"`, ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := nextCursor(tt.link); got != tt.want {
+			t.Errorf("nextCursor(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
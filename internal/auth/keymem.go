@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+// maxKeyFileSize bounds how much of a private key file is read. A 4096-bit
+// RSA key takes a little over 3KB in PEM.
+const maxKeyFileSize = 64 << 10
+
+// ReadKeyFile reads the private key file at path into memory locked against
+// swapping where the platform allows it. The caller must call release once
+// done with the data, which wipes and unlocks it.
+func ReadKeyFile(path string) (data []byte, release func(), err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() > maxKeyFileSize {
+		return nil, nil, fmt.Errorf("file is larger than %d bytes", maxKeyFileSize)
+	}
+
+	// Allocate and lock the buffer before the key is read into it, so the
+	// key never sits in memory that may be swapped out.
+	buf := make([]byte, info.Size())
+	unlock := lockMemory(buf)
+	release = func() {
+		clear(buf)
+		unlock()
+	}
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		release()
+		return nil, nil, err
+	}
+	return buf[:n], release, nil
+}
+
+// wipeKey overwrites the private parts of key with zeros, so a signed JWT
+// leaves no usable key behind in memory. The crypto packages keep copies of
+// their own while signing, which this cannot reach; it narrows exposure in
+// core dumps and swap rather than removing it.
+func wipeKey(key *rsa.PrivateKey) {
+	ints := []*big.Int{key.D, key.Precomputed.Dp, key.Precomputed.Dq, key.Precomputed.Qinv}
+	ints = append(ints, key.Primes...)
+	for _, n := range ints {
+		if n != nil {
+			clear(n.Bits())
+			n.SetInt64(0)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !windows
+
+package auth
+
+// lockMemory leaves b as it is: the syscall package has no mlock on this
+// platform, and locking is only ever best effort.
+func lockMemory([]byte) (unlock func()) {
+	return func() {}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package auth
+
+import "syscall"
+
+// lockMemory keeps b out of swap with mlock, and returns the function that
+// unlocks it. Without the privilege or the limit to lock memory, b is left
+// as it is.
+func lockMemory(b []byte) (unlock func()) {
+	if len(b) == 0 || syscall.Mlock(b) != nil {
+		return func() {}
+	}
+	return func() { _ = syscall.Munlock(b) }
+}
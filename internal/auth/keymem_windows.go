@@ -0,0 +1,28 @@
+//go:build windows
+
+package auth
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32          = syscall.NewLazyDLL("kernel32.dll")
+	procVirtualLock   = kernel32.NewProc("VirtualLock")
+	procVirtualUnlock = kernel32.NewProc("VirtualUnlock")
+)
+
+// lockMemory keeps b out of the paging file with VirtualLock, and returns
+// the function that unlocks it. When the working set is too small to lock
+// b, it is left as it is.
+func lockMemory(b []byte) (unlock func()) {
+	if len(b) == 0 {
+		return func() {}
+	}
+	addr, size := uintptr(unsafe.Pointer(&b[0])), uintptr(len(b))
+	if r, _, _ := procVirtualLock.Call(addr, size); r == 0 {
+		return func() {}
+	}
+	return func() { procVirtualUnlock.Call(addr, size) }
+}
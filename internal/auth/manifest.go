@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ManifestApp holds the credentials of an App created from a manifest. They
+// are only returned once, by the conversion endpoint.
+type ManifestApp struct {
+	ID            int64  `json:"id"`
+	Slug          string `json:"slug"`
+	Name          string `json:"name"`
+	HTMLURL       string `json:"html_url"`
+	ClientID      string `json:"client_id"`
+	ClientSecret  string `json:"client_secret"`
+	WebhookSecret string `json:"webhook_secret"`
+	PEM           string `json:"pem"`
+}
+
+// ConvertManifest exchanges the temporary code from the App Manifest flow for
+// the new App's credentials. The endpoint requires no authentication.
+func ConvertManifest(code string, opts ...Option) (*ManifestApp, error) {
+	o := buildOpts(opts)
+
+	path := fmt.Sprintf("/app-manifests/%s/conversions", url.PathEscape(code))
+	body, _, err := o.do("converting app manifest", http.MethodPost, path, "", nil, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	var app ManifestApp
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, fmt.Errorf("parsing manifest conversion response: %w", err)
+	}
+	if app.ID == 0 || app.PEM == "" {
+		return nil, fmt.Errorf("manifest conversion response is missing the app ID or private key")
+	}
+	return &app, nil
+}
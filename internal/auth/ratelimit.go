@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RateLimit is the core REST API quota of the token it was fetched with.
+// Every token of an installation shares the installation's quota.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Used      int
+	Reset     time.Time
+}
+
+type rateLimitResponse struct {
+	Resources struct {
+		Core struct {
+			Limit     int   `json:"limit"`
+			Remaining int   `json:"remaining"`
+			Used      int   `json:"used"`
+			Reset     int64 `json:"reset"`
+		} `json:"core"`
+	} `json:"resources"`
+}
+
+// GetRateLimit returns the core rate limit of token. Asking does not count
+// against the limit.
+func GetRateLimit(token string, opts ...Option) (*RateLimit, error) {
+	o := buildOpts(opts)
+
+	body, _, err := o.do("fetching rate limit", http.MethodGet, "/rate_limit", token, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp rateLimitResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parsing rate limit response: %w", err)
+	}
+	core := resp.Resources.Core
+	return &RateLimit{Limit: core.Limit, Remaining: core.Remaining, Used: core.Used, Reset: time.Unix(core.Reset, 0)}, nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" {
+			t.Errorf("path = %s, want /rate_limit", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer ghs_token" {
+			t.Errorf("Authorization = %q, want installation token", got)
+		}
+		w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4990, "used": 10, "reset": 1700000000}, "search": {"limit": 30}}}`))
+	}))
+	defer srv.Close()
+
+	got, err := GetRateLimit("ghs_token", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetRateLimit: %v", err)
+	}
+	want := RateLimit{Limit: 5000, Remaining: 4990, Used: 10, Reset: time.Unix(1700000000, 0)}
+	if *got != want {
+		t.Errorf("GetRateLimit = %+v, want %+v", *got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const reposPerPage = 100
+
+// Repository is a repository accessible to an installation.
+type Repository struct {
+	ID       int64  `json:"id"`
+	FullName string `json:"full_name"`
+	Private  bool   `json:"private"`
+}
+
+type installationReposResponse struct {
+	TotalCount   int          `json:"total_count"`
+	Repositories []Repository `json:"repositories"`
+}
+
+// ListInstallationRepos lists every repository the installation token can
+// access, following pagination until all pages have been read.
+func ListInstallationRepos(installToken string, opts ...Option) ([]Repository, error) {
+	o := buildOpts(opts)
+
+	var repos []Repository
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/installation/repositories?per_page=%d&page=%d", reposPerPage, page)
+		body, _, err := o.do("listing installation repositories", http.MethodGet, path, installToken, nil, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp installationReposResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("parsing repositories response: %w", err)
+		}
+
+		repos = append(repos, resp.Repositories...)
+		if len(resp.Repositories) < reposPerPage || len(repos) >= resp.TotalCount {
+			return repos, nil
+		}
+	}
+}
+
+// GetRepoInstallation returns the App's installation that covers the given
+// repository. GitHub answers 404 when the App is not installed on it.
+func GetRepoInstallation(jwtToken, owner, repo string, opts ...Option) (*Installation, error) {
+	o := buildOpts(opts)
+
+	path := fmt.Sprintf("/repos/%s/%s/installation", url.PathEscape(owner), url.PathEscape(repo))
+	body, _, err := o.do("looking up repository installation", http.MethodGet, path, jwtToken, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	var inst Installation
+	if err := json.Unmarshal(body, &inst); err != nil {
+		return nil, fmt.Errorf("parsing installation response: %w", err)
+	}
+	return &inst, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestListInstallationRepos_Paginates(t *testing.T) {
+	const total = 150
+	var pages []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/installation/repositories" {
+			t.Errorf("path = %s, want /installation/repositories", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer ghs_token" {
+			t.Errorf("Authorization = %q, want installation token", got)
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		pages = append(pages, r.URL.Query().Get("page"))
+
+		var repos []map[string]any
+		for i := (page - 1) * perPage; i < total && i < page*perPage; i++ {
+			repos = append(repos, map[string]any{"id": i, "full_name": fmt.Sprintf("org/repo-%d", i)})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"total_count": total, "repositories": repos})
+	}))
+	defer srv.Close()
+
+	got, err := ListInstallationRepos("ghs_token", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("ListInstallationRepos: %v", err)
+	}
+	if len(got) != total {
+		t.Errorf("len = %d, want %d", len(got), total)
+	}
+	if strings.Join(pages, ",") != "1,2" {
+		t.Errorf("pages requested = %v, want [1 2]", pages)
+	}
+	if got[149].FullName != "org/repo-149" {
+		t.Errorf("last repo = %q, want org/repo-149", got[149].FullName)
+	}
+}
+
+func TestListInstallationRepos_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Forbidden"}`))
+	}))
+	defer srv.Close()
+
+	_, err := ListInstallationRepos("ghs_token", WithBaseURL(srv.URL))
+	if err == nil {
+		t.Fatal("expected error for 403 response")
+	}
+	if !strings.Contains(err.Error(), "403") {
+		t.Errorf("error = %q, want substring %q", err.Error(), "403")
+	}
+}
+
+func TestGetRepoInstallation(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/widgets/installation" {
+			t.Errorf("path = %s, want /repos/acme/widgets/installation", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer fake-jwt" {
+			t.Errorf("Authorization = %q, want JWT bearer", got)
+		}
+		w.Write([]byte(`{"id": 55, "account": {"login": "acme"}, "repository_selection": "selected", "permissions": {"contents": "read"}}`))
+	}))
+	defer srv.Close()
+
+	inst, err := GetRepoInstallation("fake-jwt", "acme", "widgets", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("GetRepoInstallation: %v", err)
+	}
+	if inst.ID != 55 || inst.RepositorySelection != "selected" || inst.Permissions["contents"] != "read" {
+		t.Errorf("inst = %+v", inst)
+	}
+}
+
+func TestGetRepoInstallation_NotInstalled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer srv.Close()
+
+	_, err := GetRepoInstallation("fake-jwt", "acme", "nope", WithBaseURL(srv.URL))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want *APIError with 404", err)
+	}
+}
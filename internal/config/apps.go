@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// App holds the configuration for one additional GitHub App, selected by
+// name with --app, GHA_APP, a .gha.yaml app key or a routing rule.
+type App struct {
+	AppID          int64            `yaml:"app_id"`
+	InstallationID int64            `yaml:"installation_id,omitempty"`
+	PrivateKeyPath string           `yaml:"private_key_path"`
+	Installations  map[string]int64 `yaml:"installations,omitempty"`
+}
+
+// Route sends commands that target an org or repository matching Match to
+// the App named App. Match is an account login ("acme"), a repository
+// ("acme/api") or a path.Match pattern over either ("acme/*", "team-*").
+type Route struct {
+	Match string `yaml:"match"`
+	App   string `yaml:"app"`
+}
+
+var appNameRE = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ForApp returns the configuration for the App called name. An empty name
+// yields c itself.
+func (c *Config) ForApp(name string) (*Config, error) {
+	if name == "" {
+		return c, nil
+	}
+	if c.fromEnv {
+		return nil, fmt.Errorf("cannot select app %s: configuration comes from %s", name, AppIDEnv)
+	}
+	if c.host != "" {
+		return nil, fmt.Errorf("cannot select app %s: apps are only supported for %s", name, DefaultHost)
+	}
+	a, ok := c.Apps[name]
+	if !ok {
+		return nil, fmt.Errorf("no app named %s - add it under apps: in the config file", name)
+	}
+	return &Config{
+		AppID:          a.AppID,
+		InstallationID: a.InstallationID,
+		PrivateKeyPath: a.PrivateKeyPath,
+		TargetType:     c.TargetType,
+		Hooks:          c.Hooks,
+		TokenEnv:       c.TokenEnv,
+		ScrubEnv:       c.ScrubEnv,
+
+		StrictPermissions: c.StrictPermissions,
+		KeyReload:         c.KeyReload,
+		Isolated:          c.Isolated,
+		Revoke:            c.Revoke,
+		Policy:            c.Policy,
+		Serve:             c.Serve,
+		MinGhVersion:      c.MinGhVersion,
+		GhUpdateNotifier:  c.GhUpdateNotifier,
+		AuditLog:          c.AuditLog,
+		AuditLogPath:      c.AuditLogPath,
+		dir:               c.dir,
+		Installations:     a.Installations,
+		appName:           name,
+		scope:             "apps/" + name,
+	}, nil
+}
+
+// AppName returns the name of the App this config was selected for, or ""
+// for the top-level App.
+func (c *Config) AppName() string {
+	return c.appName
+}
+
+// Route returns the App named by the first routing rule matching target, an
+// account login or owner/repo, or "" when no rule matches.
+func (c *Config) Route(target string) string {
+	target = strings.ToLower(target)
+	owner, _, _ := strings.Cut(target, "/")
+	for _, r := range c.Routes {
+		pattern := strings.ToLower(r.Match)
+		if ok, _ := path.Match(pattern, target); ok {
+			return r.App
+		}
+		// A rule for a repository pattern such as acme/* also covers
+		// commands that only name the account.
+		if p, rest, ok := strings.Cut(pattern, "/"); ok && rest == "*" && !strings.Contains(target, "/") {
+			if ok, _ := path.Match(p, owner); ok {
+				return r.App
+			}
+		}
+		// A rule for an account covers all of its repositories.
+		if !strings.Contains(pattern, "/") && strings.Contains(target, "/") {
+			if ok, _ := path.Match(pattern, owner); ok {
+				return r.App
+			}
+		}
+	}
+	return ""
+}
+
+// ValidAppName reports whether name may name an App under apps:.
+func ValidAppName(name string) bool {
+	return appNameRE.MatchString(name)
+}
+
+func (a *App) validate(name string) error {
+	if !appNameRE.MatchString(name) {
+		return fmt.Errorf("apps.%s: app names may only contain letters, digits, '.', '_' and '-'", name)
+	}
+	if a.AppID <= 0 {
+		return fmt.Errorf("apps.%s.app_id must be a positive integer", name)
+	}
+	if a.InstallationID < 0 {
+		return fmt.Errorf("apps.%s.installation_id must not be negative", name)
+	}
+	if strings.TrimSpace(a.PrivateKeyPath) == "" {
+		return fmt.Errorf("apps.%s.private_key_path is required", name)
+	}
+	a.PrivateKeyPath = filepath.Clean(strings.TrimSpace(a.PrivateKeyPath))
+	installations, err := lowerInstallations("apps."+name+".installations", a.Installations)
+	if err != nil {
+		return err
+	}
+	a.Installations = installations
+	return nil
+}
+
+func (r *Route) validate(i int, apps map[string]App) error {
+	if strings.TrimSpace(r.Match) == "" {
+		return fmt.Errorf("routes[%d].match is required", i)
+	}
+	if _, err := path.Match(r.Match, ""); err != nil {
+		return fmt.Errorf("routes[%d].match: invalid pattern %q", i, r.Match)
+	}
+	if _, ok := apps[r.App]; !ok {
+		return fmt.Errorf("routes[%d].app: no app named %q under apps:", i, r.App)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestForApp(t *testing.T) {
+	c := &Config{AppID: 1, PrivateKeyPath: "/k.pem", TargetType: "org", Apps: map[string]App{
+		"partner": {AppID: 2, PrivateKeyPath: "/p.pem", InstallationID: 3},
+	}}
+
+	if got, err := c.ForApp(""); err != nil || got != c {
+		t.Errorf(`ForApp("") = %p, %v; want the config itself`, got, err)
+	}
+
+	got, err := c.ForApp("partner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AppID != 2 || got.InstallationID != 3 || got.PrivateKeyPath != "/p.pem" || got.TargetType != "org" {
+		t.Errorf("ForApp(partner) = %+v", got)
+	}
+	if got.AppName() != "partner" || got.Scope() != "apps/partner" {
+		t.Errorf("AppName = %q, Scope = %q", got.AppName(), got.Scope())
+	}
+	if err := Save(got); err == nil {
+		t.Error("Save should refuse an app-specific config")
+	}
+
+	if _, err := c.ForApp("unknown"); err == nil {
+		t.Error("expected error for an unconfigured app")
+	}
+	if _, err := (&Config{AppID: 1, fromEnv: true}).ForApp("partner"); err == nil {
+		t.Error("expected error selecting an app for an environment config")
+	}
+	hc := &Config{AppID: 1, host: "ghe.example.com", Apps: c.Apps}
+	if _, err := hc.ForApp("partner"); err == nil {
+		t.Error("expected error selecting an app for a GHES host")
+	}
+}
+
+func TestRoute(t *testing.T) {
+	c := &Config{Routes: []Route{
+		{Match: "Partner-Org", App: "partner"},
+		{Match: "acme/infra-*", App: "infra"},
+		{Match: "tools/*", App: "tools"},
+		{Match: "team-*", App: "teams"},
+	}}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"partner-org", "partner"},
+		{"partner-org/api", "partner"},
+		{"acme/infra-dns", "infra"},
+		{"ACME/Infra-DNS", "infra"},
+		{"acme/web", ""},
+		{"acme", ""},
+		{"tools", "tools"},
+		{"tools/lint", "tools"},
+		{"team-blue", "teams"},
+		{"team-blue/repo", "teams"},
+		{"other", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := c.Route(tt.target); got != tt.want {
+			t.Errorf("Route(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_AppValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		yml  string
+		want string
+	}{
+		{
+			name: "missing key",
+			yml:  "apps:\n  partner:\n    app_id: 2\n",
+			want: "apps.partner.private_key_path is required",
+		},
+		{
+			name: "bad name",
+			yml:  "apps:\n  \"a b\":\n    app_id: 2\n    private_key_path: /p.pem\n",
+			want: "app names may only contain",
+		},
+		{
+			name: "route to unknown app",
+			yml:  "routes:\n  - match: acme\n    app: partner\n",
+			want: `routes[0].app: no app named "partner"`,
+		},
+		{
+			name: "bad pattern",
+			yml:  "apps:\n  partner:\n    app_id: 2\n    private_key_path: /p.pem\nroutes:\n  - match: \"acme/[\"\n    app: partner\n",
+			want: "routes[0].match: invalid pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := setupTestEnv(t)
+			dir := filepath.Join(tmp, ".config", configDir)
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				t.Fatal(err)
+			}
+			yml := "app_id: 1\nprivate_key_path: /k.pem\n" + tt.yml
+			if err := os.WriteFile(filepath.Join(dir, configFile), []byte(yml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRememberInstallation_App(t *testing.T) {
+	setupTestEnv(t)
+	if err := Save(&Config{AppID: 1, PrivateKeyPath: "/k.pem", Apps: map[string]App{
+		"partner": {AppID: 2, PrivateKeyPath: "/p.pem"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RememberInstallation("apps/partner", "acme", 9); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.InstallationFor("acme"); ok {
+		t.Error("an app mapping must not land in the top-level map")
+	}
+	if cfg.Apps["partner"].Installations["acme"] != 9 {
+		t.Errorf("apps map = %+v", cfg.Apps)
+	}
+
+	removed, err := ForgetInstallation("apps/partner", 9)
+	if err != nil || !removed {
+		t.Errorf("ForgetInstallation = %v, %v; want true, nil", removed, err)
+	}
+}
@@ -2,17 +2,26 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	configDir  = "github-app-cli"
-	configFile = "config.yaml"
+	configDir    = "github-app-cli"
+	configFile   = "config.yaml"
+	auditLogFile = "audit.jsonl"
 )
 
 // Config holds GitHub App credentials.
@@ -20,54 +29,540 @@ type Config struct {
 	AppID          int64  `yaml:"app_id"`
 	InstallationID int64  `yaml:"installation_id"`
 	PrivateKeyPath string `yaml:"private_key_path"`
+	TargetType     string `yaml:"target_type,omitempty"`
+
+	// StrictPermissions turns warnings about a private key or config
+	// directory other users can access into errors.
+	StrictPermissions bool `yaml:"strict_permissions,omitempty"`
+
+	// KeyReload says how a gha process that keeps running, such as a
+	// --refresh session, reads the private key file: always, the default,
+	// again whenever it changes, so a key rotated underneath its path is
+	// picked up, or once, when it first signs.
+	KeyReload string `yaml:"key_reload,omitempty"`
+
+	// PrivateKey holds PEM key contents supplied through GHA_PRIVATE_KEY. It
+	// is never written to disk.
+	PrivateKey string `yaml:"-"`
+
+	// Installations maps lowercased account logins to installation IDs. gha
+	// fills it in after resolving --org / GHA_ORG through the API.
+	Installations map[string]int64 `yaml:"installations,omitempty"`
+
+	// Hosts configures GitHub Enterprise Server hosts by hostname.
+	Hosts map[string]Host `yaml:"hosts,omitempty"`
+
+	// Apps configures additional GitHub Apps by name, and Routes picks one
+	// of them by the org or repository a command targets.
+	Apps   map[string]App `yaml:"apps,omitempty"`
+	Routes []Route        `yaml:"routes,omitempty"`
+
+	// Aliases maps alias names to the gha command line they expand to. An
+	// expansion starting with "!" is run by sh through gha exec.
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+
+	// Hooks are shell commands run around every proxied command.
+	Hooks Hooks `yaml:"hooks,omitempty"`
+
+	// TokenEnv names environment variables that receive the token besides
+	// GH_TOKEN, and ScrubEnv ones removed from the proxied command's
+	// environment besides GITHUB_TOKEN.
+	TokenEnv []string `yaml:"token_env,omitempty"`
+	ScrubEnv []string `yaml:"scrub_env,omitempty"`
+
+	// Isolated gives every proxied command a throwaway GH_CONFIG_DIR, so
+	// the App token never touches the user's own gh configuration.
+	Isolated bool `yaml:"isolated,omitempty"`
+
+	// Revoke revokes the installation token once the proxied command exits,
+	// rather than leaving it valid for the rest of its hour.
+	Revoke bool `yaml:"revoke,omitempty"`
+
+	// MinGhVersion, when set, makes gha refuse to proxy through an older gh.
+	MinGhVersion string `yaml:"min_gh_version,omitempty"`
+
+	// GhUpdateNotifier keeps gh's own update notice, which gha otherwise
+	// turns off with GH_NO_UPDATE_NOTIFIER in favour of its own.
+	GhUpdateNotifier bool `yaml:"gh_update_notifier,omitempty"`
+
+	// Channel picks the releases gha's update check and self-update follow:
+	// stable (the default), or prerelease to include release candidates.
+	Channel string `yaml:"channel,omitempty"`
+
+	// DisableUpdateCheck stops gha from asking GitHub for newer releases,
+	// for air-gapped machines where every check would time out.
+	DisableUpdateCheck bool `yaml:"disable_update_check,omitempty"`
+
+	// UpdateNoticeInterval, a duration such as 168h, repeats the notice
+	// about a new release that often; by default it is shown once.
+	UpdateNoticeInterval string `yaml:"update_notice_interval,omitempty"`
+
+	// UpdateBaseURL points the update check and self-update at a mirror of
+	// the GitHub releases API, such as
+	// https://ghe.example.com/api/v3/repos/tools/gha/releases.
+	UpdateBaseURL string `yaml:"update_base_url,omitempty"`
+
+	// AuditLog records every proxied command as a JSON line in audit.jsonl
+	// next to the config file, or in AuditLogPath, which also turns it on.
+	AuditLog     bool   `yaml:"audit_log,omitempty"`
+	AuditLogPath string `yaml:"audit_log_path,omitempty"`
+
+	// Policy denies gh commands run through gha, or has them confirmed.
+	Policy Policy `yaml:"policy,omitempty"`
+
+	// Serve configures gha serve's TLS and clients.
+	Serve Serve `yaml:"serve,omitempty"`
+
+	// Encryption, when set, keeps the file encrypted at rest.
+	Encryption *Encryption `yaml:"encryption,omitempty"`
+
+	fromEnv bool
+	host    string
+	apiURL  string
+	appName string
+
+	// dir is the directory of the config file this Config was read from.
+	dir string
+
+	// scope locates this config's installations map and caches: "" for the
+	// top level, "hosts/<host>" or "apps/<name>" otherwise.
+	scope string
 }
 
-// Dir returns the configuration directory path, respecting XDG_CONFIG_HOME.
+// Scope identifies the section of the config file this Config was selected
+// from: "" for the top level, "hosts/<host>" or "apps/<name>" otherwise.
+func (c *Config) Scope() string {
+	return c.scope
+}
+
+// envNameRE matches the names token_env and scrub_env accept.
+var envNameRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Release channels accepted by the channel key.
+const (
+	ChannelStable     = "stable"
+	ChannelPrerelease = "prerelease"
+)
+
+// Values accepted by the key_reload key.
+const (
+	KeyReloadAlways = "always"
+	KeyReloadOnce   = "once"
+)
+
+// versionRE matches the versions min_gh_version accepts.
+var versionRE = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,2}$`)
+
+// Hooks holds the shell commands run before and after a proxied command.
+type Hooks struct {
+	PreRun  string `yaml:"pre_run,omitempty"`
+	PostRun string `yaml:"post_run,omitempty"`
+}
+
+// KeyPath returns PrivateKeyPath, resolved against the directory of the
+// config file when it is relative, so a config directory holding both the
+// config and the key can be moved as a whole.
+func (c *Config) KeyPath() string {
+	if c.PrivateKeyPath == "" || c.dir == "" || filepath.IsAbs(c.PrivateKeyPath) {
+		return c.PrivateKeyPath
+	}
+	return filepath.Join(c.dir, c.PrivateKeyPath)
+}
+
+// Resolve returns p, a path from the config file, resolved against the
+// config file's directory when it is relative, as private_key_path is.
+func (c *Config) Resolve(p string) string {
+	if p == "" || c.dir == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(c.dir, p)
+}
+
+// AuditLogFile returns the file proxied commands are logged to, or "" when
+// the audit log is off. A relative audit_log_path is resolved like
+// private_key_path.
+func (c *Config) AuditLogFile() (string, error) {
+	switch {
+	case c.AuditLogPath != "":
+		if c.dir == "" || filepath.IsAbs(c.AuditLogPath) {
+			return c.AuditLogPath, nil
+		}
+		return filepath.Join(c.dir, c.AuditLogPath), nil
+	case c.AuditLog:
+		dir := c.dir
+		if dir == "" {
+			var err error
+			if dir, err = Dir(); err != nil {
+				return "", err
+			}
+		}
+		return filepath.Join(dir, auditLogFile), nil
+	default:
+		return "", nil
+	}
+}
+
+// InstallationFor returns the remembered installation ID for login.
+func (c *Config) InstallationFor(login string) (int64, bool) {
+	id, ok := c.Installations[strings.ToLower(login)]
+	return id, ok
+}
+
+// lowerInstallations returns the installations map key, as written, with
+// its logins lowercased, as lookups expect them. Logins that differ only by
+// case, and IDs that are not positive, are refused.
+func lowerInstallations(key string, m map[string]int64) (map[string]int64, error) {
+	if m == nil {
+		return nil, nil
+	}
+	logins := make([]string, 0, len(m))
+	for login := range m {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	lowered := make(map[string]int64, len(m))
+	seen := make(map[string]string, len(m))
+	for _, login := range logins {
+		if m[login] <= 0 {
+			return nil, fmt.Errorf("%s.%s must be a positive integer", key, login)
+		}
+		lower := strings.ToLower(login)
+		if other, ok := seen[lower]; ok {
+			return nil, fmt.Errorf("%s.%s and %s.%s name the same account; keep only one", key, other, key, login)
+		}
+		seen[lower] = login
+		lowered[lower] = m[login]
+	}
+	return lowered, nil
+}
+
+// goos is runtime.GOOS, swapped out by tests.
+var goos = runtime.GOOS
+
+// Dir returns the configuration directory path: $XDG_CONFIG_HOME when set,
+// %APPDATA% on Windows and ~/.config elsewhere. On Windows a directory still
+// at the old ~/.config location is used until migrate moves it.
 func Dir() (string, error) {
+	legacy, dir, err := dirs()
+	if err != nil {
+		return "", err
+	}
+	if pendingMigration(legacy, dir) {
+		return legacy, nil
+	}
+	return dir, nil
+}
+
+// dirs returns the old ~/.config location of the configuration directory
+// and where it belongs now. They are the same except on Windows.
+func dirs() (legacy, dir string, err error) {
 	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
-		return filepath.Join(xdg, configDir), nil
+		dir = filepath.Join(xdg, configDir)
+		return dir, dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	legacy = filepath.Join(home, ".config", configDir)
+	appData := os.Getenv("APPDATA")
+	if goos != "windows" || appData == "" {
+		return legacy, legacy, nil
+	}
+	return legacy, filepath.Join(appData, configDir), nil
+}
+
+// pendingMigration reports whether a directory is left at legacy that has
+// not been moved to dir yet.
+func pendingMigration(legacy, dir string) bool {
+	if legacy == dir {
+		return false
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return false
+	}
+	info, err := os.Stat(legacy)
+	return err == nil && info.IsDir()
+}
+
+// migrate moves a configuration directory left at the old ~/.config
+// location on Windows to %APPDATA%. Loading and saving the config call it,
+// so commands that only ask where the config is never move anything. When
+// the move fails the old directory keeps being used, so an existing config
+// is never lost.
+func migrate() {
+	legacy, dir, err := dirs()
+	if err != nil || !pendingMigration(legacy, dir) {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+		return
+	}
+	_ = os.Rename(legacy, dir)
+}
+
+// Source is one of the places gha looks for its configuration, as listed by
+// LookupOrder.
+type Source struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	Set  bool   `json:"set"`
+	Used bool   `json:"used"`
+}
+
+// LookupOrder returns where gha looks for its configuration, first to last:
+// GHA_APP_ID, which reads it from the environment instead of a file;
+// --config or GHA_CONFIG; and the directories Dir chooses between. Set
+// reports whether a source is configured here, and Used marks the first of
+// them, the one in effect.
+func LookupOrder() ([]Source, error) {
+	inUse, err := Dir()
+	if err != nil {
+		return nil, err
 	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return "", fmt.Errorf("cannot determine home directory: %w", err)
+		return nil, fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	fromEnv := os.Getenv(AppIDEnv) != ""
+	path := os.Getenv(PathEnv)
+	sources := []Source{
+		{Name: AppIDEnv, Set: fromEnv},
+		{Name: "--config, " + PathEnv, Path: path, Set: path != ""},
+		dirSource("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME")),
+	}
+	if goos == "windows" {
+		sources = append(sources, dirSource("APPDATA", os.Getenv("APPDATA")))
+	}
+	sources = append(sources, dirSource("~/.config", filepath.Join(home, ".config")))
+	for i := range sources {
+		if sources[i].Set && (i < 2 || sources[i].Path == inUse) {
+			sources[i].Used = true
+			break
+		}
+	}
+	return sources, nil
+}
+
+// dirSource returns the Source for gha's directory inside base, which name
+// sets. An empty base leaves it unset.
+func dirSource(name, base string) Source {
+	if base == "" {
+		return Source{Name: name}
+	}
+	return Source{Name: name, Path: filepath.Join(base, configDir), Set: true}
+}
+
+// ErrNotFound is returned when there is no config file to load.
+var ErrNotFound = errors.New("configuration not found - run 'gha configure' first")
+
+// PathEnv names the environment variable that overrides the config file path.
+const PathEnv = "GHA_CONFIG"
+
+// Path returns the path of the configuration file, which is $GHA_CONFIG when
+// set. Otherwise it is whichever of config.yaml, config.toml and config.json
+// exists inside Dir, defaulting to config.yaml.
+func Path() (string, error) {
+	if p := os.Getenv(PathEnv); p != "" {
+		return p, nil
+	}
+	paths, err := DefaultPaths()
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return paths[0], nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("found several config files (%s) - keep only one", strings.Join(found, ", "))
 	}
-	return filepath.Join(home, ".config", configDir), nil
 }
 
 // Load reads configuration from disk.
 func Load() (*Config, error) {
-	dir, err := Dir()
+	migrate()
+	path, err := Path()
 	if err != nil {
 		return nil, err
 	}
+	return load(path)
+}
 
-	data, err := os.ReadFile(filepath.Join(dir, configFile))
+func load(path string) (*Config, error) {
+	cfg, err := read(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("configuration not found - run 'gha configure' first")
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadPartial reads the config file without validating it, so it can be
+// edited one key at a time. A missing file yields an empty Config.
+func LoadPartial() (*Config, error) {
+	migrate()
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := read(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
+
+func read(path string) (*Config, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := unmarshal(data, FormatOf(path))
+	if err != nil {
+		return nil, err
+	}
+	cfg.dir = filepath.Dir(path)
+	return cfg, nil
+}
+
+// readFile returns the contents of the config file at path, decrypted if it
+// is encrypted.
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
 		}
 		return nil, fmt.Errorf("reading config: %w", err)
 	}
+	return decrypt(data)
+}
 
-	var cfg Config
-	dec := yaml.NewDecoder(bytes.NewReader(data))
-	dec.KnownFields(true)
-	if err := dec.Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("parsing config: %w", err)
+// ReadRaw returns the config file's contents, decrypted if it is encrypted.
+// A missing file yields an error satisfying os.IsNotExist.
+func ReadRaw() ([]byte, error) {
+	migrate()
+	path, err := Path()
+	if err != nil {
+		return nil, err
 	}
+	return readFile(path)
+}
 
+func (cfg *Config) validate() error {
 	if cfg.AppID <= 0 {
-		return nil, fmt.Errorf("app_id must be a positive integer")
+		return fmt.Errorf("app_id must be a positive integer")
 	}
 	if cfg.InstallationID < 0 {
-		return nil, fmt.Errorf("installation_id must not be negative")
+		return fmt.Errorf("installation_id must not be negative")
 	}
 	if strings.TrimSpace(cfg.PrivateKeyPath) == "" {
-		return nil, fmt.Errorf("private_key_path is required in config")
+		return fmt.Errorf("private_key_path is required in config")
 	}
 	cfg.PrivateKeyPath = filepath.Clean(strings.TrimSpace(cfg.PrivateKeyPath))
+	installations, err := lowerInstallations("installations", cfg.Installations)
+	if err != nil {
+		return err
+	}
+	cfg.Installations = installations
+	switch cfg.TargetType {
+	case "", "org", "user":
+	default:
+		return fmt.Errorf("target_type must be org or user, got %q", cfg.TargetType)
+	}
+	for name, h := range cfg.Hosts {
+		if err := h.validate(name); err != nil {
+			return err
+		}
+		cfg.Hosts[name] = h
+	}
+	for name, a := range cfg.Apps {
+		if err := a.validate(name); err != nil {
+			return err
+		}
+		cfg.Apps[name] = a
+	}
+	for i, r := range cfg.Routes {
+		if err := r.validate(i, cfg.Apps); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Encryption.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Policy.validate(); err != nil {
+		return err
+	}
+	if err := cfg.Serve.validate(); err != nil {
+		return err
+	}
+	for key, names := range map[string][]string{"token_env": cfg.TokenEnv, "scrub_env": cfg.ScrubEnv} {
+		for _, name := range names {
+			if !envNameRE.MatchString(name) {
+				return fmt.Errorf("%s: %q is not a valid environment variable name", key, name)
+			}
+		}
+	}
+	if cfg.MinGhVersion != "" && !versionRE.MatchString(cfg.MinGhVersion) {
+		return fmt.Errorf("min_gh_version must be a version such as 2.40.0, got %q", cfg.MinGhVersion)
+	}
+	if cfg.Channel != "" && cfg.Channel != ChannelStable && cfg.Channel != ChannelPrerelease {
+		return fmt.Errorf("channel must be stable or prerelease, got %q", cfg.Channel)
+	}
+	if cfg.KeyReload != "" && cfg.KeyReload != KeyReloadAlways && cfg.KeyReload != KeyReloadOnce {
+		return fmt.Errorf("key_reload must be always or once, got %q", cfg.KeyReload)
+	}
+	if cfg.UpdateNoticeInterval != "" {
+		if err := checkInterval("update_notice_interval", cfg.UpdateNoticeInterval); err != nil {
+			return err
+		}
+	}
+	if cfg.UpdateBaseURL != "" {
+		if err := checkURL("update_base_url", cfg.UpdateBaseURL); err != nil {
+			return err
+		}
+	}
+	cfg.AuditLogPath = filepath.Clean(strings.TrimSpace(cfg.AuditLogPath))
+	if cfg.AuditLogPath == "." {
+		cfg.AuditLogPath = ""
+	}
+	for name, expansion := range cfg.Aliases {
+		if strings.TrimSpace(expansion) == "" {
+			return fmt.Errorf("aliases.%s must not be empty", name)
+		}
+	}
+	return nil
+}
 
-	return &cfg, nil
+// checkInterval validates a positive duration such as 24h for key.
+func checkInterval(key, value string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s must be a positive duration such as 168h, got %q", key, value)
+	}
+	return nil
+}
+
+// checkURL validates an http or https URL for key.
+func checkURL(key, value string) error {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%s must be an http or https URL, got %q", key, value)
+	}
+	return nil
 }
 
 // Save writes configuration to disk with secure file permissions.
@@ -75,31 +570,270 @@ func Save(cfg *Config) error {
 	if cfg == nil {
 		return fmt.Errorf("config must not be nil")
 	}
+	if cfg.fromEnv {
+		return fmt.Errorf("configuration comes from %s; it cannot be saved", AppIDEnv)
+	}
+	if cfg.scope != "" {
+		key := strings.Replace(cfg.scope, "/", ".", 1)
+		return fmt.Errorf("settings under %s cannot be saved this way; edit them in the config file", key)
+	}
+
+	return withLock(func(path string) error { return save(cfg, path) })
+}
+
+func save(cfg *Config, path string) error {
+	data, err := marshal(cfg, FormatOf(path))
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+	return writeFileAt(path, data, cfg.Encryption)
+}
+
+// SaveRaw validates data as a complete config file and writes it verbatim,
+// preserving comments and layout. It is encrypted first if its encryption
+// section asks for it.
+func SaveRaw(data []byte) error {
+	return withLock(func(path string) error {
+		cfg, err := Parse(data, FormatOf(path))
+		if err != nil {
+			return err
+		}
+		return writeFileAt(path, data, cfg.Encryption)
+	})
+}
 
-	dir, err := Dir()
+// withLock runs fn with the config file's path while holding its lock, so
+// concurrent gha processes do not lose each other's updates.
+func withLock(fn func(path string) error) error {
+	migrate()
+	path, err := Path()
 	if err != nil {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	unlock, err := fsutil.Lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn(path)
+}
+
+// Parse decodes and validates a config file's contents in format.
+func Parse(data []byte, format string) (*Config, error) {
+	cfg, err := unmarshal(data, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
+func writeFileAt(path string, data []byte, enc *Encryption) error {
+	data, err := encrypt(data, enc)
+	if err != nil {
+		return fmt.Errorf("encrypting config: %w", err)
+	}
+
+	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return fmt.Errorf("creating config directory: %w", err)
 	}
-	if err := os.Chmod(dir, 0o700); err != nil {
-		return fmt.Errorf("setting config directory permissions: %w", err)
+	// Only tighten our own directory; a $GHA_CONFIG file may live anywhere.
+	if os.Getenv(PathEnv) == "" {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return fmt.Errorf("setting config directory permissions: %w", err)
+		}
+	}
+
+	if err := fsutil.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// RememberInstallation records login → installationID in the installations
+// map of scope (see Config.Scope) in the saved config. The file is only
+// rewritten when the entry changes.
+func RememberInstallation(scope, login string, installationID int64) error {
+	if os.Getenv(AppIDEnv) != "" {
+		// The file, if any, may belong to a different App.
+		return nil
+	}
+	return withLock(func(path string) error {
+		return editInstallations(path, scope, func(m map[string]int64) bool {
+			key := strings.ToLower(login)
+			if m[key] == installationID {
+				return false
+			}
+			m[key] = installationID
+			return true
+		})
+	})
+}
+
+// ForgetInstallation removes every entry of scope's installations map that
+// points at installationID and reports whether any was removed.
+func ForgetInstallation(scope string, installationID int64) (bool, error) {
+	if os.Getenv(AppIDEnv) != "" {
+		return false, nil
 	}
+	removed := false
+	err := withLock(func(path string) error {
+		return editInstallations(path, scope, func(m map[string]int64) bool {
+			for login, id := range m {
+				if id == installationID {
+					delete(m, login)
+					removed = true
+				}
+			}
+			return removed
+		})
+	})
+	return removed, err
+}
 
-	data, err := yaml.Marshal(cfg)
+// editInstallations applies fn to scope's installations map in the config
+// file at path. When fn reports a change in a YAML file, only that map's
+// node is rewritten, so the user's comments and key order survive. TOML and
+// JSON files cannot be edited node by node and are rewritten whole.
+func editInstallations(path, scope string, fn func(map[string]int64) bool) error {
+	cfg, err := load(path)
 	if err != nil {
-		return fmt.Errorf("marshaling config: %w", err)
+		return err
+	}
+	format := FormatOf(path)
+	var data []byte
+	if format == "yaml" {
+		data, err = readFile(path)
+	} else {
+		data, err = yaml.Marshal(cfg)
+	}
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+	parent, err := scopeNode(&doc, scope)
+	if err != nil {
+		return err
 	}
 
-	path := filepath.Join(dir, configFile)
-	if err := os.WriteFile(path, data, 0o600); err != nil {
-		return fmt.Errorf("writing config: %w", err)
+	node := mappingValue(parent, "installations")
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "installations"}, node)
+	} else if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		node.Kind, node.Tag, node.Value = yaml.MappingNode, "!!map", ""
+	}
+	var written map[string]int64
+	if err := node.Decode(&written); err != nil {
+		return fmt.Errorf("parsing config: installations: %w", err)
+	}
+	m, err := lowerInstallations("installations", written)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		m = map[string]int64{}
+	}
+	if !fn(m) {
+		return nil
+	}
+	setInstallations(node, m)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+	if format != "yaml" {
+		updated, err := unmarshal(buf.Bytes(), "yaml")
+		if err != nil {
+			return err
+		}
+		return save(updated, path)
+	}
+	return writeFileAt(path, buf.Bytes(), cfg.Encryption)
+}
+
+// scopeNode returns the mapping node of doc that holds scope's settings: the
+// top level, or an entry of hosts or apps.
+func scopeNode(doc *yaml.Node, scope string) (*yaml.Node, error) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("parsing config: not a mapping")
+	}
+	root := doc.Content[0]
+	kind, name, _ := strings.Cut(scope, "/")
+	var node *yaml.Node
+	switch kind {
+	case "":
+		return root, nil
+	case "hosts", "apps":
+		if section := mappingValue(root, kind); section != nil && section.Kind == yaml.MappingNode {
+			node = mappingValue(section, name)
+		}
+	default:
+		return nil, fmt.Errorf("unknown config scope %q", scope)
 	}
-	if err := os.Chmod(path, 0o600); err != nil {
-		return fmt.Errorf("setting config file permissions: %w", err)
+	if node == nil || node.Kind != yaml.MappingNode {
+		if kind == "hosts" {
+			return nil, fmt.Errorf("no configuration for host %s", name)
+		}
+		return nil, fmt.Errorf("no app named %s", name)
 	}
+	return node, nil
+}
 
+// mappingValue returns the value of key in the mapping node m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
 	return nil
 }
+
+// setInstallations makes the mapping node hold m, whose logins are
+// lowercased. Entries still in m keep their place, comments and spelling;
+// new ones are appended in login order.
+func setInstallations(node *yaml.Node, m map[string]int64) {
+	var content []*yaml.Node
+	kept := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, v := node.Content[i], node.Content[i+1]
+		login := strings.ToLower(k.Value)
+		id, ok := m[login]
+		if !ok {
+			continue
+		}
+		kept[login] = true
+		if v.Kind != yaml.ScalarNode || v.Value != strconv.FormatInt(id, 10) {
+			v.Kind, v.Tag, v.Value, v.Style = yaml.ScalarNode, "!!int", strconv.FormatInt(id, 10), 0
+		}
+		content = append(content, k, v)
+	}
+	var added []string
+	for login := range m {
+		if !kept[login] {
+			added = append(added, login)
+		}
+	}
+	sort.Strings(added)
+	for _, login := range added {
+		content = append(content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: login},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(m[login], 10)})
+	}
+	node.Content = content
+}
@@ -1,10 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -13,7 +15,12 @@ func setupTestEnv(t *testing.T) string {
 	tmp := t.TempDir()
 	t.Setenv("HOME", tmp)
 	t.Setenv("USERPROFILE", tmp)
+	t.Setenv("APPDATA", filepath.Join(tmp, ".config"))
 	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(PathEnv, "")
+	t.Setenv(AppIDEnv, "")
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(AgeIdentityEnv, "")
 	return tmp
 }
 
@@ -81,11 +88,51 @@ func TestLoad_ValidationErrors(t *testing.T) {
 			yaml:    "app_id: 1\ninstallation_id: 1\n",
 			wantErr: "private_key_path is required",
 		},
+		{
+			name:    "invalid target_type",
+			yaml:    "app_id: 1\nprivate_key_path: /tmp/k.pem\ntarget_type: team\n",
+			wantErr: "target_type must be org or user",
+		},
+		{
+			name:    "invalid installations entry",
+			yaml:    "app_id: 1\nprivate_key_path: /tmp/k.pem\ninstallations:\n  acme: 0\n",
+			wantErr: "installations.acme must be a positive integer",
+		},
 		{
 			name:    "whitespace-only private_key_path",
 			yaml:    "app_id: 1\ninstallation_id: 1\nprivate_key_path: \"   \"\n",
 			wantErr: "private_key_path is required",
 		},
+		{
+			name:    "invalid token_env name",
+			yaml:    "app_id: 1\nprivate_key_path: /k.pem\ntoken_env: [GH_PAT, \"BAD-NAME\"]\n",
+			wantErr: `token_env: "BAD-NAME" is not a valid environment variable name`,
+		},
+		{
+			name:    "policy rule starting with a flag",
+			yaml:    "app_id: 1\nprivate_key_path: /k.pem\npolicy:\n  deny: [\"-X DELETE\"]\n",
+			wantErr: `policy.deny: rule "-X DELETE" must start with a gh command`,
+		},
+		{
+			name:    "empty policy rule",
+			yaml:    "app_id: 1\nprivate_key_path: /k.pem\npolicy:\n  confirm: [\" \"]\n",
+			wantErr: "policy.confirm: rules must not be empty",
+		},
+		{
+			name:    "serve client without credentials",
+			yaml:    "app_id: 1\nprivate_key_path: /k.pem\nserve:\n  clients:\n    - name: ci\n",
+			wantErr: "serve.clients[0] (ci): set token_sha256, common_name or both",
+		},
+		{
+			name:    "serve client certificate without a CA",
+			yaml:    "app_id: 1\nprivate_key_path: /k.pem\nserve:\n  clients:\n    - name: ci\n      common_name: ci.internal\n",
+			wantErr: "common_name needs serve.client_ca",
+		},
+		{
+			name:    "serve client token not a digest",
+			yaml:    "app_id: 1\nprivate_key_path: /k.pem\nserve:\n  clients:\n    - name: ci\n      token_sha256: secret\n",
+			wantErr: "token_sha256 must be a hex SHA-256 digest",
+		},
 	}
 
 	for _, tt := range tests {
@@ -170,6 +217,72 @@ func TestLoad_UnknownField(t *testing.T) {
 	}
 }
 
+func TestKeyPath(t *testing.T) {
+	tmp := setupTestEnv(t)
+	if err := Save(&Config{AppID: 1, PrivateKeyPath: "keys/app.pem", Hosts: map[string]Host{
+		"ghe.example.com": {AppID: 2, PrivateKeyPath: "/abs/ghe.pem"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(tmp, ".config", configDir, "keys", "app.pem")
+	if got := cfg.KeyPath(); got != want {
+		t.Errorf("KeyPath = %q, want %q", got, want)
+	}
+	if cfg.PrivateKeyPath != filepath.Join("keys", "app.pem") {
+		t.Errorf("PrivateKeyPath = %q, want it kept relative", cfg.PrivateKeyPath)
+	}
+	hc, err := cfg.ForHost("ghe.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hc.KeyPath(); got != "/abs/ghe.pem" {
+		t.Errorf("host KeyPath = %q, want the absolute path unchanged", got)
+	}
+
+	// Saving back must not bake in the resolved path.
+	if err := RememberInstallation("", "acme", 1); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, _ = Load(); cfg.PrivateKeyPath != filepath.Join("keys", "app.pem") {
+		t.Errorf("PrivateKeyPath after save = %q", cfg.PrivateKeyPath)
+	}
+
+	if got := (&Config{PrivateKeyPath: "rel.pem"}).KeyPath(); got != "rel.pem" {
+		t.Errorf("KeyPath without a file = %q, want it unchanged", got)
+	}
+}
+
+func TestAuditLogFile(t *testing.T) {
+	tmp := setupTestEnv(t)
+	dir := filepath.Join(tmp, ".config", configDir)
+
+	for _, tt := range []struct {
+		cfg  Config
+		want string
+	}{
+		{Config{AppID: 1, PrivateKeyPath: "k.pem"}, ""},
+		{Config{AppID: 1, PrivateKeyPath: "k.pem", AuditLog: true}, filepath.Join(dir, auditLogFile)},
+		{Config{AppID: 1, PrivateKeyPath: "k.pem", AuditLogPath: "logs/gha.jsonl"}, filepath.Join(dir, "logs", "gha.jsonl")},
+		{Config{AppID: 1, PrivateKeyPath: "k.pem", AuditLogPath: filepath.Join(tmp, "audit.jsonl")}, filepath.Join(tmp, "audit.jsonl")},
+	} {
+		if err := Save(&tt.cfg); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := cfg.AuditLogFile(); err != nil || got != tt.want {
+			t.Errorf("AuditLogFile() = %q, %v; want %q", got, err, tt.want)
+		}
+	}
+}
+
 func TestSave_CreatesDirectory(t *testing.T) {
 	tmp := setupTestEnv(t)
 
@@ -273,3 +386,269 @@ func TestDir_XDGConfigHome(t *testing.T) {
 		t.Errorf("Dir() = %q, want %q", dir, want)
 	}
 }
+
+func TestDir_Windows(t *testing.T) {
+	tmp := setupTestEnv(t)
+	appData := filepath.Join(tmp, "AppData", "Roaming")
+	t.Setenv("APPDATA", appData)
+	orig := goos
+	goos = "windows"
+	t.Cleanup(func() { goos = orig })
+
+	legacy := filepath.Join(tmp, ".config", configDir)
+	if err := os.MkdirAll(legacy, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, configFile), []byte("app_id: 1\nprivate_key_path: /k.pem\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Asking where the config is does not move it.
+	if dir, _ := Dir(); dir != legacy {
+		t.Errorf("Dir() before loading = %q, want %q", dir, legacy)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Fatalf("Dir moved the legacy directory: %v", err)
+	}
+
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(appData, configDir)
+	if dir, _ := Dir(); dir != want {
+		t.Errorf("Dir() = %q, want %q", dir, want)
+	}
+	if _, err := os.Stat(filepath.Join(want, configFile)); err != nil {
+		t.Errorf("config was not migrated: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy directory still exists: %v", err)
+	}
+
+	// Once the new directory exists the old one is left alone.
+	if err := os.MkdirAll(legacy, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if dir, _ := Dir(); dir != want {
+		t.Errorf("Dir() = %q, want %q", dir, want)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("legacy directory was touched: %v", err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	tmp := setupTestEnv(t)
+
+	got, err := Path()
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	want := filepath.Join(tmp, ".config", configDir, configFile)
+	if got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+}
+
+func TestRememberInstallation_Concurrent(t *testing.T) {
+	setupTestEnv(t)
+	if err := Save(&Config{AppID: 1, PrivateKeyPath: "/tmp/k.pem"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := RememberInstallation("", fmt.Sprintf("org%d", i), int64(i+1)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Installations) != 10 {
+		t.Errorf("installations = %v, want all 10 entries", cfg.Installations)
+	}
+}
+
+func TestRememberAndForgetInstallation(t *testing.T) {
+	setupTestEnv(t)
+	if err := Save(&Config{AppID: 1, PrivateKeyPath: "/tmp/k.pem"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RememberInstallation("", "Acme", 11); err != nil {
+		t.Fatalf("RememberInstallation: %v", err)
+	}
+	if err := RememberInstallation("", "other", 22); err != nil {
+		t.Fatalf("RememberInstallation: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := cfg.InstallationFor("ACME"); !ok || id != 11 {
+		t.Errorf("InstallationFor(ACME) = %d, %v; want 11, true", id, ok)
+	}
+
+	removed, err := ForgetInstallation("", 11)
+	if err != nil || !removed {
+		t.Fatalf("ForgetInstallation(11) = %v, %v; want true, nil", removed, err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.InstallationFor("acme"); ok {
+		t.Error("acme should be forgotten")
+	}
+	if _, ok := cfg.InstallationFor("other"); !ok {
+		t.Error("other should be kept")
+	}
+	if removed, _ := ForgetInstallation("", 11); removed {
+		t.Error("second ForgetInstallation(11) = true, want false")
+	}
+}
+
+func TestRememberInstallation_KeepsLayout(t *testing.T) {
+	tmp := setupTestEnv(t)
+	path := writeConfigFile(t, tmp, "config.yaml", `# my GitHub App
+private_key_path: /k.pem # kept in the vault
+app_id: 1
+hosts:
+    ghe.example.com:
+        app_id: 2
+        private_key_path: /g.pem
+installations:
+    # hand-written
+    zeta: 9
+    stale: 5
+`)
+
+	if err := RememberInstallation("", "Acme", 11); err != nil {
+		t.Fatal(err)
+	}
+	if err := RememberInstallation("hosts/ghe.example.com", "corp", 12); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ForgetInstallation("", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# my GitHub App
+private_key_path: /k.pem # kept in the vault
+app_id: 1
+hosts:
+    ghe.example.com:
+        app_id: 2
+        private_key_path: /g.pem
+        installations:
+            corp: 12
+installations:
+    # hand-written
+    zeta: 9
+    acme: 11
+`
+	if string(data) != want {
+		t.Errorf("config.yaml =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestInstallations_MixedCase(t *testing.T) {
+	tmp := setupTestEnv(t)
+	path := writeConfigFile(t, tmp, "config.yaml", "app_id: 1\nprivate_key_path: /k.pem\ninstallations:\n    MyOrg: 123\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := cfg.InstallationFor("myorg"); !ok || id != 123 {
+		t.Errorf("InstallationFor(myorg) = %d, %v; want 123, true", id, ok)
+	}
+	if err := RememberInstallation("", "myorg", 456); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "app_id: 1\nprivate_key_path: /k.pem\ninstallations:\n    MyOrg: 456\n"; string(data) != want {
+		t.Errorf("config.yaml =\n%s\nwant the entry as written updated in place", data)
+	}
+
+	writeConfigFile(t, tmp, "config.yaml", "app_id: 1\nprivate_key_path: /k.pem\ninstallations:\n    MyOrg: 1\n    myorg: 2\n")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "name the same account") {
+		t.Errorf("Load = %v, want logins differing only by case refused", err)
+	}
+}
+
+func TestRememberInstallation_NoConfig(t *testing.T) {
+	setupTestEnv(t)
+
+	if err := RememberInstallation("", "acme", 1); err == nil {
+		t.Error("expected error without a saved config")
+	}
+}
+
+func TestPath_Env(t *testing.T) {
+	setupTestEnv(t)
+	want := filepath.Join(t.TempDir(), "ci", "gha.yaml")
+	t.Setenv(PathEnv, want)
+
+	got, err := Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+
+	if err := Save(&Config{AppID: 7, PrivateKeyPath: "/tmp/k.pem"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.AppID != 7 {
+		t.Errorf("AppID = %d, want 7", cfg.AppID)
+	}
+	if _, err := os.Stat(want); err != nil {
+		t.Errorf("config not written to $%s: %v", PathEnv, err)
+	}
+}
+
+func TestSaveRaw(t *testing.T) {
+	setupTestEnv(t)
+
+	if err := SaveRaw([]byte("app_id: 1\nbogus: x\n")); err == nil {
+		t.Fatal("SaveRaw should reject unknown fields")
+	}
+	if _, err := os.Stat(filepath.Join(os.Getenv("HOME"), ".config", configDir, configFile)); !os.IsNotExist(err) {
+		t.Errorf("rejected config must not be written, stat err = %v", err)
+	}
+
+	raw := "# keep me\napp_id: 3\nprivate_key_path: /tmp/k.pem\n"
+	if err := SaveRaw([]byte(raw)); err != nil {
+		t.Fatalf("SaveRaw: %v", err)
+	}
+	path, _ := Path()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != raw {
+		t.Errorf("file = %q, want verbatim %q", data, raw)
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Environment variables that unlock an encrypted config file.
+const (
+	PassphraseEnv  = "GHA_CONFIG_PASSPHRASE"
+	AgeIdentityEnv = "GHA_AGE_IDENTITY"
+)
+
+// Encryption selects how the config file is encrypted at rest. It is stored
+// inside the encrypted document, so saving re-encrypts the same way.
+type Encryption struct {
+	// Passphrase encrypts with a key derived from $GHA_CONFIG_PASSPHRASE.
+	Passphrase bool `yaml:"passphrase,omitempty"`
+	// AgeRecipients encrypts to these age recipients with the age CLI;
+	// decrypting uses the identity file at $GHA_AGE_IDENTITY.
+	AgeRecipients []string `yaml:"age_recipients,omitempty"`
+}
+
+const (
+	passphraseHeader = "gha-encrypted-config/v1\n"
+	pbkdf2Iterations = 600_000
+	saltSize         = 16
+)
+
+var ageHeaders = [][]byte{[]byte("age-encryption.org/"), []byte("-----BEGIN AGE ENCRYPTED FILE-----")}
+
+func (e *Encryption) validate() error {
+	if e == nil {
+		return nil
+	}
+	if e.Passphrase && len(e.AgeRecipients) > 0 {
+		return fmt.Errorf("encryption: set only one of passphrase and age_recipients")
+	}
+	if !e.Passphrase && len(e.AgeRecipients) == 0 {
+		return fmt.Errorf("encryption: set passphrase: true or age_recipients")
+	}
+	for i, r := range e.AgeRecipients {
+		if strings.TrimSpace(r) == "" {
+			return fmt.Errorf("encryption.age_recipients[%d] must not be empty", i)
+		}
+	}
+	return nil
+}
+
+// IsEncrypted reports whether data is an encrypted config file.
+func IsEncrypted(data []byte) bool {
+	if bytes.HasPrefix(data, []byte(passphraseHeader)) {
+		return true
+	}
+	for _, h := range ageHeaders {
+		if bytes.HasPrefix(data, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// decrypt returns the plaintext of an encrypted config file, and data itself
+// when it is not encrypted.
+func decrypt(data []byte) ([]byte, error) {
+	if rest, ok := bytes.CutPrefix(data, []byte(passphraseHeader)); ok {
+		return decryptPassphrase(rest)
+	}
+	if IsEncrypted(data) {
+		return decryptAge(data)
+	}
+	return data, nil
+}
+
+// encrypt encrypts data as e selects; a nil e leaves it as is.
+func encrypt(data []byte, e *Encryption) ([]byte, error) {
+	switch {
+	case e == nil:
+		return data, nil
+	case e.Passphrase:
+		return encryptPassphrase(data)
+	default:
+		return encryptAge(data, e.AgeRecipients)
+	}
+}
+
+func passphraseKey(salt []byte) ([]byte, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("%s is not set; it holds the config file's passphrase", PassphraseEnv)
+	}
+	return pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+}
+
+func encryptPassphrase(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := passphraseKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := append(append(salt, nonce...), gcm.Seal(nil, nonce, plaintext, nil)...)
+	out := passphraseHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"
+	return []byte(out), nil
+}
+
+func decryptPassphrase(encoded []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(sealed) < saltSize {
+		return nil, fmt.Errorf("decrypting config: malformed encrypted file")
+	}
+	key, err := passphraseKey(sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	rest := sealed[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("decrypting config: malformed encrypted file")
+	}
+	plaintext, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting config: wrong %s or corrupted file", PassphraseEnv)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptAge(plaintext []byte, recipients []string) ([]byte, error) {
+	args := []string{"--encrypt", "--armor"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	return runAge(args, plaintext)
+}
+
+func decryptAge(ciphertext []byte) ([]byte, error) {
+	identity := os.Getenv(AgeIdentityEnv)
+	if identity == "" {
+		return nil, fmt.Errorf("the config file is encrypted with age - set %s to your identity file", AgeIdentityEnv)
+	}
+	return runAge([]string{"--decrypt", "--identity", identity}, ciphertext)
+}
+
+// runAge runs the age CLI with input on stdin and returns its output.
+func runAge(args []string, input []byte) ([]byte, error) {
+	path, err := exec.LookPath("age")
+	if err != nil {
+		return nil, fmt.Errorf("age CLI not found in PATH - install it from https://age-encryption.org: %w", err)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("age %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoad_Passphrase(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv(PassphraseEnv, "correct horse")
+
+	cfg := &Config{AppID: 42, PrivateKeyPath: "/k.pem", Installations: map[string]int64{"acme": 7},
+		Encryption: &Encryption{Passphrase: true}}
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	path, _ := Path()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(data) || strings.Contains(string(data), "acme") {
+		t.Errorf("config file is not encrypted:\n%s", data)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.AppID != 42 || loaded.Installations["acme"] != 7 || loaded.Encryption == nil {
+		t.Errorf("Load = %+v", loaded)
+	}
+
+	// Updates keep the file encrypted.
+	if err := RememberInstallation("", "other", 8); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !IsEncrypted(data) {
+		t.Error("RememberInstallation wrote the config in plaintext")
+	}
+	if raw, err := ReadRaw(); err != nil || !strings.Contains(string(raw), "other: 8") {
+		t.Errorf("ReadRaw = %q, %v", raw, err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "wrong "+PassphraseEnv) {
+		t.Errorf("wrong passphrase: err = %v", err)
+	}
+	t.Setenv(PassphraseEnv, "")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), PassphraseEnv+" is not set") {
+		t.Errorf("no passphrase: err = %v", err)
+	}
+}
+
+func TestSaveRaw_Encrypts(t *testing.T) {
+	setupTestEnv(t)
+	t.Setenv(PassphraseEnv, "pw")
+
+	raw := "# comment kept\napp_id: 1\nprivate_key_path: /k.pem\nencryption:\n  passphrase: true\n"
+	if err := SaveRaw([]byte(raw)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != raw {
+		t.Errorf("ReadRaw = %q, want %q", got, raw)
+	}
+}
+
+func TestEncryptionValidate(t *testing.T) {
+	tests := []struct {
+		enc  *Encryption
+		want string
+	}{
+		{nil, ""},
+		{&Encryption{Passphrase: true}, ""},
+		{&Encryption{AgeRecipients: []string{"age1xyz"}}, ""},
+		{&Encryption{}, "set passphrase: true or age_recipients"},
+		{&Encryption{Passphrase: true, AgeRecipients: []string{"age1xyz"}}, "only one of"},
+		{&Encryption{AgeRecipients: []string{" "}}, "age_recipients[0] must not be empty"},
+	}
+	for _, tt := range tests {
+		err := tt.enc.validate()
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("validate(%+v) = %v, want %q", tt.enc, err, tt.want)
+		}
+	}
+}
+
+func TestDecrypt_Age(t *testing.T) {
+	if _, err := exec.LookPath("age-keygen"); err != nil {
+		t.Skip("age CLI not installed")
+	}
+	identity := t.TempDir() + "/key.txt"
+	out, err := exec.Command("age-keygen", "-o", identity).CombinedOutput()
+	if err != nil {
+		t.Fatalf("age-keygen: %v: %s", err, out)
+	}
+	_, recipient, _ := strings.Cut(strings.TrimSpace(string(out)), "Public key: ")
+	t.Setenv(AgeIdentityEnv, identity)
+
+	ciphertext, err := encrypt([]byte("app_id: 1\n"), &Encryption{AgeRecipients: []string{recipient}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(ciphertext) {
+		t.Fatalf("not detected as encrypted: %q", ciphertext)
+	}
+	plaintext, err := decrypt(ciphertext)
+	if err != nil || string(plaintext) != "app_id: 1\n" {
+		t.Errorf("decrypt = %q, %v", plaintext, err)
+	}
+}
+
+func TestDecrypt_Plaintext(t *testing.T) {
+	got, err := decrypt([]byte("app_id: 1\n"))
+	if err != nil || string(got) != "app_id: 1\n" {
+		t.Errorf("decrypt = %q, %v; want the input unchanged", got, err)
+	}
+}
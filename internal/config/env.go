@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that configure gha without a config file.
+const (
+	AppIDEnv          = "GHA_APP_ID"
+	PrivateKeyEnv     = "GHA_PRIVATE_KEY"
+	PrivateKeyPathEnv = "GHA_PRIVATE_KEY_PATH"
+)
+
+// FromEnv builds a Config from GHA_APP_ID and GHA_PRIVATE_KEY or
+// GHA_PRIVATE_KEY_PATH. It returns nil when GHA_APP_ID is unset, so callers
+// fall back to Load. The installation is not read here: GHA_INSTALLATION_ID is
+// already honoured during installation resolution.
+func FromEnv() (*Config, error) {
+	raw := strings.TrimSpace(os.Getenv(AppIDEnv))
+	if raw == "" {
+		return nil, nil
+	}
+	appID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || appID <= 0 {
+		return nil, fmt.Errorf("%s must be a positive integer, got %q", AppIDEnv, raw)
+	}
+
+	cfg := &Config{AppID: appID, fromEnv: true}
+	key := os.Getenv(PrivateKeyEnv)
+	keyPath := strings.TrimSpace(os.Getenv(PrivateKeyPathEnv))
+	switch {
+	case key != "" && keyPath != "":
+		return nil, fmt.Errorf("set only one of %s and %s", PrivateKeyEnv, PrivateKeyPathEnv)
+	case key != "":
+		// CI secret stores often flatten newlines; accept the escaped form.
+		if !strings.Contains(key, "\n") {
+			key = strings.ReplaceAll(key, `\n`, "\n")
+		}
+		cfg.PrivateKey = key
+	case keyPath != "":
+		cfg.PrivateKeyPath = keyPath
+	default:
+		return nil, fmt.Errorf("%s is set but neither %s nor %s is", AppIDEnv, PrivateKeyEnv, PrivateKeyPathEnv)
+	}
+	return cfg, nil
+}
+
+// FromEnvironment reports whether the Config was built by FromEnv rather than
+// read from a file. Such configs must not be saved.
+func (c *Config) FromEnvironment() bool {
+	return c.fromEnv
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats lists the config file formats gha reads and writes, named after
+// their file extensions. When several files exist, none is preferred.
+var Formats = []string{"yaml", "toml", "json"}
+
+// FormatOf returns the format of the config file at path, judged by its
+// extension. Anything but .toml and .json is read as YAML.
+func FormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return "toml"
+	case ".json":
+		return "json"
+	default:
+		return "yaml"
+	}
+}
+
+// DefaultPaths returns the config file gha looks for inside Dir in each
+// format, config.yaml first.
+func DefaultPaths() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(Formats))
+	for _, f := range Formats {
+		paths = append(paths, filepath.Join(dir, "config."+f))
+	}
+	return paths, nil
+}
+
+// unmarshal decodes a config file in format. TOML and JSON documents are
+// converted to YAML first, so the same field names and unknown-key checks
+// apply to every format.
+func unmarshal(data []byte, format string) (*Config, error) {
+	if format != "yaml" {
+		var doc map[string]any
+		switch format {
+		case "toml":
+			if _, err := toml.Decode(string(data), &doc); err != nil {
+				return nil, fmt.Errorf("parsing config: %w", err)
+			}
+		case "json":
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.UseNumber()
+			if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("parsing config: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown config format %q", format)
+		}
+		var err error
+		if data, err = yaml.Marshal(jsonNumbers(doc)); err != nil {
+			return nil, fmt.Errorf("parsing config: %w", err)
+		}
+	}
+
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// marshal encodes cfg in format.
+func marshal(cfg *Config, format string) ([]byte, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil || format == "yaml" {
+		return data, err
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	switch format {
+	case "toml":
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "json":
+		if doc == nil {
+			doc = map[string]any{}
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+}
+
+// jsonNumbers replaces the json.Numbers in v with int64s or float64s, which
+// YAML encodes as numbers rather than strings.
+func jsonNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = jsonNumbers(e)
+		}
+	}
+	return v
+}
+
+// Convert rewrites the config file in format, next to the current file and
+// with the matching extension, and removes the old file. It returns both
+// paths.
+func Convert(format string) (from, to string, err error) {
+	if FormatOf("config."+format) != format {
+		return "", "", fmt.Errorf("unknown config format %q (valid formats: %s)", format, strings.Join(Formats, ", "))
+	}
+	err = withLock(func(path string) error {
+		from = path
+		if FormatOf(from) == format {
+			return fmt.Errorf("%s is already in %s format", from, format)
+		}
+		cfg, err := read(from)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		data, err := marshal(cfg, format)
+		if err != nil {
+			return fmt.Errorf("marshaling config: %w", err)
+		}
+		to = strings.TrimSuffix(from, filepath.Ext(from)) + "." + format
+		if _, err := os.Stat(to); err == nil {
+			return fmt.Errorf("%s already exists", to)
+		}
+		if err := writeFileAt(to, data, cfg.Encryption); err != nil {
+			return err
+		}
+		if err := os.Remove(from); err != nil {
+			return fmt.Errorf("removing %s: %w", from, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return from, to, nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, tmp, name, content string) string {
+	t.Helper()
+	dir := filepath.Join(tmp, ".config", configDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]string{
+		"config.yaml": "yaml", "c.yml": "yaml", "config": "yaml",
+		"config.toml": "toml", "C.TOML": "toml", "config.json": "json",
+	} {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	tmp := setupTestEnv(t)
+	writeConfigFile(t, tmp, "config.toml", `app_id = 12345678901
+private_key_path = "/k.pem"
+
+[installations]
+acme = 7
+
+[[routes]]
+match = "partner-*"
+app = "partner"
+
+[apps.partner]
+app_id = 2
+private_key_path = "/p.pem"
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 12345678901 || cfg.Installations["acme"] != 7 || cfg.Apps["partner"].AppID != 2 || cfg.Route("partner-x") != "partner" {
+		t.Errorf("Load = %+v", cfg)
+	}
+}
+
+func TestLoad_JSON(t *testing.T) {
+	tmp := setupTestEnv(t)
+	writeConfigFile(t, tmp, "config.json", `{"app_id": 12345678901, "private_key_path": "/k.pem", "hosts": {"ghe.example.com": {"app_id": 2, "private_key_path": "/g.pem"}}}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 12345678901 || cfg.Hosts["ghe.example.com"].AppID != 2 {
+		t.Errorf("Load = %+v", cfg)
+	}
+}
+
+func TestLoad_UnknownKeyInEveryFormat(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.toml": "app_id = 1\nprivate_key_path = \"/k.pem\"\nappid = 2\n",
+		"config.json": `{"app_id": 1, "private_key_path": "/k.pem", "appid": 2}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			tmp := setupTestEnv(t)
+			writeConfigFile(t, tmp, name, content)
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), "appid") {
+				t.Errorf("err = %v, want unknown key error", err)
+			}
+		})
+	}
+}
+
+func TestPath_SeveralFiles(t *testing.T) {
+	tmp := setupTestEnv(t)
+	writeConfigFile(t, tmp, "config.yaml", "app_id: 1\n")
+	writeConfigFile(t, tmp, "config.json", "{}")
+
+	if _, err := Path(); err == nil || !strings.Contains(err.Error(), "keep only one") {
+		t.Errorf("err = %v, want ambiguity error", err)
+	}
+}
+
+func TestSave_KeepsFormat(t *testing.T) {
+	tmp := setupTestEnv(t)
+	path := writeConfigFile(t, tmp, "config.toml", "app_id = 1\nprivate_key_path = \"/k.pem\"\n")
+
+	if err := RememberInstallation("", "acme", 7); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "[installations]") || !strings.Contains(string(data), "acme = 7") {
+		t.Errorf("config.toml =\n%s", data)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	setupTestEnv(t)
+	want := &Config{AppID: 1, PrivateKeyPath: "/k.pem", Installations: map[string]int64{"acme": 7},
+		Routes: []Route{{Match: "acme", App: "other"}}, Apps: map[string]App{"other": {AppID: 2, PrivateKeyPath: "/o.pem"}}}
+	if err := Save(want); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{"toml", "json", "yaml"} {
+		from, to, err := Convert(format)
+		if err != nil {
+			t.Fatalf("Convert(%s): %v", format, err)
+		}
+		if FormatOf(to) != format {
+			t.Errorf("Convert(%s) wrote %s", format, to)
+		}
+		if _, err := os.Stat(from); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", from)
+		}
+		got, err := Load()
+		if err != nil {
+			t.Fatalf("Load after converting to %s: %v", format, err)
+		}
+		if got.AppID != 1 || got.Installations["acme"] != 7 || got.Route("acme") != "other" || got.Apps["other"].AppID != 2 {
+			t.Errorf("after converting to %s: %+v", format, got)
+		}
+	}
+
+	if _, _, err := Convert("yaml"); err == nil || !strings.Contains(err.Error(), "already in yaml format") {
+		t.Errorf("err = %v, want already in format error", err)
+	}
+	if _, _, err := Convert("ini"); err == nil || !strings.Contains(err.Error(), "unknown config format") {
+		t.Errorf("err = %v, want unknown format error", err)
+	}
+}
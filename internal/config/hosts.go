@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultHost is the host the top-level config keys apply to.
+const DefaultHost = "github.com"
+
+// Host holds the App configuration for one GitHub Enterprise Server host.
+type Host struct {
+	AppID          int64            `yaml:"app_id"`
+	InstallationID int64            `yaml:"installation_id,omitempty"`
+	PrivateKeyPath string           `yaml:"private_key_path"`
+	APIURL         string           `yaml:"api_url,omitempty"`
+	Installations  map[string]int64 `yaml:"installations,omitempty"`
+}
+
+// ForHost returns the configuration for host. An empty host or github.com
+// yields c itself; any other host must have an entry under hosts:, except
+// for environment-based configs, which apply to whichever host is selected.
+func (c *Config) ForHost(host string) (*Config, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" || host == DefaultHost {
+		return c, nil
+	}
+
+	if c.fromEnv {
+		hc := *c
+		hc.host, hc.apiURL, hc.scope = host, enterpriseAPIURL(host), "hosts/"+host
+		return &hc, nil
+	}
+
+	h, ok := c.Hosts[host]
+	if !ok {
+		return nil, fmt.Errorf("no configuration for host %s - add it under hosts: in the config file", host)
+	}
+	apiURL := h.APIURL
+	if apiURL == "" {
+		apiURL = enterpriseAPIURL(host)
+	}
+	return &Config{
+		AppID:          h.AppID,
+		InstallationID: h.InstallationID,
+		PrivateKeyPath: h.PrivateKeyPath,
+		TargetType:     c.TargetType,
+		Hooks:          c.Hooks,
+		TokenEnv:       c.TokenEnv,
+		ScrubEnv:       c.ScrubEnv,
+
+		StrictPermissions: c.StrictPermissions,
+		KeyReload:         c.KeyReload,
+		Isolated:          c.Isolated,
+		Revoke:            c.Revoke,
+		Policy:            c.Policy,
+		Serve:             c.Serve,
+		MinGhVersion:      c.MinGhVersion,
+		GhUpdateNotifier:  c.GhUpdateNotifier,
+		AuditLog:          c.AuditLog,
+		AuditLogPath:      c.AuditLogPath,
+		dir:               c.dir,
+		Installations:     h.Installations,
+		host:              host,
+		apiURL:            strings.TrimSuffix(apiURL, "/"),
+		scope:             "hosts/" + host,
+	}, nil
+}
+
+// Host returns the GitHub Enterprise Server host this config was selected
+// for, or "" for github.com.
+func (c *Config) Host() string {
+	return c.host
+}
+
+// APIURL returns the REST API base URL for the selected host, or "" for the
+// default github.com API.
+func (c *Config) APIURL() string {
+	return c.apiURL
+}
+
+func enterpriseAPIURL(host string) string {
+	return "https://" + host + "/api/v3"
+}
+
+func (h *Host) validate(name string) error {
+	if h.AppID <= 0 {
+		return fmt.Errorf("hosts.%s.app_id must be a positive integer", name)
+	}
+	if h.InstallationID < 0 {
+		return fmt.Errorf("hosts.%s.installation_id must not be negative", name)
+	}
+	if strings.TrimSpace(h.PrivateKeyPath) == "" {
+		return fmt.Errorf("hosts.%s.private_key_path is required", name)
+	}
+	h.PrivateKeyPath = filepath.Clean(strings.TrimSpace(h.PrivateKeyPath))
+	installations, err := lowerInstallations("hosts."+name+".installations", h.Installations)
+	if err != nil {
+		return err
+	}
+	h.Installations = installations
+	return nil
+}
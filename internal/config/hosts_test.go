@@ -0,0 +1,105 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestForHost(t *testing.T) {
+	c := &Config{AppID: 1, PrivateKeyPath: "/k.pem", TargetType: "org", KeyReload: KeyReloadOnce, Hosts: map[string]Host{
+		"ghe.example.com": {AppID: 2, PrivateKeyPath: "/ghe.pem", InstallationID: 3},
+		"ghe.internal":    {AppID: 4, PrivateKeyPath: "/i.pem", APIURL: "https://api.ghe.internal/"},
+	}}
+
+	for _, host := range []string{"", "github.com", "GitHub.com"} {
+		got, err := c.ForHost(host)
+		if err != nil || got != c {
+			t.Errorf("ForHost(%q) = %p, %v; want the config itself", host, got, err)
+		}
+	}
+
+	got, err := c.ForHost("GHE.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AppID != 2 || got.InstallationID != 3 || got.PrivateKeyPath != "/ghe.pem" || got.TargetType != "org" || got.KeyReload != KeyReloadOnce {
+		t.Errorf("ForHost(ghe.example.com) = %+v", got)
+	}
+	if got.Host() != "ghe.example.com" || got.APIURL() != "https://ghe.example.com/api/v3" {
+		t.Errorf("Host = %q, APIURL = %q", got.Host(), got.APIURL())
+	}
+	if err := Save(got); err == nil {
+		t.Error("Save should refuse a host-specific config")
+	}
+
+	got, err = c.ForHost("ghe.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.APIURL() != "https://api.ghe.internal" {
+		t.Errorf("APIURL = %q, want explicit api_url without trailing slash", got.APIURL())
+	}
+
+	if _, err := c.ForHost("unknown.example.com"); err == nil {
+		t.Error("expected error for an unconfigured host")
+	}
+}
+
+func TestForHost_FromEnv(t *testing.T) {
+	c := &Config{AppID: 1, PrivateKey: "pem", fromEnv: true}
+
+	got, err := c.ForHost("ghe.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AppID != 1 || got.APIURL() != "https://ghe.example.com/api/v3" {
+		t.Errorf("ForHost = %+v", got)
+	}
+}
+
+func TestLoad_HostValidation(t *testing.T) {
+	tmp := setupTestEnv(t)
+	dir := filepath.Join(tmp, ".config", configDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	yml := "app_id: 1\nprivate_key_path: /k.pem\nhosts:\n  ghe.example.com:\n    app_id: 2\n"
+	if err := os.WriteFile(filepath.Join(dir, configFile), []byte(yml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "hosts.ghe.example.com.private_key_path is required") {
+		t.Errorf("err = %v, want host private_key_path error", err)
+	}
+}
+
+func TestRememberInstallation_Host(t *testing.T) {
+	setupTestEnv(t)
+	if err := Save(&Config{AppID: 1, PrivateKeyPath: "/k.pem", Hosts: map[string]Host{
+		"ghe.example.com": {AppID: 2, PrivateKeyPath: "/ghe.pem"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RememberInstallation("hosts/ghe.example.com", "acme", 9); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.InstallationFor("acme"); ok {
+		t.Error("a GHES mapping must not land in the github.com map")
+	}
+	if cfg.Hosts["ghe.example.com"].Installations["acme"] != 9 {
+		t.Errorf("hosts map = %+v", cfg.Hosts)
+	}
+
+	removed, err := ForgetInstallation("hosts/ghe.example.com", 9)
+	if err != nil || !removed {
+		t.Errorf("ForgetInstallation = %v, %v; want true, nil", removed, err)
+	}
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const installationsPrefix = "installations."
+
+// Keys lists the scalar keys accepted by Get, Set and Unset, in file order.
+// Entries of the installations map are addressed as installations.<login>.
+var Keys = []string{"app_id", "installation_id", "private_key_path", "target_type", "strict_permissions", "key_reload", "isolated", "revoke", "min_gh_version", "gh_update_notifier", "audit_log", "audit_log_path", "channel", "disable_update_check", "update_notice_interval", "update_base_url"}
+
+// Get returns the value stored under key and whether it is set.
+func (c *Config) Get(key string) (string, bool, error) {
+	if login, ok := strings.CutPrefix(key, installationsPrefix); ok {
+		id, set := c.InstallationFor(login)
+		return formatID(id), set, nil
+	}
+	switch key {
+	case "app_id":
+		return formatID(c.AppID), c.AppID != 0, nil
+	case "installation_id":
+		return formatID(c.InstallationID), c.InstallationID != 0, nil
+	case "private_key_path":
+		return c.PrivateKeyPath, c.PrivateKeyPath != "", nil
+	case "target_type":
+		return c.TargetType, c.TargetType != "", nil
+	case "strict_permissions":
+		return strconv.FormatBool(c.StrictPermissions), c.StrictPermissions, nil
+	case "key_reload":
+		return c.KeyReload, c.KeyReload != "", nil
+	case "isolated":
+		return strconv.FormatBool(c.Isolated), c.Isolated, nil
+	case "revoke":
+		return strconv.FormatBool(c.Revoke), c.Revoke, nil
+	case "min_gh_version":
+		return c.MinGhVersion, c.MinGhVersion != "", nil
+	case "gh_update_notifier":
+		return strconv.FormatBool(c.GhUpdateNotifier), c.GhUpdateNotifier, nil
+	case "audit_log":
+		return strconv.FormatBool(c.AuditLog), c.AuditLog, nil
+	case "audit_log_path":
+		return c.AuditLogPath, c.AuditLogPath != "", nil
+	case "channel":
+		return c.Channel, c.Channel != "", nil
+	case "disable_update_check":
+		return strconv.FormatBool(c.DisableUpdateCheck), c.DisableUpdateCheck, nil
+	case "update_notice_interval":
+		return c.UpdateNoticeInterval, c.UpdateNoticeInterval != "", nil
+	case "update_base_url":
+		return c.UpdateBaseURL, c.UpdateBaseURL != "", nil
+	default:
+		return "", false, unknownKey(key)
+	}
+}
+
+// Set validates value and stores it under key.
+func (c *Config) Set(key, value string) error {
+	if login, ok := strings.CutPrefix(key, installationsPrefix); ok {
+		if login == "" {
+			return unknownKey(key)
+		}
+		id, err := parseID(key, value)
+		if err != nil {
+			return err
+		}
+		if c.Installations == nil {
+			c.Installations = map[string]int64{}
+		}
+		c.Installations[strings.ToLower(login)] = id
+		return nil
+	}
+
+	switch key {
+	case "app_id":
+		id, err := parseID(key, value)
+		if err != nil {
+			return err
+		}
+		c.AppID = id
+	case "installation_id":
+		id, err := parseID(key, value)
+		if err != nil {
+			return err
+		}
+		c.InstallationID = id
+	case "private_key_path":
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("private_key_path must not be empty")
+		}
+		c.PrivateKeyPath = filepath.Clean(strings.TrimSpace(value))
+	case "target_type":
+		if value != "org" && value != "user" {
+			return fmt.Errorf("target_type must be org or user, got %q", value)
+		}
+		c.TargetType = value
+	case "strict_permissions":
+		b, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		c.StrictPermissions = b
+	case "isolated":
+		b, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		c.Isolated = b
+	case "revoke":
+		b, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		c.Revoke = b
+	case "min_gh_version":
+		value = strings.TrimSpace(value)
+		if !versionRE.MatchString(value) {
+			return fmt.Errorf("min_gh_version must be a version such as 2.40.0, got %q", value)
+		}
+		c.MinGhVersion = value
+	case "gh_update_notifier":
+		b, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		c.GhUpdateNotifier = b
+	case "audit_log":
+		b, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		c.AuditLog = b
+	case "audit_log_path":
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("audit_log_path must not be empty")
+		}
+		c.AuditLogPath = filepath.Clean(strings.TrimSpace(value))
+	case "key_reload":
+		if value != KeyReloadAlways && value != KeyReloadOnce {
+			return fmt.Errorf("key_reload must be always or once, got %q", value)
+		}
+		c.KeyReload = value
+	case "channel":
+		if value != ChannelStable && value != ChannelPrerelease {
+			return fmt.Errorf("channel must be stable or prerelease, got %q", value)
+		}
+		c.Channel = value
+	case "disable_update_check":
+		b, err := parseBool(key, value)
+		if err != nil {
+			return err
+		}
+		c.DisableUpdateCheck = b
+	case "update_notice_interval":
+		if err := checkInterval(key, value); err != nil {
+			return err
+		}
+		c.UpdateNoticeInterval = strings.TrimSpace(value)
+	case "update_base_url":
+		if err := checkURL(key, value); err != nil {
+			return err
+		}
+		c.UpdateBaseURL = strings.TrimSpace(value)
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// Unset clears key.
+func (c *Config) Unset(key string) error {
+	if login, ok := strings.CutPrefix(key, installationsPrefix); ok {
+		delete(c.Installations, strings.ToLower(login))
+		return nil
+	}
+	switch key {
+	case "app_id":
+		c.AppID = 0
+	case "installation_id":
+		c.InstallationID = 0
+	case "private_key_path":
+		c.PrivateKeyPath = ""
+	case "target_type":
+		c.TargetType = ""
+	case "strict_permissions":
+		c.StrictPermissions = false
+	case "isolated":
+		c.Isolated = false
+	case "revoke":
+		c.Revoke = false
+	case "min_gh_version":
+		c.MinGhVersion = ""
+	case "gh_update_notifier":
+		c.GhUpdateNotifier = false
+	case "audit_log":
+		c.AuditLog = false
+	case "audit_log_path":
+		c.AuditLogPath = ""
+	case "key_reload":
+		c.KeyReload = ""
+	case "channel":
+		c.Channel = ""
+	case "disable_update_check":
+		c.DisableUpdateCheck = false
+	case "update_notice_interval":
+		c.UpdateNoticeInterval = ""
+	case "update_base_url":
+		c.UpdateBaseURL = ""
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// List returns every set key with its value, scalar keys first and
+// installations entries sorted by login.
+func (c *Config) List() [][2]string {
+	var out [][2]string
+	for _, key := range Keys {
+		if v, ok, _ := c.Get(key); ok {
+			out = append(out, [2]string{key, v})
+		}
+	}
+	logins := make([]string, 0, len(c.Installations))
+	for login := range c.Installations {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	for _, login := range logins {
+		out = append(out, [2]string{installationsPrefix + login, formatID(c.Installations[login])})
+	}
+	return out
+}
+
+func parseID(key, value string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", key, value)
+	}
+	return id, nil
+}
+
+func parseBool(key, value string) (bool, error) {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", key, value)
+	}
+	return b, nil
+}
+
+func formatID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func unknownKey(key string) error {
+	return fmt.Errorf("unknown config key %q (valid keys: %s, installations.<login>)", key, strings.Join(Keys, ", "))
+}
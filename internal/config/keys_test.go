@@ -0,0 +1,111 @@
+package config
+
+import "testing"
+
+func TestConfigSetGetUnset(t *testing.T) {
+	var c Config
+
+	if err := c.Set("installations.Acme", "42"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := c.Get("installations.acme"); err != nil || !ok || v != "42" {
+		t.Errorf("Get(installations.acme) = %q, %v, %v; want 42, true, nil", v, ok, err)
+	}
+	if err := c.Unset("installations.ACME"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.Get("installations.acme"); ok {
+		t.Error("installations.acme should be unset")
+	}
+
+	if err := c.Set("private_key_path", " /keys//app.pem "); err != nil {
+		t.Fatal(err)
+	}
+	if c.PrivateKeyPath != "/keys/app.pem" {
+		t.Errorf("PrivateKeyPath = %q, want cleaned path", c.PrivateKeyPath)
+	}
+
+	if err := c.Set("installations.", "1"); err == nil {
+		t.Error("expected error for empty login")
+	}
+	if _, _, err := c.Get("nope"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestConfigChannelKey(t *testing.T) {
+	var c Config
+
+	if err := c.Set("channel", "prerelease"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := c.Get("channel"); !ok || v != ChannelPrerelease {
+		t.Errorf("Get(channel) = %q, %v", v, ok)
+	}
+	if err := c.Set("channel", "nightly"); err == nil {
+		t.Error("expected error for an unknown channel")
+	}
+	if err := c.Unset("channel"); err != nil || c.Channel != "" {
+		t.Errorf("Unset = %v, Channel = %q", err, c.Channel)
+	}
+}
+
+func TestConfigKeyReloadKey(t *testing.T) {
+	var c Config
+
+	if err := c.Set("key_reload", "once"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := c.Get("key_reload"); !ok || v != KeyReloadOnce {
+		t.Errorf("Get(key_reload) = %q, %v", v, ok)
+	}
+	if err := c.Set("key_reload", "never"); err == nil {
+		t.Error("expected error for an unknown key_reload")
+	}
+	if err := c.Unset("key_reload"); err != nil || c.KeyReload != "" {
+		t.Errorf("Unset = %v, KeyReload = %q", err, c.KeyReload)
+	}
+}
+
+func TestConfigUpdateNoticeIntervalKey(t *testing.T) {
+	var c Config
+
+	if err := c.Set("update_notice_interval", " 168h "); err != nil || c.UpdateNoticeInterval != "168h" {
+		t.Fatalf("Set = %v, UpdateNoticeInterval = %q", err, c.UpdateNoticeInterval)
+	}
+	for _, bad := range []string{"weekly", "0s", "-1h"} {
+		if err := c.Set("update_notice_interval", bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestConfigUpdateBaseURLKey(t *testing.T) {
+	var c Config
+
+	if err := c.Set("update_base_url", "https://ghe.example.com/api/v3/repos/tools/gha/releases"); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"ghe.example.com/releases", "ftp://mirror/releases", "https://"} {
+		if err := c.Set("update_base_url", bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestConfigStrictPermissionsKey(t *testing.T) {
+	var c Config
+
+	if err := c.Set("strict_permissions", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := c.Get("strict_permissions"); !ok || v != "true" || !c.StrictPermissions {
+		t.Errorf("Get(strict_permissions) = %q, %v", v, ok)
+	}
+	if err := c.Set("strict_permissions", "maybe"); err == nil {
+		t.Error("expected error for a non-boolean value")
+	}
+	if err := c.Unset("strict_permissions"); err != nil || c.StrictPermissions {
+		t.Errorf("Unset = %v, StrictPermissions = %v", err, c.StrictPermissions)
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+import "syscall"
+
+// stRDOnly is ST_RDONLY, the statfs flag of a read-only mount.
+const stRDOnly = 0x1
+
+// readOnlyMount reports whether path is on a filesystem mounted read-only,
+// as Docker and Kubernetes mount secrets.
+func readOnlyMount(path string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false
+	}
+	return st.Flags&stRDOnly != 0
+}
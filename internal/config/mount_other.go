@@ -0,0 +1,10 @@
+//go:build !linux
+
+package config
+
+// readOnlyMount reports whether path is on a filesystem mounted read-only.
+// Secrets are mounted that way in Linux containers only, so elsewhere it
+// always reports false.
+func readOnlyMount(string) bool {
+	return false
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package config
+
+import "os"
+
+// fileOwner is not available on Windows, where files are owned by security
+// identifiers rather than user IDs.
+func fileOwner(os.FileInfo) (int, bool) {
+	return 0, false
+}
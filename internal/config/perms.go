@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// PermissionProblems reports a private key file or config directory that
+// other users can access, the key problem first. It returns nil on Windows,
+// where mode bits do not reflect who can read a file.
+func (c *Config) PermissionProblems() []string {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var problems []string
+	if p := c.KeyProblem(); p != "" {
+		problems = append(problems, p)
+	}
+	// A $GHA_CONFIG file may live in a shared directory on purpose.
+	if c.dir != "" && os.Getenv(PathEnv) == "" {
+		if info, err := os.Stat(c.dir); err == nil && info.Mode().Perm()&0o077 != 0 {
+			problems = append(problems, fmt.Sprintf("config directory %s is accessible by other users (mode %04o); run chmod 700 %s",
+				c.dir, info.Mode().Perm(), c.dir))
+		}
+	}
+	return problems
+}
+
+// KeyProblem reports a private key file that other users can access or that
+// another user owns, the identity files ssh refuses to use, and is empty
+// when there is none. Like PermissionProblems, it is always empty on Windows.
+func (c *Config) KeyProblem() string {
+	path := c.KeyPath()
+	if runtime.GOOS == "windows" || path == "" {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	// Docker and Kubernetes mount secrets read-only, readable by the
+	// container's other users unless told otherwise; such a key is left to
+	// the orchestrator's permissions, as long as no one else can write it.
+	if perm := info.Mode().Perm(); perm&0o077 != 0 && (perm&0o022 != 0 || !readOnlyMount(path)) {
+		return fmt.Sprintf("private key %s is accessible by other users (mode %04o); run chmod 600 %s", path, perm, path)
+	}
+	if uid, ok := fileOwner(info); ok && uid != os.Getuid() && uid != 0 {
+		return fmt.Sprintf("private key %s is owned by another user (uid %d); copy it to a file of your own", path, uid)
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPermissionProblems(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not checked on Windows")
+	}
+	setupTestEnv(t)
+	if err := Save(&Config{AppID: 1, PrivateKeyPath: "app.pem"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.KeyPath(), []byte("pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if problems := cfg.PermissionProblems(); len(problems) != 0 {
+		t.Errorf("PermissionProblems = %q, want none", problems)
+	}
+
+	if err := os.Chmod(cfg.KeyPath(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(cfg.KeyPath())
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	problems := cfg.PermissionProblems()
+	if len(problems) != 2 || !strings.Contains(problems[0], "chmod 600") || !strings.Contains(problems[1], "chmod 700") {
+		t.Errorf("PermissionProblems = %q, want key and directory problems", problems)
+	}
+
+	// A directory chosen through $GHA_CONFIG is the user's business.
+	t.Setenv(PathEnv, filepath.Join(dir, configFile))
+	if problems := cfg.PermissionProblems(); len(problems) != 1 {
+		t.Errorf("PermissionProblems with %s = %q, want only the key", PathEnv, problems)
+	}
+}
+
+func TestKeyProblem_Owner(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("changing a file's owner needs root")
+	}
+	path := filepath.Join(t.TempDir(), "app.pem")
+	if err := os.WriteFile(path, []byte("pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{PrivateKeyPath: path}
+	if p := cfg.KeyProblem(); p != "" {
+		t.Errorf("KeyProblem = %q, want none", p)
+	}
+	if err := os.Chown(path, 4242, -1); err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.KeyProblem(); !strings.Contains(p, "owned by another user (uid 4242)") {
+		t.Errorf("KeyProblem = %q, want the owner reported", p)
+	}
+}
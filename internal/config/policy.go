@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Policy restricts the gh commands run through gha. Each rule is the start
+// of a gh command line, such as "repo delete", optionally followed by flags
+// the command must also carry for the rule to apply, as in "api -X DELETE".
+type Policy struct {
+	// Deny lists commands gha refuses to run.
+	Deny []string `yaml:"deny,omitempty"`
+
+	// Confirm lists commands gha runs only once confirmed at the terminal.
+	Confirm []string `yaml:"confirm,omitempty"`
+}
+
+// policyRuleRE matches a rule that starts with a gh command rather than a
+// flag.
+var policyRuleRE = regexp.MustCompile(`^\s*[^\s-]`)
+
+func (p Policy) validate() error {
+	for _, list := range []struct {
+		key   string
+		rules []string
+	}{{"policy.deny", p.Deny}, {"policy.confirm", p.Confirm}} {
+		key := list.key
+		for _, rule := range list.rules {
+			if strings.TrimSpace(rule) == "" {
+				return fmt.Errorf("%s: rules must not be empty", key)
+			}
+			if !policyRuleRE.MatchString(rule) {
+				return fmt.Errorf("%s: rule %q must start with a gh command, such as \"repo delete\"", key, rule)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the name of the per-directory configuration file.
+const ProjectFile = ".gha.yaml"
+
+// Project holds per-directory settings that override the global config for
+// commands run inside that directory tree.
+type Project struct {
+	App            string            `yaml:"app,omitempty"`
+	Org            string            `yaml:"org,omitempty"`
+	InstallationID int64             `yaml:"installation_id,omitempty"`
+	Permissions    map[string]string `yaml:"permissions,omitempty"`
+}
+
+var permissionLevels = map[string]bool{"read": true, "write": true, "admin": true}
+
+// ParsePermissions parses a comma-separated list of name:level pairs, such
+// as contents:read,issues:write, into the permissions of a token request.
+func ParsePermissions(s string) (map[string]string, error) {
+	perms := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, level, ok := strings.Cut(pair, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("permission %q must be name:level, such as contents:read", pair)
+		}
+		if !permissionLevels[level] {
+			return nil, fmt.Errorf("permission %s must be read, write or admin, got %q", name, level)
+		}
+		perms[name] = level
+	}
+	return perms, nil
+}
+
+// FindProject walks up from dir looking for a .gha.yaml file and loads the
+// nearest one. It returns a nil Project and an empty path when none exists.
+func FindProject(dir string) (*Project, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		p, err := loadProject(path)
+		if err == nil {
+			return p, path, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, "", nil
+		}
+		dir = parent
+	}
+}
+
+func loadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Project
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	if p.InstallationID < 0 {
+		return nil, fmt.Errorf("%s: installation_id must not be negative", path)
+	}
+	for name, level := range p.Permissions {
+		if !permissionLevels[level] {
+			return nil, fmt.Errorf("%s: permission %s must be read, write or admin, got %q", path, name, level)
+		}
+	}
+	return &p, nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProjectFile(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ProjectFile)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindProject_WalksUp(t *testing.T) {
+	root := t.TempDir()
+	want := writeProjectFile(t, root, "org: acme\ninstallation_id: 42\npermissions:\n  contents: read\n")
+	nested := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	p, path, err := FindProject(nested)
+	if err != nil {
+		t.Fatalf("FindProject: %v", err)
+	}
+	if path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	if p.Org != "acme" || p.InstallationID != 42 || p.Permissions["contents"] != "read" {
+		t.Errorf("project = %+v", p)
+	}
+}
+
+func TestFindProject_NearestWins(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFile(t, root, "org: outer\n")
+	inner := filepath.Join(root, "inner")
+	if err := os.Mkdir(inner, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeProjectFile(t, inner, "org: inner\n")
+
+	p, _, err := FindProject(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Org != "inner" {
+		t.Errorf("org = %q, want inner", p.Org)
+	}
+}
+
+func TestFindProject_None(t *testing.T) {
+	p, path, err := FindProject(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil || path != "" {
+		t.Errorf("FindProject = %+v, %q; want nil, empty", p, path)
+	}
+}
+
+func TestFindProject_Empty(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "")
+
+	p, _, err := FindProject(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil {
+		t.Fatal("empty .gha.yaml should yield an empty project")
+	}
+}
+
+func TestFindProject_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"unknown field", "organisation: acme\n", "parsing"},
+		{"negative installation_id", "installation_id: -1\n", "installation_id must not be negative"},
+		{"bad permission level", "permissions:\n  contents: all\n", "permission contents must be read, write or admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeProjectFile(t, dir, tt.yaml)
+
+			_, _, err := FindProject(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePermissions(t *testing.T) {
+	perms, err := ParsePermissions("contents:read, issues:write,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(perms) != 2 || perms["contents"] != "read" || perms["issues"] != "write" {
+		t.Errorf("ParsePermissions = %v", perms)
+	}
+	for _, bad := range []string{"contents", ":read", "contents:all"} {
+		if _, err := ParsePermissions(bad); err == nil {
+			t.Errorf("ParsePermissions(%q): expected an error", bad)
+		}
+	}
+}
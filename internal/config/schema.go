@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// schemaConstraints narrows the generated schema for keys whose values
+// validate restricts further than their Go type, by dotted key path with "*"
+// for map entries and array items.
+var schemaConstraints = map[string]map[string]any{
+	"app_id":                        {"minimum": 1},
+	"installation_id":               {"minimum": 0},
+	"target_type":                   {"enum": []string{"org", "user"}},
+	"min_gh_version":                {"pattern": versionRE.String()},
+	"channel":                       {"enum": []string{ChannelStable, ChannelPrerelease}},
+	"key_reload":                    {"enum": []string{KeyReloadAlways, KeyReloadOnce}},
+	"installations.*":               {"minimum": 1},
+	"hosts.*.app_id":                {"minimum": 1},
+	"hosts.*.installation_id":       {"minimum": 0},
+	"hosts.*.installations.*":       {"minimum": 1},
+	"apps":                          {"propertyNames": map[string]any{"pattern": appNameRE.String()}},
+	"apps.*.app_id":                 {"minimum": 1},
+	"apps.*.installation_id":        {"minimum": 0},
+	"apps.*.installations.*":        {"minimum": 1},
+	"aliases.*":                     {"minLength": 1},
+	"token_env.*":                   {"pattern": envNameRE.String()},
+	"scrub_env.*":                   {"pattern": envNameRE.String()},
+	"encryption.age_recipients":     {"minItems": 1},
+	"policy.deny.*":                 {"pattern": policyRuleRE.String()},
+	"policy.confirm.*":              {"pattern": policyRuleRE.String()},
+	"serve.clients.*.name":          {"pattern": serveClientNameRE.String()},
+	"serve.clients.*.token_sha256":  {"pattern": sha256HexRE.String()},
+	"serve.clients.*.permissions.*": {"enum": []string{"read", "write", "admin"}},
+}
+
+// Schema returns a JSON Schema describing the config file, generated from
+// the Config struct so it follows every key gha accepts.
+func Schema() ([]byte, error) {
+	s := schemaFor(reflect.TypeFor[Config](), "")
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "gha configuration"
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func schemaFor(t reflect.Type, path string) map[string]any {
+	var s map[string]any
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem(), path)
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if !f.IsExported() || name == "-" || name == "" {
+				continue
+			}
+			props[name] = schemaFor(f.Type, joinKey(path, name))
+		}
+		s = map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), joinKey(path, "*"))}
+	case reflect.Slice:
+		s = map[string]any{"type": "array", "items": schemaFor(t.Elem(), joinKey(path, "*"))}
+	case reflect.String:
+		s = map[string]any{"type": "string"}
+	case reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		s = map[string]any{"type": "integer"}
+	default:
+		panic(fmt.Sprintf("config: no schema for %s at %q", t, path))
+	}
+	for k, v := range schemaConstraints[path] {
+		s[k] = v
+	}
+	return s
+}
+
+func joinKey(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s map[string]any
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if s["additionalProperties"] != false {
+		t.Error("top level should reject unknown keys")
+	}
+	props := s["properties"].(map[string]any)
+	for _, key := range append(Keys, "installations", "hosts", "apps", "routes", "aliases", "hooks", "token_env", "scrub_env", "encryption", "policy", "serve") {
+		if _, ok := props[key]; !ok {
+			t.Errorf("schema has no property %q", key)
+		}
+	}
+	for _, hidden := range []string{"PrivateKey", "private_key", "-"} {
+		if _, ok := props[hidden]; ok {
+			t.Errorf("schema exposes %q", hidden)
+		}
+	}
+	target := props["target_type"].(map[string]any)
+	if enum, _ := json.Marshal(target["enum"]); string(enum) != `["org","user"]` {
+		t.Errorf("target_type enum = %s", enum)
+	}
+}
+
+// Every constraint must land on a key that exists, so renaming a field
+// cannot silently drop one.
+func TestSchemaConstraintsMatchFields(t *testing.T) {
+	data, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, constraint := range schemaConstraints {
+		node := root
+		for _, part := range strings.Split(path, ".") {
+			var next any
+			switch {
+			case part != "*":
+				props, _ := node["properties"].(map[string]any)
+				next = props[part]
+			case node["type"] == "array":
+				next = node["items"]
+			default:
+				next = node["additionalProperties"]
+			}
+			var ok bool
+			if node, ok = next.(map[string]any); !ok {
+				t.Errorf("constraint %q: no such key", path)
+				break
+			}
+		}
+		for k := range constraint {
+			if node != nil && node[k] == nil {
+				t.Errorf("constraint %q: %s not applied", path, k)
+			}
+		}
+	}
+}
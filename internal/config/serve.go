@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Serve configures gha serve: TLS, and the clients allowed to request
+// tokens with what each may ask for.
+type Serve struct {
+	// TLSCert and TLSKey are the PEM files of the server's certificate and
+	// key; with them, gha serve speaks HTTPS.
+	TLSCert string `yaml:"tls_cert,omitempty"`
+	TLSKey  string `yaml:"tls_key,omitempty"`
+
+	// ClientCA is a PEM file of the CA certificates client certificates
+	// are verified against, for clients identified by common_name.
+	ClientCA string `yaml:"client_ca,omitempty"`
+
+	// Clients lists the callers allowed to request tokens. Without any,
+	// anyone who can reach the server may.
+	Clients []ServeClient `yaml:"clients,omitempty"`
+}
+
+// ServeClient is a caller of gha serve, identified by a bearer token or a
+// client certificate, and what it may request.
+type ServeClient struct {
+	Name string `yaml:"name"`
+
+	// TokenSHA256 is the hex SHA-256 digest of the bearer token the client
+	// authenticates with, so the token itself is never stored.
+	TokenSHA256 string `yaml:"token_sha256,omitempty"`
+
+	// CommonName is the subject common name of the client certificate the
+	// client authenticates with, verified against client_ca.
+	CommonName string `yaml:"common_name,omitempty"`
+
+	// Orgs lists the accounts, as patterns such as acme-*, whose
+	// installations the client may get tokens for. Empty allows any.
+	Orgs []string `yaml:"orgs,omitempty"`
+
+	// Permissions are the most the client may request, and what its tokens
+	// get when it requests none. Empty allows anything the App has.
+	Permissions map[string]string `yaml:"permissions,omitempty"`
+}
+
+// sha256HexRE matches a hex SHA-256 digest.
+var sha256HexRE = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// serveClientNameRE matches the names clients may have.
+var serveClientNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+func (s Serve) validate() error {
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return fmt.Errorf("serve: set both tls_cert and tls_key, or neither")
+	}
+	if s.ClientCA != "" && s.TLSCert == "" {
+		return fmt.Errorf("serve.client_ca needs tls_cert and tls_key: client certificates are only sent over TLS")
+	}
+	names := map[string]bool{}
+	for i, c := range s.Clients {
+		key := fmt.Sprintf("serve.clients[%d]", i)
+		if !serveClientNameRE.MatchString(c.Name) {
+			return fmt.Errorf("%s.name must be letters, digits, '.', '_' or '-', got %q", key, c.Name)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("%s: duplicate client name %q", key, c.Name)
+		}
+		names[c.Name] = true
+		if c.TokenSHA256 == "" && c.CommonName == "" {
+			return fmt.Errorf("%s (%s): set token_sha256, common_name or both", key, c.Name)
+		}
+		if c.TokenSHA256 != "" && !sha256HexRE.MatchString(c.TokenSHA256) {
+			return fmt.Errorf("%s (%s): token_sha256 must be a hex SHA-256 digest", key, c.Name)
+		}
+		if c.CommonName != "" && s.ClientCA == "" {
+			return fmt.Errorf("%s (%s): common_name needs serve.client_ca to verify certificates against", key, c.Name)
+		}
+		for _, org := range c.Orgs {
+			if _, err := path.Match(strings.ToLower(org), ""); err != nil || org == "" {
+				return fmt.Errorf("%s (%s): invalid org pattern %q", key, c.Name, org)
+			}
+		}
+		for name, level := range c.Permissions {
+			if !permissionLevels[level] {
+				return fmt.Errorf("%s (%s): permission %s must be read, write or admin, got %q", key, c.Name, name, level)
+			}
+		}
+	}
+	return nil
+}
+
+// AllowsOrg reports whether c may get tokens for installations on the
+// account login.
+func (c ServeClient) AllowsOrg(login string) bool {
+	if len(c.Orgs) == 0 {
+		return true
+	}
+	for _, pattern := range c.Orgs {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(login)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionRank orders the permission levels.
+var permissionRank = map[string]int{"read": 1, "write": 2, "admin": 3}
+
+// NarrowPermissions returns the permissions c's token is minted with for a
+// request of requested: requested itself when c may have all of it, or c's
+// own permissions when it requests none. An error names the first
+// permission c may not have.
+func (c ServeClient) NarrowPermissions(requested map[string]string) (map[string]string, error) {
+	if len(c.Permissions) == 0 {
+		return requested, nil
+	}
+	if len(requested) == 0 {
+		return c.Permissions, nil
+	}
+	for name, level := range requested {
+		if permissionRank[level] > permissionRank[c.Permissions[name]] {
+			if c.Permissions[name] == "" {
+				return nil, fmt.Errorf("client %s may not request %s", c.Name, name)
+			}
+			return nil, fmt.Errorf("client %s may request %s:%s at most, not %s", c.Name, name, c.Permissions[name], level)
+		}
+	}
+	return requested, nil
+}
@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestServeClient(t *testing.T) {
+	c := ServeClient{Name: "ci", Orgs: []string{"acme-*"}, Permissions: map[string]string{"contents": "write", "issues": "read"}}
+
+	if !c.AllowsOrg("ACME-web") || c.AllowsOrg("other") {
+		t.Error("AllowsOrg does not follow the orgs patterns")
+	}
+	if !(ServeClient{}).AllowsOrg("anyone") {
+		t.Error("a client without orgs should be allowed any")
+	}
+
+	got, err := c.NarrowPermissions(map[string]string{"contents": "read"})
+	if err != nil || len(got) != 1 || got["contents"] != "read" {
+		t.Errorf("NarrowPermissions(contents:read) = %v, %v", got, err)
+	}
+	if got, err := c.NarrowPermissions(nil); err != nil || len(got) != 2 {
+		t.Errorf("NarrowPermissions(nil) = %v, %v; want the client's own", got, err)
+	}
+	for _, req := range []map[string]string{{"issues": "write"}, {"actions": "read"}} {
+		if _, err := c.NarrowPermissions(req); err == nil {
+			t.Errorf("NarrowPermissions(%v): expected an error", req)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+//go:build aix || solaris
+
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// fcntl locks belong to the process rather than the open file, so they do
+// not keep two goroutines apart. held does that, by lock file path.
+var (
+	heldMu sync.Mutex
+	held   = map[string]bool{}
+)
+
+func tryLock(f *os.File) error {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	if held[f.Name()] {
+		return errLocked
+	}
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK}
+	err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EACCES) {
+		return errLocked
+	}
+	if err == nil {
+		held[f.Name()] = true
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	heldMu.Lock()
+	defer heldMu.Unlock()
+	delete(held, f.Name())
+	lk := syscall.Flock_t{Type: syscall.F_UNLCK}
+	return syscall.FcntlFlock(f.Fd(), syscall.F_SETLK, &lk)
+}
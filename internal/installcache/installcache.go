@@ -0,0 +1,156 @@
+// Package installcache remembers which installation belongs to which account
+// login, so resolving --org does not list every installation on each run.
+package installcache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
+)
+
+const (
+	cacheFile = "installations-cache.json"
+	ttl       = 24 * time.Hour
+)
+
+// Mapping associates an account login with its installation.
+type Mapping struct {
+	Login      string
+	TargetType string // "Organization" or "User", as reported by GitHub
+	ID         int64
+}
+
+type entry struct {
+	ID         int64     `json:"id"`
+	TargetType string    `json:"target_type,omitempty"`
+	CachedAt   time.Time `json:"cached_at"`
+}
+
+// Lookup returns the cached installation ID for login if a fresh entry exists.
+// A non-empty targetType only matches entries recorded with that type.
+func Lookup(dir, login, targetType string) (int64, bool) {
+	entries := read(dir)
+	e, ok := entries[key(login)]
+	if !ok || time.Since(e.CachedAt) >= ttl {
+		return 0, false
+	}
+	if targetType != "" && e.TargetType != targetType {
+		return 0, false
+	}
+	return e.ID, true
+}
+
+// Store records installation mappings by login, replacing existing entries.
+func Store(dir string, mappings []Mapping) {
+	unlock, ok := lock(dir)
+	if !ok {
+		return
+	}
+	defer unlock()
+	entries := read(dir)
+	now := time.Now()
+	for _, m := range mappings {
+		entries[key(m.Login)] = entry{ID: m.ID, TargetType: m.TargetType, CachedAt: now}
+	}
+	write(dir, entries)
+}
+
+// All returns every fresh mapping, sorted by login. Logins are lowercased.
+func All(dir string) []Mapping {
+	var mappings []Mapping
+	for login, e := range read(dir) {
+		if time.Since(e.CachedAt) < ttl {
+			mappings = append(mappings, Mapping{Login: login, TargetType: e.TargetType, ID: e.ID})
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Login < mappings[j].Login })
+	return mappings
+}
+
+// Entry is a cached mapping with when it was cached and when it expires.
+type Entry struct {
+	Mapping
+	CachedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Entries returns every entry, expired or not, sorted by login. Logins are
+// lowercased.
+func Entries(dir string) []Entry {
+	var entries []Entry
+	for login, e := range read(dir) {
+		entries = append(entries, Entry{
+			Mapping:   Mapping{Login: login, TargetType: e.TargetType, ID: e.ID},
+			CachedAt:  e.CachedAt,
+			ExpiresAt: e.CachedAt.Add(ttl),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Login < entries[j].Login })
+	return entries
+}
+
+// Forget removes every entry pointing at installationID and reports whether
+// any was removed. Callers use it when a cached ID turns out to be stale.
+func Forget(dir string, installationID int64) bool {
+	unlock, ok := lock(dir)
+	if !ok {
+		return false
+	}
+	defer unlock()
+	entries := read(dir)
+	removed := false
+	for login, e := range entries {
+		if e.ID == installationID {
+			delete(entries, login)
+			removed = true
+		}
+	}
+	if removed {
+		write(dir, entries)
+	}
+	return removed
+}
+
+// Path returns the cache file location inside dir.
+func Path(dir string) string {
+	return filepath.Join(dir, cacheFile)
+}
+
+func key(login string) string {
+	return strings.ToLower(login)
+}
+
+// lock takes the cache file's lock, creating dir first. The cache is best
+// effort, so callers skip their update when this fails.
+func lock(dir string) (unlock func(), ok bool) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, false
+	}
+	unlock, err := fsutil.Lock(Path(dir))
+	return unlock, err == nil
+}
+
+func read(dir string) map[string]entry {
+	entries := map[string]entry{}
+	data, err := os.ReadFile(Path(dir))
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return map[string]entry{}
+	}
+	return entries
+}
+
+func write(dir string, entries map[string]entry) {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	_ = fsutil.WriteFile(Path(dir), data, 0o600)
+}
@@ -0,0 +1,128 @@
+package installcache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreAndLookup(t *testing.T) {
+	dir := t.TempDir()
+
+	Store(dir, []Mapping{{Login: "Acme", ID: 11}, {Login: "other", ID: 22}})
+
+	if id, ok := Lookup(dir, "acme", ""); !ok || id != 11 {
+		t.Errorf("Lookup(acme) = %d, %v; want 11, true (case-insensitive)", id, ok)
+	}
+	if id, ok := Lookup(dir, "OTHER", ""); !ok || id != 22 {
+		t.Errorf("Lookup(OTHER) = %d, %v; want 22, true", id, ok)
+	}
+	if _, ok := Lookup(dir, "missing", ""); ok {
+		t.Error("Lookup(missing) should miss")
+	}
+}
+
+func TestAll(t *testing.T) {
+	dir := t.TempDir()
+	Store(dir, []Mapping{{Login: "Other", ID: 22}, {Login: "acme", TargetType: "Organization", ID: 11}})
+
+	got := All(dir)
+	want := []Mapping{{Login: "acme", TargetType: "Organization", ID: 11}, {Login: "other", ID: 22}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("All = %+v, want %+v", got, want)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	dir := t.TempDir()
+	cachedAt := time.Now().Add(-25 * time.Hour).UTC().Truncate(time.Second)
+	stale := map[string]entry{"acme": {ID: 11, TargetType: "Organization", CachedAt: cachedAt}}
+	data, _ := json.Marshal(stale)
+	if err := os.WriteFile(filepath.Join(dir, cacheFile), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := Entries(dir)
+	if len(got) != 1 || got[0].Mapping != (Mapping{Login: "acme", TargetType: "Organization", ID: 11}) {
+		t.Fatalf("Entries = %+v, want the expired acme entry", got)
+	}
+	if !got[0].CachedAt.Equal(cachedAt) || !got[0].ExpiresAt.Equal(cachedAt.Add(ttl)) {
+		t.Errorf("CachedAt, ExpiresAt = %v, %v; want %v and a day later", got[0].CachedAt, got[0].ExpiresAt, cachedAt)
+	}
+}
+
+func TestLookup_TargetType(t *testing.T) {
+	dir := t.TempDir()
+	Store(dir, []Mapping{{Login: "acme", TargetType: "Organization", ID: 11}})
+
+	if id, ok := Lookup(dir, "acme", "Organization"); !ok || id != 11 {
+		t.Errorf("Lookup(acme, Organization) = %d, %v; want 11, true", id, ok)
+	}
+	if _, ok := Lookup(dir, "acme", "User"); ok {
+		t.Error("Lookup(acme, User) should miss for an organization entry")
+	}
+}
+
+func TestLookup_Expired(t *testing.T) {
+	dir := t.TempDir()
+	stale := map[string]entry{"acme": {ID: 11, CachedAt: time.Now().Add(-25 * time.Hour)}}
+	data, _ := json.Marshal(stale)
+	if err := os.WriteFile(filepath.Join(dir, cacheFile), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Lookup(dir, "acme", ""); ok {
+		t.Error("expired entry should miss")
+	}
+}
+
+func TestLookup_CorruptCache(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, cacheFile), []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Lookup(dir, "acme", ""); ok {
+		t.Error("corrupt cache should miss")
+	}
+	Store(dir, []Mapping{{Login: "acme", ID: 1}})
+	if _, ok := Lookup(dir, "acme", ""); !ok {
+		t.Error("Store should replace a corrupt cache")
+	}
+}
+
+func TestForget(t *testing.T) {
+	dir := t.TempDir()
+	Store(dir, []Mapping{{Login: "acme", ID: 11}, {Login: "acme-alias", ID: 11}, {Login: "other", ID: 22}})
+
+	if !Forget(dir, 11) {
+		t.Error("Forget(11) = false, want true")
+	}
+	if _, ok := Lookup(dir, "acme", ""); ok {
+		t.Error("acme should be forgotten")
+	}
+	if _, ok := Lookup(dir, "acme-alias", ""); ok {
+		t.Error("acme-alias should be forgotten")
+	}
+	if _, ok := Lookup(dir, "other", ""); !ok {
+		t.Error("other should be kept")
+	}
+	if Forget(dir, 99) {
+		t.Error("Forget(99) = true, want false for unknown ID")
+	}
+}
+
+func TestStore_FilePermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	Store(dir, []Mapping{{Login: "acme", ID: 1}})
+
+	info, err := os.Stat(filepath.Join(dir, cacheFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.PathSeparator == '/' && info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %o, want 600", info.Mode().Perm())
+	}
+}
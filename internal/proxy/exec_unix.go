@@ -3,21 +3,28 @@
 package proxy
 
 import (
+	"os"
+	"os/exec"
 	"syscall"
 )
 
-// Exec replaces the current process with gh, injecting the token via GH_TOKEN.
-// Does not return on success.
-func Exec(args []string, token string) error {
-	if err := validateToken(token); err != nil {
-		return err
-	}
+// forwardedSignals are the signals runPath hands on to the child.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
 
-	ghPath, err := resolveGh()
-	if err != nil {
-		return err
-	}
+// execPath replaces the current process with the program at path, injecting
+// the token via GH_TOKEN. Does not return on success.
+func execPath(path string, args []string, token string, o options) error {
+	env := buildEnv(token, o)
+	return syscall.Exec(path, append([]string{path}, args...), env)
+}
 
-	env := buildEnv(token)
-	return syscall.Exec(ghPath, append([]string{ghPath}, args...), env)
+func prepareCommand(cmd *exec.Cmd) {}
+
+func forwardSignal(p *os.Process, sig os.Signal) {
+	if sig == os.Interrupt {
+		// Ctrl+C reaches the child directly through the terminal's process
+		// group; sending it again would interrupt twice.
+		return
+	}
+	_ = p.Signal(sig)
 }
@@ -5,29 +5,41 @@ package proxy
 import (
 	"os"
 	"os/exec"
+	"syscall"
 )
 
-// Exec runs gh as a child process on Windows (no syscall.Exec available).
-// Forwards stdin/stdout/stderr and exits with gh's exit code.
-func Exec(args []string, token string) error {
-	if err := validateToken(token); err != nil {
-		return err
-	}
+// forwardedSignals are the signals runPath hands on to the child. Go reports
+// Ctrl+C and Ctrl+Break as os.Interrupt, and closing the console, logging off
+// or shutting down as SIGTERM.
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
 
-	ghPath, err := resolveGh()
+var procGenerateConsoleCtrlEvent = syscall.NewLazyDLL("kernel32.dll").NewProc("GenerateConsoleCtrlEvent")
+
+// execPath runs the program at path as a child process on Windows (no
+// syscall.Exec available) and returns an *ExitError when it fails, so the
+// caller can exit with the child's code.
+func execPath(path string, args []string, token string, o options) error {
+	code, err := runPath(path, args, token, o)
 	if err != nil {
 		return err
 	}
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
 
-	cmd := exec.Command(ghPath, args...)
-	cmd.Env = buildEnv(token)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+// prepareCommand starts the child in its own process group, so console
+// events reach gha alone and forwardSignal decides what the child sees.
+func prepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
 
-	if err := cmd.Run(); err != nil {
-		os.Exit(cmd.ProcessState.ExitCode())
+// forwardSignal sends the child a Ctrl+Break, which Go programs such as gh
+// handle like Ctrl+C. A child without a console cannot receive it and is
+// killed instead.
+func forwardSignal(p *os.Process, sig os.Signal) {
+	if r, _, _ := procGenerateConsoleCtrlEvent.Call(syscall.CTRL_BREAK_EVENT, uintptr(p.Pid)); r == 0 {
+		_ = p.Kill()
 	}
-	os.Exit(0)
-	return nil
 }
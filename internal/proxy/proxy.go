@@ -1,57 +1,301 @@
 package proxy
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
 )
 
 var errEmptyToken = fmt.Errorf("token must not be empty")
 
+// ErrGhNotFound is returned when there is no gh in PATH to run.
+var ErrGhNotFound = errors.New("gh CLI not found in PATH - install it from https://cli.github.com")
+
 // GhBinary is the name of the gh CLI binary to look up in PATH.
 const GhBinary = "gh"
 
 func resolveGh() (string, error) {
 	p, err := exec.LookPath(GhBinary)
 	if err != nil {
-		return "", fmt.Errorf("gh CLI not found in PATH - install it from https://cli.github.com: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrGhNotFound, err)
 	}
 	return p, nil
 }
 
-func buildEnv(token string) []string {
-	env := filterEnv(os.Environ(), "GH_TOKEN", "GITHUB_TOKEN")
-	return append(env, "GH_TOKEN="+token)
+// LookGh returns the path of the gh binary Exec and Run would use.
+func LookGh() (string, error) {
+	return resolveGh()
 }
 
-func validateToken(token string) error {
-	if strings.TrimSpace(token) == "" {
-		return errEmptyToken
+// GhVersion runs the gh at path with --version and returns the version it
+// reports, such as "2.40.1".
+func GhVersion(path string) (string, error) {
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("running %s --version: %w", path, err)
 	}
-	return nil
+	// gh version 2.40.1 (2023-12-13)
+	fields := strings.Fields(string(out))
+	if len(fields) < 3 || fields[0] != "gh" || fields[1] != "version" {
+		return "", fmt.Errorf("unexpected output from %s --version: %q", path, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimPrefix(fields[2], "v"), nil
+}
+
+// ExitError reports that a program gha ran as a child exited with a
+// non-zero code, which gha passes on as its own exit code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Exec replaces the current process with gh, injecting the token via GH_TOKEN.
+// On Windows gh runs as a child process instead: Exec returns nil when it
+// succeeds and an *ExitError carrying its exit code when it fails. Elsewhere
+// Exec does not return on success.
+func Exec(args []string, token string, opts ...Option) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+
+	ghPath, err := resolveGh()
+	if err != nil {
+		return err
+	}
+	return execPath(ghPath, args, token, buildOpts(opts))
+}
+
+// ExecCommand is Exec for an arbitrary program, looked up in PATH.
+func ExecCommand(name string, args []string, token string, opts ...Option) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("%s not found in PATH: %w", name, err)
+	}
+	return execPath(path, args, token, buildOpts(opts))
+}
+
+type options struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	tokenEnv, scrubEnv []string
+	host               string
+	env                []string
+}
+
+// Option configures how a program is run. Exec and ExecCommand honour the
+// options that shape the environment: WithTokenEnv, WithScrubEnv, WithHost
+// and WithEnv. Where they replace gha's process, the program keeps gha's
+// standard streams, so WithStdin, WithStdout and WithStderr have no effect.
+type Option func(*options)
+
+// WithTokenEnv also passes the token in each of the named variables.
+func WithTokenEnv(names ...string) Option {
+	return func(o *options) { o.tokenEnv = append(o.tokenEnv, names...) }
+}
+
+// WithEnv adds NAME=value entries to the program's environment, replacing
+// any inherited value.
+func WithEnv(vars ...string) Option {
+	return func(o *options) { o.env = append(o.env, vars...) }
+}
+
+// WithHost names the GitHub host the token is for. gh reads tokens for
+// GitHub Enterprise Server hosts from GH_ENTERPRISE_TOKEN, so it is set too.
+func WithHost(host string) Option {
+	return func(o *options) { o.host = host }
+}
+
+// IsEnterprise reports whether gh treats host as a GitHub Enterprise Server
+// host, reading its token from GH_ENTERPRISE_TOKEN rather than GH_TOKEN.
+func IsEnterprise(host string) bool {
+	host = strings.ToLower(host)
+	return host != "" && host != "github.com" && host != "ghe.com" && !strings.HasSuffix(host, ".ghe.com")
+}
+
+// WithScrubEnv removes the named variables from the program's environment.
+func WithScrubEnv(names ...string) Option {
+	return func(o *options) { o.scrubEnv = append(o.scrubEnv, names...) }
+}
+
+// WithStdin reads the child's standard input from r instead of gha's. A nil
+// r gives it the null device.
+func WithStdin(r io.Reader) Option {
+	return func(o *options) { o.stdin = r }
+}
+
+// WithStdout streams the child's standard output to w instead of gha's.
+func WithStdout(w io.Writer) Option {
+	return func(o *options) { o.stdout = w }
+}
+
+// WithStderr streams the child's standard error to w instead of gha's.
+func WithStderr(w io.Writer) Option {
+	return func(o *options) { o.stderr = w }
+}
+
+func buildOpts(opts []Option) options {
+	o := options{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return o
 }
 
-// RunCapture runs gh as a child process and returns combined output.
-// Intended for testing; production code uses Exec.
-func RunCapture(args []string, token string) (string, error) {
+// Run runs gh as a child process with the token in GH_TOKEN, attached to
+// gha's standard streams unless opts redirect them, and returns gh's exit
+// code. Output is streamed as gh writes it. Unlike Exec it returns, so gha
+// can act once gh has finished.
+func Run(args []string, token string, opts ...Option) (int, error) {
 	if err := validateToken(token); err != nil {
-		return "", err
+		return 0, err
 	}
 
 	ghPath, err := resolveGh()
 	if err != nil {
-		return "", err
+		return 0, err
+	}
+	return runPath(ghPath, args, token, buildOpts(opts))
+}
+
+// RunCommand is Run for an arbitrary program, looked up in PATH.
+func RunCommand(name string, args []string, token string, opts ...Option) (int, error) {
+	if err := validateToken(token); err != nil {
+		return 0, err
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return 0, fmt.Errorf("%s not found in PATH: %w", name, err)
+	}
+	return runPath(path, args, token, buildOpts(opts))
+}
+
+func runPath(path string, args []string, token string, o options) (int, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Env = buildEnv(token, o)
+	cmd.Stdin = o.stdin
+	cmd.Stdout = o.stdout
+	cmd.Stderr = o.stderr
+	prepareCommand(cmd)
+
+	// Rather than dying first and leaving the child orphaned, hand signals
+	// on to it and let it decide how to exit.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("running %s: %w", path, err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				forwardSignal(cmd.Process, sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitCode(exitErr), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("running %s: %w", path, err)
+	}
+	return 0, nil
+}
+
+// exitCode returns the code a shell would report for the finished child:
+// its exit code, or 128 plus the signal number when a signal killed it.
+func exitCode(err *exec.ExitError) int {
+	if ws, ok := err.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	if code := err.ExitCode(); code != 0 {
+		return code
+	}
+	return 1
+}
+
+// credentialEnv are the variables that configure gha with the App's own
+// credentials. No child gets them: with the key, it could sign JWTs as the
+// App.
+var credentialEnv = []string{config.AppIDEnv, config.PrivateKeyEnv, config.PrivateKeyPathEnv}
+
+// WithoutCredentials returns env without the variables holding the App's
+// credentials, for any child gha runs.
+func WithoutCredentials(env []string) []string {
+	return filterEnv(env, credentialEnv...)
+}
+
+// buildEnv returns gha's environment without any token gh would read, the
+// App's credentials or the scrubbed variables, and with the token in GH_TOKEN (plus
+// GH_ENTERPRISE_TOKEN for an enterprise host) and every extra token variable.
+func buildEnv(token string, o options) []string {
+	tokenEnv := []string{"GH_TOKEN"}
+	if IsEnterprise(o.host) {
+		tokenEnv = append(tokenEnv, "GH_ENTERPRISE_TOKEN")
+	}
+	tokenEnv = append(tokenEnv, o.tokenEnv...)
+	drop := append([]string{"GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}, o.scrubEnv...)
+	drop = append(drop, credentialEnv...)
+	drop = append(drop, tokenEnv...)
+	for _, kv := range o.env {
+		name, _, _ := strings.Cut(kv, "=")
+		drop = append(drop, name)
+	}
+	env := filterEnv(os.Environ(), drop...)
+	for _, name := range tokenEnv {
+		env = append(env, name+"="+token)
 	}
+	return append(env, o.env...)
+}
 
-	cmd := exec.Command(ghPath, args...)
-	cmd.Env = buildEnv(token)
+func validateToken(token string) error {
+	if strings.TrimSpace(token) == "" {
+		return errEmptyToken
+	}
+	return nil
+}
 
-	out, err := cmd.CombinedOutput()
+// RunCapture runs gh as a child process and returns its standard output
+// and standard error interleaved. gh reads gha's standard input unless
+// WithStdin says otherwise, so commands such as --body-file - work. Intended
+// for testing; use Run with WithStdout and WithStderr to keep the streams
+// apart.
+func RunCapture(args []string, token string, opts ...Option) (string, error) {
+	var out bytes.Buffer
+	opts = append(opts, WithStdout(&out), WithStderr(&out))
+	code, err := Run(args, token, opts...)
 	if err != nil {
-		return string(out), fmt.Errorf("gh %s: %w", strings.Join(args, " "), err)
+		return out.String(), err
+	}
+	if code != 0 {
+		return out.String(), fmt.Errorf("gh %s: %w", strings.Join(args, " "), &ExitError{Code: code})
 	}
-	return string(out), nil
+	return out.String(), nil
 }
 
 func filterEnv(env []string, keys ...string) []string {
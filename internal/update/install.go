@@ -0,0 +1,96 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// InstallMethod is how the gha binary was installed, which decides how it
+// should be upgraded.
+type InstallMethod int
+
+const (
+	// InstallManual is a binary downloaded from GitHub Releases, which gha
+	// self-update can replace.
+	InstallManual InstallMethod = iota
+	InstallHomebrew
+	InstallGo
+	InstallScoop
+	InstallChocolatey
+	InstallDeb
+)
+
+const releasesPage = "https://github.com/haribote-lab/github-app-cli/releases"
+
+// DetectInstall guesses how the executable at exe was installed from where
+// it lives.
+func DetectInstall(exe string) InstallMethod {
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	slashed := filepath.ToSlash(exe)
+	lower := strings.ToLower(slashed)
+	switch {
+	case isHomebrew(slashed):
+		return InstallHomebrew
+	case strings.Contains(lower, "/scoop/apps/"):
+		return InstallScoop
+	case strings.Contains(lower, "/chocolatey/"):
+		return InstallChocolatey
+	case inGoBin(filepath.Dir(exe)):
+		return InstallGo
+	case runtime.GOOS == "linux" && filepath.Dir(exe) == "/usr/bin":
+		return InstallDeb
+	}
+	return InstallManual
+}
+
+// UpgradeCommand returns the command that upgrades a gha installed with m,
+// or "" for a .deb package, which is upgraded by installing the new one.
+func (m InstallMethod) UpgradeCommand() string {
+	switch m {
+	case InstallHomebrew:
+		return "brew upgrade gha"
+	case InstallGo:
+		return "go install github.com/haribote-lab/github-app-cli@latest"
+	case InstallScoop:
+		return "scoop update gha"
+	case InstallChocolatey:
+		return "choco upgrade gha"
+	case InstallDeb:
+		return ""
+	}
+	return "gha self-update"
+}
+
+func isHomebrew(exe string) bool {
+	return strings.Contains(exe, "/Cellar/") || strings.Contains(exe, "/homebrew/") || strings.Contains(exe, "/linuxbrew/")
+}
+
+// inGoBin reports whether dir is where go install puts binaries: GOBIN, or
+// the bin directory of a GOPATH entry (by default ~/go).
+func inGoBin(dir string) bool {
+	var bins []string
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		bins = append(bins, gobin)
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			gopath = filepath.Join(home, "go")
+		}
+	}
+	for _, p := range filepath.SplitList(gopath) {
+		if p != "" {
+			bins = append(bins, filepath.Join(p, "bin"))
+		}
+	}
+	for _, bin := range bins {
+		if filepath.Clean(bin) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
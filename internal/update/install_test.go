@@ -0,0 +1,37 @@
+package update
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDetectInstall(t *testing.T) {
+	gopath := t.TempDir()
+	t.Setenv("GOBIN", "")
+	t.Setenv("GOPATH", gopath)
+
+	tests := map[string]InstallMethod{
+		"/opt/homebrew/bin/gha":                      InstallHomebrew,
+		"/usr/local/Cellar/gha/1.0.0/bin/gha":        InstallHomebrew,
+		"/home/linuxbrew/.linuxbrew/bin/gha":         InstallHomebrew,
+		"C:/Users/me/scoop/apps/gha/current/gha.exe": InstallScoop,
+		"C:/ProgramData/chocolatey/lib/gha/gha.exe":  InstallChocolatey,
+		filepath.Join(gopath, "bin", "gha"):          InstallGo,
+		"/usr/local/bin/gha":                         InstallManual,
+	}
+	if runtime.GOOS == "linux" {
+		tests["/usr/bin/gha"] = InstallDeb
+	}
+	for path, want := range tests {
+		if got := DetectInstall(filepath.FromSlash(path)); got != want {
+			t.Errorf("DetectInstall(%q) = %d, want %d", path, got, want)
+		}
+	}
+
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	if got := DetectInstall(filepath.Join(gobin, "gha")); got != InstallGo {
+		t.Errorf("GOBIN: DetectInstall = %d, want InstallGo", got)
+	}
+}
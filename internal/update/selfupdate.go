@@ -0,0 +1,216 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	releaseTimeout  = 30 * time.Second
+	downloadTimeout = 5 * time.Minute
+	maxDownload     = 200 << 20
+	projectName     = "gha"
+)
+
+// Release is a published release and its downloadable assets.
+type Release struct {
+	Version string
+	Assets  map[string]string // asset name → download URL
+}
+
+// LatestRelease fetches the latest release on the configured channel.
+func LatestRelease(opts ...Option) (*Release, error) {
+	resp, _, err := fetchRelease(buildOpts(opts), releaseTimeout, validators{})
+	if err != nil {
+		return nil, fmt.Errorf("fetching the latest release: %w", err)
+	}
+	r := &Release{Version: resp.version(), Assets: map[string]string{}}
+	for _, a := range resp.Assets {
+		r.Assets[a.Name] = a.URL
+	}
+	return r, nil
+}
+
+// archiveName returns the release archive holding the binary for goos and
+// goarch, following the naming in .goreleaser.yml.
+func archiveName(version, goos, goarch string) string {
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("%s_%s_%s_%s%s", projectName, version, goos, goarch, ext)
+}
+
+func checksumsName(version string) string {
+	return fmt.Sprintf("%s_%s_checksums.txt", projectName, version)
+}
+
+// SelfUpdate replaces the executable at exe with the binary for the running
+// platform from r, after checking it against the release's checksums and
+// their signature. Unsigned releases are refused.
+func SelfUpdate(r *Release, exe string) error {
+	if publicKey == "" {
+		return errNoPublicKey
+	}
+	archive := archiveName(r.Version, runtime.GOOS, runtime.GOARCH)
+	archiveURL, ok := r.Assets[archive]
+	if !ok {
+		return fmt.Errorf("release v%s has no %s", r.Version, archive)
+	}
+	sumsURL, ok := r.Assets[checksumsName(r.Version)]
+	if !ok {
+		return fmt.Errorf("release v%s has no checksums file", r.Version)
+	}
+	sigURL, ok := r.Assets[signatureName(r.Version)]
+	if !ok {
+		return fmt.Errorf("release v%s is not signed", r.Version)
+	}
+
+	sums, err := download(sumsURL, maxResponse, downloadTimeout)
+	if err != nil {
+		return fmt.Errorf("downloading checksums: %w", err)
+	}
+	sig, err := download(sigURL, maxResponse, downloadTimeout)
+	if err != nil {
+		return fmt.Errorf("downloading the checksums signature: %w", err)
+	}
+	if err := verifySignature(publicKey, sums, sig); err != nil {
+		return err
+	}
+	data, err := download(archiveURL, maxDownload, downloadTimeout)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", archive, err)
+	}
+	if err := verifyChecksum(sums, archive, data); err != nil {
+		return err
+	}
+
+	binary := projectName
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	bin, err := extractBinary(archive, data, binary)
+	if err != nil {
+		return err
+	}
+	return replaceExecutable(exe, bin)
+}
+
+// verifyChecksum checks data against name's SHA-256 in a sha256sum-style
+// checksums file.
+func verifyChecksum(sums []byte, name string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("checksum mismatch for %s: the download may be corrupt or tampered with", name)
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum for %s in the release", name)
+}
+
+// extractBinary returns the contents of the file named binary in a .tar.gz
+// or .zip archive.
+func extractBinary(archive string, data []byte, binary string) ([]byte, error) {
+	if strings.HasSuffix(archive, ".zip") {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", archive, err)
+		}
+		for _, f := range zr.File {
+			if path.Base(f.Name) != binary || f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", archive, err)
+			}
+			defer rc.Close()
+			return io.ReadAll(io.LimitReader(rc, maxDownload))
+		}
+		return nil, fmt.Errorf("%s does not contain %s", archive, binary)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", archive, err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s does not contain %s", archive, binary)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", archive, err)
+		}
+		if hdr.Typeflag == tar.TypeReg && path.Base(hdr.Name) == binary {
+			return io.ReadAll(io.LimitReader(tr, maxDownload))
+		}
+	}
+}
+
+// replaceExecutable atomically swaps the file at exe for data. Windows
+// cannot overwrite a running executable, so there the old one is first
+// moved aside to exe+".old", which the next update removes.
+func replaceExecutable(exe string, data []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(exe)
+	tmp, err := os.CreateTemp(dir, ".gha-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write to %s: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, exe); err != nil {
+			_ = os.Rename(old, exe)
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmpPath, exe)
+}
+
+// Newer reports whether version latest is newer than current.
+func Newer(latest, current string) bool {
+	return isNewer(latest, current)
+}
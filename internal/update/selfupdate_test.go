@@ -0,0 +1,244 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{"README.md", []byte("readme")}, {name, data}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o755, Size: int64(len(f.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newReleaseServer serves a release of version whose archive for the
+// running platform holds binary, with a checksums file covering sums that
+// is signed with a fresh release signing key.
+func newReleaseServer(t *testing.T, version string, binary []byte, sums func(archive []byte) string) *httptest.Server {
+	t.Helper()
+	priv := useTestKey(t)
+	name := archiveName(version, runtime.GOOS, runtime.GOARCH)
+	var archive []byte
+	if runtime.GOOS == "windows" {
+		archive = zipArchive(t, "gha.exe", binary)
+	} else {
+		archive = tarGz(t, "gha", binary)
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			json.NewEncoder(w).Encode(map[string]any{
+				"tag_name": "v" + version,
+				"assets": []map[string]string{
+					{"name": name, "browser_download_url": srv.URL + "/archive"},
+					{"name": checksumsName(version), "browser_download_url": srv.URL + "/checksums"},
+					{"name": signatureName(version), "browser_download_url": srv.URL + "/checksums.minisig"},
+				},
+			})
+		case "/archive":
+			w.Write(archive)
+		case "/checksums":
+			fmt.Fprint(w, sums(archive))
+		case "/checksums.minisig":
+			w.Write(minisign(priv, []byte(sums(archive))))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSelfUpdate(t *testing.T) {
+	srv := newReleaseServer(t, "1.2.0", []byte("new binary"), func(archive []byte) string {
+		return fmt.Sprintf("%s  gha_1.2.0_checksums.txt\n%s  %s\n", strings.Repeat("0", 64), sha256Hex(archive), archiveName("1.2.0", runtime.GOOS, runtime.GOARCH))
+	})
+	release, err := LatestRelease(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "1.2.0" {
+		t.Errorf("Version = %q, want 1.2.0", release.Version)
+	}
+
+	exe := filepath.Join(t.TempDir(), "gha")
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SelfUpdate(release, exe); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new binary" {
+		t.Errorf("executable = %q, want the new binary", got)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(exe); info.Mode().Perm()&0o111 == 0 {
+			t.Errorf("mode = %v, want executable", info.Mode())
+		}
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if runtime.GOOS != "windows" && len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
+
+func TestSelfUpdate_ChecksumMismatch(t *testing.T) {
+	srv := newReleaseServer(t, "1.2.0", []byte("new binary"), func([]byte) string {
+		return sha256Hex([]byte("something else")) + "  " + archiveName("1.2.0", runtime.GOOS, runtime.GOARCH) + "\n"
+	})
+	release, err := LatestRelease(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(t.TempDir(), "gha")
+	if err := os.WriteFile(exe, []byte("old binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err = SelfUpdate(release, exe)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("err = %v, want a checksum mismatch", err)
+	}
+	if got, _ := os.ReadFile(exe); string(got) != "old binary" {
+		t.Errorf("executable = %q, want it untouched", got)
+	}
+}
+
+func TestSelfUpdate_MissingAsset(t *testing.T) {
+	useTestKey(t)
+	release := &Release{Version: "1.2.0", Assets: map[string]string{}}
+	err := SelfUpdate(release, filepath.Join(t.TempDir(), "gha"))
+	if err == nil || !strings.Contains(err.Error(), "has no") {
+		t.Fatalf("err = %v, want a missing asset error", err)
+	}
+}
+
+func TestSelfUpdate_Unsigned(t *testing.T) {
+	useTestKey(t)
+	release := &Release{Version: "1.2.0", Assets: map[string]string{
+		archiveName("1.2.0", runtime.GOOS, runtime.GOARCH): "http://127.0.0.1:0/archive",
+		checksumsName("1.2.0"):                             "http://127.0.0.1:0/checksums",
+	}}
+	err := SelfUpdate(release, filepath.Join(t.TempDir(), "gha"))
+	if err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Fatalf("err = %v, want an unsigned release error", err)
+	}
+}
+
+func TestSelfUpdate_NoPublicKey(t *testing.T) {
+	srv := newReleaseServer(t, "1.2.0", []byte("new binary"), func(archive []byte) string {
+		return sha256Hex(archive) + "  " + archiveName("1.2.0", runtime.GOOS, runtime.GOARCH) + "\n"
+	})
+	publicKey = ""
+	release, err := LatestRelease(WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SelfUpdate(release, filepath.Join(t.TempDir(), "gha")); err != errNoPublicKey {
+		t.Fatalf("err = %v, want errNoPublicKey", err)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("archive")
+	sums := []byte(sha256Hex(data) + " *gha_1.0.0_linux_amd64.tar.gz\n")
+	if err := verifyChecksum(sums, "gha_1.0.0_linux_amd64.tar.gz", data); err != nil {
+		t.Errorf("binary-mode entry: %v", err)
+	}
+	if err := verifyChecksum(sums, "gha_1.0.0_linux_arm64.tar.gz", data); err == nil {
+		t.Error("expected an error for an archive with no checksum")
+	}
+}
+
+func TestExtractBinary(t *testing.T) {
+	for _, tt := range []struct {
+		archive string
+		data    []byte
+	}{
+		{"gha_1.0.0_linux_amd64.tar.gz", tarGz(t, "gha", []byte("bin"))},
+		{"gha_1.0.0_windows_amd64.zip", zipArchive(t, "gha.exe", []byte("bin"))},
+	} {
+		binary := "gha"
+		if strings.HasSuffix(tt.archive, ".zip") {
+			binary = "gha.exe"
+		}
+		got, err := extractBinary(tt.archive, tt.data, binary)
+		if err != nil {
+			t.Errorf("%s: %v", tt.archive, err)
+			continue
+		}
+		if string(got) != "bin" {
+			t.Errorf("%s: got %q, want %q", tt.archive, got, "bin")
+		}
+		if _, err := extractBinary(tt.archive, tt.data, "missing"); err == nil {
+			t.Errorf("%s: expected an error for a missing binary", tt.archive)
+		}
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	if got := archiveName("1.0.0", "linux", "amd64"); got != "gha_1.0.0_linux_amd64.tar.gz" {
+		t.Errorf("linux = %q", got)
+	}
+	if got := archiveName("1.0.0", "windows", "arm64"); got != "gha_1.0.0_windows_arm64.zip" {
+		t.Errorf("windows = %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package update
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// publicKey is the minisign public key release checksums are signed with.
+// Release builds set it with
+// -ldflags "-X github.com/haribote-lab/github-app-cli/internal/update.publicKey=RW...".
+var publicKey string
+
+// errNoPublicKey means this build cannot check signatures, so it cannot
+// update itself either.
+var errNoPublicKey = errors.New("this build of gha has no release signing key, so it cannot verify an update - download the release manually")
+
+const (
+	sigAlgorithm       = "Ed" // legacy, non-prehashed Ed25519 (minisign -l)
+	sigAlgorithmHashed = "ED"
+	keyIDLen           = 8
+	trustedPrefix      = "trusted comment: "
+)
+
+// signatureName returns the minisign signature published alongside the
+// checksums file.
+func signatureName(version string) string {
+	return checksumsName(version) + ".minisig"
+}
+
+// parsePublicKey decodes a minisign public key, given either as the key
+// line alone or as the contents of a .pub file.
+func parsePublicKey(s string) (keyID []byte, key ed25519.PublicKey, err error) {
+	line := lastLine(s)
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+keyIDLen+ed25519.PublicKeySize || string(raw[:2]) != sigAlgorithm {
+		return nil, nil, errors.New("invalid release signing key")
+	}
+	return raw[2 : 2+keyIDLen], ed25519.PublicKey(raw[2+keyIDLen:]), nil
+}
+
+// verifySignature checks a minisign signature of message, including its
+// trusted comment, against the public key pub.
+func verifySignature(pub string, message, sigFile []byte) error {
+	if pub == "" {
+		return errNoPublicKey
+	}
+	keyID, key, err := parsePublicKey(pub)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(string(sigFile), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return errors.New("malformed release signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+keyIDLen+ed25519.SignatureSize {
+		return errors.New("malformed release signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return errors.New("malformed release signature")
+	}
+
+	switch string(sig[:2]) {
+	case sigAlgorithm:
+	case sigAlgorithmHashed:
+		return errors.New("prehashed minisign signatures are not supported - sign releases with minisign -l")
+	default:
+		return fmt.Errorf("unknown signature algorithm %q", sig[:2])
+	}
+	if !bytes.Equal(sig[2:2+keyIDLen], keyID) {
+		return errors.New("release signed with an unknown key")
+	}
+	if !ed25519.Verify(key, message, sig[2+keyIDLen:]) {
+		return errors.New("release signature does not match: the download may be corrupt or tampered with")
+	}
+	trusted := strings.TrimPrefix(lines[2], trustedPrefix)
+	if !ed25519.Verify(key, slices.Concat(sig[2+keyIDLen:], []byte(trusted)), globalSig) {
+		return errors.New("release signature's trusted comment does not match")
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
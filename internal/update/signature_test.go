@@ -0,0 +1,67 @@
+package update
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testKeyID = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+// useTestKey makes a fresh key the release signing key for the test.
+func useTestKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := publicKey
+	publicKey = "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(slices.Concat([]byte("Ed"), testKeyID, pub)) + "\n"
+	t.Cleanup(func() { publicKey = orig })
+	return priv
+}
+
+// minisign signs message the way minisign -S -l does.
+func minisign(priv ed25519.PrivateKey, message []byte) []byte {
+	sig := ed25519.Sign(priv, message)
+	trusted := "timestamp:1700000000\tfile:checksums.txt"
+	global := ed25519.Sign(priv, slices.Concat(sig, []byte(trusted)))
+	return fmt.Appendf(nil, "untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(slices.Concat([]byte("Ed"), testKeyID, sig)),
+		trusted,
+		base64.StdEncoding.EncodeToString(global))
+}
+
+func TestVerifySignature(t *testing.T) {
+	priv := useTestKey(t)
+	message := []byte("checksums")
+	sig := minisign(priv, message)
+
+	if err := verifySignature(publicKey, message, sig); err != nil {
+		t.Fatalf("valid signature: %v", err)
+	}
+	if err := verifySignature(publicKey, []byte("tampered"), sig); err == nil {
+		t.Error("expected an error for a tampered message")
+	}
+	if err := verifySignature("", message, sig); err != errNoPublicKey {
+		t.Errorf("no key: err = %v, want errNoPublicKey", err)
+	}
+
+	_, other, _ := ed25519.GenerateKey(nil)
+	if err := verifySignature(publicKey, message, minisign(other, message)); err == nil {
+		t.Error("expected an error for a signature from another key")
+	}
+
+	lines := strings.Split(string(sig), "\n")
+	lines[2] = "trusted comment: forged"
+	if err := verifySignature(publicKey, message, []byte(strings.Join(lines, "\n"))); err == nil {
+		t.Error("expected an error for a forged trusted comment")
+	}
+	if err := verifySignature(publicKey, message, []byte("garbage")); err == nil {
+		t.Error("expected an error for a malformed signature")
+	}
+}
@@ -2,6 +2,7 @@ package update
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
 )
 
 const (
@@ -17,23 +20,50 @@ const (
 	checkInterval = 24 * time.Hour
 	httpTimeout   = 3 * time.Second
 	maxResponse   = 1 << 20
-	releaseURL    = "https://api.github.com/repos/haribote-lab/github-app-cli/releases/latest"
+	releasesURL   = "https://api.github.com/repos/haribote-lab/github-app-cli/releases"
+
+	// prereleasePage is how many recent releases the prerelease channel
+	// looks through for the newest one.
+	prereleasePage = 30
 )
 
 type options struct {
-	baseURL string
+	baseURL        string
+	prerelease     bool
+	remindInterval time.Duration
 }
 
 // Option configures update check behaviour.
 type Option func(*options)
 
-// WithBaseURL overrides the GitHub API release URL (used for testing).
+// WithBaseURL overrides the releases API URL, for a mirror of the GitHub
+// releases API. The latest release is read from url + "/latest".
 func WithBaseURL(url string) Option {
-	return func(o *options) { o.baseURL = url }
+	return func(o *options) { o.baseURL = strings.TrimSuffix(url, "/") }
+}
+
+// WithPrerelease makes release candidates count as updates, following the
+// prerelease channel rather than only stable releases.
+func WithPrerelease(prerelease bool) Option {
+	return func(o *options) { o.prerelease = prerelease }
+}
+
+// WithRemindInterval makes Notify repeat the notice for a version it has
+// already shown once interval has passed. By default it is shown once.
+func WithRemindInterval(interval time.Duration) Option {
+	return func(o *options) { o.remindInterval = interval }
+}
+
+// channel names the release channel o follows, as recorded in the cache.
+func (o options) channel() string {
+	if o.prerelease {
+		return "prerelease"
+	}
+	return "stable"
 }
 
 func buildOpts(opts []Option) options {
-	o := options{baseURL: releaseURL}
+	o := options{baseURL: releasesURL}
 	for _, fn := range opts {
 		fn(&o)
 	}
@@ -43,69 +73,267 @@ func buildOpts(opts []Option) options {
 type state struct {
 	LatestVersion string    `json:"latest_version"`
 	CheckedAt     time.Time `json:"checked_at"`
+	Channel       string    `json:"channel,omitempty"`
+
+	// NotifiedVersion is the release the user was last told about, at
+	// NotifiedAt.
+	NotifiedVersion string    `json:"notified_version,omitempty"`
+	NotifiedAt      time.Time `json:"notified_at,omitzero"`
+
+	// ETag and LastModified validate the cached release on the next
+	// refresh, which then usually costs a 304.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validators are the cache validators of a response, sent back as
+// If-None-Match and If-Modified-Since.
+type validators struct {
+	etag, lastModified string
 }
 
+// errNotModified reports a 304: the cached release is still current.
+var errNotModified = errors.New("not modified")
+
 // Result holds the latest version info when an update is available.
 type Result struct {
 	Latest  string
 	Current string
 }
 
+// CachePath returns the location of the update-check cache inside cacheDir.
+func CachePath(cacheDir string) string {
+	return filepath.Join(cacheDir, cacheFile)
+}
+
+// CheckState is what the update-check cache remembers of the last check.
+type CheckState struct {
+	LatestVersion string
+	Channel       string
+	CheckedAt     time.Time
+	// NextCheck is when the cached release is next refreshed.
+	NextCheck       time.Time
+	NotifiedVersion string
+	NotifiedAt      time.Time
+}
+
+// ReadCheck returns the update-check cache inside cacheDir, or nil when
+// there is none.
+func ReadCheck(cacheDir string) *CheckState {
+	s := readCache(CachePath(cacheDir))
+	if s == nil {
+		return nil
+	}
+	channel := s.Channel
+	if channel == "" {
+		channel = "stable"
+	}
+	return &CheckState{
+		LatestVersion:   s.LatestVersion,
+		Channel:         channel,
+		CheckedAt:       s.CheckedAt,
+		NextCheck:       s.CheckedAt.Add(checkInterval),
+		NotifiedVersion: s.NotifiedVersion,
+		NotifiedAt:      s.NotifiedAt,
+	}
+}
+
 // Check returns non-nil Result if a newer version is available.
 // It caches the result for 24 hours. Returns nil on any error or if up-to-date.
 func Check(currentVersion, cacheDir string, opts ...Option) *Result {
-	if currentVersion == "" || currentVersion == "dev" {
-		return nil
+	if Due(currentVersion, cacheDir, opts...) {
+		_ = Refresh(cacheDir, opts...)
 	}
+	return Cached(currentVersion, cacheDir, opts...)
+}
 
-	cachePath := filepath.Join(cacheDir, cacheFile)
-	cached := readCache(cachePath)
+// Cached returns non-nil Result if the cache, however old, knows of a newer
+// version on the channel. It never touches the network.
+func Cached(currentVersion, cacheDir string, opts ...Option) *Result {
+	if !released(currentVersion) {
+		return nil
+	}
+	cached := readChannelCache(cacheDir, buildOpts(opts))
+	if cached != nil && isNewer(cached.LatestVersion, currentVersion) {
+		return &Result{Latest: cached.LatestVersion, Current: currentVersion}
+	}
+	return nil
+}
 
-	if cached != nil && time.Since(cached.CheckedAt) < checkInterval {
-		if isNewer(cached.LatestVersion, currentVersion) {
-			return &Result{Latest: cached.LatestVersion, Current: currentVersion}
+// Notify returns the Cached result the first time it sees a given newer
+// version, and nil after that until the remind interval, if any, passes.
+func Notify(currentVersion, cacheDir string, opts ...Option) *Result {
+	r := Cached(currentVersion, cacheDir, opts...)
+	if r == nil {
+		return nil
+	}
+	o := buildOpts(opts)
+	notify := false
+	updateCache(CachePath(cacheDir), func(s *state) {
+		if s.NotifiedVersion == r.Latest && (o.remindInterval <= 0 || time.Since(s.NotifiedAt) < o.remindInterval) {
+			return
 		}
+		s.NotifiedVersion, s.NotifiedAt = r.Latest, time.Now()
+		notify = true
+	})
+	if !notify {
 		return nil
 	}
+	return r
+}
 
+// Due reports whether the cache is missing or older than a day, so a
+// Refresh is needed. Development builds are never due.
+func Due(currentVersion, cacheDir string, opts ...Option) bool {
+	if !released(currentVersion) {
+		return false
+	}
+	cached := readChannelCache(cacheDir, buildOpts(opts))
+	return cached == nil || time.Since(cached.CheckedAt) >= checkInterval
+}
+
+// Refresh fetches the latest release on the channel and caches it. The
+// request is conditional on the cached one, so an unchanged release costs a
+// 304, which GitHub does not count against the rate limit.
+func Refresh(cacheDir string, opts ...Option) error {
 	o := buildOpts(opts)
-	latest := fetchLatestVersion(o.baseURL)
-	if latest == "" {
+	path := CachePath(cacheDir)
+	var prev validators
+	if cached := readChannelCache(cacheDir, o); cached != nil && cached.LatestVersion != "" {
+		prev = validators{etag: cached.ETag, lastModified: cached.LastModified}
+	}
+	release, v, err := fetchRelease(o, httpTimeout, prev)
+	if errors.Is(err, errNotModified) {
+		updateCache(path, func(s *state) { s.CheckedAt = time.Now() })
 		return nil
 	}
+	if err != nil {
+		return err
+	}
+	updateCache(path, func(s *state) {
+		s.LatestVersion, s.CheckedAt, s.Channel = release.version(), time.Now(), o.channel()
+		s.ETag, s.LastModified = v.etag, v.lastModified
+	})
+	return nil
+}
 
-	writeCache(cachePath, &state{LatestVersion: latest, CheckedAt: time.Now()})
+func released(version string) bool {
+	return version != "" && version != "dev"
+}
 
-	if isNewer(latest, currentVersion) {
-		return &Result{Latest: latest, Current: currentVersion}
+// readChannelCache returns the cache when it was written for o's channel.
+func readChannelCache(cacheDir string, o options) *state {
+	cached := readCache(CachePath(cacheDir))
+	if cached == nil {
+		return nil
 	}
-	return nil
+	// A cache written before channels existed holds a stable release.
+	if cached.Channel == "" {
+		cached.Channel = "stable"
+	}
+	if cached.Channel != o.channel() {
+		return nil
+	}
+	return cached
+}
+
+type releaseResponse struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r *releaseResponse) version() string {
+	return strings.TrimPrefix(r.TagName, "v")
 }
 
-func fetchLatestVersion(url string) string {
-	client := &http.Client{Timeout: httpTimeout}
-	resp, err := client.Get(url)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		if resp != nil {
-			resp.Body.Close()
+// fetchRelease returns the latest release on o's channel and the
+// response's validators, or errNotModified when prev still holds. The stable
+// channel asks for the latest release, which GitHub never resolves to a
+// prerelease; the prerelease channel picks the newest of the recent ones.
+func fetchRelease(o options, timeout time.Duration, prev validators) (*releaseResponse, validators, error) {
+	if !o.prerelease {
+		body, v, err := fetch(o.baseURL+"/latest", maxResponse, timeout, prev)
+		if err != nil {
+			return nil, v, err
 		}
-		return ""
+		var release releaseResponse
+		if err := json.Unmarshal(body, &release); err != nil {
+			return nil, v, fmt.Errorf("parsing release response: %w", err)
+		}
+		if release.TagName == "" {
+			return nil, v, fmt.Errorf("release response has no tag_name")
+		}
+		return &release, v, nil
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	listURL := fmt.Sprintf("%s?per_page=%d", o.baseURL, prereleasePage)
+	body, v, err := fetch(listURL, maxResponse, timeout, prev)
 	if err != nil {
-		return ""
+		return nil, v, err
 	}
-
-	var release struct {
-		TagName string `json:"tag_name"`
+	var releases []releaseResponse
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, v, fmt.Errorf("parsing releases response: %w", err)
 	}
-	if err := json.Unmarshal(body, &release); err != nil {
-		return ""
+	var newest *releaseResponse
+	for i, r := range releases {
+		if r.Draft || r.TagName == "" {
+			continue
+		}
+		if newest == nil || isNewer(r.version(), newest.version()) {
+			newest = &releases[i]
+		}
 	}
+	if newest == nil {
+		return nil, v, fmt.Errorf("no releases found")
+	}
+	return newest, v, nil
+}
+
+func download(url string, limit int64, timeout time.Duration) ([]byte, error) {
+	data, _, err := fetch(url, limit, timeout, validators{})
+	return data, err
+}
 
-	return strings.TrimPrefix(release.TagName, "v")
+// fetch GETs url, conditionally on prev when it has validators, and
+// returns at most limit bytes of the body with the response's validators.
+func fetch(url string, limit int64, timeout time.Duration, prev validators) ([]byte, validators, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, validators{}, err
+	}
+	if prev.etag != "" {
+		req.Header.Set("If-None-Match", prev.etag)
+	}
+	if prev.lastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.lastModified)
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, validators{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, prev, errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, validators{}, fmt.Errorf("HTTP %d from %s", resp.StatusCode, url)
+	}
+	v := validators{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, v, err
+	}
+	if int64(len(data)) > limit {
+		return nil, v, fmt.Errorf("%s is larger than %d bytes", url, limit)
+	}
+	return data, v, nil
 }
 
 func readCache(path string) *state {
@@ -120,32 +348,97 @@ func readCache(path string) *state {
 	return &s
 }
 
-func writeCache(path string, s *state) {
+// updateCache applies fn to the cached state under the cache lock, so
+// concurrent refreshes and notices do not drop each other's fields.
+func updateCache(path string, fn func(s *state)) {
+	unlock, err := fsutil.Lock(path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	s := readCache(path)
+	if s == nil {
+		s = &state{}
+	}
+	fn(s)
 	data, err := json.Marshal(s)
 	if err != nil {
 		return
 	}
-	_ = os.WriteFile(path, data, 0o600)
+	_ = fsutil.WriteFile(path, data, 0o600)
 }
 
+// isNewer reports whether latest is newer than current by semver
+// precedence, so 1.2.0 is newer than 1.2.0-rc.2, which is newer than
+// 1.2.0-rc.1.
 func isNewer(latest, current string) bool {
-	latest = strings.TrimPrefix(latest, "v")
-	current = strings.TrimPrefix(current, "v")
+	return compareVersions(latest, current) > 0
+}
 
-	lParts := strings.Split(latest, ".")
-	cParts := strings.Split(current, ".")
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
 
+	aParts := strings.Split(aCore, ".")
+	bParts := strings.Split(bCore, ".")
 	for i := 0; i < 3; i++ {
-		l := part(lParts, i)
-		c := part(cParts, i)
-		if l > c {
-			return true
+		if c := cmpInt(part(aParts, i), part(bParts, i)); c != 0 {
+			return c
 		}
-		if l < c {
-			return false
+	}
+
+	// A release sorts after its prereleases.
+	switch {
+	case aPre == "" && bPre == "":
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	aIDs := strings.Split(aPre, ".")
+	bIDs := strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := comparePrereleaseID(aIDs[i], bIDs[i]); c != 0 {
+			return c
 		}
 	}
-	return false
+	return cmpInt(len(aIDs), len(bIDs))
+}
+
+// splitVersion splits v into its dotted core and pre-release suffix,
+// dropping a leading "v" and any build metadata.
+func splitVersion(v string) (core, pre string) {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "+")
+	core, pre, _ = strings.Cut(v, "-")
+	return core, pre
+}
+
+// comparePrereleaseID orders numeric identifiers numerically and below
+// alphanumeric ones, which compare as strings.
+func comparePrereleaseID(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmpInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
 
 func part(parts []string, i int) int {
@@ -156,10 +449,12 @@ func part(parts []string, i int) int {
 	return n
 }
 
-// FormatNotice returns the update notification message.
-func FormatNotice(r *Result) string {
-	return fmt.Sprintf(
-		"A new version of gha is available: v%s → v%s\nRun `brew upgrade gha` or visit https://github.com/haribote-lab/github-app-cli/releases\n",
-		r.Current, r.Latest,
-	)
+// FormatNotice returns the update notification message, with the upgrade
+// command for a gha installed with method.
+func FormatNotice(r *Result, method InstallMethod) string {
+	how := fmt.Sprintf("Download the new .deb from %s/tag/v%s", releasesPage, r.Latest)
+	if cmd := method.UpgradeCommand(); cmd != "" {
+		how = fmt.Sprintf("Run `%s` or visit %s", cmd, releasesPage)
+	}
+	return fmt.Sprintf("A new version of gha is available: v%s → v%s\n%s\n", r.Current, r.Latest, how)
 }
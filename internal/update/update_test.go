@@ -108,6 +108,26 @@ func TestCheck_UsesCache(t *testing.T) {
 	}
 }
 
+func TestReadCheck(t *testing.T) {
+	dir := t.TempDir()
+	if s := ReadCheck(dir); s != nil {
+		t.Fatalf("ReadCheck with no cache = %+v, want nil", s)
+	}
+
+	checkedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	data, _ := json.Marshal(&state{LatestVersion: "2.0.0", CheckedAt: checkedAt})
+	if err := os.WriteFile(filepath.Join(dir, cacheFile), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := ReadCheck(dir)
+	if s == nil || s.LatestVersion != "2.0.0" || s.Channel != "stable" {
+		t.Fatalf("ReadCheck = %+v, want 2.0.0 on the stable channel", s)
+	}
+	if !s.NextCheck.Equal(checkedAt.Add(checkInterval)) {
+		t.Errorf("NextCheck = %v, want a day after %v", s.NextCheck, checkedAt)
+	}
+}
+
 func TestCheck_StaleCache(t *testing.T) {
 	srv := newTestServer(t, "v3.0.0", http.StatusOK)
 	defer srv.Close()
@@ -148,6 +168,126 @@ func TestCheck_FreshCacheNoUpdate(t *testing.T) {
 	}
 }
 
+// newChannelServer serves a stable release at /releases/latest and the
+// recent releases, including a prerelease and a draft, at /releases.
+func newChannelServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/latest":
+			json.NewEncoder(w).Encode(map[string]any{"tag_name": "v1.1.0"})
+		case "/releases":
+			if r.URL.Query().Get("per_page") == "" {
+				t.Errorf("releases requested without per_page: %s", r.URL)
+			}
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"tag_name": "v1.3.0", "draft": true},
+				{"tag_name": "v1.2.0-rc.1", "prerelease": true},
+				{"tag_name": "v1.2.0-rc.2", "prerelease": true},
+				{"tag_name": "v1.1.0"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheck_Channels(t *testing.T) {
+	srv := newChannelServer(t)
+	dir := t.TempDir()
+
+	stable := Check("1.0.0", dir, WithBaseURL(srv.URL+"/releases"))
+	if stable == nil || stable.Latest != "1.1.0" {
+		t.Fatalf("stable = %+v, want 1.1.0", stable)
+	}
+
+	// Switching channels must not reuse the stable channel's cache.
+	pre := Check("1.0.0", dir, WithBaseURL(srv.URL+"/releases"), WithPrerelease(true))
+	if pre == nil || pre.Latest != "1.2.0-rc.2" {
+		t.Fatalf("prerelease = %+v, want 1.2.0-rc.2", pre)
+	}
+
+	if r := Check("1.2.0-rc.2", dir, WithBaseURL(srv.URL+"/releases"), WithPrerelease(true)); r != nil {
+		t.Errorf("expected no update from the newest prerelease, got %+v", r)
+	}
+}
+
+func TestRefresh_Conditional(t *testing.T) {
+	var conditional, full int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v2"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v2"`)
+		json.NewEncoder(w).Encode(map[string]string{"tag_name": "v2.0.0"})
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	if err := Refresh(dir, WithBaseURL(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	before := readCache(CachePath(dir))
+	if before == nil || before.ETag != `"v2"` {
+		t.Fatalf("cache = %+v, want the ETag stored", before)
+	}
+	time.Sleep(time.Millisecond)
+	if err := Refresh(dir, WithBaseURL(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if full != 1 || conditional != 1 {
+		t.Errorf("full = %d, conditional = %d; want one of each", full, conditional)
+	}
+	after := readCache(CachePath(dir))
+	if after.LatestVersion != "2.0.0" || !after.CheckedAt.After(before.CheckedAt) {
+		t.Errorf("cache after 304 = %+v, want 2.0.0 kept and CheckedAt bumped", after)
+	}
+
+	// Another channel's validators are not sent.
+	if err := Refresh(dir, WithBaseURL(srv.URL), WithPrerelease(true)); err == nil {
+		t.Error("expected the list response to fail to parse, not a 304")
+	}
+	if conditional != 1 {
+		t.Errorf("conditional = %d, want no conditional request for another channel", conditional)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	srv := newTestServer(t, "v2.0.0", http.StatusOK)
+	defer srv.Close()
+	dir := t.TempDir()
+	if err := Refresh(dir, WithBaseURL(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := Notify("1.0.0", dir); r == nil || r.Latest != "2.0.0" {
+		t.Fatalf("first Notify = %+v, want 2.0.0", r)
+	}
+	if r := Notify("1.0.0", dir); r != nil {
+		t.Errorf("second Notify = %+v, want nil", r)
+	}
+
+	// A refresh keeps the record of what was shown.
+	if err := Refresh(dir, WithBaseURL(srv.URL)); err != nil {
+		t.Fatal(err)
+	}
+	if r := Notify("1.0.0", dir); r != nil {
+		t.Errorf("Notify after refresh = %+v, want nil", r)
+	}
+
+	if r := Notify("1.0.0", dir, WithRemindInterval(time.Nanosecond)); r == nil {
+		t.Error("Notify past the remind interval = nil, want a reminder")
+	}
+	if r := Notify("1.0.0", dir, WithRemindInterval(time.Hour)); r != nil {
+		t.Errorf("Notify within the remind interval = %+v, want nil", r)
+	}
+}
+
 func TestIsNewer(t *testing.T) {
 	tests := []struct {
 		latest, current string
@@ -161,6 +301,15 @@ func TestIsNewer(t *testing.T) {
 		{"v1.0.1", "v1.0.0", true},
 		{"1.0.0", "1.0.1", false},
 		{"0.0.2", "0.0.1", true},
+		{"1.2.0", "1.2.0-rc.1", true},
+		{"1.2.0-rc.1", "1.2.0", false},
+		{"1.2.0-rc.2", "1.2.0-rc.1", true},
+		{"1.2.0-rc.10", "1.2.0-rc.9", true},
+		{"1.2.0-rc.1", "1.1.9", true},
+		{"1.2.0-rc.1", "1.2.0-beta.3", true},
+		{"1.2.0-rc.1.1", "1.2.0-rc.1", true},
+		{"1.2.0-rc.1", "1.2.0-1", true},
+		{"1.2.0+build.5", "1.2.0", false},
 	}
 
 	for _, tt := range tests {
@@ -175,11 +324,23 @@ func TestIsNewer(t *testing.T) {
 
 func TestFormatNotice(t *testing.T) {
 	r := &Result{Latest: "2.0.0", Current: "1.0.0"}
-	notice := FormatNotice(r)
+	notice := FormatNotice(r, InstallHomebrew)
 	if !strings.Contains(notice, "v1.0.0") || !strings.Contains(notice, "v2.0.0") {
 		t.Errorf("notice = %q, want both versions", notice)
 	}
 	if !strings.Contains(notice, "brew upgrade") {
 		t.Errorf("notice = %q, want brew upgrade instruction", notice)
 	}
+
+	for method, want := range map[InstallMethod]string{
+		InstallManual:     "`gha self-update`",
+		InstallGo:         "`go install github.com/haribote-lab/github-app-cli@latest`",
+		InstallScoop:      "`scoop update gha`",
+		InstallChocolatey: "`choco upgrade gha`",
+		InstallDeb:        "releases/tag/v2.0.0",
+	} {
+		if notice := FormatNotice(r, method); !strings.Contains(notice, want) {
+			t.Errorf("method %d: notice = %q, want %s", method, notice, want)
+		}
+	}
 }
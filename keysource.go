@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// keySource signs the JWTs of a gha process that keeps running, such as a
+// --refresh session. It keeps the private key between JWTs and, unless
+// key_reload is once, watches the file for a rotated key, which it switches
+// to without interrupting signing: a new key that cannot be used yet, such
+// as one caught half written, leaves the previous one in use.
+type keySource struct {
+	cfg *config.Config
+
+	mu sync.Mutex
+	// pem is the key last read, from the file as version describes it, in
+	// memory locked against swapping; release wipes and unlocks it.
+	pem     []byte
+	release func()
+	version keyVersion
+	// rejected is a version that could not be used, and warned the last
+	// warning about it, so each problem is reported once.
+	rejected keyVersion
+	warned   string
+}
+
+// keyVersion tells one version of a key file from another: by the file a
+// Kubernetes secret volume's symlinks resolve to, which changes with every
+// update of the secret, and by the file's modification time and size.
+type keyVersion struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+func newKeySource(cfg *config.Config) *keySource {
+	return &keySource{cfg: cfg}
+}
+
+// statKey returns the current version of the key file at path.
+func statKey(path string) (keyVersion, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return keyVersion{}, err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return keyVersion{}, err
+	}
+	return keyVersion{path: resolved, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// sign returns a freshly signed JWT for the App.
+func (k *keySource) sign() (string, error) {
+	if k.cfg.PrivateKey != "" {
+		return signJWT(k.cfg)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.pem == nil || k.cfg.KeyReload != config.KeyReloadOnce {
+		if err := k.reload(); err != nil {
+			return "", err
+		}
+	}
+	jwtToken, err := auth.GenerateJWTFromPEM(k.cfg.AppID, k.pem)
+	if err != nil {
+		return "", &configError{fmt.Errorf("generating JWT: %w", err)}
+	}
+	return jwtToken, nil
+}
+
+// reload reads the key file again if it changed since it was last read. A
+// changed file that cannot be used is an error only when there is no
+// previous key to keep signing with.
+func (k *keySource) reload() error {
+	path := k.cfg.KeyPath()
+	version, err := statKey(path)
+	if err == nil && k.pem != nil && (version == k.version || version == k.rejected) {
+		return nil
+	}
+	var (
+		pem     []byte
+		release func()
+	)
+	if err == nil {
+		if pem, release, err = k.read(); err != nil {
+			k.rejected = version
+		}
+	}
+	if err != nil {
+		if k.pem == nil {
+			return err
+		}
+		if msg := err.Error(); msg != k.warned {
+			fmt.Fprintf(logOutput, "warning: private key %s changed but cannot be used (%v); signing with the previous key\n", path, err)
+			k.warned = msg
+		}
+		return nil
+	}
+	if k.pem != nil {
+		debugf("private key %s changed; signing with the new key", path)
+		k.release()
+	}
+	k.pem, k.release, k.version, k.rejected, k.warned = pem, release, version, keyVersion{}, ""
+	return nil
+}
+
+// read reads the key file after the checks signJWT makes, into memory
+// locked as auth.GenerateJWT's, and makes sure a JWT can be signed with it.
+// The caller must call release once done with the key.
+func (k *keySource) read() (pem []byte, release func(), err error) {
+	if err := checkPermissions(k.cfg, logOutput); err != nil {
+		return nil, nil, &configError{err}
+	}
+	pem, release, err = auth.ReadKeyFile(k.cfg.KeyPath())
+	if err != nil {
+		return nil, nil, &configError{fmt.Errorf("reading private key %s: %w", k.cfg.KeyPath(), err)}
+	}
+	if _, err := auth.GenerateJWTFromPEM(k.cfg.AppID, pem); err != nil {
+		release()
+		return nil, nil, &configError{fmt.Errorf("generating JWT: %w", err)}
+	}
+	return pem, release, nil
+}
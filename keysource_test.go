@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestKeySource(t *testing.T) {
+	setupTestEnv(t)
+	var warnings strings.Builder
+	logOutput = &warnings
+	t.Cleanup(func() { logOutput = os.Stderr })
+	for _, reload := range []string{"", config.KeyReloadAlways, config.KeyReloadOnce} {
+		keyPath := generateTestKeyFile(t)
+		first, err := os.ReadFile(keyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := newKeySource(&config.Config{AppID: 1, PrivateKeyPath: keyPath, KeyReload: reload})
+		if _, err := keys.sign(); err != nil {
+			t.Fatalf("key_reload %q: %v", reload, err)
+		}
+
+		// A key caught half written leaves the previous one in use.
+		rotate(t, keyPath, []byte("-----BEGIN RSA PRIV"), 1)
+		jwtToken, err := keys.sign()
+		if err != nil {
+			t.Fatalf("key_reload %q: half written key: %v", reload, err)
+		}
+		checkSignedBy(t, jwtToken, first)
+		if _, err := keys.sign(); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(warnings.String(), "cannot be used"); reload != config.KeyReloadOnce && n != 1 {
+			t.Errorf("key_reload %q: warned %d times about the half written key, want once", reload, n)
+		}
+		warnings.Reset()
+
+		// Once it is complete, the new key is switched to unless key_reload
+		// is once.
+		writeTestKey(t, keyPath)
+		second, err := os.ReadFile(keyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rotate(t, keyPath, second, 2)
+		old := keys.pem
+		jwtToken, err = keys.sign()
+		if err != nil {
+			t.Fatalf("key_reload %q: rotated key: %v", reload, err)
+		}
+		if reload == config.KeyReloadOnce {
+			checkSignedBy(t, jwtToken, first)
+		} else {
+			checkSignedBy(t, jwtToken, second)
+			if strings.Trim(string(old), "\x00") != "" {
+				t.Errorf("key_reload %q: the previous key was not wiped", reload)
+			}
+		}
+	}
+}
+
+// rotate replaces the key file at path with data, dated later by n
+// seconds, so the change shows however coarse the file system's clock.
+func rotate(t *testing.T, path string, data []byte, n int) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Duration(n) * time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkSignedBy fails the test unless jwtToken was signed with pemData.
+func checkSignedBy(t *testing.T, jwtToken string, pemData []byte) {
+	t.Helper()
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(jwtToken, func(*jwt.Token) (any, error) { return &key.PublicKey, nil }); err != nil {
+		t.Errorf("JWT not signed by the expected key: %v", err)
+	}
+}
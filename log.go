@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logFormatEnv, set by --log-format, selects how gha writes its own
+// diagnostics on stderr: text (the default) or json.
+const logFormatEnv = "GHA_LOG_FORMAT"
+
+// logOutput receives diagnostics raised away from a command's stderr, such
+// as warnings about the private key's permissions.
+var logOutput io.Writer = os.Stderr
+
+// logPrefixes map the prefixes of gha's messages to record levels.
+var logPrefixes = []struct {
+	prefix string
+	level  slog.Level
+}{
+	{"error: ", slog.LevelError},
+	{"warning: ", slog.LevelWarn},
+	{"debug: ", slog.LevelDebug},
+	{"gha: ", slog.LevelInfo},
+}
+
+// jsonLog turns each message written to it into a JSON record on out, with
+// the level taken from the message's "error: ", "warning: " or "debug: "
+// prefix and info otherwise.
+type jsonLog struct {
+	out    *lockedWriter
+	logger *slog.Logger
+}
+
+func newJSONLog(w io.Writer) *jsonLog {
+	out := &lockedWriter{w: w}
+	handler := slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return &jsonLog{out: out, logger: slog.New(handler)}
+}
+
+func (l *jsonLog) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\r\n")
+	if strings.TrimSpace(msg) == "" {
+		return len(p), nil
+	}
+	level := slog.LevelInfo
+	for _, lp := range logPrefixes {
+		if rest, ok := strings.CutPrefix(msg, lp.prefix); ok {
+			msg, level = rest, lp.level
+			break
+		}
+	}
+	l.logger.Log(context.Background(), level, msg)
+	return len(p), nil
+}
+
+// logWriter returns stderr as gha writes its own messages to it, which
+// under --log-format json is a jsonLog, and when colored a colorLog.
+func logWriter(stderr io.Writer) (io.Writer, error) {
+	switch format := os.Getenv(logFormatEnv); format {
+	case "", "text":
+		if colorEnabled(stderr) {
+			return colorLog{stderr}, nil
+		}
+		return stderr, nil
+	case "json":
+		return newJSONLog(stderr), nil
+	default:
+		return nil, usageErrorf("--log-format must be text or json, got %q", format)
+	}
+}
+
+// rawOutput returns where output that is not gha's own, such as a hook's or
+// gh's, goes: stderr itself, or the stream under a jsonLog or colorLog.
+func rawOutput(stderr io.Writer) io.Writer {
+	switch l := stderr.(type) {
+	case *jsonLog:
+		return l.out
+	case colorLog:
+		return l.w
+	}
+	return stderr
+}
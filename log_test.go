@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// logRecords decodes the JSON records written under --log-format json.
+func logRecords(t *testing.T, stderr string) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("stderr line is not a JSON record: %q", line)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestRun_LogFormatJSON(t *testing.T) {
+	setupTestEnv(t)
+
+	_, stderr, code := runCmd(t, []string{"gha", "--log-format", "json", "pr", "list"}, "")
+	if code != exitConfig {
+		t.Fatalf("exit code = %d, want %d", code, exitConfig)
+	}
+	records := logRecords(t, stderr)
+	if len(records) != 1 {
+		t.Fatalf("records = %v, want one", records)
+	}
+	if r := records[0]; r["level"] != "ERROR" || !strings.Contains(r["msg"].(string), "configuration not found") || r["time"] == nil {
+		t.Errorf("record = %v, want the config error", r)
+	}
+}
+
+func TestRun_LogFormatJSON_Debug(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	saveTestConfig(t, &config.Config{AppID: 1, InstallationID: 7})
+	t.Setenv(logFormatEnv, "json")
+
+	stdout, stderr, code := runCmd(t, []string{"gha", "--debug", "--dry-run", "pr", "list"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "installation: 7") {
+		t.Errorf("stdout = %q, want the dry run as text", stdout)
+	}
+	var found bool
+	for _, r := range logRecords(t, stderr) {
+		if r["level"] == "DEBUG" && r["msg"] == "installation: 7 (from installation_id in config)" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no debug record for the installation in %s", stderr)
+	}
+}
+
+func TestRun_LogFormatInvalid(t *testing.T) {
+	setupTestEnv(t)
+	_, stderr, code := runCmd(t, []string{"gha", "--log-format=xml", "pr", "list"}, "")
+	if code != exitUsage || !strings.Contains(stderr, "text or json") {
+		t.Errorf("code = %d, stderr = %q, want a format error", code, stderr)
+	}
+}
+
+func TestJSONLog_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	l := newJSONLog(&buf)
+	for _, msg := range []string{"warning: key is readable\n", "gha: skipping installation 3\n", "plain\n", "\n"} {
+		l.Write([]byte(msg))
+	}
+	records := logRecords(t, buf.String())
+	want := [][2]string{{"WARN", "key is readable"}, {"INFO", "skipping installation 3"}, {"INFO", "plain"}}
+	if len(records) != len(want) {
+		t.Fatalf("records = %v, want %d", records, len(want))
+	}
+	for i, w := range want {
+		if records[i]["level"] != w[0] || records[i]["msg"] != w[1] {
+			t.Errorf("record %d = %v, want %s %q", i, records[i], w[0], w[1])
+		}
+	}
+	if rawOutput(l) == l {
+		t.Error("rawOutput returned the jsonLog itself")
+	}
+}
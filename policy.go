@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// policyError reports a gh command the config's policy refused to run.
+type policyError struct {
+	command string
+	rule    string
+	key     string
+	reason  string
+}
+
+func (e *policyError) Error() string {
+	return fmt.Sprintf("%s: gh %s (rule %q under %s in the config)", e.reason, e.command, e.rule, e.key)
+}
+
+// policyArgs returns the gh arguments the policy applies to: those of a
+// proxied gh command, or of gh run through gha exec. Other programs, and
+// what is typed into gha shell, are beyond it.
+func policyArgs(ghArgs, execArgs []string) ([]string, bool) {
+	if execArgs == nil {
+		return ghArgs, len(ghArgs) > 0
+	}
+	if strings.TrimSuffix(filepath.Base(execArgs[0]), ".exe") == "gh" {
+		return execArgs[1:], true
+	}
+	return nil, false
+}
+
+// checkPolicy refuses args when a policy.deny rule matches them. When a
+// policy.confirm rule does, it asks on stderr and reads the answer from
+// stdin, refusing when stdin is not a terminal; with ask false, as in a dry
+// run, it lets the command through unasked.
+func checkPolicy(policy config.Policy, args []string, ask bool, stdin io.Reader, stderr io.Writer) error {
+	command := strings.Join(args, " ")
+	if rule, ok := matchPolicy(policy.Deny, args); ok {
+		return &policyError{command: command, rule: rule, key: "policy.deny", reason: "denied by policy"}
+	}
+	rule, ok := matchPolicy(policy.Confirm, args)
+	if !ok || !ask {
+		return nil
+	}
+	if !isTerminal(stdin) {
+		return &policyError{command: command, rule: rule, key: "policy.confirm", reason: "needs confirmation at a terminal"}
+	}
+	answer, err := prompt(bufio.NewReader(stdin), stderr, fmt.Sprintf("gha: policy asks to confirm gh %s. Run it? [y/N]: ", command))
+	if err != nil || !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		return &policyError{command: command, rule: rule, key: "policy.confirm", reason: "not confirmed"}
+	}
+	return nil
+}
+
+// matchPolicy returns the first of rules that matches args.
+func matchPolicy(rules []string, args []string) (string, bool) {
+	for _, rule := range rules {
+		if matchRule(strings.Fields(rule), args) {
+			return rule, true
+		}
+	}
+	return "", false
+}
+
+// shortFlags maps the one-letter flags of gh commands to their long names,
+// so a rule matches a flag however it is spelt. The "" entry holds the flags
+// every command shares; the others, those of the command named, since the
+// same letter means different flags in different commands.
+var shortFlags = map[string]map[string]string{
+	"":    {"-R": "--repo"},
+	"api": {"-X": "--method", "-H": "--header", "-F": "--field", "-f": "--raw-field", "-i": "--include", "-p": "--preview", "-q": "--jq", "-t": "--template"},
+}
+
+// longFlags returns words with the one-letter flags of gh command command
+// spelt by their long names: "-X DELETE", "-X=DELETE" and "-XDELETE" become
+// "--method DELETE", "--method=DELETE" and "--method=DELETE".
+func longFlags(command string, words []string) []string {
+	out := make([]string, 0, len(words))
+	for j, word := range words {
+		if word == "--" {
+			return append(out, words[j:]...)
+		}
+		long, ok := shortFlags[strings.ToLower(command)][word[:min(2, len(word))]]
+		if !ok {
+			long, ok = shortFlags[""][word[:min(2, len(word))]]
+		}
+		switch {
+		case !ok || strings.HasPrefix(word, "--"):
+			out = append(out, word)
+		case len(word) == 2:
+			out = append(out, long)
+		default:
+			out = append(out, long+"="+strings.TrimPrefix(word[2:], "="))
+		}
+	}
+	return out
+}
+
+// matchRule reports whether args, a gh command line, matches rule: its
+// commands, the words before its first flag, must begin args, and each of
+// its flags must appear in args with the value that follows it in the rule.
+// A flag matches as "-X DELETE", "-X=DELETE" or, for a one-letter flag,
+// "-XDELETE", and by either its short or its long name where shortFlags
+// knows both. Commands and values are matched without regard to case.
+func matchRule(rule, args []string) bool {
+	if len(args) > 0 {
+		rule, args = longFlags(args[0], rule), longFlags(args[0], args)
+	}
+	i := 0
+	for ; i < len(rule) && !strings.HasPrefix(rule[i], "-"); i++ {
+		if i >= len(args) || !strings.EqualFold(rule[i], args[i]) {
+			return false
+		}
+	}
+	for i < len(rule) {
+		flag, value := rule[i], ""
+		i++
+		if i < len(rule) && !strings.HasPrefix(rule[i], "-") {
+			value = rule[i]
+			i++
+		}
+		if !hasFlag(args, flag, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasFlag reports whether args carry flag, with value when it is not empty.
+func hasFlag(args []string, flag, value string) bool {
+	for j, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		switch {
+		case value == "" && arg == flag:
+			return true
+		case value == "":
+		case arg == flag && j+1 < len(args) && strings.EqualFold(args[j+1], value):
+			return true
+		case strings.EqualFold(arg, flag+"="+value):
+			return true
+		case len(flag) == 2 && strings.EqualFold(arg, flag+value):
+			return true
+		}
+	}
+	return false
+}
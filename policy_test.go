@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestMatchRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		args []string
+		want bool
+	}{
+		{"repo delete", []string{"repo", "delete", "acme/app", "--yes"}, true},
+		{"repo delete", []string{"Repo", "Delete"}, true},
+		{"repo delete", []string{"repo", "view"}, false},
+		{"repo delete", []string{"repo"}, false},
+		{"secret", []string{"secret", "set", "TOKEN"}, true},
+		{"api -X DELETE", []string{"api", "-X", "DELETE", "/repos/acme/app"}, true},
+		{"api -X DELETE", []string{"api", "/repos/acme/app", "-X", "delete"}, true},
+		{"api -X DELETE", []string{"api", "-XDELETE", "/repos/acme/app"}, true},
+		{"api -X DELETE", []string{"api", "-X=DELETE", "/repos/acme/app"}, true},
+		{"api --method DELETE", []string{"api", "--method=DELETE", "/repos/acme/app"}, true},
+		{"api -X DELETE", []string{"api", "-X", "GET", "/repos/acme/app"}, false},
+		{"api -X DELETE", []string{"api", "/repos/acme/app"}, false},
+		{"api -X DELETE", []string{"api", "--", "-X", "DELETE"}, false},
+		{"api -X DELETE", []string{"api", "--method", "DELETE", "/repos/acme/app"}, true},
+		{"api -X DELETE", []string{"api", "--method=delete", "/repos/acme/app"}, true},
+		{"api --method DELETE", []string{"api", "-XDELETE", "/repos/acme/app"}, true},
+		{"pr merge -R acme/app", []string{"pr", "merge", "12", "--repo", "acme/app"}, true},
+		{"pr create -X DELETE", []string{"pr", "create", "--method", "DELETE"}, false},
+		{"pr merge --admin", []string{"pr", "merge", "12", "--admin"}, true},
+		{"pr merge --admin", []string{"pr", "merge", "12"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchRule(strings.Fields(tt.rule), tt.args); got != tt.want {
+			t.Errorf("matchRule(%q, %q) = %v, want %v", tt.rule, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyArgs(t *testing.T) {
+	if args, ok := policyArgs([]string{"repo", "delete"}, nil); !ok || len(args) != 2 {
+		t.Errorf("gh command: got %q, %v", args, ok)
+	}
+	if args, ok := policyArgs(nil, []string{"/usr/bin/gh", "repo", "delete"}); !ok || args[0] != "repo" {
+		t.Errorf("exec gh: got %q, %v", args, ok)
+	}
+	if _, ok := policyArgs(nil, []string{"git", "push"}); ok {
+		t.Error("exec of another program should not be checked")
+	}
+	if _, ok := policyArgs(nil, nil); ok {
+		t.Error("gha env should not be checked")
+	}
+}
+
+func TestCheckPolicy(t *testing.T) {
+	policy := config.Policy{Deny: []string{"repo delete"}, Confirm: []string{"secret set"}}
+
+	err := checkPolicy(policy, []string{"repo", "delete", "acme/app"}, true, strings.NewReader(""), &bytes.Buffer{})
+	var policyErr *policyError
+	if !errors.As(err, &policyErr) || !strings.Contains(err.Error(), `denied by policy: gh repo delete acme/app (rule "repo delete" under policy.deny`) {
+		t.Errorf("deny: err = %v", err)
+	}
+	if code := newErrorReport(err).Code; code != "policy_denied" {
+		t.Errorf("error code = %q, want policy_denied", code)
+	}
+
+	// Without a terminal to ask at, a command needing confirmation is refused.
+	err = checkPolicy(policy, []string{"secret", "set", "TOKEN"}, true, strings.NewReader("y\n"), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "needs confirmation at a terminal") {
+		t.Errorf("confirm: err = %v", err)
+	}
+	// A dry run does not ask, but is still denied.
+	if err := checkPolicy(policy, []string{"secret", "set", "TOKEN"}, false, strings.NewReader(""), &bytes.Buffer{}); err != nil {
+		t.Errorf("confirm in dry run: err = %v", err)
+	}
+	if err := checkPolicy(policy, []string{"repo", "delete"}, false, strings.NewReader(""), &bytes.Buffer{}); err == nil {
+		t.Error("deny in dry run: expected an error")
+	}
+	if err := checkPolicy(policy, []string{"pr", "list"}, true, strings.NewReader(""), &bytes.Buffer{}); err != nil {
+		t.Errorf("unlisted command: err = %v", err)
+	}
+}
+
+func TestRun_PolicyDeny(t *testing.T) {
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+	saveTestConfig(t, &config.Config{
+		AppID:          1,
+		InstallationID: 7,
+		PrivateKeyPath: generateTestKeyFile(t),
+		Policy:         config.Policy{Deny: []string{"repo delete", "api -X DELETE"}},
+	})
+
+	for _, args := range [][]string{
+		{"gha", "repo", "delete", "acme/app", "--yes"},
+		{"gha", "exec", "gh", "api", "-X", "DELETE", "/repos/acme/app"},
+	} {
+		_, stderr, code := runCmd(t, args, "")
+		if code != exitFailure || !strings.Contains(stderr, "error: denied by policy") {
+			t.Errorf("%q: exit code = %d, stderr = %s", args[1:], code, stderr)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+// showRateLimitEnv, set by --show-rate-limit, reports the installation's
+// remaining API quota once the proxied command exits.
+const showRateLimitEnv = "GHA_SHOW_RATE_LIMIT"
+
+// reportRateLimit prints the installation's remaining rate limit and its
+// reset time to stderr. Every token of an installation shares its quota, so
+// when the command outlived token one is minted afresh to ask with. A
+// failure is only a warning: the command itself has already run.
+func reportRateLimit(stderr io.Writer, token *auth.InstallationToken, mint func() (*auth.InstallationToken, error), opts ...auth.Option) {
+	if !time.Now().Before(token.ExpiresAt) {
+		fresh, err := mint()
+		if err != nil {
+			fmt.Fprintf(stderr, "warning: checking the rate limit: %v\n", err)
+			return
+		}
+		token = fresh
+	}
+	limit, err := auth.GetRateLimit(token.Token, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: checking the rate limit: %v\n", err)
+		return
+	}
+	fmt.Fprintf(stderr, "gha: rate limit: %d of %d remaining, resets at %s\n", limit.Remaining, limit.Limit, limit.Reset.Local().Format(time.RFC3339))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+func TestReportRateLimit(t *testing.T) {
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		w.Write([]byte(`{"resources": {"core": {"limit": 5000, "remaining": 4321, "used": 679, "reset": 1700000000}}}`))
+	}))
+	defer srv.Close()
+	mint := func() (*auth.InstallationToken, error) {
+		return &auth.InstallationToken{Token: "ghs_fresh", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	var stderr bytes.Buffer
+	valid := &auth.InstallationToken{Token: "ghs_valid", ExpiresAt: time.Now().Add(time.Hour)}
+	reportRateLimit(&stderr, valid, mint, auth.WithBaseURL(srv.URL))
+	want := "gha: rate limit: 4321 of 5000 remaining, resets at " + time.Unix(1700000000, 0).Format(time.RFC3339) + "\n"
+	if stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+
+	// A token that expired while the command ran is replaced to ask with.
+	expired := &auth.InstallationToken{Token: "ghs_stale", ExpiresAt: time.Now().Add(-time.Minute)}
+	reportRateLimit(&bytes.Buffer{}, expired, mint, auth.WithBaseURL(srv.URL))
+	if got := strings.Join(tokens, ","); got != "ghs_valid,ghs_fresh" {
+		t.Errorf("asked with tokens %s, want ghs_valid,ghs_fresh", got)
+	}
+}
+
+func TestReportRateLimit_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var stderr bytes.Buffer
+	token := &auth.InstallationToken{Token: "ghs_valid", ExpiresAt: time.Now().Add(time.Hour)}
+	reportRateLimit(&stderr, token, nil, auth.WithBaseURL(srv.URL))
+	if !strings.HasPrefix(stderr.String(), "warning: checking the rate limit: ") {
+		t.Errorf("stderr = %q, want a warning", stderr.String())
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+	"github.com/haribote-lab/github-app-cli/internal/fsutil"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+// refreshEnv, set by --refresh, keeps the token fresh while a long-running
+// command runs.
+const refreshEnv = "GHA_REFRESH"
+
+// tokenFileEnv names the file holding the current token in --refresh mode.
+const tokenFileEnv = "GHA_TOKEN_FILE"
+
+// refreshMargin is how long before expiry a token is re-issued, and
+// refreshRetry how long to wait after a failed attempt.
+var (
+	refreshMargin = 5 * time.Minute
+	refreshRetry  = time.Minute
+)
+
+// tokenRefresher keeps a token file current, re-issuing the installation
+// token shortly before it expires until it is closed.
+type tokenRefresher struct {
+	mint   func() (*auth.InstallationToken, error)
+	dir    string
+	stderr io.Writer
+
+	mu    sync.Mutex
+	token *auth.InstallationToken
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startRefresher writes token to a new private token file and starts
+// refreshing it with mint.
+func startRefresher(token *auth.InstallationToken, mint func() (*auth.InstallationToken, error), stderr io.Writer) (*tokenRefresher, error) {
+	dir, err := os.MkdirTemp("", "gha-token-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating token directory: %w", err)
+	}
+	r := &tokenRefresher{mint: mint, dir: dir, stderr: stderr, stop: make(chan struct{}), done: make(chan struct{})}
+	if err := r.set(token); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	go r.loop()
+	return r, nil
+}
+
+// path returns the token file's location.
+func (r *tokenRefresher) path() string {
+	return filepath.Join(r.dir, "token")
+}
+
+// current returns the most recently issued token.
+func (r *tokenRefresher) current() *auth.InstallationToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+func (r *tokenRefresher) set(token *auth.InstallationToken) error {
+	if token.ExpiresAt.IsZero() {
+		token.ExpiresAt = time.Now().Add(time.Hour)
+	}
+	if err := fsutil.WriteFile(r.path(), []byte(token.Token), 0o600); err != nil {
+		return fmt.Errorf("writing token file: %w", err)
+	}
+	r.mu.Lock()
+	r.token = token
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *tokenRefresher) loop() {
+	defer close(r.done)
+	wait := time.Until(r.current().ExpiresAt.Add(-refreshMargin))
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-time.After(wait):
+		}
+		token, err := r.mint()
+		if err == nil {
+			err = r.set(token)
+		}
+		if err != nil {
+			fmt.Fprintf(r.stderr, "warning: refreshing the installation token: %v\n", err)
+			wait = refreshRetry
+			continue
+		}
+		wait = time.Until(token.ExpiresAt.Add(-refreshMargin))
+	}
+}
+
+// close stops refreshing and removes the token file.
+func (r *tokenRefresher) close() {
+	close(r.stop)
+	<-r.done
+	_ = os.RemoveAll(r.dir)
+}
+
+// env returns the variables pointing the command, and any git it runs for
+// host, at the token file.
+func (r *tokenRefresher) env(host string) ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating gha for the git credential helper: %w", err)
+	}
+	// Append to any GIT_CONFIG_* entries already set rather than replace them.
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	key := "credential.https://" + host + ".helper"
+	helper := "!" + shellQuote(exe) + " git-credential --token-file " + shellQuote(r.path())
+	return []string{
+		tokenFileEnv + "=" + r.path(),
+		"GIT_CONFIG_COUNT=" + strconv.Itoa(n+2),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=", n), // an empty helper resets the list
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", n+1, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n+1, helper),
+	}, nil
+}
+
+// repeatableCommands lists, as policy rules, the gh commands that only read
+// and so may be run again once their token has expired. gh api is judged by
+// repeatableAPI instead.
+var repeatableCommands = []string{
+	"run watch", "run view", "run list",
+	"pr checks", "pr view", "pr list", "pr diff", "pr status",
+	"issue view", "issue list", "issue status",
+	"repo view", "repo list", "release view", "release list",
+	"workflow view", "workflow list", "search", "status",
+}
+
+// repeatable reports whether the gh command line args only reads, so that
+// running it a second time cannot repeat a change.
+func repeatable(args []string) bool {
+	if len(args) > 0 && strings.EqualFold(args[0], "api") {
+		return repeatableAPI(longFlags("api", args))
+	}
+	_, ok := matchPolicy(repeatableCommands, args)
+	return ok
+}
+
+// repeatableAPI reports whether the gh api command line args, with long flag
+// names, sends a GET: either it says so with --method, or it has no method,
+// fields or input that would make gh send something else.
+func repeatableAPI(args []string) bool {
+	if hasFlag(args, "--method", "GET") {
+		return true
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		switch name, _, _ := strings.Cut(arg, "="); name {
+		case "--method", "--field", "--raw-field", "--input":
+			return false
+		}
+	}
+	return true
+}
+
+// runRefreshing runs a command through run while a tokenRefresher keeps the
+// token file, and git's credentials for host, current. gh only reads
+// GH_TOKEN when it starts, so with retry set, a command that fails after the
+// token it started with has expired is run once more with a fresh token.
+// Callers only set retry for gh commands that are safe to repeat.
+func runRefreshing(token *auth.InstallationToken, mint func() (*auth.InstallationToken, error), host string, run func(token string, opts ...proxy.Option) (int, error), retry bool, stderr io.Writer) (int, error) {
+	if host == "" {
+		host = config.DefaultHost
+	}
+	r, err := startRefresher(token, mint, stderr)
+	if err != nil {
+		return 0, err
+	}
+	defer r.close()
+	env, err := r.env(host)
+	if err != nil {
+		return 0, err
+	}
+	withEnv := proxy.WithEnv(env...)
+
+	started := r.current()
+	code, err := run(started.Token, withEnv)
+	if err != nil || code == 0 || time.Now().Before(started.ExpiresAt) {
+		return code, err
+	}
+	if !retry {
+		infof(stderr, "gha: the installation token expired while the command ran; it is not run again, as it may not be safe to repeat\n")
+		return code, nil
+	}
+	fresh := r.current()
+	if fresh.Token == started.Token {
+		if fresh, err = mint(); err != nil {
+			return code, nil
+		}
+	}
+	infof(stderr, "gha: the installation token expired while the command ran; retrying with a fresh token\n")
+	return run(fresh.Token, withEnv)
+}
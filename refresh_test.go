@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/proxy"
+)
+
+func TestTokenRefresher(t *testing.T) {
+	oldMargin := refreshMargin
+	refreshMargin = time.Hour - 50*time.Millisecond
+	t.Cleanup(func() { refreshMargin = oldMargin })
+
+	var minted atomic.Int32
+	mint := func() (*auth.InstallationToken, error) {
+		n := minted.Add(1)
+		return &auth.InstallationToken{Token: fmt.Sprintf("ghs_%d", n), ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+	r, err := startRefresher(&auth.InstallationToken{Token: "ghs_0", ExpiresAt: time.Now().Add(time.Hour)}, mint, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := r.path()
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if perm := info.Mode().Perm(); perm&0o077 != 0 && runtime.GOOS != "windows" {
+		t.Errorf("token file mode = %o, want no group or other access", perm)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for minted.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.close()
+	if minted.Load() < 2 {
+		t.Fatalf("minted %d tokens, want the refresher to keep re-issuing", minted.Load())
+	}
+	if got := r.current().Token; got == "ghs_0" {
+		t.Errorf("current token = %q, want a refreshed one", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("token file still exists after close: %v", err)
+	}
+}
+
+func TestTokenRefresher_Env(t *testing.T) {
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	r, err := startRefresher(&auth.InstallationToken{Token: "ghs_0"}, nil, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.close()
+	env, err := r.env("ghe.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(env, "\n")
+	for _, want := range []string{
+		tokenFileEnv + "=" + r.path(),
+		"GIT_CONFIG_COUNT=3",
+		"GIT_CONFIG_KEY_1=credential.https://ghe.example.com.helper",
+		"GIT_CONFIG_VALUE_1=\n",
+		"GIT_CONFIG_KEY_2=credential.https://ghe.example.com.helper",
+		"git-credential --token-file",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("env missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRunRefreshing_RetriesAfterExpiry(t *testing.T) {
+	mint := func() (*auth.InstallationToken, error) {
+		return &auth.InstallationToken{Token: "ghs_fresh", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+	var tokens []string
+	run := func(token string, _ ...proxy.Option) (int, error) {
+		tokens = append(tokens, token)
+		if token == "ghs_stale" {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	var stderr bytes.Buffer
+	expired := &auth.InstallationToken{Token: "ghs_stale", ExpiresAt: time.Now().Add(-time.Minute)}
+	code, err := runRefreshing(expired, mint, "", run, true, &stderr)
+	if err != nil || code != 0 {
+		t.Fatalf("runRefreshing = %d, %v; want 0, nil", code, err)
+	}
+	if want := []string{"ghs_stale", "ghs_fresh"}; strings.Join(tokens, ",") != strings.Join(want, ",") {
+		t.Errorf("ran with tokens %v, want %v", tokens, want)
+	}
+	if !strings.Contains(stderr.String(), "retrying with a fresh token") {
+		t.Errorf("stderr = %q, want a retry notice", stderr.String())
+	}
+}
+
+func TestRunRefreshing_NoRetryBeforeExpiry(t *testing.T) {
+	runs := 0
+	run := func(string, ...proxy.Option) (int, error) {
+		runs++
+		return 3, nil
+	}
+	token := &auth.InstallationToken{Token: "ghs_valid", ExpiresAt: time.Now().Add(time.Hour)}
+	code, err := runRefreshing(token, nil, "", run, true, &bytes.Buffer{})
+	if err != nil || code != 3 || runs != 1 {
+		t.Errorf("runRefreshing = %d, %v after %d runs; want 3, nil after 1", code, err, runs)
+	}
+}
+
+func TestRunRefreshing_NoRetryUnlessRepeatable(t *testing.T) {
+	runs := 0
+	run := func(string, ...proxy.Option) (int, error) {
+		runs++
+		return 1, nil
+	}
+	var stderr bytes.Buffer
+	expired := &auth.InstallationToken{Token: "ghs_stale", ExpiresAt: time.Now().Add(-time.Minute)}
+	mint := func() (*auth.InstallationToken, error) {
+		return &auth.InstallationToken{Token: "ghs_fresh", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+	code, err := runRefreshing(expired, mint, "", run, false, &stderr)
+	if err != nil || code != 1 || runs != 1 {
+		t.Errorf("runRefreshing = %d, %v after %d runs; want 1, nil after 1", code, err, runs)
+	}
+	if !strings.Contains(stderr.String(), "it is not run again") {
+		t.Errorf("stderr = %q, want a notice that the command was not retried", stderr.String())
+	}
+}
+
+func TestRepeatable(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"run", "watch", "123"}, true},
+		{[]string{"pr", "checks", "--watch"}, true},
+		{[]string{"api", "repos/o/r"}, true},
+		{[]string{"api", "-X", "GET", "search/issues", "-f", "q=bug"}, true},
+		{[]string{"api", "--method=get", "repos/o/r"}, true},
+		{[]string{"api", "-X", "POST", "repos/o/r/issues"}, false},
+		{[]string{"api", "-XDELETE", "repos/o/r"}, false},
+		{[]string{"api", "repos/o/r/issues", "-f", "title=x"}, false},
+		{[]string{"api", "graphql", "--input", "q.json"}, false},
+		{[]string{"pr", "merge", "1"}, false},
+		{[]string{"repo", "clone", "o/r"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := repeatable(tt.args); got != tt.want {
+			t.Errorf("repeatable(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunGitCredential(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("ghs_abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	stdin := strings.NewReader("protocol=https\nhost=github.com\n\n")
+	if err := runGitCredential([]string{"--token-file", tokenFile, "get"}, stdin, &stdout, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "username=x-access-token\npassword=ghs_abc\n"; stdout.String() != want {
+		t.Errorf("get = %q, want %q", stdout.String(), want)
+	}
+
+	stdout.Reset()
+	if err := runGitCredential([]string{"--token-file", tokenFile, "erase"}, strings.NewReader("\n"), &stdout, &bytes.Buffer{}); err != nil || stdout.Len() != 0 {
+		t.Errorf("erase = %q, %v; want no output", stdout.String(), err)
+	}
+
+	// Without --token-file a token is minted for the request's host; with
+	// no host there is nothing to answer.
+	if err := runGitCredential([]string{"get"}, strings.NewReader(""), &stdout, &bytes.Buffer{}); err != nil || stdout.Len() != 0 {
+		t.Errorf("get without a host = %q, %v; want no output", stdout.String(), err)
+	}
+	if err := runGitCredential([]string{"--token-file", tokenFile}, strings.NewReader(""), &stdout, &bytes.Buffer{}); err == nil {
+		t.Error("expected a usage error without an operation")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+)
+
+// revokeEnv, set by --revoke, revokes the installation token once the
+// proxied command exits.
+const revokeEnv = "GHA_REVOKE"
+
+// mintedTokens remembers every token issued for one command, the first and
+// any --refresh or the rate limit report minted after it, so that none is
+// left valid once the command is done.
+type mintedTokens struct {
+	mu     sync.Mutex
+	tokens []*auth.InstallationToken
+}
+
+func (m *mintedTokens) add(token *auth.InstallationToken) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens = append(m.tokens, token)
+}
+
+// revoke revokes the tokens that have not expired yet. A failure is only a
+// warning: the command itself has already run, and the token still expires
+// within the hour.
+func (m *mintedTokens) revoke(stderr io.Writer, opts ...auth.Option) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, token := range m.tokens {
+		if !token.ExpiresAt.IsZero() && !now.Before(token.ExpiresAt) {
+			continue
+		}
+		if err := auth.RevokeInstallationToken(token.Token, opts...); err != nil {
+			fmt.Fprintf(stderr, "warning: revoking the installation token: %v\n", err)
+			continue
+		}
+		debugf("revoked the installation token expiring at %s", token.ExpiresAt.Format(time.RFC3339))
+	}
+	m.tokens = nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Revoke(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && r.URL.Path == "/installation/token" {
+			revoked = append(revoked, r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_short", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "gh"), []byte("#!/bin/sh\nexit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+
+	// The token is revoked even when the command fails.
+	if _, stderr, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "--revoke", "--installation-id", "7", "pr", "list"}, ""); code != 3 {
+		t.Fatalf("exit code = %d, want 3; stderr = %s", code, stderr)
+	}
+	if len(revoked) != 1 || revoked[0] != "Bearer ghs_short" {
+		t.Errorf("revoked = %v, want the command's token once", revoked)
+	}
+
+	stdout, _, code := runCmd(t, []string{"gha", "--hostname", "ghe.test", "--revoke", "--dry-run", "--installation-id", "7", "pr", "list"}, "")
+	if code != 0 || !strings.Contains(stdout, "token:        revoked once the command exits") {
+		t.Errorf("dry run: code = %d, stdout = %s", code, stdout)
+	}
+}
+
+func TestMintedTokens_Revoke(t *testing.T) {
+	var revoked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		revoked = append(revoked, token)
+		if token == "ghs_bad" {
+			http.Error(w, `{"message": "Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var minted mintedTokens
+	minted.add(&auth.InstallationToken{Token: "ghs_expired", ExpiresAt: time.Now().Add(-time.Minute)})
+	minted.add(&auth.InstallationToken{Token: "ghs_bad", ExpiresAt: time.Now().Add(time.Hour)})
+	minted.add(&auth.InstallationToken{Token: "ghs_live", ExpiresAt: time.Now().Add(time.Hour)})
+
+	var stderr bytes.Buffer
+	minted.revoke(&stderr, auth.WithBaseURL(srv.URL))
+	if got := strings.Join(revoked, ","); got != "ghs_bad,ghs_live" {
+		t.Errorf("revoked %s, want the unexpired tokens", got)
+	}
+	if !strings.HasPrefix(stderr.String(), "warning: revoking the installation token: ") {
+		t.Errorf("stderr = %q, want a warning for the failed revocation", stderr.String())
+	}
+}
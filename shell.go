@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// shellAccountEnv names the account a "gha shell" session targets, for
+// prompts that want to show it.
+const shellAccountEnv = "GHA_SHELL_ACCOUNT"
+
+// userShell returns the command "gha shell" starts: $SHELL, or the command
+// interpreter on Windows, falling back to /bin/sh.
+func userShell() string {
+	if sh := os.Getenv("SHELL"); sh != "" {
+		return sh
+	}
+	if runtime.GOOS == "windows" {
+		if sh := os.Getenv("COMSPEC"); sh != "" {
+			return sh
+		}
+		return "cmd.exe"
+	}
+	return "/bin/sh"
+}
+
+// shellAccount names the account a shell session targets: the login from
+// target, else the cached login for installationID, else the ID itself.
+func shellAccount(target string, installations map[string]int64, installationID int64) string {
+	if target != "" {
+		return target
+	}
+	for login, id := range installations {
+		if id == installationID {
+			return login
+		}
+	}
+	return fmt.Sprintf("installation %d", installationID)
+}
+
+// shellEnv returns the variables marking a "gha shell" session for account,
+// prefixing the prompt so the session is hard to mistake for a normal one.
+func shellEnv(account string) []string {
+	tag := "(gha:" + account + ") "
+	if runtime.GOOS == "windows" {
+		prompt := os.Getenv("PROMPT")
+		if prompt == "" {
+			prompt = "$P$G"
+		}
+		return []string{shellAccountEnv + "=" + account, "PROMPT=" + tag + prompt}
+	}
+	ps1 := os.Getenv("PS1")
+	if ps1 == "" {
+		ps1 = "$ "
+	}
+	return []string{shellAccountEnv + "=" + account, "PS1=" + tag + ps1}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRun_Shell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake shell uses sh")
+	}
+	setupTestEnv(t)
+	t.Chdir(t.TempDir())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token": "ghs_shell", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	seen := filepath.Join(t.TempDir(), "seen")
+	sh := filepath.Join(t.TempDir(), "fake-shell")
+	script := "#!/bin/sh\nprintf '%s\\n%s\\n%s\\n' \"$GH_TOKEN\" \"$" + shellAccountEnv + "\" \"$PS1\" > " + seen + "\n"
+	if err := os.WriteFile(sh, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SHELL", sh)
+	t.Setenv("PS1", "% ")
+
+	// --isolated runs the shell as a child so the test process survives.
+	saveTestConfig(t, &config.Config{
+		AppID: 1,
+		Hosts: map[string]config.Host{"ghe.test": {AppID: 1, PrivateKeyPath: generateTestKeyFile(t), APIURL: srv.URL}},
+	})
+	_, stderr, code := runCmd(t, []string{"gha", "--isolated", "--hostname", "ghe.test", "--org", "acme", "--installation-id", "7", "shell"}, "")
+	if code != 0 {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	data, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ghs_shell\nacme\n(gha:acme) % \n"; string(data) != want {
+		t.Errorf("shell saw %q, want %q", data, want)
+	}
+
+	_, stderr, code = runCmd(t, []string{"gha", "shell", "extra"}, "")
+	if code == 0 || !strings.Contains(stderr, "usage: gha [flags] shell") {
+		t.Errorf("extra arguments: code = %d, stderr = %s", code, stderr)
+	}
+}
+
+func TestShellAccount(t *testing.T) {
+	installations := map[string]int64{"acme": 7}
+	for _, tt := range []struct {
+		target string
+		id     int64
+		want   string
+	}{
+		{"acme/app", 7, "acme/app"},
+		{"", 7, "acme"},
+		{"", 8, "installation 8"},
+	} {
+		if got := shellAccount(tt.target, installations, tt.id); got != tt.want {
+			t.Errorf("shellAccount(%q, %d) = %q, want %q", tt.target, tt.id, got, tt.want)
+		}
+	}
+}
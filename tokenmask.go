@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io"
+	"regexp"
+)
+
+// tokenPattern matches the credentials gha handles or may come across in a
+// trace: GitHub tokens by their prefixes, and JWTs.
+var tokenPattern = regexp.MustCompile(`\b(?:gh[pousr]_|github_pat_)[A-Za-z0-9_]{8,}|\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// maskToken keeps enough of token to tell it apart from another, its first
+// eight and last four characters, and masks the rest.
+func maskToken(token string) string {
+	if len(token) < 20 {
+		return token[:min(len(token), 4)] + "****"
+	}
+	return token[:8] + "****" + token[len(token)-4:]
+}
+
+// maskTokens masks every token in s.
+func maskTokens(s string) string {
+	return tokenPattern.ReplaceAllStringFunc(s, maskToken)
+}
+
+// maskedWriter masks tokens in what is written to it, so a trace can never
+// carry a usable credential, whatever ends up in it.
+type maskedWriter struct {
+	w io.Writer
+}
+
+func (m maskedWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, maskTokens(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+// maxKeyCandidates caps how many private keys the wizard offers.
+const maxKeyCandidates = 10
+
+// keySearchDirs are where the wizard looks for private keys: the current
+// directory, Downloads, where browsers save a newly generated key, the home
+// directory and the config directory. Tests point it elsewhere.
+var keySearchDirs = func() []string {
+	dirs := []string{"."}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, "Downloads"), home)
+	}
+	if dir, err := config.Dir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// runWizard is gha configure for someone at a terminal. Rather than bare
+// prompts, it explains where to find the App ID, offers the private keys it
+// finds, checks them against GitHub, lists the App's installations to pick
+// from, and can save the App under a profile name as well.
+func runWizard(reader *bufio.Reader, stderr io.Writer, opts ...auth.Option) error {
+	fmt.Fprintln(stderr, "Let's set up gha for your GitHub App.")
+	fmt.Fprintln(stderr)
+	fmt.Fprintln(stderr, "The App ID is shown under About on the App's settings page:")
+	fmt.Fprintf(stderr, "  %s/settings/apps for your own Apps\n", githubWebURL)
+	fmt.Fprintf(stderr, "  %s/organizations/<org>/settings/apps for an organization's\n", githubWebURL)
+	appIDStr, err := prompt(reader, stderr, "App ID: ")
+	if err != nil {
+		return fmt.Errorf("reading App ID: %w", err)
+	}
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	if err != nil || appID <= 0 {
+		return usageErrorf("invalid App ID %q: must be a positive integer", appIDStr)
+	}
+
+	keyPath, err := promptKey(reader, stderr)
+	if err != nil {
+		return err
+	}
+	cfg := &config.Config{AppID: appID, PrivateKeyPath: keyPath}
+
+	fmt.Fprintln(stderr)
+	installID, err := promptInstallation(reader, stderr, cfg, opts...)
+	if err != nil {
+		return err
+	}
+	cfg.InstallationID = installID
+
+	fmt.Fprintln(stderr)
+	profile, err := prompt(reader, stderr, "Profile name, to also select this App with --app (empty to skip): ")
+	if err != nil {
+		return fmt.Errorf("reading profile name: %w", err)
+	}
+	if profile != "" {
+		if !config.ValidAppName(profile) {
+			return usageErrorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", profile)
+		}
+		cfg.Apps = map[string]config.App{profile: {AppID: appID, InstallationID: installID, PrivateKeyPath: keyPath}}
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	path, _ := config.Path()
+	infof(stderr, "Configuration saved to %s\n", path)
+	return nil
+}
+
+// offerWizard, when a command finds no configuration and someone is at the
+// terminal to answer, offers to run the wizard. It reports whether the
+// configuration was then saved, so the command can be run again.
+func offerWizard(stdin io.Reader, stderr io.Writer) bool {
+	if !isTerminal(stdin) || envBool(errorJSONEnv) {
+		return false
+	}
+	reader := bufio.NewReader(stdin)
+	answer, err := prompt(reader, stderr, "gha is not configured yet. Set it up now? [Y/n]: ")
+	if err != nil || answer != "" && !strings.EqualFold(answer, "y") && !strings.EqualFold(answer, "yes") {
+		return false
+	}
+	fmt.Fprintln(stderr)
+	if err := runWizard(reader, stderr); err != nil {
+		reportError(stderr, err)
+		return false
+	}
+	fmt.Fprintln(stderr)
+	return true
+}
+
+// promptKey offers the private keys found on this machine and returns the
+// absolute path of the one picked by number, or of the path typed instead.
+func promptKey(reader *bufio.Reader, stderr io.Writer) (string, error) {
+	keys := findPrivateKeys(keySearchDirs())
+	fmt.Fprintln(stderr)
+	if len(keys) > 0 {
+		fmt.Fprintln(stderr, "Private keys found:")
+		for i, k := range keys {
+			fmt.Fprintf(stderr, "  %d. %s\n", i+1, k)
+		}
+	} else {
+		fmt.Fprintln(stderr, "No private keys (*.pem) were found in the current directory, Downloads or your home directory.")
+	}
+	fmt.Fprintln(stderr, "Generate one under Private keys on the App's settings page if you have none.")
+	answer, err := prompt(reader, stderr, "Private key (number or path): ")
+	if err != nil {
+		return "", fmt.Errorf("reading private key: %w", err)
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(keys) {
+		return keys[n-1], nil
+	}
+	if answer == "" {
+		return "", fmt.Errorf("private key path must not be empty")
+	}
+	if strings.HasPrefix(answer, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			answer = filepath.Join(home, answer[2:])
+		}
+	}
+	info, err := os.Stat(answer)
+	if err != nil {
+		return "", fmt.Errorf("private key file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("private key path is not a regular file: %s", answer)
+	}
+	if abs, err := filepath.Abs(answer); err == nil {
+		answer = abs
+	}
+	return answer, nil
+}
+
+// findPrivateKeys returns the absolute paths of PEM private keys directly
+// in dirs, newest first.
+func findPrivateKeys(dirs []string) []string {
+	type key struct {
+		path    string
+		modTime int64
+	}
+	var keys []key
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+		for _, m := range matches {
+			abs, err := filepath.Abs(m)
+			if err != nil || seen[abs] {
+				continue
+			}
+			seen[abs] = true
+			info, err := os.Stat(abs)
+			if err != nil || !info.Mode().IsRegular() || info.Size() > 64<<10 {
+				continue
+			}
+			if data, err := os.ReadFile(abs); err != nil || !strings.Contains(string(data), "PRIVATE KEY-----") {
+				continue
+			}
+			keys = append(keys, key{abs, info.ModTime().UnixNano()})
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].modTime > keys[j].modTime })
+	paths := make([]string, 0, len(keys))
+	for i, k := range keys {
+		if i == maxKeyCandidates {
+			break
+		}
+		paths = append(paths, k.path)
+	}
+	return paths
+}
+
+// promptInstallation checks the App ID and key against GitHub, then lists
+// the App's installations to pick one by number or ID. Empty picks none, so
+// each command resolves its own. When GitHub cannot be reached the ID is
+// asked for outright.
+func promptInstallation(reader *bufio.Reader, stderr io.Writer, cfg *config.Config, opts ...auth.Option) (int64, error) {
+	opts = append(apiOptions(cfg), opts...)
+	var installations []auth.Installation
+	jwtToken, err := signJWT(cfg)
+	if err == nil {
+		var app *auth.App
+		if app, err = auth.GetApp(jwtToken, opts...); err == nil {
+			fmt.Fprintf(stderr, "Authenticated as %s (owner %s).\n", app.Slug, app.Owner.Login)
+			installations, err = auth.GetInstallations(jwtToken, opts...)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "warning: could not check the App with GitHub: %v\n", err)
+	}
+
+	switch {
+	case err != nil:
+	case len(installations) == 0:
+		fmt.Fprintln(stderr, "The App is not installed anywhere yet; run gha install once it is configured.")
+		return 0, nil
+	default:
+		fmt.Fprintln(stderr, "Installations:")
+		for i, inst := range installations {
+			fmt.Fprintf(stderr, "  %d. %d %s (%s)\n", i+1, inst.ID, inst.Account.Login, inst.TargetType)
+		}
+	}
+	answer, err := prompt(reader, stderr, "Installation (number or ID, empty to resolve per command): ")
+	if err != nil {
+		return 0, fmt.Errorf("reading installation: %w", err)
+	}
+	if answer == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(answer, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, usageErrorf("invalid installation %q: must be a number from the list or an installation ID", answer)
+	}
+	if n <= int64(len(installations)) {
+		return installations[n-1].ID, nil
+	}
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/haribote-lab/github-app-cli/internal/auth"
+	"github.com/haribote-lab/github-app-cli/internal/config"
+)
+
+func TestRunWizard(t *testing.T) {
+	setupTestEnv(t)
+	keyDir := t.TempDir()
+	keyPath := filepath.Join(keyDir, "bot.2024-05-01.private-key.pem")
+	writeTestKey(t, keyPath)
+	if err := os.WriteFile(filepath.Join(keyDir, "cert.pem"), []byte("-----BEGIN CERTIFICATE-----\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	orig := keySearchDirs
+	keySearchDirs = func() []string { return []string{keyDir} }
+	t.Cleanup(func() { keySearchDirs = orig })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app":
+			w.Write([]byte(`{"id": 123, "slug": "bot", "owner": {"login": "acme"}}`))
+		case "/app/installations":
+			json.NewEncoder(w).Encode([]map[string]any{
+				{"id": 11, "account": map[string]string{"login": "acme"}, "target_type": "Organization"},
+				{"id": 22, "account": map[string]string{"login": "octocat"}, "target_type": "User"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var stderr bytes.Buffer
+	input := bufio.NewReader(strings.NewReader("123\n1\n2\nbot\n"))
+	if err := runWizard(input, &stderr, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("runWizard: %v\n%s", err, stderr.String())
+	}
+	for _, want := range []string{"settings/apps", "1. " + keyPath, "Authenticated as bot (owner acme)", "2. 22 octocat (User)"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, stderr.String())
+		}
+	}
+	if strings.Contains(stderr.String(), "cert.pem") {
+		t.Error("offered a certificate as a private key")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AppID != 123 || cfg.InstallationID != 22 || cfg.PrivateKeyPath != keyPath {
+		t.Errorf("config = %+v, want app 123, installation 22 and the offered key", cfg)
+	}
+	if app := cfg.Apps["bot"]; app.AppID != 123 || app.InstallationID != 22 {
+		t.Errorf("apps.bot = %+v, want the same App", app)
+	}
+}
+
+func TestRunWizard_Offline(t *testing.T) {
+	setupTestEnv(t)
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	writeTestKey(t, keyPath)
+	orig := keySearchDirs
+	keySearchDirs = func() []string { return nil }
+	t.Cleanup(func() { keySearchDirs = orig })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var stderr bytes.Buffer
+	input := bufio.NewReader(strings.NewReader("123\n" + keyPath + "\n456\n\n"))
+	if err := runWizard(input, &stderr, auth.WithBaseURL(srv.URL)); err != nil {
+		t.Fatalf("runWizard: %v\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "warning: could not check the App") {
+		t.Errorf("output lacks a warning:\n%s", stderr.String())
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InstallationID != 456 || len(cfg.Apps) != 0 {
+		t.Errorf("config = %+v, want installation 456 and no profile", cfg)
+	}
+}